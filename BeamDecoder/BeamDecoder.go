@@ -1,7 +1,11 @@
 package BeamDecoder
 
 import (
+	"fmt"
+	"math"
 	"sort"
+	"strings"
+	"unicode/utf8"
 )
 
 // StandardPattern 定义标准字符的时长比例序列
@@ -41,6 +45,13 @@ var Patterns = []StandardPattern{
 	{"Y", []float64{3.0, 1.0, 1.0, 1.0, 3.0, 1.0, 3.0}}, // - . - -
 	{"Z", []float64{3.0, 1.0, 3.0, 1.0, 1.0, 1.0, 1.0}}, // - - . .
 
+	// 带重音的字母 (ITU)，Char 是多字节的 UTF-8 字符串
+	{"É", []float64{1.0, 1.0, 1.0, 1.0, 3.0, 1.0, 1.0, 1.0, 1.0}}, // . . - . .
+	{"Ñ", []float64{3.0, 1.0, 3.0, 1.0, 1.0, 1.0, 3.0, 1.0, 3.0}}, // - - . - -
+	{"Ü", []float64{1.0, 1.0, 1.0, 1.0, 3.0, 1.0, 3.0}},           // . . - -
+	{"Ä", []float64{1.0, 1.0, 3.0, 1.0, 1.0, 1.0, 3.0}},           // . - . -
+	{"Ö", []float64{3.0, 1.0, 3.0, 1.0, 3.0, 1.0, 1.0}},           // - - - .
+
 	// 数字（0-9）
 	{"0", []float64{3.0, 1.0, 3.0, 1.0, 3.0, 1.0, 3.0, 1.0, 3.0}}, // - - - - -
 	{"1", []float64{1.0, 1.0, 3.0, 1.0, 3.0, 1.0, 3.0, 1.0, 3.0}}, // . - - - -
@@ -72,6 +83,43 @@ var Patterns = []StandardPattern{
 	{"\"", []float64{1.0, 1.0, 3.0, 1.0, 1.0, 1.0, 1.0, 1.0, 3.0, 1.0, 1.0}},          // . - . . - . 引号
 	{"$", []float64{1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 3.0, 1.0, 1.0, 1.0, 1.0, 1.0, 3.0}}, // . . . - . . - 美元
 	{"@", []float64{1.0, 1.0, 3.0, 1.0, 3.0, 1.0, 1.0, 1.0, 3.0, 1.0, 1.0}},           // . - - . - . AT符号
+
+	// 没有同码型标点的勤务符号 (Prosigns)，输出为尖括号形式
+	// AR/BT/KN/AS 与 + = ( & 码型相同，默认按标点输出，见 BeamConfig.BracketProsigns
+	// 码型与两个字母连写完全相同 (见 prosignLetters)
+	{"<SK>", []float64{1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 3.0, 1.0, 1.0, 1.0, 3.0}},           // . . . - . -   通联结束
+	{"<BK>", []float64{3.0, 1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 1.0, 3.0, 1.0, 1.0, 1.0, 3.0}}, // - . . . - . - 插话
+}
+
+// prosignPunctuation 与勤务符号码型相同的标点，BracketProsigns 时改为输出勤务符号
+var prosignPunctuation = map[string]string{
+	"+": "<AR>",
+	"=": "<BT>",
+	"(": "<KN>",
+	"&": "<AS>",
+}
+
+// bracketPatterns 返回把 + = ( & 换成 <AR> <BT> <KN> <AS> 的字符模板
+func bracketPatterns() []StandardPattern {
+	out := make([]StandardPattern, len(Patterns))
+	for i, p := range Patterns {
+		if ps, ok := prosignPunctuation[p.Char]; ok {
+			p.Char = ps
+		}
+		out[i] = p
+	}
+	return out
+}
+
+// prosignLetters 勤务符号对应的连写字母对
+// Step 会同时生成勤务符号 (或同码型的标点) 和字母对两种候选，由语言模型决定取哪一个
+var prosignLetters = map[string]string{
+	"<AR>": "AR",
+	"<SK>": "SK",
+	"<BT>": "BT",
+	"<KN>": "KN",
+	"<AS>": "AS",
+	"<BK>": "BK",
 }
 
 // Path 代表一条解码路径（一条时间线）
@@ -81,26 +129,134 @@ type Path struct {
 	TotalScore float64 // 总得分 (Log Probability)
 }
 
+// BeamConfig 束搜索参数：束宽越大越准确，但每步的计算量也越大
+// 嵌入式设备可以用 3 左右，离线重新处理噪声很大的录音可以用 50。
+type BeamConfig struct {
+	BeamWidth      int     // 束宽 (K)，每一轮只保留前 K 个最优解
+	MaxBeamWidth   int     // BeamWidth 的上限
+	PruneThreshold float64 // 允许落后第一名多少分 (Log Probability)
+
+	// LengthMismatchPenalty 信号与字符模板的元素个数不同时，每个丢失或多出的点划扣的分。
+	// 0 (默认) 表示长度不同的字符直接判死；设为正数 (例如 6) 时按对齐打分，
+	// 丢点/多点的字符仍可以由上下文救回 (代价是每步计算量增加，噪声中更容易出现替换)。
+	LengthMismatchPenalty float64
+
+	// LowSNRPruneThreshold 信噪比很低时使用的剪枝阈值，0 (默认) 表示不随信噪比调整。
+	// 开启后 (见 SetSNR)，信噪比从 HighSNRdB 降到 LowSNRdB 的过程中，
+	// 束宽从 BeamWidth 线性放宽到 MaxBeamWidth，剪枝阈值从 PruneThreshold 放宽到此值：
+	// 噪声中一时落后的正确路径不会被过早剪掉，之后还能由上下文救回。
+	LowSNRPruneThreshold float64
+	LowSNRdB             float64 // 低于此信噪比 (dB) 时使用最宽的束，0 = DefaultLowSNRdB
+	HighSNRdB            float64 // 高于此信噪比 (dB) 时使用 BeamWidth 和 PruneThreshold，0 = DefaultHighSNRdB
+
+	// LMWeight 语言模型转移分的权重：路径得分 = 发射分 + LMWeight * 转移分。0 = 1 (声学和语言模型同等对待)。
+	// 信号干净、觉得解码器 "纠正过度" (例如把正确的呼号改成常见单词) 时调小 (例如 0.5)；噪声大时调大。
+	LMWeight float64
+
+	// BracketProsigns 把与标点码型相同的勤务符号输出为尖括号形式：+ = ( & 改为 <AR> <BT> <KN> <AS>。
+	// 默认 (false) 输出标点；<SK> <BK> 没有对应的标点，总是输出尖括号形式。
+	BracketProsigns bool
+}
+
+// DefaultBeamConfig 返回默认的束搜索参数
+func DefaultBeamConfig() BeamConfig {
+	return BeamConfig{
+		BeamWidth:      DefaultBeamWidth,
+		MaxBeamWidth:   MaxBeamWidth,
+		PruneThreshold: PruneThreshold,
+	}
+}
+
+// Validate 检查参数是否合法
+func (c BeamConfig) Validate() error {
+	if c.BeamWidth < 1 {
+		return fmt.Errorf("beam width must be >= 1, got %d", c.BeamWidth)
+	}
+	if c.BeamWidth > c.MaxBeamWidth {
+		return fmt.Errorf("beam width %d exceeds max beam width %d", c.BeamWidth, c.MaxBeamWidth)
+	}
+	if c.PruneThreshold <= 0 {
+		return fmt.Errorf("prune threshold must be > 0, got %f", c.PruneThreshold)
+	}
+	if c.LengthMismatchPenalty < 0 {
+		return fmt.Errorf("length mismatch penalty must be >= 0, got %f", c.LengthMismatchPenalty)
+	}
+	if c.LMWeight < 0 {
+		return fmt.Errorf("LM weight must be >= 0, got %f", c.LMWeight)
+	}
+	if c.LowSNRPruneThreshold < 0 {
+		return fmt.Errorf("low SNR prune threshold must be >= 0, got %f", c.LowSNRPruneThreshold)
+	}
+	if c.LowSNRPruneThreshold > 0 {
+		if c.LowSNRPruneThreshold < c.PruneThreshold {
+			return fmt.Errorf("low SNR prune threshold %f is below prune threshold %f", c.LowSNRPruneThreshold, c.PruneThreshold)
+		}
+		if low, high := c.snrRange(); high <= low {
+			return fmt.Errorf("high SNR %f dB must be above low SNR %f dB", high, low)
+		}
+	}
+	return nil
+}
+
+// lmWeight 返回语言模型权重，0 按 1 处理
+func (c BeamConfig) lmWeight() float64 {
+	if c.LMWeight == 0 {
+		return 1
+	}
+	return c.LMWeight
+}
+
+// snrRange 返回自适应剪枝的信噪比区间 (dB)，0 使用默认值
+func (c BeamConfig) snrRange() (low, high float64) {
+	low, high = c.LowSNRdB, c.HighSNRdB
+	if low == 0 {
+		low = DefaultLowSNRdB
+	}
+	if high == 0 {
+		high = DefaultHighSNRdB
+	}
+	return low, high
+}
+
 // BeamDecoder 维特比束搜索解码器
 type BeamDecoder struct {
-	lm        *LanguageModel
-	beamWidth int    // 束宽 (K)，每一轮只保留前 K 个最优解
-	paths     []Path // 当前活着的所有路径
+	lm    *LanguageModel
+	cfg   BeamConfig
+	paths []Path // 当前活着的所有路径
 
 	//  字符模板库 (你需要填充之前定义的 Patterns)
 	patterns []StandardPattern
 
 	statsAnalyzer *StatisticalAnalyzer // 新增
+
+	// --- 手键模式 ---
+	straightKey bool        // 是否使用 CalculateEmissionScore_StraightKey
+	keyedTiming StatsResult // 上层给出的实测点划统计 (已归一化到 unitTime)
+	weighting   Weighting   // 发报者实测的点划比例，零值 = 标准 1:3 模板
+
+	optionalSpace bool // 下一次 Step 时每条路径同时尝试 "插入空格" 和 "保持连写"，见 OfferSpace
+
+	// --- 自适应剪枝 (见 BeamConfig.LowSNRPruneThreshold) ---
+	snrDB  float64 // 上层测得的信噪比 (dB)
+	hasSNR bool    // 是否调用过 SetSNR；没有时按固定参数剪枝
 }
 
-func NewBeamDecoder(lm *LanguageModel) *BeamDecoder {
+// NewBeamDecoder 使用给定的束搜索参数创建解码器
+func NewBeamDecoder(lm *LanguageModel, cfg BeamConfig) (*BeamDecoder, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	patterns := Patterns // 引用全局的 Patterns
+	if cfg.BracketProsigns {
+		patterns = bracketPatterns()
+	}
 	return &BeamDecoder{
 		lm:            lm,
-		beamWidth:     10,                                                    // 设为 5 到 10 即可，太大了没必要
+		cfg:           cfg,
 		paths:         []Path{{Sentence: "", LastChar: "", TotalScore: 0.0}}, // 初始状态：空路径
-		patterns:      Patterns,
-		statsAnalyzer: NewAnalyzer(20), // 引用全局的 Patterns
-	}
+		patterns:      patterns,
+		statsAnalyzer: NewAnalyzer(20),
+	}, nil
 }
 
 // Step 核心迭代：接收一个新的信号片段，更新所有路径
@@ -115,23 +271,38 @@ func (bd *BeamDecoder) Step(inputSignal []float64) {
 			DahStats: SignalStats{StdDev: 0.4}, // 默认划比较宽容
 		}
 	}
+	// 手键模式：优先使用上层实测的点划统计
+	if bd.straightKey && bd.keyedTiming.Valid {
+		currentStats = bd.keyedTiming
+	}
+	model := newEmissionModel(currentStats, bd.straightKey)
+	model.weighting = bd.weighting
 	// --- 1. 扩展 (Expansion) ---
 	// 对于上一轮保留下来的每一条路径...
-	for _, prevPath := range bd.paths {
+	for _, prevPath := range bd.expansionBases() {
 
 		// 尝试每一个可能的字符 (A-Z, 0-9)
 		for _, pattern := range bd.patterns {
 
 			// A. 计算发射分 (长得像不像?)
-			emitScore := CalculateEmissionScore_Advanced(inputSignal, pattern.Sequence, currentStats)
+			emitScore := model.score(inputSignal, pattern.Sequence, bd.cfg.LengthMismatchPenalty)
 
 			// 性能优化：如果这一步这就已经极其不像了，直接跳过，没必要查表了
 			if emitScore < -50.0 {
 				continue
 			}
 
+			// 勤务符号与连写字母对的码型相同，两种解释都作为候选
+			prosign := pattern.Char
+			if ps, ok := prosignPunctuation[prosign]; ok {
+				prosign = ps
+			}
+			if letters, ok := prosignLetters[prosign]; ok {
+				candidates = append(candidates, bd.letterPairPath(prevPath, letters, emitScore))
+			}
+
 			// B. 计算转移分 (接在这个词后面合不合理?)
-			transScore := bd.lm.GetTransitionScore(prevPath.LastChar, pattern.Char)
+			transScore := bd.transition(prevPath.Sentence, prevPath.LastChar, pattern.Char)
 
 			// C. 生成新候选路径
 			newScore := prevPath.TotalScore + emitScore + transScore
@@ -155,6 +326,59 @@ func (bd *BeamDecoder) Step(inputSignal []float64) {
 	bd.paths = bd.PrunePaths(candidates)
 }
 
+// expansionBases 返回本次 Step 要扩展的路径
+// OfferSpace 之后，每条路径额外带上一个以空格结尾的版本 (加上 P(空格|末字符))，
+// 扩展时再加上 P(下一个字符|空格)，与连写的 P(下一个字符|末字符) 直接竞争；
+// 两者结尾字符相同，PrunePaths 的状态去重只保留得分高的一个。
+func (bd *BeamDecoder) expansionBases() []Path {
+	if !bd.optionalSpace {
+		return bd.paths
+	}
+	bd.optionalSpace = false
+	bases := make([]Path, 0, len(bd.paths)*2)
+	for _, p := range bd.paths {
+		bases = append(bases, p)
+		if p.Sentence == "" || p.Sentence[len(p.Sentence)-1] == ' ' {
+			continue
+		}
+		bases = append(bases, Path{
+			Sentence:   p.Sentence + " ",
+			LastChar:   " ",
+			TotalScore: p.TotalScore + bd.transition(p.Sentence, p.LastChar, " "),
+		})
+	}
+	return bases
+}
+
+// OfferSpace 可选的单词间隔：不像 InjectSpace 那样强制插入空格，而是在下一个字符到来时
+// 由语言模型决定。用于稍长于字符间隔、可能只是手键停顿的空窗 (避免 "PARIS" 被拆成 "PAR IS")。
+func (bd *BeamDecoder) OfferSpace() {
+	bd.optionalSpace = true
+}
+
+// letterPairPath 将勤务符号的码型解释为两个连写的字母 (例如 <SK> -> "SK")
+// 发射分相同，转移分逐个字母累加，由语言模型与勤务符号本身竞争
+func (bd *BeamDecoder) letterPairPath(prev Path, letters string, emitScore float64) Path {
+	score := prev.TotalScore + emitScore
+	sentence, last := prev.Sentence, prev.LastChar
+	for _, r := range letters {
+		score += bd.transition(sentence, last, string(r))
+		last = string(r)
+		sentence += last
+	}
+	return Path{
+		Sentence:   sentence,
+		LastChar:   last,
+		TotalScore: score,
+	}
+}
+
+// transition 路径 sentence 之后接 next 的转移分 (已乘 LMWeight)，把当前单词交给语言模型 (呼号模式需要)
+func (bd *BeamDecoder) transition(sentence, last, next string) float64 {
+	token := sentence[strings.LastIndexByte(sentence, ' ')+1:]
+	return bd.cfg.lmWeight() * bd.lm.GetTokenTransitionScore(token, last, next)
+}
+
 // GetResult 获取当前最优解
 func (bd *BeamDecoder) GetResult() string {
 	if len(bd.paths) == 0 {
@@ -163,75 +387,267 @@ func (bd *BeamDecoder) GetResult() string {
 	return bd.paths[0].Sentence
 }
 
+// StablePrefix 返回所有候选路径的公共前缀
+// 之后的路径都由当前路径延伸而来，这部分文本不会再被修正
+func (bd *BeamDecoder) StablePrefix() string {
+	if len(bd.paths) == 0 {
+		return ""
+	}
+	prefix := bd.paths[0].Sentence
+	for _, p := range bd.paths[1:] {
+		n := 0
+		for n < len(prefix) && n < len(p.Sentence) && prefix[n] == p.Sentence[n] {
+			n++
+		}
+		// 不在多字节字符 (例如 É) 的中间截断
+		for n > 0 && n < len(prefix) && !utf8.RuneStart(prefix[n]) {
+			n--
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
+}
+
+// CommitBest 把当前最优解定为结论：丢弃与它不一致的候选，返回最优解
+func (bd *BeamDecoder) CommitBest() string {
+	best := bd.GetResult()
+	kept := bd.paths[:0]
+	for _, p := range bd.paths {
+		if strings.HasPrefix(p.Sentence, best) {
+			kept = append(kept, p)
+		}
+	}
+	bd.paths = kept
+	return best
+}
+
+// Commit 把所有路径共享的稳定前缀定为结论：从每条路径的 Sentence 中去掉这部分并返回，由调用方保存或输出。
+// 长时间运行 (24 小时值守) 时定期调用，Sentence 只保留尚未确定的尾部，不会无限增长；
+// 之后 GetResult 只返回尚未提交的部分。
+// 只在单词边界 (空格之后) 截断：转移分按当前单词计算，从单词中间截断会改变之后的得分。
+func (bd *BeamDecoder) Commit() string {
+	stable := bd.StablePrefix()
+	n := strings.LastIndexByte(stable, ' ') + 1
+	if n == 0 {
+		return ""
+	}
+	for i := range bd.paths {
+		// 复制尾部，旧的长字符串才能被回收
+		bd.paths[i].Sentence = strings.Clone(bd.paths[i].Sentence[n:])
+	}
+	return stable[:n]
+}
+
+// 发射分的 sigma 限幅
+const (
+	machineSigmaMin = 0.35 // 机器键：防止 sigma 过小导致得分负无穷
+	machineSigmaMax = 5.0  // 信号极差时 sigma 会变得巨大，所有分数都接近 0，无法区分
+
+	// 手键 (Straight Key) 的点划长度本身就离散，下限放宽到 0.6，
+	// 否则发报者的"摆动"会被当成另一种字符的强证据
+	straightKeySigmaMin = 0.6
+	straightKeySigmaMax = 8.0
+
+	// 实测划/点比低于此值时认为统计尚未分出点划 (例如只收到 "SEIS")，退回 1:3 模板
+	straightKeyMinRatio = 1.5
+)
+
 // CalculateEmissionScore_Advanced
 // signal: 实际收到的归一化时长序列 (e.g. [1.1, 0.9, 3.2])
 // pattern: 字符的标准模板序列 (e.g. [1.0, 1.0, 3.0])
 // stats: 统计分析器给出的当前环境下的点划特征
 func CalculateEmissionScore_Advanced(signal []float64, pattern []float64, stats StatsResult) float64 {
+	return emissionScore(signal, pattern, stats, false, 0)
+}
+
+// CalculateEmissionScore_StraightKey 手键模式的发射分
+// 与 CalculateEmissionScore_Advanced 相同，但 Mark 的期望值取 stats 中实测的点/划均值
+// (归一化到 unitTime) 而不是固定的 1.0/3.0，并使用更宽的 sigma 限幅。
+// 代价：机器键发出的规整信号上，点划之间的区分度会下降，噪声中更容易把点划判错。
+func CalculateEmissionScore_StraightKey(signal []float64, pattern []float64, stats StatsResult) float64 {
+	return emissionScore(signal, pattern, stats, true, 0)
+}
+
+// emissionScore 逐元素计算高斯对数概率之和
+// 长度不同时：lengthPenalty 为 0 直接返回 -1000 (判死)，否则按 emissionModel.aligned 对齐打分
+func emissionScore(signal []float64, pattern []float64, stats StatsResult, straightKey bool, lengthPenalty float64) float64 {
+	return newEmissionModel(stats, straightKey).score(signal, pattern, lengthPenalty)
+}
+
+// emissionModel 一次打分用到的点划统计和 sigma 限幅
+type emissionModel struct {
+	stats       StatsResult
+	sigmaMin    float64
+	sigmaMax    float64
+	useMeasured bool      // 手键：Mark 的期望值取实测均值
+	weighting   Weighting // 校准过的划/点比和间隔/点比 (零值 = 1:3 模板)
+}
 
-	// 1. 长度硬校验
+func newEmissionModel(stats StatsResult, straightKey bool) emissionModel {
+	m := emissionModel{stats: stats, sigmaMin: machineSigmaMin, sigmaMax: machineSigmaMax}
+	if straightKey {
+		m.sigmaMin, m.sigmaMax = straightKeySigmaMin, straightKeySigmaMax
+		m.useMeasured = stats.DitStats.Mean > 0 &&
+			stats.DahStats.Mean >= stats.DitStats.Mean*straightKeyMinRatio
+	}
+	return m
+}
+
+// score 信号与一个字符模板的发射分，长度不同时见 emissionScore
+func (m emissionModel) score(signal []float64, pattern []float64, lengthPenalty float64) float64 {
+	// 1. 长度校验
 	if len(signal) != len(pattern) {
-		return -1000.0 // 极大的惩罚
+		if lengthPenalty <= 0 {
+			return -1000.0 // 极大的惩罚
+		}
+		return m.aligned(signal, pattern, lengthPenalty)
 	}
 
 	totalScore := 0.0
 
 	// 2. 逐个元素比对
 	for i := 0; i < len(signal); i++ {
-		observed := signal[i]  // 实际值
-		expected := pattern[i] // 理论值 (1.0 或 3.0)
+		totalScore += m.term(signal[i], pattern, i)
+	}
 
-		var sigma float64
+	return totalScore
+}
 
-		// 3. 动态选择方差 (Sigma)
-		// 这里假设 pattern 里 1.0 代表点(或空), 3.0 代表划
-		if expected > 2.0 {
-			// 这是一个“划”
-			sigma = stats.DahStats.StdDev
+// term 实际值 observed 对应模板第 i 个元素的高斯对数概率
+func (m emissionModel) term(observed float64, pattern []float64, i int) float64 {
+	expected := pattern[i] // 理论值 (1.0 或 3.0)
+
+	var sigma float64
+
+	// 3. 动态选择方差 (Sigma)
+	// 这里假设 pattern 里 1.0 代表点(或空), 3.0 代表划
+	isDah := pattern[i] > 2.0
+	if isDah {
+		// 这是一个“划”
+		sigma = m.stats.DahStats.StdDev
+	} else {
+		// 这是一个“点” (或者是内部间隔 Gap)
+		sigma = m.stats.DitStats.StdDev
+	}
+
+	// 校准过的发报者：划和内部间隔按实测比例 (点仍为 1.0，unitTime 跟踪的就是点长)
+	if i%2 == 0 && isDah && m.weighting.DahRatio > 0 {
+		expected = m.weighting.DahRatio
+	}
+	if i%2 == 1 && m.weighting.GapRatio > 0 {
+		expected = m.weighting.GapRatio
+	}
+
+	// 手键：Mark (偶数位) 的期望值由实测均值决定
+	if m.useMeasured && i%2 == 0 {
+		if isDah {
+			expected = m.stats.DahStats.Mean
 		} else {
-			// 这是一个“点” (或者是内部间隔 Gap)
-			sigma = stats.DitStats.StdDev
+			expected = m.stats.DitStats.Mean
 		}
+	}
 
-		// --- 鲁棒性保护 (Safety Clamp) ---
-		// 极其重要！防止 sigma 为 0 (导致除零panic) 或 sigma 过小 (导致得分负无穷)
-		// 尤其是在刚开始没统计到足够数据时
-		if sigma < 0.35 {
-			sigma = 0.35
-		}
+	// --- 鲁棒性保护 (Safety Clamp) ---
+	// 极其重要！防止 sigma 为 0 (导致除零panic) 或 sigma 过小 (导致得分负无穷)
+	// 尤其是在刚开始没统计到足够数据时
+	if sigma < m.sigmaMin {
+		sigma = m.sigmaMin
+	}
+	if sigma > m.sigmaMax {
+		sigma = m.sigmaMax
+	}
 
-		// 如果信号质量极差，sigma 可能会变得巨大，导致所有分数都接近 0，无法区分
-		// 可以设置一个上限
-		if sigma > 5.0 {
-			sigma = 5.0
-		}
+	// 4. 计算高斯对数概率
+	// Log(P) ≈ - (x - μ)^2 / (2 * σ^2)
+	// 注意：这里的 μ (mean) 其实就是 observed 和 expected 的差值概念
+	// 但因为我们已经把 signal 归一化了，所以 expected 就是 1.0 或 3.0 (手键模式下为实测均值)
+	// 而 observed 是 实际值 / unitTime
 
-		// 4. 计算高斯对数概率
-		// Log(P) ≈ - (x - μ)^2 / (2 * σ^2)
-		// 注意：这里的 μ (mean) 其实就是 observed 和 expected 的差值概念
-		// 但因为我们已经把 signal 归一化了，所以 expected 就是 1.0 或 3.0
-		// 而 observed 是 实际值 / unitTime
+	diff := observed - expected
 
-		diff := observed - expected
-		termScore := -(diff * diff) / (2.0 * sigma * sigma)
+	// 还可以加上对数正规化项 -log(σ)，但在比较大小时可以省略，
+	// 除非你要比较不同长度的序列。为了严谨建议加上：
+	// termScore -= math.Log(sigma)
 
-		// 还可以加上对数正规化项 -log(σ)，但在比较大小时可以省略，
-		// 除非你要比较不同长度的序列。为了严谨建议加上：
-		// termScore -= math.Log(sigma)
+	return -(diff * diff) / (2.0 * sigma * sigma)
+}
 
-		totalScore += termScore
+// aligned 长度不同时的对齐打分 (动态规划，类似编辑距离)：
+// 信号与模板的元素按 Mark/间隔 交替排列，丢失或多出一个点划相当于跳过一对 (Mark + 相邻间隔)，
+// 每跳过一对扣 penalty，其余元素照常逐个打分，取得分最高的对齐方式。
+// 这样 "...." 丢了一个点收成 "..." 时，H 只比 S 低 penalty 分，语言模型仍有机会把它纠正回来。
+func (m emissionModel) aligned(signal, pattern []float64, penalty float64) float64 {
+	n, k := len(signal), len(pattern)
+	// dp[i][j]: signal[:i] 与 pattern[:j] 对齐的最高得分；i - j 始终为偶数，Mark 只和 Mark 比
+	dp := make([][]float64, n+1)
+	for i := range dp {
+		dp[i] = make([]float64, k+1)
+		for j := range dp[i] {
+			dp[i][j] = math.Inf(-1)
+		}
 	}
-
-	return totalScore
+	dp[0][0] = 0
+	for i := 0; i <= n; i++ {
+		for j := 0; j <= k; j++ {
+			cur := dp[i][j]
+			if math.IsInf(cur, -1) {
+				continue
+			}
+			if i < n && j < k {
+				dp[i+1][j+1] = max(dp[i+1][j+1], cur+m.term(signal[i], pattern, j))
+			}
+			if j+2 <= k { // 信号丢了一对
+				dp[i][j+2] = max(dp[i][j+2], cur-penalty)
+			}
+			if i+2 <= n { // 信号多了一对
+				dp[i+2][j] = max(dp[i+2][j], cur-penalty)
+			}
+		}
+	}
+	if math.IsInf(dp[n][k], -1) {
+		return -1000.0 // 奇偶不同 (不是完整的 Mark/间隔 序列)，无法对齐
+	}
+	return dp[n][k]
 }
 
-// Config 定义剪枝的参数
+// 剪枝参数的默认值 (见 BeamConfig)
 const (
-	MaxBeamWidth   = 20   // K值：每一轮最多保留多少条路径 (建议 20-50)
-	PruneThreshold = 10.0 // 阈值：允许落后第一名多少分 (Log Probability)
+	DefaultBeamWidth = 20   // 默认束宽 (与 BeamConfig 出现之前固定的束宽相同)
+	MaxBeamWidth     = 20   // K值：每一轮最多保留多少条路径 (建议 20-50)
+	PruneThreshold   = 10.0 // 阈值：允许落后第一名多少分 (Log Probability)
 	// 解释：e^-10 ≈ 0.000045。也就是说，如果某条路径的概率不到第一名的万分之四，就杀掉。
 )
 
+// 自适应剪枝的默认信噪比区间 (dB，ExperimentalDecoder.CurrentSNR 的量纲)
+const (
+	DefaultLowSNRdB  = 6.0
+	DefaultHighSNRdB = 20.0
+)
+
+// SetSNR 设置当前测得的信噪比 (dB)，之后的剪枝按它放宽束宽和阈值
+// 只在 BeamConfig.LowSNRPruneThreshold > 0 时生效；+Inf (纯数字静音的底噪) 视为信号很好，NaN 被忽略。
+func (bd *BeamDecoder) SetSNR(db float64) {
+	if math.IsNaN(db) {
+		return
+	}
+	bd.snrDB = db
+	bd.hasSNR = true
+}
+
+// pruneParams 返回当前使用的束宽和剪枝阈值
+func (bd *BeamDecoder) pruneParams() (int, float64) {
+	c := bd.cfg
+	if c.LowSNRPruneThreshold <= 0 || !bd.hasSNR {
+		return c.BeamWidth, c.PruneThreshold
+	}
+	low, high := c.snrRange()
+	// w: 0 = 信号很好 (固定参数)，1 = 信号很差 (最宽)
+	w := math.Max(0, math.Min(1, (high-bd.snrDB)/(high-low)))
+	width := c.BeamWidth + int(math.Round(w*float64(c.MaxBeamWidth-c.BeamWidth)))
+	threshold := c.PruneThreshold + w*(c.LowSNRPruneThreshold-c.PruneThreshold)
+	return width, threshold
+}
+
 // PrunePaths 执行剪枝操作
 // candidates: 刚刚扩展出来的所有候选路径
 // 返回值: 下一轮存活的路径
@@ -253,16 +669,17 @@ func (bd *BeamDecoder) PrunePaths(candidates []Path) []Path {
 	// Key 是 "LastChar" (对于 Bigram) 或者 "LastTwoChars" (对于 Trigram)
 	seenStates := make(map[string]bool)
 
-	survivors := make([]Path, 0, MaxBeamWidth)
+	beamWidth, pruneThreshold := bd.pruneParams()
+	survivors := make([]Path, 0, beamWidth)
 
 	for _, path := range candidates {
 		// 1. 硬限额检查
-		if len(survivors) >= MaxBeamWidth {
+		if len(survivors) >= beamWidth {
 			break
 		}
 
 		// 2. 阈值检查
-		if path.TotalScore < (bestScore - PruneThreshold) {
+		if path.TotalScore < (bestScore - pruneThreshold) {
 			break
 		}
 
@@ -300,7 +717,7 @@ func (bd *BeamDecoder) InjectSpace() {
 
 		// 3. 计算转移分 P(Space | LastChar)
 		// [重要]：你的 bigrams.json 必须包含 " " 键，或者在 LM 里对空格做特殊处理
-		transScore := bd.lm.GetTransitionScore(p.LastChar, " ")
+		transScore := bd.transition(p.Sentence, p.LastChar, " ")
 
 		// 4. 生成新路径
 		newPath := Path{
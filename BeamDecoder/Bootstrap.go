@@ -0,0 +1,86 @@
+package BeamDecoder
+
+import "sort"
+
+// 初始速度估计的安全范围 (ms)
+const (
+	bootstrapMinUnit = 20.0  // 60 WPM
+	bootstrapMaxUnit = 240.0 // 5 WPM
+)
+
+// signalEvent 一次施密特触发器输出 (启动阶段暂存用)
+type signalEvent struct {
+	durationMs float64
+	state      SignalState
+}
+
+// feedBootstrap 启动阶段：暂存输入，收集够 BootstrapMarks 个 Mark 后估计 unitTime，
+// 再把暂存的输入按新速度重放一遍。这样开头几个字符也能按正确速度解码，
+// 而不是等 updateWPM1 从 InitialWPM 慢慢收敛。
+func (d *CWDecoder) feedBootstrap(durationMs float64, state SignalState) string {
+	d.bootstrapEvents = append(d.bootstrapEvents, signalEvent{durationMs, state})
+	if state == StateOn && durationMs > d.glitchThreshold() {
+		d.bootstrapMarks++
+	}
+	if d.bootstrapMarks < d.cfg.BootstrapMarks {
+		return ""
+	}
+	return d.finishBootstrap()
+}
+
+// finishBootstrap 结束启动阶段并重放暂存的输入
+func (d *CWDecoder) finishBootstrap() string {
+	d.bootstrapping = false
+
+	var marks []float64
+	for _, ev := range d.bootstrapEvents {
+		if ev.state == StateOn && ev.durationMs > d.glitchThreshold() {
+			marks = append(marks, ev.durationMs)
+		}
+	}
+	if unit, ok := estimateUnitTime(marks); ok {
+		d.unitTime = unit
+	}
+
+	var result string
+	for _, ev := range d.bootstrapEvents {
+		result = d.feed(ev.durationMs, ev.state)
+	}
+	d.bootstrapEvents = nil
+	return result
+}
+
+// estimateUnitTime 根据一组 Mark 时长估计点长 (1t)
+// 优先使用 StatisticalAnalyzer 的点划聚类；如果样本里点划区分不开
+// (例如全是点)，则只有在最短和最长 Mark 差距明显时才用最短 Mark 兜底。
+func estimateUnitTime(marks []float64) (float64, bool) {
+	if len(marks) < 2 {
+		return 0, false
+	}
+
+	analyzer := NewAnalyzer(len(marks))
+	for _, m := range marks {
+		analyzer.AddObservation(m)
+	}
+
+	var unit float64
+	if stats := analyzer.Analyze(); stats.Valid {
+		unit = stats.DitStats.Mean
+	} else {
+		sorted := make([]float64, len(marks))
+		copy(sorted, marks)
+		sort.Float64s(sorted)
+		if sorted[len(sorted)-1] < sorted[0]*2.0 {
+			return 0, false // 全是同一种元素，无法判断是点还是划
+		}
+		unit = sorted[0]
+	}
+
+	if unit < bootstrapMinUnit {
+		unit = bootstrapMinUnit
+	}
+	if unit > bootstrapMaxUnit {
+		unit = bootstrapMaxUnit
+	}
+	return unit, true
+}
@@ -3,6 +3,8 @@ package BeamDecoder
 import (
 	"fmt"
 	"math"
+	"sort"
+	"strings"
 )
 
 // 定义信号状态
@@ -15,9 +17,23 @@ const (
 
 // DecoderConfig 配置参数
 type DecoderConfig struct {
-	InitialWPM        float64 // 初始猜测速度，推荐 20
-	GlitchThresholdMs float64 // 缝合阈值：小于此值的空窗会被忽略并缝合信号 (推荐 15-30ms)
-	UpdateAlpha       float64 // EMA 平滑因子 (推荐 0.25)
+	InitialWPM        float64    // 初始猜测速度，推荐 20
+	GlitchThresholdMs float64    // 缝合阈值：小于此值的空窗会被忽略并缝合信号 (0 = 随速度自适应，取 unitTime 的 30%)
+	UpdateAlpha       float64    // EMA 平滑因子 (推荐 0.25)
+	BootstrapMarks    int        // 启动时先收集多少个 Mark 估计初始速度 (0 = 关闭，直接使用 InitialWPM，推荐 8)
+	Beam              BeamConfig // 束搜索参数 (为 0 的字段逐个取 DefaultBeamConfig 的值，例如只设 LMWeight)
+
+	// StraightKeyMode 手键模式：放宽发射分的 sigma 限幅，并用实测的点/划均值代替固定的 1:3 模板，
+	// 以容忍手键发报的"摆动"。机器键发出的规整信号上准确率会略有下降，只在接收手键信号时开启。
+	StraightKeyMode bool
+
+	// Weighting 发报者实测的点划比例 (零值 = 标准 1:3)，通常由校准得到，见 Weighting
+	Weighting Weighting
+
+	// IncrementalOutput 增量输出：FeedNew / CheckTimeout 只返回新确定的文本，调用方直接追加即可。
+	// 默认 (false) 返回完整的最优路径，末尾可能被修正 (例如先 "T" 后 "Q")，直接追加会得到 "TQ"。
+	// 增量模式只输出所有候选都一致的部分，因此比最优路径滞后；CheckTimeout 时把最优路径定为结论并全部输出。
+	IncrementalOutput bool
 }
 
 // CWDecoder 解码器核心结构
@@ -29,30 +45,119 @@ type CWDecoder struct {
 	pendingMarkDuration float64
 	lastGapDuration     float64
 
+	// 时间轴 (FeedNew 输入时长的累计，见 FlushIfIdle)
+	streamMs       float64 // 已输入的总时长 (ms)
+	lastMarkEndMs  float64 // 最后一个 Mark 结束的时刻 (ms)
+	markSinceFlush bool    // 上次结算之后是否收到过新的 Mark
+
 	// 结果缓冲
 	charBuffer string
+	emitted    string // 增量模式下已经输出的文本
 
 	statsAnalyzer *StatisticalAnalyzer // 新增
+
+	// --- 间隔速度 (Farnsworth) ---
+	// Farnsworth 发报时字符本身按字符速度发送，字符/单词间隔却按更慢的整体速度拉长，
+	// 因此单词间隔不能用 unitTime 判定，需要独立跟踪字符间隔的时长。
+	spaceAnalyzer *StatisticalAnalyzer // 字符边界处的空窗时长 (ms)
+	charEnded     bool                 // 上一个字符已交给 BeamDecoder，下一个空窗需要计入 spaceAnalyzer
+
+	onSymbol func(sym string, durationMs float64) // 码元回调 (调试用)，见 SetOnSymbol
 	// --- 后端引擎 ---
 	beamDecoder *BeamDecoder
 	// --- 信号缓冲 (Staging Area) ---
 	// 用来存当前正在接收的字符序列，例如 [点, 间隔, 划] 的时长
 	pulseBuffer []float64
+
+	// --- 多发信方检测 (同频交错信标) ---
+	inconsistentRun int  // 时序统计持续无法收敛的 Mark 计数
+	multiSender     bool // 是否怀疑有多个发信方交错发送
+
+	// --- 初始速度估计 (Bootstrap) ---
+	bootstrapping   bool          // 是否仍在收集启动样本
+	bootstrapEvents []signalEvent // 收集期间暂存的全部输入，估计完成后重放
+	bootstrapMarks  int           // 已收集的有效 Mark 数量
+}
+
+// maxPulseElements pulseBuffer 的长度上限 (Mark 与间隔合计)，比最长的合法字符 (13 段) 稍长
+const maxPulseElements = 15
+
+// glitchUnitRatio 自适应缝合阈值占 unitTime 的比例
+// 40 WPM (30ms) -> 9ms，10 WPM (120ms) -> 36ms
+const glitchUnitRatio = 0.3
+
+// 多发信方检测参数
+const (
+	multiSenderMaxCV    = 0.35 // 点或划任一堆的变异系数 (StdDev/Mean) 超过此值视为不一致
+	multiSenderMinRatio = 2.0  // 划/点 均值比的合理下限
+	multiSenderMaxRatio = 4.5  // 划/点 均值比的合理上限
+	multiSenderRunLimit = 20   // 连续不一致多少个 Mark 后报警
+)
+
+// beamConfig 返回实际使用的束搜索参数：BeamWidth / MaxBeamWidth / PruneThreshold 为 0 时取默认值，
+// 其余字段的零值本来就表示默认行为。只设 BeamWidth 时上限至少放宽到 BeamWidth。
+func (c DecoderConfig) beamConfig() BeamConfig {
+	b := c.Beam
+	def := DefaultBeamConfig()
+	if b.BeamWidth == 0 {
+		b.BeamWidth = def.BeamWidth
+	}
+	if b.MaxBeamWidth == 0 {
+		b.MaxBeamWidth = max(def.MaxBeamWidth, b.BeamWidth)
+	}
+	if b.PruneThreshold == 0 {
+		b.PruneThreshold = def.PruneThreshold
+	}
+	return b
 }
 
-// NewCWDecoder 初始化
-func NewCWDecoder(cfg DecoderConfig, lm *LanguageModel) *CWDecoder {
+// Validate 检查束搜索参数和点划比例是否合法
+func (c DecoderConfig) Validate() error {
+	if err := c.beamConfig().Validate(); err != nil {
+		return fmt.Errorf("invalid beam config: %w", err)
+	}
+	if err := c.Weighting.Validate(); err != nil {
+		return fmt.Errorf("invalid weighting: %w", err)
+	}
+	return nil
+}
+
+// NewCWDecoder 初始化，cfg.Beam 为零值时使用 DefaultBeamConfig
+// 束搜索参数或点划比例不合法时返回错误 (不会悄悄换成默认值)
+func NewCWDecoder(cfg DecoderConfig, lm *LanguageModel) (*CWDecoder, error) {
 	// 标准莫尔斯电码计算：WPM = 1200 / unitTime(ms)
 	// 所以 unitTime = 1200 / WPM
 	initialUnit := 1200.0 / cfg.InitialWPM
 
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg.Beam = cfg.beamConfig()
+	beam, err := NewBeamDecoder(lm, cfg.Beam)
+	if err != nil {
+		return nil, err
+	}
+	beam.straightKey = cfg.StraightKeyMode
+	beam.weighting = cfg.Weighting
+
 	return &CWDecoder{
 		cfg:           cfg,
 		unitTime:      initialUnit,
 		statsAnalyzer: NewAnalyzer(10),
-		beamDecoder:   NewBeamDecoder(lm),
+		spaceAnalyzer: NewAnalyzer(10),
+		beamDecoder:   beam,
 		pulseBuffer:   make([]float64, 0, 8), // 预分配，一般字符不超过8段
-	}
+		bootstrapping: cfg.BootstrapMarks > 0,
+	}, nil
+}
+
+// Reset 清空已解码的文本、缓冲的码元和速度统计，回到刚创建时的状态 (保留配置和码元回调)
+func (d *CWDecoder) Reset() {
+	onSymbol := d.onSymbol
+	// d.cfg 创建时已经检查过，不会出错
+	fresh, _ := NewCWDecoder(d.cfg, d.beamDecoder.lm)
+	*d = *fresh
+	d.onSymbol = onSymbol
 }
 
 // Feed 接收来自施密特触发器的序列
@@ -60,18 +165,79 @@ func NewCWDecoder(cfg DecoderConfig, lm *LanguageModel) *CWDecoder {
 // state: StateOn 或 StateOff
 // 返回: 解码出的字符 (如果没有则返回空字符串 "")
 func (d *CWDecoder) FeedNew(durationMs float64, state SignalState) string {
+	d.streamMs += durationMs
+	if state == StateOn {
+		d.lastMarkEndMs = d.streamMs
+		d.markSinceFlush = true
+	}
+	if d.bootstrapping {
+		return d.output(d.feedBootstrap(durationMs, state), false)
+	}
+	return d.output(d.feed(durationMs, state), false)
+}
+
+// output 按输出模式转换结果：默认原样返回完整路径，增量模式返回新确定的部分
+// final 为 true 时把当前最优解定为结论 (信号已经停止)
+func (d *CWDecoder) output(result string, final bool) string {
+	if !d.cfg.IncrementalOutput || result == "" {
+		return result
+	}
+	stable := d.beamDecoder.StablePrefix()
+	if final {
+		stable = d.beamDecoder.CommitBest()
+	}
+	if len(stable) <= len(d.emitted) || !strings.HasPrefix(stable, d.emitted) {
+		return ""
+	}
+	text := stable[len(d.emitted):]
+	d.emitted = stable
+	return text
+}
+
+// Commit 把所有候选一致的完整单词定为结论 (见 BeamDecoder.Commit)，长时间运行时定期调用，避免路径无限增长。
+// 默认模式：返回提交的文本，之后 FeedNew / CheckTimeout / GetBestPath 只包含尚未提交的尾部，
+// 需要完整文本的调用方把每次 Commit 的返回值按顺序拼在前面。
+// 增量模式：已经由 FeedNew / CheckTimeout 输出过的部分不再返回，只返回其余部分，调用方照常追加即可。
+func (d *CWDecoder) Commit() string {
+	committed := d.beamDecoder.Commit()
+	if !d.cfg.IncrementalOutput || committed == "" {
+		return committed
+	}
+	// emitted 与 Sentence 同样从头算起，去掉提交的部分
+	switch {
+	case strings.HasPrefix(d.emitted, committed):
+		d.emitted = d.emitted[len(committed):]
+		return ""
+	case strings.HasPrefix(committed, d.emitted):
+		text := committed[len(d.emitted):]
+		d.emitted = ""
+		return text
+	}
+	// 已输出的文本与提交的不一致 (之后被修正过)，不重复输出
+	d.emitted = ""
+	return ""
+}
+
+// feed 正常解码流程
+func (d *CWDecoder) feed(durationMs float64, state SignalState) string {
 	//fmt.Printf("[feedNew] %.1f  %d\n", durationMs, state)
 	// 第一层：噪声缝合 (保留原有的抗噪逻辑)
 	// --- 1. 噪声过滤与信号缝合 (Noise Stitching) ---
 	if state == StateOff {
 		// 收到空窗：先不处理，暂存起来，看看是不是只是个短毛刺
 		d.lastGapDuration += durationMs
+
+		// 空窗已经超过单词间隔：音频流可能就此结束，不再等下一个 StateOn，
+		// 直接结算最后一个字符。空格仍由下一个 StateOn 按 lastGapDuration 插入。
+		if d.pendingMarkDuration > 0 && d.lastGapDuration > d.unitTime*5.0 {
+			return d.flushPending()
+		}
 		return ""
 	}
 
 	// 现在的 state == StateOn
 	// 检查上一个 Gap 是否非常短（毛刺/断裂）
-	if d.lastGapDuration > 0 && d.lastGapDuration < d.cfg.GlitchThresholdMs {
+	if d.lastGapDuration > 0 && d.lastGapDuration < d.glitchThreshold() {
 		// on ->off-> on 合并为一个on
 		// 【缝合核心】：上个空窗太短了，被视为噪声！
 		// 操作：把“之前的Mark” + “短空窗” + “现在的Mark” 合并成一个大信号
@@ -83,34 +249,7 @@ func (d *CWDecoder) FeedNew(durationMs float64, state SignalState) string {
 	// 当我们收到 StateOn 时，才去结算上一个 Gap 和之前的 Mark
 	// 第二层：处理上一个完整的动作
 	// 1. 如果有之前的 Mark 还没处理，先入库
-	if d.pendingMarkDuration > 0 {
-		// >>> [新增逻辑] 信号去抖 (Mark Filtering) <<<
-		// 只有当信号时长超过阈值时，才被视为有效信号。
-		// 否则它就是一个高电平毛刺 (Spike)，直接丢弃，不污染 Buffer。
-		if d.pendingMarkDuration > d.cfg.GlitchThresholdMs {
-			// 有效信号，更新 WPM 并入库
-			d.updateWPM1(d.pendingMarkDuration)
-			d.AddCode(d.pendingMarkDuration)
-		} else {
-			// [修改逻辑] 这是一个噪声! (e.g. 10ms)
-			// 我们不仅要丢弃它，还要把"它前后的空窗"连起来。
-			noiseDur := d.pendingMarkDuration
-			// 1. 尝试从 Buffer 末尾回溯上一个 Gap
-			// Buffer 结构预期是 [Mark, Gap, Mark, Gap...]
-			// 所以如果 Buffer 非空，最后一个元素一定是 Gap
-			if len(d.pulseBuffer) > 0 {
-				prevGap := d.delCode()
-				// 3. 合并时长：前Gap + 噪声 + 后Gap (即当前的 d.lastGapDuration)
-				// 这样 d.lastGapDuration 就变成了真实的物理静音时长
-				d.lastGapDuration += prevGap + noiseDur
-				// fmt.Printf("Noise merged! New Gap: %.1f\n", d.lastGapDuration)
-			} else {
-				// 如果 Buffer 是空的，说明噪声出现在字符开头
-				// 直接把噪声时长加到当前的 Gap 里即可
-				d.lastGapDuration += noiseDur
-			}
-		}
-	}
+	d.commitPendingMark()
 
 	// 2. 检查上一个 Gap 是什么性质？(字符内间隔 vs 字符间间隔)
 	// 阈值通常设为 2.5 * unitTime
@@ -118,15 +257,29 @@ func (d *CWDecoder) FeedNew(durationMs float64, state SignalState) string {
 		// >>> 触发 Beam Search !!! <<<
 		// 发现了一个足够长的空窗，说明 pulseBuffer 里已经攒够了一个完整的字符
 
-		if len(d.pulseBuffer) > 0 {
-			d.beamDecoder.Step(d.pulseBuffer)
-			d.pulseBuffer = d.pulseBuffer[:0] // reset
+		d.stepBeam()
+		charEnded := d.charEnded
+		if charEnded {
+			d.spaceAnalyzer.AddObservation(d.lastGapDuration)
+			d.charEnded = false
 		}
 		// D. 处理空格 (Word Space)
-		// 如果空窗特别长 (比如 > 5.0t)，说明是单词间隔
-		if d.lastGapDuration > d.unitTime*5.0 {
-			// 可以在这里强制 BeamDecoder 提交单词，或者插入一个空格
-			d.beamDecoder.InjectSpace()
+		// 如果空窗特别长 (比如 > 5 个间隔单位)，说明是单词间隔
+		isWordGap := d.lastGapDuration > d.wordGapThreshold()
+		if charEnded {
+			if isWordGap {
+				d.emitSymbol("/", d.lastGapDuration)
+			} else {
+				d.emitSymbol(" ", d.lastGapDuration)
+			}
+		}
+		if isWordGap {
+			if d.lastGapDuration < d.gapUnit()*smartSpaceMaxUnits {
+				// 只比阈值稍长：可能是手键的停顿，由语言模型决定是否断词
+				d.beamDecoder.OfferSpace()
+			} else {
+				d.beamDecoder.InjectSpace()
+			}
 		}
 	} else if d.lastGapDuration > 0 {
 		// 这是一个短 Gap (点划之间的间隔)，也要存进去！
@@ -145,15 +298,135 @@ func (d *CWDecoder) FeedNew(durationMs float64, state SignalState) string {
 	return d.beamDecoder.GetResult()
 }
 
+// glitchThreshold 当前的缝合/去抖阈值 (ms)
+// 显式配置了 GlitchThresholdMs 时直接使用，否则随 unitTime 变化
+func (d *CWDecoder) glitchThreshold() float64 {
+	if d.cfg.GlitchThresholdMs > 0 {
+		return d.cfg.GlitchThresholdMs
+	}
+	return d.unitTime * glitchUnitRatio
+}
+
+// commitPendingMark 结算被扣押的 Mark：有效信号入库，毛刺则并入前后的空窗
+func (d *CWDecoder) commitPendingMark() {
+	if d.pendingMarkDuration <= 0 {
+		return
+	}
+
+	// >>> [新增逻辑] 信号去抖 (Mark Filtering) <<<
+	// 只有当信号时长超过阈值时，才被视为有效信号。
+	// 否则它就是一个高电平毛刺 (Spike)，直接丢弃，不污染 Buffer。
+	if d.pendingMarkDuration > d.glitchThreshold() {
+		// 有效信号，更新 WPM 并入库
+		symbol := d.updateWPM1(d.pendingMarkDuration)
+		d.emitSymbol(symbol, d.pendingMarkDuration)
+		d.AddCode(d.pendingMarkDuration)
+	} else {
+		// [修改逻辑] 这是一个噪声! (e.g. 10ms)
+		// 我们不仅要丢弃它，还要把"它前后的空窗"连起来。
+		noiseDur := d.pendingMarkDuration
+		// 1. 尝试从 Buffer 末尾回溯上一个 Gap
+		// Buffer 结构预期是 [Mark, Gap, Mark, Gap...]
+		// 所以如果 Buffer 非空，最后一个元素一定是 Gap
+		if len(d.pulseBuffer) > 0 {
+			prevGap := d.delCode()
+			// 3. 合并时长：前Gap + 噪声 + 后Gap (即当前的 d.lastGapDuration)
+			// 这样 d.lastGapDuration 就变成了真实的物理静音时长
+			d.lastGapDuration += prevGap + noiseDur
+			// fmt.Printf("Noise merged! New Gap: %.1f\n", d.lastGapDuration)
+		} else {
+			// 如果 Buffer 是空的，说明噪声出现在字符开头
+			// 直接把噪声时长加到当前的 Gap 里即可
+			d.lastGapDuration += noiseDur
+		}
+	}
+	d.pendingMarkDuration = 0
+}
+
+// flushPending 在尾部长静音中结算最后一个字符并返回当前结果
+func (d *CWDecoder) flushPending() string {
+	d.commitPendingMark()
+	d.stepBeam()
+	return d.beamDecoder.GetResult()
+}
+
+// stepBeam 把 pulseBuffer 中攒好的一个字符交给 BeamDecoder 并清空
+func (d *CWDecoder) stepBeam() {
+	if len(d.pulseBuffer) == 0 {
+		return
+	}
+	if d.cfg.StraightKeyMode {
+		d.beamDecoder.keyedTiming = d.normalizedStats()
+	}
+	d.beamDecoder.Step(d.pulseBuffer)
+	d.pulseBuffer = d.pulseBuffer[:0] // reset
+	d.charEnded = true
+}
+
+// farnsworthMinSamples 字符边界样本少于此数时，长于标准单词间隔 (7t) 的空窗不作为字符间隔的证据
+const farnsworthMinSamples = 3
+
+// gapUnit 间隔速度下的 1t (ms)
+// 字符间隔远多于单词间隔，取最近字符边界空窗的 (下) 中位数作为字符间隔 (3t)；
+// 最慢不低于字符速度 (标准间隔)。样本很少时，超过 7t 的空窗更可能是单词间隔，不予采信。
+func (d *CWDecoder) gapUnit() float64 {
+	samples := d.spaceAnalyzer.Samples()
+	if len(samples) == 0 {
+		return d.unitTime
+	}
+	sort.Float64s(samples)
+	median := samples[(len(samples)-1)/2] // 偶数个样本取偏小的一个，单词间隔只占少数
+	if len(samples) < farnsworthMinSamples && median >= d.unitTime*7.0 {
+		return d.unitTime
+	}
+	return math.Max(d.unitTime, median/3.0)
+}
+
+// SpacingRatio 间隔速度与字符速度之比 (gapUnit / unitTime)
+// 标准发报约为 1.0；明显大于 1 说明是 Farnsworth 发报，
+// 此时整体 (间隔) 速度约为 WPM() / SpacingRatio()，例如 18 WPM 字符速度、比值 3.6 -> 约 5 WPM 的间隔速度。
+func (d *CWDecoder) SpacingRatio() float64 {
+	return d.gapUnit() / d.unitTime
+}
+
+// smartSpaceMaxUnits 长于单词间隔阈值 (5) 但短于此值 (以间隔单位计) 的空窗只作为可选的单词间隔，
+// 标准单词间隔为 7，超过此值的空窗强制插入空格
+const smartSpaceMaxUnits = 6.0
+
+// wordGapThreshold 单词间隔的判定阈值 (ms)：5 个间隔单位，介于字符间隔 (3) 和单词间隔 (7) 之间
+func (d *CWDecoder) wordGapThreshold() float64 {
+	return d.gapUnit() * 5.0
+}
+
+// normalizedStats 把 statsAnalyzer 的点划统计 (ms) 换算成以 unitTime 为 1 的单位，与 pulseBuffer 一致
+func (d *CWDecoder) normalizedStats() StatsResult {
+	stats := d.statsAnalyzer.Analyze()
+	if !stats.Valid {
+		return stats
+	}
+	stats.OptimalThreshold /= d.unitTime
+	stats.DitStats.Mean /= d.unitTime
+	stats.DitStats.StdDev /= d.unitTime
+	stats.DahStats.Mean /= d.unitTime
+	stats.DahStats.StdDev /= d.unitTime
+	return stats
+}
+
 func (d *CWDecoder) AddCode(dur float64) {
 	//fmt.Printf("code %.1f\r\n", dur/d.unitTime)
+	// 一直等不到字符间隔 (长按电键、连续噪声) 时强制结算，防止缓冲无限增长、Step 越来越慢。
+	// 只在追加 Mark (偶数位置) 时检查，去掉末尾的间隔，保证交给 Step 的序列以 Mark 结尾。
+	if n := len(d.pulseBuffer); n%2 == 0 && n > maxPulseElements {
+		d.pulseBuffer = d.pulseBuffer[:n-1]
+		d.stepBeam()
+	}
 	d.pulseBuffer = append(d.pulseBuffer, dur/d.unitTime)
 }
 func (d *CWDecoder) delCode() float64 {
 	lastIndex := len(d.pulseBuffer) - 1
 	prevGap := d.pulseBuffer[lastIndex]
 	d.pulseBuffer = d.pulseBuffer[:lastIndex]
-	fmt.Printf("code del\r\n")
+	logger.Debug("pulse removed")
 	return prevGap * d.unitTime
 }
 
@@ -165,12 +438,14 @@ func (d *CWDecoder) getThreshold(dur float64) (float64, float64) {
 
 	// 2. 获取高阶统计结果
 	stats := d.statsAnalyzer.Analyze()
+	d.trackConsistency(stats)
 
 	var threshold float64
 	var currentAlpha float64
 
 	// 默认参数
-	defaultThreshold := d.unitTime * 2.2
+	// 1:3 时为 2.2，即点划中点再放宽 10%
+	defaultThreshold := d.unitTime * (1 + d.cfg.Weighting.dahRatio()) / 2 * 1.1
 	baseAlpha := d.cfg.UpdateAlpha // 比如 0.25
 
 	if stats.Valid {
@@ -210,13 +485,70 @@ func (d *CWDecoder) getThreshold(dur float64) (float64, float64) {
 	return threshold, currentAlpha
 }
 
+// trackConsistency 检查 Mark 时长统计是否符合单一发信方的模型
+// 单一发信方的点划应聚成两堆，且划约为点的 3 倍；
+// 两个速度不同的信标交错发送时，点和划各自混入两种长度，统计会长期无法收敛。
+func (d *CWDecoder) trackConsistency(stats StatsResult) {
+	if !d.statsAnalyzer.full {
+		return // 样本不足，不做判断
+	}
+
+	consistent := stats.Valid
+	if consistent {
+		ditCV := stats.DitStats.StdDev / stats.DitStats.Mean
+		dahCV := stats.DahStats.StdDev / stats.DahStats.Mean
+		ratio := stats.DahStats.Mean / stats.DitStats.Mean
+		consistent = ditCV <= multiSenderMaxCV && dahCV <= multiSenderMaxCV &&
+			ratio >= multiSenderMinRatio && ratio <= multiSenderMaxRatio
+	}
+
+	if consistent {
+		// 带迟滞地恢复，避免在边界上反复报警
+		if d.inconsistentRun > 0 {
+			d.inconsistentRun--
+		}
+		if d.inconsistentRun == 0 {
+			d.multiSender = false
+		}
+		return
+	}
+
+	if d.inconsistentRun < multiSenderRunLimit {
+		d.inconsistentRun++
+	}
+	if d.inconsistentRun >= multiSenderRunLimit {
+		d.multiSender = true
+	}
+}
+
+// WPM 当前估计的字符速度 (WPM = 1200 / unitTime)
+func (d *CWDecoder) WPM() float64 {
+	return 1200.0 / d.unitTime
+}
+
+// MultipleSendersSuspected 报告当前的元素时序是否与单一发信方不符
+// (例如 QRSS 频段上同一音调的多个信标交错发送)。
+// 注意：解码器本身只维护一个时序模型，出现这种情况时解码结果不可信。
+func (d *CWDecoder) MultipleSendersSuspected() bool {
+	return d.multiSender
+}
+
+// SetSNR 把上层测得的信噪比 (dB) 交给束搜索，信号越差剪枝越宽松
+// 只在 DecoderConfig.Beam.LowSNRPruneThreshold > 0 时生效，见 BeamDecoder.SetSNR。
+func (d *CWDecoder) SetSNR(db float64) {
+	d.beamDecoder.SetSNR(db)
+}
+
 // 简单的 WPM 更新逻辑 (EMA)
-func (d *CWDecoder) updateWPM1(dur float64) {
+// 返回该 Mark 的分类 ("." 或 "-")，仅供码元回调使用，最终判决仍由 BeamDecoder 完成
+func (d *CWDecoder) updateWPM1(dur float64) string {
 	threshold, currentAlpha := d.getThreshold(dur)
 
 	var sampleUnit float64
+	symbol := "."
 	if dur > threshold {
-		sampleUnit = dur / 3.0 // 划是 3t，还原回 1t
+		sampleUnit = dur / d.cfg.Weighting.dahRatio() // 划是 3t (或校准的比例)，还原回 1t
+		symbol = "-"
 	} else {
 		sampleUnit = dur // 点是 1t
 	}
@@ -237,11 +569,24 @@ func (d *CWDecoder) updateWPM1(dur float64) {
 		//fmt.Printf("\u001B[s\u001B[H\u001B[11B DEBUG: Sample=%.1f ms, New UnitTime=%.1f ms (%.1f WPM)\u001B[u \n", sampleUnit, d.unitTime, 1200.0/d.unitTime)
 	}
 	if d.unitTime < 10.0 {
-		fmt.Printf("ERROR: 时间间隔小于10，有问题。先强制到60。Sample=%.1f ms, New UnitTime=%.1f ms (%.1f WPM)\n", sampleUnit, d.unitTime, 1200.0/d.unitTime)
+		logger.Error("unit time below 10 ms, forcing to 60 ms", "sample_ms", sampleUnit, "unit_ms", d.unitTime, "wpm", 1200.0/d.unitTime)
 		d.unitTime = 60.0 // 默认 20 WPM
 	}
 	// 调试日志：你可以打开这个看它如何自适应速度
 	//fmt.Printf("DEBUG: Sample=%.1f ms, New UnitTime=%.1f ms (%.1f WPM)\n", sampleUnit, d.unitTime, 1200.0/d.unitTime)
+	return symbol
+}
+
+// SetOnSymbol 设置码元回调：每个 Mark 分类后报告 "." / "-"，字符边界报告 " " (字符间隔) 或 "/" (单词间隔)
+// 点划分类只是前端的初判，BeamDecoder 会结合整个字符和语言模型重新判决，两者不一致正说明错误出在分类。
+func (d *CWDecoder) SetOnSymbol(callback func(sym string, durationMs float64)) {
+	d.onSymbol = callback
+}
+
+func (d *CWDecoder) emitSymbol(sym string, durationMs float64) {
+	if d.onSymbol != nil {
+		d.onSymbol(sym, durationMs)
+	}
 }
 
 // --- 辅助逻辑 ---
@@ -277,25 +622,87 @@ func (d *CWDecoder) GetBestPath() string {
 	return d.beamDecoder.GetBestPath()
 }
 
-// 在 CWDecoder 中增加这个方法
-func (d *CWDecoder) CheckTimeout() string {
-	// 假设我们定义超时时间为 5倍单位时长 (即单词间隔)
-	//timeout := d.unitTime * 5.0
+// GetNBest 返回分数最高的前 n 条路径 (按分数从高到低)
+// 路径数不足 n 时返回全部
+func (bd *BeamDecoder) GetNBest(n int) []Path {
+	if n <= 0 {
+		return nil
+	}
+	if n > len(bd.paths) {
+		n = len(bd.paths)
+	}
+	// paths 在每次剪枝后已按分数排序，返回副本避免调用者修改内部状态
+	out := make([]Path, n)
+	copy(out, bd.paths[:n])
+	return out
+}
 
-	// 如果手里有存货，且上次 Gap 已经持续很久了
-	// 注意：这需要 Feed 函数配合，记录最后一次 Feed 的时间戳
-	// 这里为了简单，我们假设外部调用这个函数意味着"已经静默很久了"
+// GetAlternatives 返回前 n 个候选解码结果，第一个与 GetBestPath 相同
+// 弱信号下最优结果看起来不对时，可以参考第 2、3 个候选 (例如模糊的呼号)
+func (d *CWDecoder) GetAlternatives(n int) []string {
+	paths := d.beamDecoder.GetNBest(n)
+	out := make([]string, len(paths))
+	for i, p := range paths {
+		out[i] = p.Sentence
+	}
+	return out
+}
+
+// CheckTimeout 信号已经停止 (流结束，或调用方确认已长时间静音) 时结算最后一个字符
+// 触发器只在下一个 Mark 开始时才报告空窗，不调用它最后一个字符会一直悬着。
+// 无条件结算；周期性检查请用 FlushIfIdle。
+func (d *CWDecoder) CheckTimeout() string {
+	d.markSinceFlush = false
+	if d.bootstrapping {
+		// 样本还没收集够，用已有的数据估计速度并重放
+		d.finishBootstrap()
+	}
 	if d.pendingMarkDuration > 0 {
-		// 1. 把扣押的 Mark 放入 Buffer
+		// 1. 把扣押的 Mark 放入 Buffer (不更新速度，按当前 unitTime 粗分点划)
+		if d.pendingMarkDuration > d.unitTime*2.0 {
+			d.emitSymbol("-", d.pendingMarkDuration)
+		} else {
+			d.emitSymbol(".", d.pendingMarkDuration)
+		}
 		d.AddCode(d.pendingMarkDuration)
 		d.pendingMarkDuration = 0
-
-		// 2. 强行触发解码
-		if len(d.pulseBuffer) > 0 {
-			d.beamDecoder.Step(d.pulseBuffer) // 喂给 Beam
-			d.pulseBuffer = d.pulseBuffer[:0] // 清空
-			return d.beamDecoder.GetResult()
-		}
+	}
+	// 2. 强行触发解码
+	if len(d.pulseBuffer) > 0 {
+		d.stepBeam() // 喂给 Beam
+		return d.output(d.beamDecoder.GetResult(), true)
+	}
+	if d.cfg.IncrementalOutput {
+		// 最后一个字符可能已在长空窗中结算，仍要把尚未确定的尾部输出
+		return d.output(d.beamDecoder.GetResult(), true)
 	}
 	return ""
 }
+
+// idleTimeoutUnits FlushIfIdle 的静音超时 (单位数)，即单词间隔
+const idleTimeoutUnits = 7.0
+
+// IdleTimeout FlushIfIdle 判定信号已经停止所需的静音时长 (ms)：unitTime 的 7 倍。
+// 启动估速阶段速度还不可信，按允许的最慢速度 (5 WPM) 计算，避免在字符间隔中提前结束估速。
+func (d *CWDecoder) IdleTimeout() float64 {
+	unit := d.unitTime
+	if d.bootstrapping {
+		unit = bootstrapMaxUnit
+	}
+	return unit * idleTimeoutUnits
+}
+
+// FlushIfIdle 供周期性调用的超时检查：最后一个 Mark 结束后已静音超过 IdleTimeout，
+// 且之后还没有结算过时才结算最后一个字符 (同 CheckTimeout)，否则什么也不做，返回 ""。
+// nowMs 是与 FeedNew 相同时间轴上的当前时刻，即从第一次 FeedNew 起累计的毫秒数
+// (例如已处理的采样数换算成的时间)。
+func (d *CWDecoder) FlushIfIdle(nowMs float64) string {
+	if !d.markSinceFlush || nowMs-d.lastMarkEndMs < d.IdleTimeout() {
+		return ""
+	}
+	if !d.bootstrapping {
+		// Mark 已经完整，与 feed 中长空窗的处理一样正常入库 (参与速度估计和去毛刺)
+		d.commitPendingMark()
+	}
+	return d.CheckTimeout()
+}
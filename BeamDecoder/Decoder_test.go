@@ -2,12 +2,50 @@ package BeamDecoder
 
 import (
 	"fmt"
+	"math"
+	"os"
+	"strings"
 	"testing"
 )
 
+// newEmptyLanguageModel 返回不依赖模型文件的语言模型，所有转移都使用默认分
+func newEmptyLanguageModel() *LanguageModel {
+	return &LanguageModel{
+		LogProbs:    make(map[string]map[string]float64),
+		DefaultProb: math.Log(1e-6),
+	}
+}
+
+// setBigram 设置语言模型中 prev -> next 的概率
+func setBigram(lm *LanguageModel, prev, next string, p float64) {
+	if lm.LogProbs[prev] == nil {
+		lm.LogProbs[prev] = make(map[string]float64)
+	}
+	lm.LogProbs[prev][next] = math.Log(p)
+}
+
+// mustNewCWDecoder 创建解码器，配置不合法时测试失败
+func mustNewCWDecoder(t testing.TB, cfg DecoderConfig, lm *LanguageModel) *CWDecoder {
+	t.Helper()
+	d, err := NewCWDecoder(cfg, lm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// loadTestLanguageModel 加载 NewLanguageModel 使用的模型文件，文件不存在时跳过测试
+func loadTestLanguageModel(t *testing.T) *LanguageModel {
+	t.Helper()
+	if _, err := os.Stat(bigramModelPath); err != nil {
+		t.Skipf("bigram model not available: %v", err)
+	}
+	return NewLanguageModel()
+}
+
 func TestNewCWDecoder(t *testing.T) {
-	lm := NewLanguageModel()
-	decoder := NewCWDecoder(DecoderConfig{
+	lm := loadTestLanguageModel(t)
+	decoder := mustNewCWDecoder(t, DecoderConfig{
 		InitialWPM:        20,   // 初始假设 20 WPM
 		GlitchThresholdMs: 20.0, // 20ms 以下的空窗视为噪声并缝合
 		UpdateAlpha:       0.25,
@@ -42,7 +80,7 @@ func TestNewCWDecoder(t *testing.T) {
 }
 
 func TestLoadModel(t *testing.T) {
-	model := NewLanguageModel()
+	model := loadTestLanguageModel(t)
 	score := model.GetTransitionScore("0", "0")
 	fmt.Printf("%f\r\n", score)
 	score = model.GetTransitionScore("1", "V")
@@ -51,8 +89,8 @@ func TestLoadModel(t *testing.T) {
 
 func TestNewBeamDecoder(t *testing.T) {
 	// 1. 初始化
-	lm := NewLanguageModel() // 只有 Q->U 的概率很高
-	decoder := NewBeamDecoder(lm)
+	lm := loadTestLanguageModel(t) // 只有 Q->U 的概率很高
+	decoder, _ := NewBeamDecoder(lm, DefaultBeamConfig())
 
 	// ==========================================
 	// 信号 1: 比较标准的 Q (--.-)
@@ -103,8 +141,8 @@ func printPaths(paths []Path) {
 }
 
 func TestNewIntegratedCWDecoder(t *testing.T) {
-	lm := NewLanguageModel()
-	cwDecoder := NewCWDecoder(DecoderConfig{
+	lm := loadTestLanguageModel(t)
+	cwDecoder := mustNewCWDecoder(t, DecoderConfig{
 		InitialWPM:        20,   // 初始假设 20 WPM
 		GlitchThresholdMs: 20.0, // 20ms 以下的空窗视为噪声并缝合
 		UpdateAlpha:       0.25,
@@ -190,7 +228,7 @@ func generateSignal(pattern string, wpm float64) []TestInput {
 func TestCWDecoder_Logic(t *testing.T) {
 	// 1. 准备环境
 	// 这是一个简单的 LM，只有 Q->U 的概率高，方便测试 Beam Search
-	lm := NewLanguageModel()
+	lm := loadTestLanguageModel(t)
 
 	// 定义测试用例集
 	tests := []struct {
@@ -434,7 +472,7 @@ func TestCWDecoder_Logic(t *testing.T) {
 	// 3. 执行循环
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			decoder := NewCWDecoder(tt.cfg, lm)
+			decoder := mustNewCWDecoder(t, tt.cfg, lm)
 			var output string
 
 			for _, in := range tt.inputs {
@@ -463,3 +501,1073 @@ func TestCWDecoder_Logic(t *testing.T) {
 		})
 	}
 }
+
+func TestCWDecoder_MultipleSenders(t *testing.T) {
+	cfg := DecoderConfig{InitialWPM: 20, GlitchThresholdMs: 15, UpdateAlpha: 0.25}
+	text := ".--. .- .-. .. ... "
+
+	// 单一发信方：时序稳定，不应报警
+	single := mustNewCWDecoder(t, cfg, newEmptyLanguageModel())
+	for i := 0; i < 6; i++ {
+		for _, in := range generateSignal(text, 20) {
+			single.FeedNew(in.Dur, in.State)
+		}
+	}
+	if single.MultipleSendersSuspected() {
+		t.Error("single sender should not be flagged")
+	}
+
+	// 两个信标 (20 WPM / 9 WPM) 逐字符交错发送
+	mixed := mustNewCWDecoder(t, cfg, newEmptyLanguageModel())
+	chars := strings.Fields(text)
+	for i := 0; i < 6; i++ {
+		for j, c := range chars {
+			wpm := 20.0
+			if j%2 == 1 {
+				wpm = 9.0
+			}
+			for _, in := range generateSignal(c+" ", wpm) {
+				mixed.FeedNew(in.Dur, in.State)
+			}
+		}
+	}
+	if !mixed.MultipleSendersSuspected() {
+		t.Error("interleaved senders should be flagged")
+	}
+}
+
+func TestCWDecoder_BootstrapWPM(t *testing.T) {
+	// 初始假设 30 WPM，实际信号只有 12 WPM
+	inputs := generateSignal(".--. .- .-. .. ... / .--. .- .-. .. ... ", 12)
+
+	decode := func(cfg DecoderConfig) string {
+		decoder := mustNewCWDecoder(t, cfg, newEmptyLanguageModel())
+		for _, in := range inputs {
+			decoder.FeedNew(in.Dur, in.State)
+		}
+		decoder.CheckTimeout()
+		return decoder.GetBestPath()
+	}
+
+	cfg := DecoderConfig{InitialWPM: 30, GlitchThresholdMs: 15, UpdateAlpha: 0.25, BootstrapMarks: 8}
+	if got := decode(cfg); !strings.HasPrefix(got, "PARIS PARIS") {
+		t.Errorf("bootstrap should decode from the first character, got %q", got)
+	}
+
+	// 不做 bootstrap 时开头会出错 (确认测试确实覆盖了预热问题)
+	cfg.BootstrapMarks = 0
+	if got := decode(cfg); strings.HasPrefix(got, "PARIS") {
+		t.Errorf("expected warm-up errors without bootstrap, got %q", got)
+	}
+}
+
+// patternOf 返回 Patterns 中某个字符的标准序列
+func patternOf(t *testing.T, char string) []float64 {
+	for _, p := range Patterns {
+		if p.Char == char {
+			return p.Sequence
+		}
+	}
+	t.Fatalf("pattern %q not found", char)
+	return nil
+}
+
+func TestBeamDecoder_Prosigns(t *testing.T) {
+	// 空语言模型：勤务符号只有一次转移，比字母对少一次默认惩罚
+	// 默认把与标点码型相同的勤务符号输出为标点
+	for _, ps := range []string{"+", "<SK>", "=", "(", "&", "<BK>"} {
+		bd, _ := NewBeamDecoder(newEmptyLanguageModel(), DefaultBeamConfig())
+		bd.Step(patternOf(t, ps))
+		if got := bd.GetResult(); got != ps {
+			t.Errorf("Expected %s, got %q", ps, got)
+		}
+	}
+
+	// BracketProsigns: 同样的码型输出尖括号形式
+	cfg := DefaultBeamConfig()
+	cfg.BracketProsigns = true
+	for _, c := range []struct{ pattern, want string }{
+		{"+", "<AR>"}, {"<SK>", "<SK>"}, {"=", "<BT>"}, {"(", "<KN>"}, {"&", "<AS>"}, {"<BK>", "<BK>"},
+	} {
+		bd, _ := NewBeamDecoder(newEmptyLanguageModel(), cfg)
+		bd.Step(patternOf(t, c.pattern))
+		if got := bd.GetResult(); got != c.want {
+			t.Errorf("BracketProsigns: expected %s, got %q", c.want, got)
+		}
+	}
+	// 全局模板表不受影响
+	if patternOf(t, "=") == nil {
+		t.Error("Patterns should keep the punctuation")
+	}
+}
+
+func TestBeamDecoder_AccentedLetters(t *testing.T) {
+	for _, ch := range []string{"É", "Ñ", "Ü", "Ä", "Ö"} {
+		bd, _ := NewBeamDecoder(newEmptyLanguageModel(), DefaultBeamConfig())
+		bd.Step(patternOf(t, ch))
+		if got := bd.GetResult(); got != ch {
+			t.Errorf("Expected %s, got %q", ch, got)
+		}
+	}
+
+	// 多字节字符作为语言模型的 key：C->A->F->É 的转移被用上
+	lm := newEmptyLanguageModel()
+	lm.LogProbs["F"] = map[string]float64{"É": math.Log(0.9)}
+	lm.LogProbs["É"] = map[string]float64{" ": math.Log(0.9)}
+	if got := lm.GetTransitionScore("F", "É"); got != math.Log(0.9) {
+		t.Errorf("Expected F->É transition from the model, got %f", got)
+	}
+	bd, _ := NewBeamDecoder(lm, DefaultBeamConfig())
+	for _, ch := range []string{"C", "A", "F", "É"} {
+		bd.Step(patternOf(t, ch))
+	}
+	if got := bd.GetResult(); got != "CAFÉ" {
+		t.Errorf("Expected CAFÉ, got %q", got)
+	}
+}
+
+func TestBeamDecoder_ProsignLanguageModelTieBreak(t *testing.T) {
+	// 语料中 "SK" 作为普通字母对非常常见 -> 输出字母
+	lm := newEmptyLanguageModel()
+	setBigram(lm, "A", "S", 0.5)
+	setBigram(lm, "S", "K", 0.5)
+	bd, _ := NewBeamDecoder(lm, DefaultBeamConfig())
+	bd.Step(patternOf(t, "A"))
+	bd.Step(patternOf(t, "<SK>"))
+	if got := bd.GetResult(); got != "ASK" {
+		t.Errorf("Expected letter pair ASK, got %q", got)
+	}
+
+	// 语料中 <SK> 通常跟在空格后 -> 输出勤务符号
+	lm = newEmptyLanguageModel()
+	setBigram(lm, "7", "3", 0.5)
+	setBigram(lm, "3", " ", 0.5)
+	setBigram(lm, " ", "<SK>", 0.2)
+	setBigram(lm, " ", "S", 0.05)
+	setBigram(lm, "S", "K", 0.01)
+	bd, _ = NewBeamDecoder(lm, DefaultBeamConfig())
+	bd.Step(patternOf(t, "7"))
+	bd.Step(patternOf(t, "3"))
+	bd.InjectSpace()
+	bd.Step(patternOf(t, "<SK>"))
+	if got := bd.GetResult(); got != "73 <SK>" {
+		t.Errorf("Expected 73 <SK>, got %q", got)
+	}
+}
+
+func TestBeamDecoder_OfferSpace(t *testing.T) {
+	decode := func(lm *LanguageModel) string {
+		bd, _ := NewBeamDecoder(lm, DefaultBeamConfig())
+		for _, ch := range []string{"P", "A", "R"} {
+			bd.Step(patternOf(t, ch))
+		}
+		bd.OfferSpace()
+		for _, ch := range []string{"I", "S"} {
+			bd.Step(patternOf(t, ch))
+		}
+		return bd.GetResult()
+	}
+
+	// R->I 很常见，R 之后断词、空格之后接 I 都少见 -> 保持连写
+	lm := newEmptyLanguageModel()
+	setBigram(lm, "R", "I", 0.3)
+	setBigram(lm, "R", " ", 0.05)
+	setBigram(lm, " ", "I", 0.02)
+	if got := decode(lm); got != "PARIS" {
+		t.Errorf("Expected PARIS, got %q", got)
+	}
+
+	// 反过来，断词的概率更高 -> 插入空格
+	lm = newEmptyLanguageModel()
+	setBigram(lm, "R", "I", 0.01)
+	setBigram(lm, "R", " ", 0.5)
+	setBigram(lm, " ", "I", 0.2)
+	if got := decode(lm); got != "PAR IS" {
+		t.Errorf("Expected PAR IS, got %q", got)
+	}
+
+	// 可选空格只作用于下一次 Step
+	bd, _ := NewBeamDecoder(newEmptyLanguageModel(), DefaultBeamConfig())
+	bd.OfferSpace()
+	bd.Step(patternOf(t, "E"))
+	if bd.optionalSpace {
+		t.Error("OfferSpace should be consumed by the next Step")
+	}
+	if got := bd.GetResult(); got != "E" {
+		t.Errorf("No space should be offered before the first character, got %q", got)
+	}
+}
+
+func TestLanguageModel_CallsignMode(t *testing.T) {
+	lm := newEmptyLanguageModel()
+	lm.LogProbs["T"] = map[string]float64{"H": math.Log(0.4)}
+
+	if got := lm.GetTokenTransitionScore("KB", "B", "2"); got != lm.DefaultProb {
+		t.Errorf("Callsign mode off: expected default penalty, got %f", got)
+	}
+
+	lm.SetCallsignMode(true)
+	for _, c := range []struct {
+		token, prev, next string
+		flat              bool
+	}{
+		{"KB", "B", "2", true},       // KB2
+		{"KB2", "2", "X", true},      // KB2X
+		{"9", "9", "A", true},        // 9A
+		{"K1ABC", "C", " ", true},    // 呼号结束
+		{"HEL", "L", "L", false},     // 普通单词
+		{"59", "9", "9", false},      // 纯数字
+		{"ABCD", "D", "1", false},    // 数字不在前缀位置
+		{"<SK>", "<SK>", "1", false}, // 勤务符号
+	} {
+		got := lm.GetTokenTransitionScore(c.token, c.prev, c.next)
+		if want := c.flat; (got == callsignFlatScore) != want {
+			t.Errorf("%q + %q: flattened = %v, want %v (score %f)", c.token, c.next, got == callsignFlatScore, want, got)
+		}
+	}
+	// 已有的高概率转移不会被压低
+	if got := lm.GetTokenTransitionScore("T1T", "T", "H"); got != math.Log(0.4) {
+		t.Errorf("Known transition should be kept, got %f", got)
+	}
+
+	// beam 扩展时把当前单词交给语言模型：KB2 路径的得分被抬高
+	score := func(on bool) float64 {
+		lm := newEmptyLanguageModel()
+		lm.SetCallsignMode(on)
+		bd, _ := NewBeamDecoder(lm, DefaultBeamConfig())
+		for _, ch := range []string{"K", "B", "2"} {
+			bd.Step(patternOf(t, ch))
+		}
+		if got := bd.GetResult(); got != "KB2" {
+			t.Fatalf("Expected KB2, got %q", got)
+		}
+		return bd.paths[0].TotalScore
+	}
+	if off, on := score(false), score(true); on <= off {
+		t.Errorf("Callsign mode should raise the KB2 score: off %f, on %f", off, on)
+	}
+}
+
+func TestLanguageModel_HamPriors(t *testing.T) {
+	lm := newEmptyLanguageModel()
+	lm.LogProbs["Q"] = map[string]float64{"U": math.Log(0.9), " ": math.Log(0.5)}
+
+	if got := lm.GetTransitionScore("5", "9"); got != lm.DefaultProb {
+		t.Errorf("Priors off: expected default penalty for 5->9, got %f", got)
+	}
+
+	lm.SetHamPriors(true)
+	for _, c := range []struct{ prev, next string }{
+		{"5", "9"}, {"9", "9"}, {"/", "P"}, {"/", "M"}, {"7", "3"}, {"C", "Q"}, {"1", "2"},
+	} {
+		if got := lm.GetTransitionScore(c.prev, c.next); got <= lm.DefaultProb {
+			t.Errorf("%s->%s should be raised by the ham priors, got %f", c.prev, c.next, got)
+		}
+	}
+	// 语料中更高的统计不会被先验压低
+	if got := lm.GetTransitionScore("Q", " "); got != math.Log(0.5) {
+		t.Errorf("Corpus score should win when higher, got %f", got)
+	}
+	// 与 ham 上下文无关的组合不受影响
+	if got := lm.GetTransitionScore("X", "J"); got != lm.DefaultProb {
+		t.Errorf("X->J should keep the default penalty, got %f", got)
+	}
+
+	// beam 扩展时同样使用先验
+	bd, _ := NewBeamDecoder(lm, DefaultBeamConfig())
+	for _, ch := range []string{"5", "9", "9"} {
+		bd.Step(patternOf(t, ch))
+	}
+	if got := bd.GetResult(); got != "599" {
+		t.Errorf("Expected 599, got %q", got)
+	}
+}
+
+func TestLanguageModel_ProsignAlias(t *testing.T) {
+	lm := newEmptyLanguageModel()
+	lm.LogProbs[" "] = map[string]float64{"=": math.Log(0.1)}
+	lm.LogProbs["="] = map[string]float64{" ": math.Log(0.9)}
+
+	// 模型只有 "=" 的统计时，<BT> 沿用它
+	if got, want := lm.GetTransitionScore(" ", "<BT>"), math.Log(0.1); got != want {
+		t.Errorf("P(<BT>| ) = %f, want %f", got, want)
+	}
+	if got, want := lm.GetTransitionScore("<BT>", " "), math.Log(0.9); got != want {
+		t.Errorf("P( |<BT>) = %f, want %f", got, want)
+	}
+	// 反过来，模型只有 <AR> 的统计时，标点 "+" 沿用它
+	lm.LogProbs["<AR>"] = map[string]float64{" ": math.Log(0.8)}
+	if got, want := lm.GetTransitionScore("+", " "), math.Log(0.8); got != want {
+		t.Errorf("P( |+) = %f, want %f", got, want)
+	}
+	// 没有别名的勤务符号按单词边界处理
+	if got := lm.GetTransitionScore("<SK>", " "); got != 0 {
+		t.Errorf("P( |<SK>) = %f, want 0", got)
+	}
+}
+
+func TestBeamConfig_Validate(t *testing.T) {
+	if err := DefaultBeamConfig().Validate(); err != nil {
+		t.Errorf("Default config should be valid: %v", err)
+	}
+
+	invalid := []BeamConfig{
+		{BeamWidth: 0, MaxBeamWidth: 20, PruneThreshold: 10},
+		{BeamWidth: 30, MaxBeamWidth: 20, PruneThreshold: 10},
+		{BeamWidth: 5, MaxBeamWidth: 20, PruneThreshold: 0},
+		{BeamWidth: 5, MaxBeamWidth: 20, PruneThreshold: 10, LengthMismatchPenalty: -1},
+		{BeamWidth: 5, MaxBeamWidth: 20, PruneThreshold: 10, LMWeight: -1},
+		{BeamWidth: 5, MaxBeamWidth: 20, PruneThreshold: 10, LowSNRPruneThreshold: 5},
+		{BeamWidth: 5, MaxBeamWidth: 20, PruneThreshold: 10, LowSNRPruneThreshold: 20, LowSNRdB: 20, HighSNRdB: 10},
+	}
+	for _, cfg := range invalid {
+		if _, err := NewBeamDecoder(newEmptyLanguageModel(), cfg); err == nil {
+			t.Errorf("Expected error for %+v", cfg)
+		}
+	}
+
+	// 离线处理可以把上限一起调大
+	if _, err := NewBeamDecoder(newEmptyLanguageModel(), BeamConfig{BeamWidth: 50, MaxBeamWidth: 50, PruneThreshold: 20}); err != nil {
+		t.Errorf("Wide beam should be allowed when MaxBeamWidth is raised: %v", err)
+	}
+
+	// 默认束宽保持 BeamConfig 出现之前的 20
+	if got := DefaultBeamConfig().BeamWidth; got != 20 {
+		t.Errorf("Expected default beam width 20, got %d", got)
+	}
+
+	// 只设置部分字段时，其余字段逐个取默认值，设置的字段保留
+	partial := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20, Beam: BeamConfig{LMWeight: 0.5, LengthMismatchPenalty: 6}}, newEmptyLanguageModel())
+	want := DefaultBeamConfig()
+	want.LMWeight, want.LengthMismatchPenalty = 0.5, 6
+	if got := partial.beamDecoder.cfg; got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	wide := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20, Beam: BeamConfig{BeamWidth: 50}}, newEmptyLanguageModel())
+	if got := wide.beamDecoder.cfg; got.BeamWidth != 50 || got.MaxBeamWidth != 50 {
+		t.Errorf("Expected the max beam width to follow BeamWidth 50, got %+v", got)
+	}
+
+	// 不合法的配置不会被悄悄换成默认值
+	if _, err := NewCWDecoder(DecoderConfig{InitialWPM: 20, Beam: BeamConfig{BeamWidth: 30, MaxBeamWidth: 20, PruneThreshold: 10}}, newEmptyLanguageModel()); err == nil {
+		t.Error("Expected NewCWDecoder to reject an invalid beam config")
+	}
+	if _, err := NewCWDecoder(DecoderConfig{InitialWPM: 20, Weighting: Weighting{DahRatio: 0.5}}, newEmptyLanguageModel()); err == nil {
+		t.Error("Expected NewCWDecoder to reject an invalid weighting")
+	}
+}
+
+func TestBeamDecoder_BeamWidthLimitsPaths(t *testing.T) {
+	for _, width := range []int{1, 3} {
+		bd, err := NewBeamDecoder(newEmptyLanguageModel(), BeamConfig{BeamWidth: width, MaxBeamWidth: 20, PruneThreshold: 1000})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 模糊的中间长度，让很多模板都成为候选
+		bd.Step([]float64{2.0, 1.0, 2.0})
+		bd.Step([]float64{2.0, 1.0, 2.0, 1.0, 2.0})
+		if n := len(bd.paths); n != width {
+			t.Errorf("BeamWidth %d: expected %d surviving paths, got %d", width, width, n)
+		}
+	}
+}
+
+func TestBeamDecoder_GetNBest(t *testing.T) {
+	bd, _ := NewBeamDecoder(newEmptyLanguageModel(), DefaultBeamConfig())
+	// 介于点和划之间的模糊信号，A/N/I/M 都有可能
+	bd.Step([]float64{1.6, 1.0, 2.4})
+
+	paths := bd.GetNBest(3)
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 paths, got %d", len(paths))
+	}
+	if paths[0].Sentence != bd.GetResult() {
+		t.Errorf("First N-best path %q should equal the best result %q", paths[0].Sentence, bd.GetResult())
+	}
+	for i := 1; i < len(paths); i++ {
+		if paths[i].TotalScore > paths[i-1].TotalScore {
+			t.Errorf("Paths not sorted by score: %+v", paths)
+		}
+	}
+
+	// 修改返回值不应影响解码器内部状态
+	paths[0].Sentence = "X"
+	if bd.GetResult() == "X" {
+		t.Error("GetNBest should return a copy")
+	}
+
+	if got := bd.GetNBest(1000); len(got) != len(bd.paths) {
+		t.Errorf("Expected all %d paths, got %d", len(bd.paths), len(got))
+	}
+	if got := bd.GetNBest(0); len(got) != 0 {
+		t.Errorf("Expected no paths for n=0, got %d", len(got))
+	}
+}
+
+func TestCWDecoder_GetAlternatives(t *testing.T) {
+	dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20, GlitchThresholdMs: 15}, newEmptyLanguageModel())
+	dec.FeedNew(1000, StateOff)
+	dec.FeedNew(60, StateOn)
+	dec.FeedNew(60, StateOff)
+	dec.FeedNew(180, StateOn)
+	dec.FeedNew(1000, StateOff)
+	dec.CheckTimeout()
+
+	alts := dec.GetAlternatives(3)
+	if len(alts) == 0 || alts[0] != dec.GetBestPath() {
+		t.Fatalf("First alternative should be the best path, got %v", alts)
+	}
+	if alts[0] != "A" {
+		t.Errorf("Expected best guess A, got %v", alts)
+	}
+}
+
+func TestCWDecoder_TrailingCharacterFlushed(t *testing.T) {
+	dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20, GlitchThresholdMs: 15}, newEmptyLanguageModel())
+
+	// "E" 之后只有 StateOff，流就此结束，没有新的 StateOn，也不调用 CheckTimeout
+	dec.FeedNew(1000, StateOff)
+	dec.FeedNew(60, StateOn)
+
+	var out string
+	for i := 0; i < 5; i++ {
+		if s := dec.FeedNew(100, StateOff); s != "" {
+			out = s
+		}
+	}
+	if out != "E" {
+		t.Errorf("Expected trailing E to be decoded on long silence, got %q", out)
+	}
+
+	// 之后再来信号，E 不能被重复解码，单词间隔照常插入
+	dec.FeedNew(180, StateOn)
+	dec.FeedNew(1000, StateOff)
+	if got := dec.GetBestPath(); got != "E T" {
+		t.Errorf("Expected E T, got %q", got)
+	}
+}
+
+// withDropouts 在每个划的中间插入一个长度为 unit*ratio 的短暂掉电 (模拟 QSB/干扰)
+func withDropouts(inputs []TestInput, wpm, ratio float64) []TestInput {
+	unit := 1200.0 / wpm
+	drop := unit * ratio
+	var out []TestInput
+	for _, in := range inputs {
+		if in.State == StateOn && in.Dur > unit*2 {
+			half := (in.Dur - drop) / 2
+			out = append(out, TestInput{half, StateOn}, TestInput{drop, StateOff}, TestInput{half, StateOn})
+			continue
+		}
+		out = append(out, in)
+	}
+	return out
+}
+
+func TestCWDecoder_AdaptiveGlitchThreshold(t *testing.T) {
+	for _, wpm := range []float64{15, 35} {
+		dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: wpm, UpdateAlpha: 0.25}, newEmptyLanguageModel())
+
+		unit := 1200.0 / wpm
+		if got := dec.glitchThreshold(); math.Abs(got-unit*glitchUnitRatio) > 1e-9 {
+			t.Errorf("%.0f WPM: expected glitch threshold %.1fms, got %.1fms", wpm, unit*glitchUnitRatio, got)
+		}
+
+		// 掉电 = 0.2t，低于 0.3t 的阈值，应被缝合；正常的 1t 码元间隔不能被缝合
+		inputs := append([]TestInput{{1000, StateOff}}, withDropouts(generateSignal(".- -... -.-. / -.. ", wpm), wpm, 0.2)...)
+		for _, in := range inputs {
+			dec.FeedNew(in.Dur, in.State)
+		}
+		dec.FeedNew(1000, StateOff)
+
+		if out := dec.GetBestPath(); out != "ABC D" {
+			t.Errorf("%.0f WPM: expected ABC D with dropouts stitched, got %q", wpm, out)
+		}
+	}
+}
+
+func TestCWDecoder_ExplicitGlitchThreshold(t *testing.T) {
+	dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 35, GlitchThresholdMs: 25}, newEmptyLanguageModel())
+	if got := dec.glitchThreshold(); got != 25 {
+		t.Errorf("Explicit GlitchThresholdMs should win, got %.1f", got)
+	}
+}
+
+func TestCalculateEmissionScore_StraightKey(t *testing.T) {
+	// 手键发报者的划只有点的 2 倍长
+	stats := StatsResult{
+		DitStats: SignalStats{Mean: 1.1, StdDev: 0.2},
+		DahStats: SignalStats{Mean: 2.2, StdDev: 0.3},
+		Valid:    true,
+	}
+	dah, dit := patternOf(t, "T"), patternOf(t, "E")
+	signal := []float64{1.8} // 偏短的划
+
+	if CalculateEmissionScore_Advanced(signal, dit, stats) <= CalculateEmissionScore_Advanced(signal, dah, stats) {
+		t.Fatalf("Fixed 1:3 template should mistake a short dah for E")
+	}
+	if CalculateEmissionScore_StraightKey(signal, dah, stats) <= CalculateEmissionScore_StraightKey(signal, dit, stats) {
+		t.Errorf("Straight-key mode should score a short dah as T")
+	}
+
+	// 统计还没分出点划时退回 1:3 模板
+	flat := StatsResult{DitStats: SignalStats{Mean: 1.0}, DahStats: SignalStats{Mean: 1.2}}
+	if got := CalculateEmissionScore_StraightKey([]float64{3.0}, dah, flat); got != 0 {
+		t.Errorf("Expected fallback to the 1:3 template, got %.2f", got)
+	}
+}
+
+func TestEmissionScore_LengthMismatch(t *testing.T) {
+	stats := StatsResult{
+		DitStats: SignalStats{StdDev: 0.2},
+		DahStats: SignalStats{StdDev: 0.4},
+	}
+	s, h := patternOf(t, "S"), patternOf(t, "H")
+
+	if got := emissionScore(s, h, stats, false, 0); got != -1000 {
+		t.Errorf("strict mode: got %.2f, want -1000", got)
+	}
+	// 完美的 S 对齐到 H：只差一个点，扣一次分
+	if got := emissionScore(s, h, stats, false, 6); math.Abs(got+6) > 1e-9 {
+		t.Errorf("dropped dot: got %.2f, want -6", got)
+	}
+	if got := emissionScore(h, s, stats, false, 6); math.Abs(got+6) > 1e-9 {
+		t.Errorf("inserted dot: got %.2f, want -6", got)
+	}
+	// 丢了一个点的 B (-...) 与 D (-..) 完全对齐，比与 H 对齐 (划被当成点) 得分高
+	b := patternOf(t, "B")
+	d := patternOf(t, "D")
+	if emissionScore(d, b, stats, false, 6) <= emissionScore(d, h, stats, false, 6) {
+		t.Errorf("alignment should prefer B over H for a D signal")
+	}
+	// 不是完整的 Mark/间隔 序列
+	if got := emissionScore([]float64{1, 1}, s, stats, false, 6); got != -1000 {
+		t.Errorf("odd length difference: got %.2f, want -1000", got)
+	}
+}
+
+func TestBeamDecoder_MissingDotRepair(t *testing.T) {
+	lm := newEmptyLanguageModel()
+	setBigram(lm, "T", "H", 0.5)
+	setBigram(lm, "H", "E", 0.5)
+	setBigram(lm, "T", "S", 0.001)
+	setBigram(lm, "S", "E", 0.001)
+
+	// 发送 "THE"，H (....) 丢了一个点，收到 "T S E"
+	decode := func(penalty float64) string {
+		cfg := DefaultBeamConfig()
+		cfg.LengthMismatchPenalty = penalty
+		bd, err := NewBeamDecoder(lm, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []string{"T", "S", "E"} {
+			bd.Step(patternOf(t, c))
+		}
+		return bd.GetResult()
+	}
+	if got := decode(0); got != "TSE" {
+		t.Errorf("strict mode: got %q, want TSE", got)
+	}
+	if got := decode(6); got != "THE" {
+		t.Errorf("length-tolerant mode: got %q, want THE", got)
+	}
+}
+
+func TestBeamDecoder_SNRAdaptivePruning(t *testing.T) {
+	lm := newEmptyLanguageModel()
+	setBigram(lm, "T", "H", 0.5)
+	setBigram(lm, "H", "E", 0.5)
+	setBigram(lm, "E", "H", 0.001)
+
+	// 发送 "THE"，T 的划被噪声削短到 1.6 个单位，第一步看起来更像 E
+	decode := func(cfg BeamConfig, snr float64) string {
+		bd, err := NewBeamDecoder(lm, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !math.IsNaN(snr) {
+			bd.SetSNR(snr)
+		}
+		bd.Step([]float64{1.6})
+		bd.Step(patternOf(t, "H"))
+		bd.Step(patternOf(t, "E"))
+		return bd.GetResult()
+	}
+	tight := BeamConfig{BeamWidth: 2, MaxBeamWidth: 20, PruneThreshold: 3}
+	adaptive := tight
+	adaptive.LowSNRPruneThreshold = 15
+
+	if got := decode(tight, 3); got != "EHE" {
+		t.Errorf("fixed tight pruning: got %q, want EHE", got)
+	}
+	if got := decode(adaptive, math.NaN()); got != "EHE" {
+		t.Errorf("adaptive pruning without SNR: got %q, want EHE", got)
+	}
+	if got := decode(adaptive, 30); got != "EHE" {
+		t.Errorf("adaptive pruning at high SNR: got %q, want EHE", got)
+	}
+	if got := decode(adaptive, 3); got != "THE" {
+		t.Errorf("adaptive pruning at low SNR: got %q, want THE", got)
+	}
+
+	// 区间内线性插值
+	bd, _ := NewBeamDecoder(lm, adaptive)
+	bd.SetSNR((DefaultLowSNRdB + DefaultHighSNRdB) / 2)
+	if width, threshold := bd.pruneParams(); width != 11 || threshold != 9 {
+		t.Errorf("mid SNR: got width %d threshold %.1f, want 11 and 9", width, threshold)
+	}
+	bd.SetSNR(math.Inf(1))
+	if width, threshold := bd.pruneParams(); width != 2 || threshold != 3 {
+		t.Errorf("+Inf SNR: got width %d threshold %.1f, want fixed 2 and 3", width, threshold)
+	}
+}
+
+func TestBeamDecoder_LMWeight(t *testing.T) {
+	lm := newEmptyLanguageModel()
+	setBigram(lm, "T", "I", 0.5)
+	setBigram(lm, "T", "A", 0.001)
+
+	// 发送 "TA"，A 的划偏短 (2.2 个单位)，声学上仍然更像 A，但语言模型更喜欢 "TI"
+	decode := func(weight float64) string {
+		cfg := DefaultBeamConfig()
+		cfg.LMWeight = weight
+		bd, err := NewBeamDecoder(lm, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bd.Step(patternOf(t, "T"))
+		bd.Step([]float64{1, 1, 2.2})
+		return bd.GetResult()
+	}
+	if got := decode(0); got != "TI" {
+		t.Errorf("default weight: got %q, want the LM to correct to TI", got)
+	}
+	if got := decode(0.2); got != "TA" {
+		t.Errorf("LM weight 0.2: got %q, want the acoustic evidence TA", got)
+	}
+}
+
+func TestBeamDecoder_Weighting(t *testing.T) {
+	bd, _ := NewBeamDecoder(newEmptyLanguageModel(), DefaultBeamConfig())
+	if err := bd.SetWeighting(Weighting{DahRatio: 1}); err == nil {
+		t.Error("Expected error for a dah no longer than a dit")
+	}
+	if err := bd.SetWeighting(Weighting{GapRatio: -1}); err == nil {
+		t.Error("Expected error for a negative gap ratio")
+	}
+
+	// 划是点的 4.5 倍：N (-.) 的划按 1:3 模板打分很低，校准后与模板完全吻合
+	stats := StatsResult{DitStats: SignalStats{StdDev: 0.2}, DahStats: SignalStats{StdDev: 0.4}}
+	m := newEmissionModel(stats, false)
+	signal := []float64{4.5, 1, 1}
+	strict := m.score(signal, patternOf(t, "N"), 0)
+	m.weighting = Weighting{DahRatio: 4.5}
+	if calibrated := m.score(signal, patternOf(t, "N"), 0); calibrated != 0 || strict >= calibrated {
+		t.Errorf("Expected the calibrated template to match exactly: strict %.2f, calibrated %.2f", strict, calibrated)
+	}
+}
+
+// withSwing 按比例缩放点和划的时长，模拟手键的"摆动" (点偏长、划偏短)
+func withSwing(inputs []TestInput, wpm, ditScale, dahScale float64) []TestInput {
+	unit := 1200.0 / wpm
+	out := make([]TestInput, len(inputs))
+	for i, in := range inputs {
+		out[i] = in
+		if in.State != StateOn {
+			continue
+		}
+		if in.Dur > unit*2 {
+			out[i].Dur *= dahScale
+		} else {
+			out[i].Dur *= ditScale
+		}
+	}
+	return out
+}
+
+func TestCWDecoder_StraightKeyMode(t *testing.T) {
+	const wpm = 15
+	// CQ DE K1ABC 发两遍，点偏长 20%、划偏短 35% (划/点 ≈ 1.6)
+	pattern := "-.-. --.- / -.. . / -.- .---- .- -... -.-. / -.-. --.- / -.. . / -.- .---- .- -... -.-. "
+	inputs := append([]TestInput{{1000, StateOff}}, withSwing(generateSignal(pattern, wpm), wpm, 1.2, 0.65)...)
+
+	dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: wpm, UpdateAlpha: 0.25, StraightKeyMode: true}, newEmptyLanguageModel())
+	for _, in := range inputs {
+		dec.FeedNew(in.Dur, in.State)
+	}
+	dec.FeedNew(1000, StateOff)
+
+	// 统计需要先收集几个 Mark，只检查热身之后的部分
+	if out := dec.GetBestPath(); !strings.HasSuffix(out, "DE K1ABC CQ DE K1ABC") {
+		t.Errorf("Expected swung keying to decode after warm-up, got %q", out)
+	}
+}
+
+// withFarnsworth 把 generateSignal 生成的字符/单词间隔按 gapWpm 的整体速度拉长 (码元间隔保持字符速度)
+func withFarnsworth(inputs []TestInput, wpm, gapWpm float64) []TestInput {
+	unit := 1200.0 / wpm
+	gapUnit := 1200.0 / gapWpm
+	out := make([]TestInput, len(inputs))
+	for i, in := range inputs {
+		out[i] = in
+		if in.State == StateOff && in.Dur > unit*1.5 {
+			// generateSignal 的字符/单词间隔是 2t/6t 的补充，加上前面的 1t 码元间隔
+			out[i].Dur = (in.Dur/unit+1)*gapUnit - unit
+		}
+	}
+	return out
+}
+
+func TestCWDecoder_FarnsworthSpacing(t *testing.T) {
+	// 与 TestCWDecoder_Logic 的 Case 10 场景相同 (B 发完整)，但不依赖模型文件
+	dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 30, GlitchThresholdMs: 10, UpdateAlpha: 0.25}, newEmptyLanguageModel())
+	inputs := []TestInput{
+		// A，随后 250ms 的空窗 (字符速度下约 6t)
+		{40, StateOn}, {40, StateOff}, {120, StateOn}, {250, StateOff},
+		{120, StateOn}, {40, StateOff}, {40, StateOn}, {40, StateOff}, {40, StateOn}, {40, StateOff}, {40, StateOn}, // B
+	}
+	for _, in := range inputs {
+		dec.FeedNew(in.Dur, in.State)
+	}
+	dec.CheckTimeout()
+	if out := dec.GetBestPath(); out != "AB" {
+		t.Errorf("Expected AB, got %q", out)
+	}
+
+	// 30 WPM 字符速度，15 WPM 间隔速度：字符间隔 240ms，单词间隔 560ms
+	const wpm, gapWpm = 30, 15
+	dec = mustNewCWDecoder(t, DecoderConfig{InitialWPM: wpm, UpdateAlpha: 0.25}, newEmptyLanguageModel())
+	stream := withFarnsworth(generateSignal("-.-. --.-/-.. ./-.- .---- .- -... -.-. ", wpm), wpm, gapWpm)
+	for _, in := range append([]TestInput{{1000, StateOff}}, stream...) {
+		dec.FeedNew(in.Dur, in.State)
+	}
+	dec.FeedNew(2000, StateOff)
+	if out := dec.GetBestPath(); out != "CQ DE K1ABC" {
+		t.Errorf("Expected CQ DE K1ABC with Farnsworth spacing, got %q", out)
+	}
+}
+
+func TestCWDecoder_SpacingRatio(t *testing.T) {
+	measure := func(wpm, gapWpm float64) *CWDecoder {
+		dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: wpm, UpdateAlpha: 0.25}, newEmptyLanguageModel())
+		stream := withFarnsworth(generateSignal("-.-. --.-/-.. ./-.- .---- .- -... -.-. ", wpm), wpm, gapWpm)
+		for _, in := range append([]TestInput{{1000, StateOff}}, stream...) {
+			dec.FeedNew(in.Dur, in.State)
+		}
+		return dec
+	}
+
+	if got := measure(20, 20).SpacingRatio(); math.Abs(got-1) > 0.05 {
+		t.Errorf("Standard spacing: expected ratio ~1.0, got %.2f", got)
+	}
+	// 18/5 WPM 的 Farnsworth: 比值 3.6，间隔速度 WPM/比值 约为 5
+	dec := measure(18, 5)
+	if got := dec.SpacingRatio(); math.Abs(got-3.6) > 0.1 {
+		t.Errorf("Farnsworth 18/5: expected ratio ~3.6, got %.2f", got)
+	}
+	if got := dec.WPM() / dec.SpacingRatio(); math.Abs(got-5) > 0.2 {
+		t.Errorf("Farnsworth 18/5: expected ~5 WPM spacing speed, got %.2f", got)
+	}
+}
+
+func TestCWDecoder_SymbolStream(t *testing.T) {
+	dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20, UpdateAlpha: 0.25}, newEmptyLanguageModel())
+	var symbols strings.Builder
+	dec.SetOnSymbol(func(sym string, durationMs float64) {
+		if durationMs <= 0 {
+			t.Errorf("Symbol %q reported with non-positive duration %.1f", sym, durationMs)
+		}
+		symbols.WriteString(sym)
+	})
+
+	for _, in := range append([]TestInput{{1000, StateOff}}, generateSignal("-.-. --.-/-.. ", 20)...) {
+		dec.FeedNew(in.Dur, in.State)
+	}
+	dec.CheckTimeout()
+
+	// 开头的静音不报告；最后一个字符没有后续 Mark，不报告结尾的间隔
+	if got := symbols.String(); got != "-.-. --.-/-.." {
+		t.Errorf("Expected symbol stream -.-. --.-/-.., got %q", got)
+	}
+}
+
+func TestStatisticalAnalyzer_Incremental(t *testing.T) {
+	a := NewAnalyzer(10)
+	for i := 0; i < 10; i++ {
+		d := 60.0
+		if i%2 == 1 {
+			d = 180.0
+		}
+		a.AddObservation(d)
+	}
+	if !a.Dirty() {
+		t.Fatal("Expected analyzer to be dirty after new observations")
+	}
+	first := a.Analyze()
+	if !first.Valid || a.Dirty() {
+		t.Fatalf("Expected valid result and clean analyzer, got %+v dirty=%v", first, a.Dirty())
+	}
+	if again := a.Analyze(); again != first {
+		t.Errorf("Expected cached result %+v, got %+v", first, again)
+	}
+
+	// 新样本进入窗口后重新计算
+	a.AddObservation(240)
+	if !a.Dirty() {
+		t.Fatal("Expected analyzer to be dirty after AddObservation")
+	}
+	if updated := a.Analyze(); updated.DahStats.Mean <= first.DahStats.Mean {
+		t.Errorf("Expected dah mean to grow after a longer dah, got %.1f (was %.1f)", updated.DahStats.Mean, first.DahStats.Mean)
+	}
+}
+
+func TestFistAnalyzer(t *testing.T) {
+	const paris = ".--. .- .-. .. .../"
+	report := func(inputs []TestInput) FistReport {
+		f := NewFistAnalyzer()
+		f.Feed(1000, StateOff)
+		for _, in := range inputs {
+			f.Feed(in.Dur, in.State)
+		}
+		return f.Report()
+	}
+
+	// 标准手法
+	r := report(generateSignal(strings.Repeat(paris, 3), 20))
+	if !r.Valid {
+		t.Fatalf("Expected a valid report: %v", r)
+	}
+	if math.Abs(r.Weighting-3) > 0.01 || r.DitCV > 0.01 || math.Abs(r.ElementGapRatio-1) > 0.01 || math.Abs(r.CharGapRatio-3) > 0.01 {
+		t.Errorf("Expected a perfect fist, got %+v", r)
+	}
+	if math.Abs(r.WPM-20) > 0.1 {
+		t.Errorf("Expected 20 WPM, got %.1f", r.WPM)
+	}
+	if advice := r.Advice(); len(advice) != 0 {
+		t.Errorf("Expected no advice, got %v", advice)
+	}
+
+	// 划偏长 (4:1)、码元间隔偏紧、点长忽长忽短
+	var heavy []TestInput
+	for i, in := range generateSignal(strings.Repeat(paris, 3), 20) {
+		switch {
+		case in.State == StateOn && in.Dur > 100:
+			in.Dur = 240
+		case in.State == StateOn:
+			in.Dur = 60 * (1 + 0.3*float64(i%3-1))
+		case in.Dur == 60:
+			in.Dur = 36
+		}
+		heavy = append(heavy, in)
+	}
+	r = report(heavy)
+	if !r.Valid || r.Weighting < 3.5 || r.DitCV < 0.15 || r.ElementGapRatio > 0.8 {
+		t.Fatalf("Expected a heavy, uneven, tight fist, got %+v", r)
+	}
+	text := r.String()
+	for _, want := range []string{"dahs are too long", "dit length is uneven", "element spacing is too tight"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in report:\n%s", want, text)
+		}
+	}
+
+	// 样本不足
+	if r := report(generateSignal(".-", 20)); r.Valid {
+		t.Errorf("Expected an invalid report for 2 marks, got %+v", r)
+	}
+}
+
+func TestCWDecoder_IncrementalOutput(t *testing.T) {
+	// CQ DE BG1A
+	inputs := generateSignal("-.-. --.-/-.. ./-... --. .---- .-", 20)
+
+	cfg := DecoderConfig{InitialWPM: 20, GlitchThresholdMs: 15}
+	full := mustNewCWDecoder(t, cfg, newEmptyLanguageModel())
+	cfg.IncrementalOutput = true
+	incr := mustNewCWDecoder(t, cfg, newEmptyLanguageModel())
+
+	var appended string
+	for _, in := range inputs {
+		full.FeedNew(in.Dur, in.State)
+		appended += incr.FeedNew(in.Dur, in.State)
+	}
+	full.CheckTimeout()
+	want := full.GetBestPath()
+	if appended == "" || !strings.HasPrefix(want, appended) {
+		t.Fatalf("Incremental output %q is not a prefix of the final text %q", appended, want)
+	}
+	appended += incr.CheckTimeout()
+	if appended != want {
+		t.Errorf("Appending the incremental output gave %q, want %q", appended, want)
+	}
+	if s := incr.CheckTimeout(); s != "" {
+		t.Errorf("Expected nothing new after the final flush, got %q", s)
+	}
+}
+
+func TestBeamDecoder_StablePrefix(t *testing.T) {
+	bd, _ := NewBeamDecoder(newEmptyLanguageModel(), DefaultBeamConfig())
+	bd.paths = []Path{{Sentence: "CAÉ"}, {Sentence: "CAÈ"}, {Sentence: "CAÉT"}}
+	// É (C3 89) 与 È (C3 88) 的首字节相同，不能只输出半个字符
+	if got := bd.StablePrefix(); got != "CA" {
+		t.Errorf("StablePrefix = %q, want %q", got, "CA")
+	}
+	if got := bd.CommitBest(); got != "CAÉ" || len(bd.paths) != 2 {
+		t.Errorf("CommitBest = %q with %d paths, want CAÉ with 2", got, len(bd.paths))
+	}
+	if got := bd.StablePrefix(); got != "CAÉ" {
+		t.Errorf("StablePrefix after commit = %q, want %q", got, "CAÉ")
+	}
+}
+
+func TestBeamDecoder_Commit(t *testing.T) {
+	bd, _ := NewBeamDecoder(newEmptyLanguageModel(), DefaultBeamConfig())
+	bd.paths = []Path{{Sentence: "CQ DE BG1AB"}, {Sentence: "CQ DE BG1AE"}}
+	// 稳定前缀是 "CQ DE BG1A"，只提交到最后一个完整单词
+	if got := bd.Commit(); got != "CQ DE " {
+		t.Errorf("Commit = %q, want %q", got, "CQ DE ")
+	}
+	if bd.paths[0].Sentence != "BG1AB" || bd.paths[1].Sentence != "BG1AE" {
+		t.Errorf("Sentences after commit = %q, %q", bd.paths[0].Sentence, bd.paths[1].Sentence)
+	}
+	if got := bd.Commit(); got != "" {
+		t.Errorf("second Commit = %q, want nothing", got)
+	}
+}
+
+func TestCWDecoder_CommitLongStream(t *testing.T) {
+	plain := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20}, newEmptyLanguageModel())
+	committing := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20}, newEmptyLanguageModel())
+
+	// 同样的输入，一个定期提交，一个不提交：提交的文本 + 剩余部分应与不提交的结果完全一致
+	var committed strings.Builder
+	maxLen := 0
+	word := generateSignal(".--. .- .-. .. .../", 20) // "PARIS "
+	for i := 0; i < 30; i++ {
+		for _, in := range word {
+			plain.FeedNew(in.Dur, in.State)
+			committing.FeedNew(in.Dur, in.State)
+		}
+		committed.WriteString(committing.Commit())
+		for _, p := range committing.beamDecoder.paths {
+			maxLen = max(maxLen, len(p.Sentence))
+		}
+	}
+	plain.CheckTimeout()
+	committing.CheckTimeout()
+
+	want := plain.GetBestPath()
+	if got := committed.String() + committing.GetBestPath(); got != want {
+		t.Errorf("committed stream %q differs from uncommitted %q", got, want)
+	}
+	if !strings.HasPrefix(want, "PARIS PARIS") {
+		t.Fatalf("unexpected decode %q", want)
+	}
+	if maxLen > 2*len("PARIS ") {
+		t.Errorf("Sentence grew to %d bytes despite Commit", maxLen)
+	}
+}
+
+func TestCWDecoder_CommitIncremental(t *testing.T) {
+	cfg := DecoderConfig{InitialWPM: 20, IncrementalOutput: true}
+	plain := mustNewCWDecoder(t, cfg, newEmptyLanguageModel())
+	committing := mustNewCWDecoder(t, cfg, newEmptyLanguageModel())
+
+	// 增量模式下 Commit 不重复输出已经输出过的文本：把所有输出依次拼接，结果与不提交时相同
+	var want, got strings.Builder
+	word := generateSignal(".--. .- .-. .. .../", 20) // "PARIS "
+	for i := 0; i < 10; i++ {
+		for _, in := range word {
+			want.WriteString(plain.FeedNew(in.Dur, in.State))
+			got.WriteString(committing.FeedNew(in.Dur, in.State))
+		}
+		got.WriteString(committing.Commit())
+	}
+	want.WriteString(plain.CheckTimeout())
+	got.WriteString(committing.CheckTimeout())
+
+	if got.String() != want.String() {
+		t.Errorf("committed incremental stream %q differs from uncommitted %q", got.String(), want.String())
+	}
+	if !strings.HasPrefix(want.String(), "PARIS PARIS") {
+		t.Fatalf("unexpected decode %q", want.String())
+	}
+}
+
+func TestCWDecoder_FlushIfIdle(t *testing.T) {
+	input := generateSignal("-.- / . ", 20) // "K E"，单位 60ms
+	plain := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20}, newEmptyLanguageModel())
+	idle := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20}, newEmptyLanguageModel())
+
+	if got := idle.FlushIfIdle(10000); got != "" {
+		t.Errorf("nothing received yet, FlushIfIdle = %q", got)
+	}
+
+	// 喂到 K 的最后一个 Mark
+	now := 0.0
+	split := 5
+	for _, in := range input[:split] {
+		plain.FeedNew(in.Dur, in.State)
+		idle.FeedNew(in.Dur, in.State)
+		now += in.Dur
+	}
+	if timeout := idle.IdleTimeout(); math.Abs(timeout-7*60) > 1e-9 {
+		t.Errorf("IdleTimeout = %.1f, want 420", timeout)
+	}
+	if got := idle.FlushIfIdle(now + 300); got != "" {
+		t.Errorf("FlushIfIdle within the timeout = %q, want nothing", got)
+	}
+	if got := idle.FlushIfIdle(now + 500); got != "K" {
+		t.Errorf("FlushIfIdle after the timeout = %q, want K", got)
+	}
+	if got := idle.FlushIfIdle(now + 1000); got != "" {
+		t.Errorf("second FlushIfIdle = %q, want nothing", got)
+	}
+
+	// 提前结算不影响之后的解码
+	for _, in := range input[split:] {
+		plain.FeedNew(in.Dur, in.State)
+		idle.FeedNew(in.Dur, in.State)
+	}
+	plain.CheckTimeout()
+	idle.CheckTimeout()
+	if got, want := idle.GetBestPath(), plain.GetBestPath(); got != want || want != "K E" {
+		t.Errorf("after idle flush got %q, uninterrupted decoding %q, want K E", got, want)
+	}
+
+	// 估速阶段按最慢速度计算超时
+	boot := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20, BootstrapMarks: 8}, newEmptyLanguageModel())
+	if got := boot.IdleTimeout(); got != bootstrapMaxUnit*idleTimeoutUnits {
+		t.Errorf("bootstrap IdleTimeout = %.1f, want %.1f", got, bootstrapMaxUnit*idleTimeoutUnits)
+	}
+}
+
+func TestCWDecoder_PulseBufferCap(t *testing.T) {
+	dec := mustNewCWDecoder(t, DecoderConfig{InitialWPM: 20, GlitchThresholdMs: 15}, newEmptyLanguageModel())
+
+	// 2 秒连续音调，之后是 2 秒没有字符间隔的点 (卡键 / 连续噪声)
+	dec.FeedNew(1000, StateOff)
+	dec.FeedNew(2000, StateOn)
+	dec.FeedNew(60, StateOff)
+	for i := 0; i < 2000/120; i++ {
+		dec.FeedNew(60, StateOn)
+		dec.FeedNew(60, StateOff)
+		if n := len(dec.pulseBuffer); n > 16 {
+			t.Fatalf("pulseBuffer grew to %d elements", n)
+		}
+	}
+
+	// 之后的正常信号仍能解码
+	dec.FeedNew(1000, StateOff)
+	for _, in := range generateSignal("-.-", 20) {
+		dec.FeedNew(in.Dur, in.State)
+	}
+	dec.FeedNew(1000, StateOff)
+	dec.CheckTimeout()
+	if n := len(dec.pulseBuffer); n != 0 {
+		t.Errorf("Expected an empty buffer after the timeout, got %d elements", n)
+	}
+	if got := dec.GetBestPath(); !strings.HasSuffix(got, "K") {
+		t.Errorf("Expected decoding to recover with K, got %q", got)
+	}
+}
@@ -0,0 +1,155 @@
+package BeamDecoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FistAnalyzer 发报手法 ("fist") 质量分析
+// 输入与 CWDecoder.FeedNew 相同的 Mark/Space 时长流，统计点划比 (weighting)、
+// 点的一致性和码元间隔的准确度，用于练习软件给出发报质量反馈。
+type FistAnalyzer struct {
+	marks      []float64 // Mark 时长 (ms)
+	spaces     []float64 // 完整的空窗时长 (ms)，连续的 Off 已合并
+	pendingGap float64   // 正在累积的空窗
+}
+
+// fistMinMarks 少于这么多 Mark 时不给出报告
+const fistMinMarks = 10
+
+// 手法评价的容差
+const (
+	fistIdealWeighting = 3.0  // 标准划/点比
+	fistWeightingTol   = 0.3  // 划/点比偏离超过此值提示
+	fistMaxCV          = 0.15 // 点 (或码元间隔) 的变异系数超过此值提示不稳定
+	fistGapTol         = 0.2  // 码元间隔与点长之比偏离 1.0 超过此值提示
+)
+
+// NewFistAnalyzer 创建分析器
+func NewFistAnalyzer() *FistAnalyzer {
+	return &FistAnalyzer{}
+}
+
+// Feed 输入一段 Mark 或 Space 的时长 (ms)
+func (f *FistAnalyzer) Feed(durationMs float64, state SignalState) {
+	if state == StateOff {
+		f.pendingGap += durationMs
+		return
+	}
+	// 开头的静音不算空窗
+	if f.pendingGap > 0 && len(f.marks) > 0 {
+		f.spaces = append(f.spaces, f.pendingGap)
+	}
+	f.pendingGap = 0
+	f.marks = append(f.marks, durationMs)
+}
+
+// Reset 清空已收集的时长
+func (f *FistAnalyzer) Reset() {
+	*f = FistAnalyzer{}
+}
+
+// FistReport 发报手法报告，比值都以实测的点长为 1
+type FistReport struct {
+	Valid bool    // 样本不足或分不出点划时为 false，其余字段无意义
+	Marks int     // 参与统计的 Mark 数
+	WPM   float64 // 按点长估计的字符速度
+
+	Weighting float64 // 划/点 均值比，标准为 3.0
+	DitCV     float64 // 点长的变异系数 (StdDev/Mean)，越小越稳定
+	DahCV     float64 // 划长的变异系数
+
+	ElementGapRatio float64 // 码元间隔 (字符内) / 点长，标准为 1.0
+	ElementGapCV    float64 // 码元间隔的变异系数
+	CharGapRatio    float64 // 字符间隔 / 点长，标准为 3.0；没有字符间隔时为 0
+}
+
+// Report 根据已收集的时长生成报告
+// 点划用 StatisticalAnalyzer 按全部 Mark 切分；空窗按点长分类：< 2 为码元间隔，< 5 为字符间隔，其余为单词间隔。
+func (f *FistAnalyzer) Report() FistReport {
+	if len(f.marks) < fistMinMarks {
+		return FistReport{Marks: len(f.marks)}
+	}
+	analyzer := NewAnalyzer(len(f.marks))
+	for _, m := range f.marks {
+		analyzer.AddObservation(m)
+	}
+	stats := analyzer.Analyze()
+	if !stats.Valid {
+		return FistReport{Marks: len(f.marks)}
+	}
+
+	dit, dah := stats.DitStats, stats.DahStats
+	r := FistReport{
+		Valid:     true,
+		Marks:     len(f.marks),
+		WPM:       1200.0 / dit.Mean,
+		Weighting: dah.Mean / dit.Mean,
+		DitCV:     dit.StdDev / dit.Mean,
+		DahCV:     dah.StdDev / dah.Mean,
+	}
+
+	var elementGaps, charGaps []float64
+	for _, s := range f.spaces {
+		switch units := s / dit.Mean; {
+		case units < 2:
+			elementGaps = append(elementGaps, s)
+		case units < 5:
+			charGaps = append(charGaps, s)
+		}
+	}
+	if gap := calculateStats(elementGaps); gap.Count > 0 {
+		r.ElementGapRatio = gap.Mean / dit.Mean
+		r.ElementGapCV = gap.StdDev / gap.Mean
+	}
+	if gap := calculateStats(charGaps); gap.Count > 0 {
+		r.CharGapRatio = gap.Mean / dit.Mean
+	}
+	return r
+}
+
+// Advice 根据报告给出改进建议，手法良好时返回空
+func (r FistReport) Advice() []string {
+	if !r.Valid {
+		return nil
+	}
+	var advice []string
+	switch {
+	case r.Weighting < fistIdealWeighting-fistWeightingTol:
+		advice = append(advice, fmt.Sprintf("dahs are too short (dah/dit %.1f, ideal 3.0)", r.Weighting))
+	case r.Weighting > fistIdealWeighting+fistWeightingTol:
+		advice = append(advice, fmt.Sprintf("dahs are too long (dah/dit %.1f, ideal 3.0)", r.Weighting))
+	}
+	if r.DitCV > fistMaxCV {
+		advice = append(advice, fmt.Sprintf("dit length is uneven (varies by %.0f%%)", r.DitCV*100))
+	}
+	if r.ElementGapRatio > 0 {
+		switch {
+		case r.ElementGapRatio < 1-fistGapTol:
+			advice = append(advice, fmt.Sprintf("element spacing is too tight (%.1f dits, ideal 1.0)", r.ElementGapRatio))
+		case r.ElementGapRatio > 1+fistGapTol:
+			advice = append(advice, fmt.Sprintf("element spacing is too loose (%.1f dits, ideal 1.0)", r.ElementGapRatio))
+		}
+		if r.ElementGapCV > fistMaxCV {
+			advice = append(advice, fmt.Sprintf("element spacing is uneven (varies by %.0f%%)", r.ElementGapCV*100))
+		}
+	}
+	return advice
+}
+
+// String 人可读的报告
+func (r FistReport) String() string {
+	if !r.Valid {
+		return fmt.Sprintf("not enough data (%d marks)", r.Marks)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%.0f WPM, weighting %.2f (ideal 3.0), dit consistency %.0f%%, element spacing %.2f (ideal 1.0)",
+		r.WPM, r.Weighting, (1-r.DitCV)*100, r.ElementGapRatio)
+	if r.CharGapRatio > 0 {
+		fmt.Fprintf(&sb, ", character spacing %.1f (ideal 3.0)", r.CharGapRatio)
+	}
+	for _, a := range r.Advice() {
+		sb.WriteString("\n- " + a)
+	}
+	return sb.String()
+}
@@ -0,0 +1,73 @@
+package BeamDecoder
+
+import "math"
+
+// SetHamPriors 开启/关闭业余无线电常见上下文的手工先验
+// 普通文本语料里数字和标点的统计很少，信号报告 (599, 5NN)、便携/移动后缀 (/P, /M)、73、CQ 这类组合
+// 的转移分往往很低。开启后，这些组合的得分不低于 hamPriors 中的先验，语料中更高的统计保持不变。
+// 只解码普通文本 (新闻、小说练习) 时保持关闭。
+func (lm *LanguageModel) SetHamPriors(on bool) {
+	lm.hamPriors = on
+}
+
+// HamPriors 是否开启了手工先验
+func (lm *LanguageModel) HamPriors() bool {
+	return lm.hamPriors
+}
+
+// hamPriors 手工设定的 P(next|prev)，只作为下限使用
+var hamPriors = buildHamPriors()
+
+func buildHamPriors() map[string]map[string]float64 {
+	priors := make(map[string]map[string]float64)
+	set := func(prev, next string, p float64) {
+		if priors[prev] == nil {
+			priors[prev] = make(map[string]float64)
+		}
+		priors[prev][next] = math.Log(p)
+	}
+
+	// 数字串: 信号报告、序号、功率、频率
+	const digits = "0123456789"
+	for _, a := range digits {
+		for _, b := range digits {
+			set(string(a), string(b), 0.05)
+		}
+		set(string(a), " ", 0.3)
+	}
+	set(" ", "5", 0.05) // 599 / 5NN 开头
+	set("5", "9", 0.4)
+	set("9", "9", 0.4)
+	set("5", "N", 0.2) // 5NN (比赛中 9 简写为 N)
+	set("N", "N", 0.2)
+	set("7", "3", 0.4) // 73
+	set("3", " ", 0.4)
+
+	// 便携/移动后缀: K1ABC/P, /M, /MM, /AM, /QRP
+	set("/", "P", 0.3)
+	set("/", "M", 0.2)
+	set("/", "A", 0.05)
+	set("/", "Q", 0.05)
+	set("M", "M", 0.05)
+	set("P", " ", 0.3)
+	set("M", " ", 0.3)
+
+	// CQ, CQ DX
+	set(" ", "C", 0.1)
+	set("C", "Q", 0.3)
+	set("Q", " ", 0.2)
+	set("D", "X", 0.1)
+
+	// 问号常单独成词 (QRZ?, AGN?)
+	set("?", " ", 0.5)
+	return priors
+}
+
+// hamPriorScore 查询手工先验
+func hamPriorScore(prev, next string) (float64, bool) {
+	if nextMap, ok := hamPriors[prev]; ok {
+		p, ok := nextMap[next]
+		return p, ok
+	}
+	return 0, false
+}
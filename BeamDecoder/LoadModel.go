@@ -2,9 +2,9 @@ package BeamDecoder
 
 import (
 	"encoding/json"
-	"fmt"
 	"math"
 	"os"
+	"strings"
 )
 
 // LanguageModel 管理转移概率
@@ -13,6 +13,9 @@ type LanguageModel struct {
 	// 使用对数是为了防止概率连乘导致下溢，且加法比乘法快
 	LogProbs    map[string]map[string]float64
 	DefaultProb float64 // 遇到未知组合时的惩罚分
+
+	callsignMode bool // 见 SetCallsignMode
+	hamPriors    bool // 见 SetHamPriors
 }
 
 // NewLanguageModel 初始化
@@ -27,7 +30,31 @@ func NewLanguageModel() *LanguageModel {
 }
 
 // GetTransitionScore 获取从 prevChar -> nextChar 的转移得分
+// 开启 SetHamPriors 时，取语料模型与手工先验中较高的一个
 func (lm *LanguageModel) GetTransitionScore(prevChar, nextChar string) float64 {
+	score := lm.corpusScore(prevChar, nextChar)
+	if lm.hamPriors {
+		if prior, ok := hamPriorScore(prevChar, nextChar); ok && prior > score {
+			return prior
+		}
+	}
+	return score
+}
+
+// corpusScore 语料模型 (LogProbs) 给出的转移得分
+func (lm *LanguageModel) corpusScore(prevChar, nextChar string) float64 {
+	prevChar = lm.resolveProsign(prevChar)
+	nextChar = lm.resolveProsign(nextChar)
+
+	// 模型中没有统计过的勤务符号按单词边界处理 (勤务符号通常单独成词)
+	if isProsign(prevChar) {
+		if _, ok := lm.LogProbs[prevChar]; !ok {
+			if nextChar == " " {
+				return 0
+			}
+			prevChar = " "
+		}
+	}
 	if nextMap, ok := lm.LogProbs[prevChar]; ok {
 		if prob, ok := nextMap[nextChar]; ok {
 			return prob
@@ -37,9 +64,113 @@ func (lm *LanguageModel) GetTransitionScore(prevChar, nextChar string) float64 {
 	if prevChar == "" {
 		return math.Log(0.05)
 	}
+	// 回退到一元概率：语料中少见但合法的组合不至于被一刀切罚到 DefaultProb
+	if unigrams, ok := lm.LogProbs[UnigramKey]; ok {
+		if prob, ok := unigrams[nextChar]; ok {
+			return backoffWeight + prob
+		}
+	}
 	return lm.DefaultProb
 }
 
+// UnigramKey LogProbs 中存放一元概率 log(P(next)) 的行，供回退使用 (由 bigram 包生成)
+// 不是 CW 能发送的字符，不会与真实的前驱冲突
+const UnigramKey = "*"
+
+// backoffWeight 回退到一元概率时的折扣 (stupid backoff, 0.4)
+var backoffWeight = math.Log(0.4)
+
+// callsignFlatScore 呼号模式下的转移分下限：约等于在 26 个字母 + 10 个数字中均匀猜测
+var callsignFlatScore = math.Log(1.0 / 36)
+
+// SetCallsignMode 开启/关闭呼号偏置
+// 呼号 (KB2XYZ、9A1A) 基本是随机的字母数字组合，用普通文本统计的 bigram 打分极低，
+// beam 会倾向把它 "纠正" 成常见单词。开启后，当前单词看起来像呼号时 (见 looksLikeCallsign)，
+// 转移分不低于 callsignFlatScore，模型里已有的高概率转移保持不变。
+//
+// 何时开启：解码业余无线电通联 (CQ / DE / 呼号交换) 时开启；
+// 解码新闻、练习文本等以普通单词为主的内容时关闭，此时 "A1" 这类组合多半是误码，应当受罚。
+func (lm *LanguageModel) SetCallsignMode(on bool) {
+	lm.callsignMode = on
+}
+
+// CallsignMode 是否开启了呼号偏置
+func (lm *LanguageModel) CallsignMode() bool {
+	return lm.callsignMode
+}
+
+// GetTokenTransitionScore 与 GetTransitionScore 相同，但额外传入当前单词 (最后一个空格之后的部分)
+// 呼号模式下用于判断当前单词是否像呼号
+func (lm *LanguageModel) GetTokenTransitionScore(token, prevChar, nextChar string) float64 {
+	score := lm.GetTransitionScore(prevChar, nextChar)
+	if !lm.callsignMode || score >= callsignFlatScore {
+		return score
+	}
+	// 结束单词时看单词本身，否则看接上下一个字符之后的样子 ("KB" + "2")
+	candidate := token
+	if nextChar != " " {
+		candidate += nextChar
+	}
+	if looksLikeCallsign(candidate) {
+		return callsignFlatScore
+	}
+	return score
+}
+
+// looksLikeCallsign 前缀位置 (前 3 个字符) 出现数字，且含有字母
+// 覆盖 K1ABC、KB2XYZ、9A1A、3DA0RS 等常见格式；纯数字 (599、73) 不算
+func looksLikeCallsign(token string) bool {
+	if len(token) < 2 || strings.ContainsAny(token, "<>") {
+		return false
+	}
+	digitInPrefix, hasLetter := false, false
+	for i, r := range token {
+		switch {
+		case r >= '0' && r <= '9':
+			if i < 3 {
+				digitInPrefix = true
+			}
+		case r >= 'A' && r <= 'Z':
+			hasLetter = true
+		}
+	}
+	return digitInPrefix && hasLetter
+}
+
+// prosignAliases 旧语料中勤务符号常以同码型的标点记录 (例如 BT 记为 =)
+var prosignAliases = map[string]string{
+	"<AR>": "+",
+	"<BT>": "=",
+	"<KN>": "(",
+	"<AS>": "&",
+}
+
+// resolveProsign 模型没有勤务符号本身的统计时，改用同码型标点的统计；反过来，
+// 按标点输出 (默认) 而模型只统计了勤务符号时，改用勤务符号的统计
+func (lm *LanguageModel) resolveProsign(token string) string {
+	if _, ok := lm.LogProbs[token]; ok {
+		return token
+	}
+	alias, ok := prosignAliases[token]
+	if !ok {
+		alias, ok = prosignPunctuation[token]
+	}
+	if ok {
+		if _, ok := lm.LogProbs[alias]; ok {
+			return alias
+		}
+	}
+	return token
+}
+
+// isProsign 是否为 <SK> 这类尖括号形式的勤务符号
+func isProsign(token string) bool {
+	return len(token) > 2 && token[0] == '<' && token[len(token)-1] == '>'
+}
+
+// bigramModelPath NewLanguageModel 加载的模型文件 (由 BuildModel 生成)
+const bigramModelPath = "/Users/leilei/work/goProject/src/cw/BuildModel/ham_bigrams.json"
+
 // loadDummyData 仅作演示，硬编码一些常见组合
 func (lm *LanguageModel) loadDummyData() {
 	//// 辅助函数：设置概率
@@ -57,9 +188,9 @@ func (lm *LanguageModel) loadDummyData() {
 	//set("I", "N", 0.25) // IN
 	//// ... 你需要用脚本统计几本英文小说来填充这里
 
-	content, err := os.ReadFile("/Users/leilei/work/goProject/src/cw/BuildModel/ham_bigrams.json")
+	content, err := os.ReadFile(bigramModelPath)
 	if err != nil {
-		fmt.Println(err)
+		logger.Error("load bigram model failed", "err", err)
 		panic(err)
 	}
 	json.Unmarshal(content, &lm.LogProbs)
@@ -35,6 +35,11 @@ type StatisticalAnalyzer struct {
 	history    []float64
 	cursor     int
 	full       bool
+
+	// 增量统计：只有新样本进入窗口后才重新排序和找断层
+	dirty  bool        // 上次 Analyze 之后是否有新样本
+	cached StatsResult // 上次 Analyze 的结果
+	sorted []float64   // 排序用的复用缓冲区
 }
 
 // SignalStats 存储点或划的统计特征
@@ -57,9 +62,16 @@ func NewAnalyzer(size int) *StatisticalAnalyzer {
 	return &StatisticalAnalyzer{
 		windowSize: size,
 		history:    make([]float64, size),
+		sorted:     make([]float64, size),
+		dirty:      true,
 	}
 }
 
+// Dirty 上次 Analyze 之后是否有新样本 (为 false 时 Analyze 直接返回缓存结果)
+func (s *StatisticalAnalyzer) Dirty() bool {
+	return s.dirty
+}
+
 // AddObservation 添加一个新的信号时长样本
 func (s *StatisticalAnalyzer) AddObservation(duration float64) {
 	s.history[s.cursor] = duration
@@ -67,6 +79,18 @@ func (s *StatisticalAnalyzer) AddObservation(duration float64) {
 	if s.cursor == 0 {
 		s.full = true
 	}
+	s.dirty = true
+}
+
+// Samples 返回窗口内已收集的样本副本 (窗口未满时只包含已写入的部分)
+func (s *StatisticalAnalyzer) Samples() []float64 {
+	n := s.cursor
+	if s.full {
+		n = s.windowSize
+	}
+	out := make([]float64, n)
+	copy(out, s.history[:n])
+	return out
 }
 
 // GetOptimalThreshold 计算最佳分割阈值
@@ -113,13 +137,22 @@ func (s *StatisticalAnalyzer) GetOptimalThreshold() float64 {
 }
 
 // Analyze 执行完整的统计分析
+// 窗口没有变化时直接返回上次的结果，不再重复排序
 func (s *StatisticalAnalyzer) Analyze() StatsResult {
+	if s.dirty {
+		s.cached = s.analyze()
+		s.dirty = false
+	}
+	return s.cached
+}
+
+func (s *StatisticalAnalyzer) analyze() StatsResult {
 	if !s.full {
 		return StatsResult{Valid: false}
 	}
 
-	// 1. 准备数据
-	data := make([]float64, s.windowSize)
+	// 1. 准备数据 (复用排序缓冲区)
+	data := s.sorted
 	copy(data, s.history)
 	sort.Float64s(data)
 
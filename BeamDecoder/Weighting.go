@@ -0,0 +1,49 @@
+package BeamDecoder
+
+import "fmt"
+
+// Weighting 某个发报者实测的点划比例 (以点长为 1)
+// 机械键 (bug) 或调过比重的电键发出的划未必是点的 3 倍，元素间隔也未必等于点长；
+// 用校准得到的比例代替理想的 1:3 模板打发射分，并按实测比例把划换算回点长来跟踪速度。
+// 零值表示标准比例。
+type Weighting struct {
+	DahRatio float64 // 划/点，0 = 3
+	GapRatio float64 // 字符内元素间隔/点，0 = 1
+}
+
+// Validate 检查比例是否合法：划必须比点长，间隔不能为负
+func (w Weighting) Validate() error {
+	if w.DahRatio != 0 && w.DahRatio <= 1 {
+		return fmt.Errorf("dah/dit ratio must be > 1, got %f", w.DahRatio)
+	}
+	if w.GapRatio < 0 {
+		return fmt.Errorf("gap/dit ratio must be >= 0, got %f", w.GapRatio)
+	}
+	return nil
+}
+
+// dahRatio 返回划/点比，0 按 3 处理
+func (w Weighting) dahRatio() float64 {
+	if w.DahRatio == 0 {
+		return 3
+	}
+	return w.DahRatio
+}
+
+// SetWeighting 设置发射分使用的点划比例 (零值恢复 1:3 模板)
+func (bd *BeamDecoder) SetWeighting(w Weighting) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	bd.weighting = w
+	return nil
+}
+
+// SetWeighting 设置发报者的点划比例 (见 Weighting)，Reset 后保留
+func (d *CWDecoder) SetWeighting(w Weighting) error {
+	if err := d.beamDecoder.SetWeighting(w); err != nil {
+		return err
+	}
+	d.cfg.Weighting = w
+	return nil
+}
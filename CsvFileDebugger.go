@@ -52,7 +52,7 @@ func (d *CsvFileDebugger) Record(raw, filtered, envelope, threshold float64, sta
 
 // Close 关闭文件并刷新缓冲区
 func (d *CsvFileDebugger) Close() {
-	fmt.Println("Closing CsvFileDebugger")
+	logger.Debug("closing csv debugger")
 	if d.writer != nil {
 		d.writer.Flush()
 	}
@@ -67,3 +67,16 @@ type NoOpDebugger struct{}
 
 func (d *NoOpDebugger) Record(raw, filtered, envelope, threshold float64, state bool) {}
 func (d *NoOpDebugger) Close()                                                        {}
+
+// newSignalDebugger 按配置创建调试器：路径为空或文件无法创建时返回 NoOpDebugger
+func newSignalDebugger(path string) SignalDebugger {
+	if path == "" {
+		return &NoOpDebugger{}
+	}
+	d, err := NewCsvFileDebugger(path)
+	if err != nil {
+		logger.Warn("cannot create debug csv file", "path", path, "err", err)
+		return &NoOpDebugger{}
+	}
+	return d
+}
@@ -2,12 +2,26 @@ package Filters
 
 import "math"
 
+// AFCEngine 通过 I/Q 相位差估计频率误差，累积成频率修正量
 type AFCEngine struct {
 	TargetFreq    float64
+	SampleRate    float64 // 采样率 (Hz)，用于把相位差换算成频率
 	CurrentOffset float64 // 当前修正量
 
 	lastPhase float64
+	errorHz   float64 // 平滑后的频率误差 (Hz)
 	smoothing float64 // 平滑系数 (0.0 - 1.0)
+	gain      float64 // 每次修正的比例
+}
+
+// NewAFCEngine 创建 AFC，默认平滑系数 0.1，增益 0.01
+func NewAFCEngine(sampleRate, targetFreq float64) *AFCEngine {
+	return &AFCEngine{
+		TargetFreq: targetFreq,
+		SampleRate: sampleRate,
+		smoothing:  0.1,
+		gain:       0.01,
+	}
 }
 
 func (a *AFCEngine) Update(I, Q, magnitude float64) {
@@ -30,21 +44,21 @@ func (a *AFCEngine) Update(I, Q, magnitude float64) {
 
 	a.lastPhase = currPhase
 
-	// 4. 将相位差转换为频率误差 (Hz)
+	// 4. 将相位差转换为频率误差 (Hz)，并平滑逐样本的相位噪声
 	// Formula: ErrorHz = (delta / (2*Pi)) * SampleRate
-	rawErrorHz := (delta / (2 * math.Pi)) * 48000.0
+	rawErrorHz := (delta / (2 * math.Pi)) * a.SampleRate
+	a.errorHz += (rawErrorHz - a.errorHz) * a.smoothing
 
 	// 5. 死区控制 (Deadband) - 提升精度的关键！
 	// 如果误差在 2Hz 以内，认为已经很准了，不动它，避免震荡。
-	if math.Abs(rawErrorHz) < 2.0 {
+	if math.Abs(a.errorHz) < 2.0 {
 		return
 	}
 
 	// 6. 缓慢修正 (Gain Control)
 	// 不要一次修到位，每次只修 1% (Gain = 0.01)
 	// 这样可以极大地平滑噪音带来的抖动
-	gain := 0.01
-	a.CurrentOffset += rawErrorHz * gain
+	a.CurrentOffset += a.errorHz * a.gain
 
 	// 限制最大修正范围 (比如只允许追 ±50Hz，防止跑飞)
 	if a.CurrentOffset > 50 {
@@ -0,0 +1,31 @@
+package Filters
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAFCEngine_ErrorUsesSampleRate(t *testing.T) {
+	const offsetHz = 10.0
+	for _, sr := range []float64{8000, 48000, 96000} {
+		afc := NewAFCEngine(sr, 700)
+		// 基带 I/Q 以 offsetHz 旋转
+		for n := 0; n < int(sr/10); n++ {
+			phase := 2 * math.Pi * offsetHz * float64(n) / sr
+			afc.Update(math.Cos(phase), math.Sin(phase), 1.0)
+		}
+		if math.Abs(afc.errorHz-offsetHz) > 0.1 {
+			t.Errorf("%.0f Hz: expected error %.1f Hz, got %.3f", sr, offsetHz, afc.errorHz)
+		}
+		if afc.CurrentOffset <= 0 {
+			t.Errorf("%.0f Hz: expected a positive correction, got %.3f", sr, afc.CurrentOffset)
+		}
+	}
+
+	// 信号太弱时不调整
+	afc := NewAFCEngine(8000, 700)
+	afc.Update(0, 0.01, 0.01)
+	if afc.CurrentOffset != 0 || afc.errorHz != 0 {
+		t.Error("Weak signal should not update the AFC")
+	}
+}
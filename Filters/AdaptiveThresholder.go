@@ -1,5 +1,7 @@
 package Filters
 
+import "math"
+
 // AdaptiveThresholder 实现双路包络追踪，用于生成动态的 Schmidt 触发阈值。
 // 它可以抵抗 QSB (信号衰落) 并具备自动静噪功能。
 type AdaptiveThresholder struct {
@@ -8,22 +10,53 @@ type AdaptiveThresholder struct {
 	minLevel float64 // 追踪底噪的基准 (Noise Floor)
 
 	// 配置参数
-	decayRate float64 // 衰减系数 (0.0 ~ 1.0)，控制 max 下降和 min 上升的速度
-	minRange  float64 // 最小动态范围，小于此值视为静噪开启
+	decayRate       float64 // 衰减系数 (0.0 ~ 1.0)，控制 max 下降和 min 上升的速度
+	minRange        float64 // 最小动态范围，小于此值视为静噪开启
+	hysteresisRatio float64 // 迟滞宽度 (中点上下各) 占动态范围的比例
+}
+
+// 迟滞比例 (中点上下各占动态范围的比例)，AdaptiveThresholder 和 SchmittTrigger.SetThreshold 共用
+const (
+	DefaultHysteresisRatio = 0.1  // 默认值，SetThreshold 时即 Low = High * 0.8
+	MaxHysteresisRatio     = 0.45 // 上限，再大低阈值就接近底噪了
+)
+
+// normalizeHysteresisRatio <= 0 时返回默认值，超过上限时按 MaxHysteresisRatio
+func normalizeHysteresisRatio(ratio float64) float64 {
+	if ratio <= 0 {
+		return DefaultHysteresisRatio
+	}
+	return min(ratio, MaxHysteresisRatio)
+}
+
+// HysteresisLowRatio 返回迟滞比例对应的 Low / High (1 - 2 * 比例)
+// 即把 0 到 High 视为动态范围时的关闭阈值比例，ratio <= 0 时按 DefaultHysteresisRatio
+func HysteresisLowRatio(ratio float64) float64 {
+	return 1 - 2*normalizeHysteresisRatio(ratio)
 }
 
 // NewAdaptiveThresholder 初始化追踪器
-// decayRate: 推荐 0.9995 (48kHz)
+// sampleRate: 输入包络的采样率 (Hz)
+// timeConstant: max 下降 / min 上升的时间常数 (秒)，经过这么长时间衰减到 1/e。
+// 推荐 0.042 (等价于 48kHz 下的 0.9995)；衰减系数按采样率换算，不同采样率下行为一致
 // minRange: 推荐 0.2 (视 AGC 增益策略而定)
-func NewAdaptiveThresholder(decayRate, minRange float64) *AdaptiveThresholder {
+func NewAdaptiveThresholder(sampleRate, timeConstant, minRange float64) *AdaptiveThresholder {
 	return &AdaptiveThresholder{
-		maxLevel:  0.0,
-		minLevel:  0.0,
-		decayRate: decayRate,
-		minRange:  minRange,
+		maxLevel:        0.0,
+		minLevel:        0.0,
+		decayRate:       math.Exp(-1.0 / (timeConstant * sampleRate)),
+		minRange:        minRange,
+		hysteresisRatio: DefaultHysteresisRatio,
 	}
 }
 
+// SetHysteresisRatio 设置迟滞宽度 (中点上下各) 占动态范围的比例
+// <= 0 时恢复默认值 DefaultHysteresisRatio，最大 MaxHysteresisRatio。
+// 太小时弱信号的点在边沿附近来回抖动 (chattering)，太大时靠得很近的元素会粘在一起
+func (at *AdaptiveThresholder) SetHysteresisRatio(ratio float64) {
+	at.hysteresisRatio = normalizeHysteresisRatio(ratio)
+}
+
 // Update 更新追踪器状态并计算当前的迟滞阈值。
 // 输入 sample: 经过 AGC 归一化的信号包络 (0.0 ~ 1.0)
 // 输出 high, low: 用于施密特触发器的动态阈值
@@ -68,8 +101,8 @@ func (at *AdaptiveThresholder) Update(sample float64) (high, low float64) {
 	// 中点根据 min 和 range 浮动
 	center := at.minLevel + (dynRange * 0.5)
 
-	// 迟滞宽度设为动态范围的 5% (上下各 5%，总共 10% 的缓冲区)
-	hysteresis := dynRange * 0.05
+	// 迟滞宽度 (默认上下各 10%，总共 20% 的缓冲区)
+	hysteresis := dynRange * at.hysteresisRatio
 
 	high = center + hysteresis
 	low = center - hysteresis
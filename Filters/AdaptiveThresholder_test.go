@@ -0,0 +1,54 @@
+package Filters
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAdaptiveThresholder_TimeConstantIndependentOfSampleRate(t *testing.T) {
+	const tau = 0.042
+	for _, sr := range []float64{8000, 48000, 96000} {
+		at := NewAdaptiveThresholder(sr, tau, 0.005)
+		at.Update(1.0)
+		// 静音 tau 秒后，峰值追踪衰减到 1/e
+		for i := 0; i < int(tau*sr); i++ {
+			at.Update(0)
+		}
+		if math.Abs(at.maxLevel-1/math.E) > 0.01 {
+			t.Errorf("%.0f Hz: expected maxLevel %.3f after one time constant, got %.3f", sr, 1/math.E, at.maxLevel)
+		}
+	}
+
+	// 48kHz 下与原来固定的 0.9995 基本一致
+	if got := NewAdaptiveThresholder(48000, tau, 0.005).decayRate; math.Abs(got-0.9995) > 1e-5 {
+		t.Errorf("Expected decay rate ~0.9995 at 48kHz, got %f", got)
+	}
+}
+
+func TestAdaptiveThresholder_HysteresisRatio(t *testing.T) {
+	band := func(ratio float64) float64 {
+		at := NewAdaptiveThresholder(8000, 0.042, 0.005)
+		at.SetHysteresisRatio(ratio)
+		var high, low float64
+		for i := 0; i < 800; i++ {
+			high, low = at.Update(float64(i % 2))
+		}
+		return (high - low) / (at.maxLevel - at.minLevel)
+	}
+	// 迟滞带宽 = 上下各 ratio 倍的动态范围
+	for ratio, want := range map[float64]float64{0: 2 * DefaultHysteresisRatio, 0.2: 0.4, 0.9: 2 * MaxHysteresisRatio} {
+		if got := band(ratio); math.Abs(got-want) > 1e-9 {
+			t.Errorf("ratio %.2f: expected band %.2f of the range, got %.4f", ratio, want, got)
+		}
+	}
+
+	// SchmittTrigger 把同一个比例转给内部的 AdaptiveThresholder
+	st := NewSchmittTrigger(8000, 0.2, 0.15, 0)
+	st.SetHysteresisRatio(0.2)
+	if st.thresholder.hysteresisRatio != 0.2 {
+		t.Errorf("Expected the trigger to forward the ratio, got %.2f", st.thresholder.hysteresisRatio)
+	}
+	if got := HysteresisLowRatio(0); math.Abs(got-0.8) > 1e-12 {
+		t.Errorf("Expected default low ratio 0.8, got %.3f", got)
+	}
+}
@@ -2,9 +2,13 @@ package Filters
 
 import (
 	"math"
-	"sort"
 )
 
+// AGC 自动增益控制：输入样本，返回归一化后的幅度
+type AGC interface {
+	Update(sample float64) float64
+}
+
 // SimpleAGC 实现“快充慢放”的自动增益控制
 // AGC指自动增益控制(Automatic Gain Control)，
 // 是一种自动调整信号强度（音量、亮度等）的功能，
@@ -44,37 +48,78 @@ func (agc *SimpleAGC) Update(sample float64) float64 {
 	return val / agc.peak
 }
 
+// RobustAGC 分位点跟踪 AGC
+// 用非对称的乘性步长跟踪 |sample| 的 targetQuantile 分位点 (例如 0.95)，以它作为归一化的参考幅度。
+// 与 SimpleAGC 的峰值保持不同，偶尔的强脉冲 (雷电噪声) 每个样本只能把参考幅度推高一个固定比例，
+// 不会一下子把增益压到底。
 type RobustAGC struct {
-	peak      float64
-	decayRate float64
+	peak       float64 // 当前估计的分位点
+	attackRate float64 // sample > peak 时 peak 乘以此系数
+	decayRate  float64 // sample <= peak 时 peak 乘以此系数
+	seeded     bool    // 是否已用第一个有效样本初始化 peak
+	above      int     // 连续高于 peak 的样本数
+}
+
+// robustAGCRate 分位点跟踪的步长
+// 0.95 分位点时 decayRate = 0.99995，与 SimpleAGC 的默认衰减相当
+const robustAGCRate = 0.001
+
+// robustAGCFloor peak 的安全底限，防止在纯静音时放大底噪
+const robustAGCFloor = 0.001
+
+// 连续 robustAGCSustain 个样本高于 peak 时认为是真实的电平变化 (例如信号出现)，
+// 改用 robustAGCFastAttack 快速跟上；强脉冲通常只有几毫秒，达不到这个长度
+const (
+	robustAGCSustain    = 200
+	robustAGCFastAttack = 1.05
+)
+
+// NewRobustAGC 创建跟踪 targetQuantile 分位点的 AGC，超出 (0, 1) 时使用 0.95
+// 平衡点: 只有 (1-q) 的样本高于 peak，即 (1-q)*log(attack) = -q*log(decay)，
+// 取 attack = 1 + r*q, decay = 1 - r*(1-q) 即可 (r 很小时)。
+func NewRobustAGC(targetQuantile float64) *RobustAGC {
+	q := targetQuantile
+	if q <= 0 || q >= 1 {
+		q = 0.95
+	}
+	return &RobustAGC{
+		peak:       robustAGCFloor,
+		attackRate: 1 + robustAGCRate*q,
+		decayRate:  1 - robustAGCRate*(1-q),
+	}
 }
 
+// Update 处理样本并返回归一化后的值 (0.0 - 1.0)
 func (agc *RobustAGC) Update(sample float64) float64 {
 	val := math.Abs(sample)
 
-	// 核心逻辑：非对称的增量更新
-	// 我们想锁定 95% 分位点，意味着：
-	// 我们希望只有 5% 的时间 sample > peak
-	// 所以 sample > peak 时的“惩罚”应该是 sample < peak 时的 19 倍 (95/5)
+	// 第一个有效样本直接作为初值，省去从底限慢慢爬升的过程
+	if !agc.seeded && val > robustAGCFloor {
+		agc.peak = val
+		agc.seeded = true
+	}
 
 	if val > agc.peak {
-		// 只有 5% 的机会进这里，所以步长要小，或者按比例控制
-		// 这种累加方式模拟了分位点追踪
-		agc.peak += 0.001 * val
+		agc.above++
+		if agc.above >= robustAGCSustain {
+			agc.peak *= robustAGCFastAttack
+		} else {
+			agc.peak *= agc.attackRate
+		}
 	} else {
-		// 有 95% 的机会进这里
-		agc.peak -= 0.00005 * val // 这里的减量是增量的 1/20 左右
+		agc.above = 0
+		agc.peak *= agc.decayRate
 	}
 
 	// 安全底限
-	if agc.peak < 0.001 {
-		agc.peak = 0.001
+	if agc.peak < robustAGCFloor {
+		agc.peak = robustAGCFloor
 	}
 
 	// 归一化
 	normalized := val / agc.peak
 
-	// 软限幅 (Soft Clipping)：防止因为偶尔的估算滞后导致输出 > 1.0
+	// 软限幅 (Soft Clipping)：高于分位点的样本 (以及强脉冲) 输出 1.0
 	if normalized > 1.0 {
 		normalized = 1.0
 	}
@@ -82,17 +127,17 @@ func (agc *RobustAGC) Update(sample float64) float64 {
 	return normalized
 }
 
+// medianAGCSize 中值滤波阶数 (median5 的比较网络是为 5 个元素写死的)
+const medianAGCSize = 5
+
 type MedianAGC struct {
-	buffer    []float64
+	buffer    [medianAGCSize]float64
 	cursor    int
-	size      int
 	simpleAGC *SimpleAGC
 }
 
 func NewMedianAGC() *MedianAGC {
 	return &MedianAGC{
-		size:      5,
-		buffer:    make([]float64, 5), // 5阶中值滤波
 		simpleAGC: NewSimpleAGC(0.99995),
 	}
 }
@@ -100,19 +145,39 @@ func NewMedianAGC() *MedianAGC {
 func (m *MedianAGC) Update(sample float64) float64 {
 	// 1. 存入环形缓冲区
 	m.buffer[m.cursor] = sample
-	m.cursor = (m.cursor + 1) % m.size
-
-	// 2. 复制并排序以找到中值
-	// 注意：为了性能，对于小数组(3或5)，可以用硬编码比较代替 sort
-	tmp := make([]float64, len(m.buffer))
-	copy(tmp, m.buffer)
+	m.cursor = (m.cursor + 1) % medianAGCSize
 
-	// 简单的冒泡排序或插入排序 (对于5个元素，这比 sort.Float64s 快得多)
-	// 这里为了演示用 sort，生产环境建议手写比较网络
-	sort.Float64s(tmp)
-
-	median := tmp[m.size/2] // 取第3个，即中位数
+	// 2. 用比较网络求中值 (栈上变量，无分配、无排序)
+	median := median5(m.buffer[0], m.buffer[1], m.buffer[2], m.buffer[3], m.buffer[4])
 
 	// 3. 将清洗后的数据喂给 AGC
 	return m.simpleAGC.Update(median)
 }
+
+// median5 返回 5 个数的中值
+// 7 次比较交换的中值网络，结果与排序后取第 3 个相同。
+func median5(a, b, c, d, e float64) float64 {
+	if a > b {
+		a, b = b, a
+	}
+	if d > e {
+		d, e = e, d
+	}
+	if a > d {
+		a, d = d, a
+		b, e = e, b
+	}
+	// a 是 a,b,d,e 中最小的，不可能是中值
+	if b > c {
+		b, c = c, b
+	}
+	if b > d {
+		b, d = d, b
+		c, e = e, c
+	}
+	// b 是剩余 b,c,d,e 中最小的，中值是 c 和 d 中较小的
+	if c > d {
+		return d
+	}
+	return c
+}
@@ -0,0 +1,86 @@
+package Filters
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestMedian5(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		var v [5]float64
+		for j := range v {
+			// 少量取值，覆盖相等元素的情况
+			v[j] = float64(rng.Intn(4))
+			if i%2 == 0 {
+				v[j] = rng.Float64()
+			}
+		}
+		sorted := v
+		sort.Float64s(sorted[:])
+		if got := median5(v[0], v[1], v[2], v[3], v[4]); got != sorted[2] {
+			t.Fatalf("median5(%v) = %v, want %v", v, got, sorted[2])
+		}
+	}
+}
+
+func BenchmarkMedianAGC_Update(b *testing.B) {
+	agc := NewMedianAGC()
+	samples := make([]float64, 1024)
+	rng := rand.New(rand.NewSource(1))
+	for i := range samples {
+		samples[i] = rng.Float64()
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		agc.Update(samples[i%len(samples)])
+	}
+}
+
+func TestRobustAGC_TracksQuantile(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, q := range []float64{0.5, 0.9, 0.95} {
+		agc := NewRobustAGC(q)
+		// |sample| 在 [0, 1) 上均匀分布，q 分位点就是 q
+		for i := 0; i < 200000; i++ {
+			agc.Update(rng.Float64())
+		}
+		if math.Abs(agc.peak-q) > 0.05 {
+			t.Errorf("q=%.2f: expected peak near %.2f, got %.4f", q, q, agc.peak)
+		}
+	}
+
+	if agc := NewRobustAGC(1.5); agc.decayRate != NewRobustAGC(0.95).decayRate {
+		t.Error("Invalid quantile should fall back to 0.95")
+	}
+}
+
+func TestRobustAGC_LoudCrash(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	robust, simple := NewRobustAGC(0.95), NewSimpleAGC(0.99995)
+	for i := 0; i < 100000; i++ {
+		s := 0.5 * rng.Float64()
+		robust.Update(s)
+		simple.Update(s)
+	}
+	before := robust.peak
+
+	// 20 个样本的强脉冲 (100 倍幅度)
+	for i := 0; i < 20; i++ {
+		robust.Update(50)
+		simple.Update(50)
+	}
+	if robust.peak > before*1.1 {
+		t.Errorf("Robust peak jumped from %.4f to %.4f after a short crash", before, robust.peak)
+	}
+	// 之后的正常信号: 峰值保持 AGC 的增益被压到底，分位点 AGC 仍有正常输出
+	if got := robust.Update(0.45); got < 0.8 {
+		t.Errorf("Expected robust AGC output near 1 after the crash, got %.4f", got)
+	}
+	if got := simple.Update(0.45); got > 0.05 {
+		t.Errorf("Expected peak-hold AGC to be swamped by the crash, got %.4f", got)
+	}
+}
@@ -1,7 +1,5 @@
 package Filters
 
-import "fmt"
-
 /*
 施密特触发器
 判断当前的信号是mark 还是space
@@ -23,7 +21,8 @@ type SchmittTrigger struct {
 	thresholdHigh float64
 	thresholdLow  float64
 	sampleRate    float64
-	debounceCount int64 // 需要多少个采样点确认去抖
+	hysteresis    float64 // SetThreshold 的迟滞比例：Low = High * (1 - 2 * hysteresis)
+	debounceCount int64   // 需要多少个采样点确认去抖
 
 	// 内部状态
 	currentState     bool  // 当前稳定的状态
@@ -36,13 +35,17 @@ type SchmittTrigger struct {
 	thresholder       *AdaptiveThresholder
 }
 
+// thresholderTimeConstant 自适应阈值追踪的时间常数 (秒)，即原先 48kHz 下的 0.9995
+const thresholderTimeConstant = 0.042
+
 // NewSchmittTrigger 创建触发器
 func NewSchmittTrigger(sampleRate float64, high, low, debounceMs float64) *SchmittTrigger {
-	thresholder := NewAdaptiveThresholder(0.9995, 0.005)
+	thresholder := NewAdaptiveThresholder(sampleRate, thresholderTimeConstant, 0.005)
 	return &SchmittTrigger{
 		sampleRate:    sampleRate,
 		thresholdHigh: high,
 		thresholdLow:  low,
+		hysteresis:    DefaultHysteresisRatio,
 		debounceCount: int64(debounceMs * sampleRate),
 		currentState:  false, // 默认为静音
 		thresholder:   thresholder,
@@ -57,7 +60,7 @@ func (st *SchmittTrigger) Feed(envelope float64) *StateTransition {
 	h, l := st.thresholder.Update(envelope)
 	//st.SetThresholds(h, l)
 	if st.totalSamples%200000 == 0 {
-		fmt.Printf("[DEBUG] hight%.1f,low,%.1f value%.2f\n", h, l, envelope)
+		logger.Debug("schmitt trigger", "adaptive_high", h, "adaptive_low", l, "envelope", envelope)
 	}
 
 	// 1. 原始施密特逻辑 (Raw Schmitt Logic)
@@ -120,8 +123,40 @@ func (st *SchmittTrigger) GetCurrentState() bool {
 	return st.currentState
 }
 
+// SetDebounceMs 运行时调整去抖窗口 (毫秒)，按采样率重新换算成采样点数
+// 高速发报时点和点间隔都很短，固定的去抖窗口会吞掉真实的码元
+func (st *SchmittTrigger) SetDebounceMs(ms float64) {
+	if ms < 0 {
+		ms = 0
+	}
+	st.debounceCount = int64(ms / 1000.0 * st.sampleRate)
+}
+
+// DebounceMs 返回当前的去抖窗口 (毫秒)
+func (st *SchmittTrigger) DebounceMs() float64 {
+	return float64(st.debounceCount) / st.sampleRate * 1000.0
+}
+
 // SetThresholds 动态调整阈值
 func (st *SchmittTrigger) SetThresholds(high, low float64) {
 	st.thresholdHigh = high
 	st.thresholdLow = low
 }
+
+// SetThreshold 按迟滞比例设置阈值：High = threshold，Low = threshold * (1 - 2 * 比例)
+// 即把 0 到 threshold 视为动态范围，与 AdaptiveThresholder 的迟滞宽度含义相同 (默认 Low = High * 0.8)
+func (st *SchmittTrigger) SetThreshold(threshold float64) {
+	st.SetThresholds(threshold, threshold*(1-2*st.hysteresis))
+}
+
+// SetHysteresisRatio 同时设置 SetThreshold 和内部 AdaptiveThresholder 的迟滞比例，
+// 下一次 SetThreshold 时生效。<= 0 时恢复默认值 DefaultHysteresisRatio，最大 MaxHysteresisRatio。
+func (st *SchmittTrigger) SetHysteresisRatio(ratio float64) {
+	st.thresholder.SetHysteresisRatio(ratio)
+	st.hysteresis = normalizeHysteresisRatio(ratio)
+}
+
+// Thresholds 返回当前的高/低阈值
+func (st *SchmittTrigger) Thresholds() (high, low float64) {
+	return st.thresholdHigh, st.thresholdLow
+}
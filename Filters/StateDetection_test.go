@@ -0,0 +1,99 @@
+package Filters
+
+import (
+	"math"
+	"testing"
+)
+
+// feedPulse 输入 silence - pulse - silence 的包络，返回触发器报告的 Mark 时长
+func feedPulse(st *SchmittTrigger, sampleRate float64, pulseMs float64) []float64 {
+	var marks []float64
+	feed := func(level float64, ms float64) {
+		for i := 0; i < int(ms/1000*sampleRate); i++ {
+			if tr := st.Feed(level); tr != nil && tr.FinishedState {
+				marks = append(marks, tr.DurationMs)
+			}
+		}
+	}
+	feed(0, 100)
+	feed(1, pulseMs)
+	feed(0, 100)
+	return marks
+}
+
+func TestSchmittTrigger_SetDebounceMs(t *testing.T) {
+	const sampleRate = 8000
+	st := NewSchmittTrigger(sampleRate, 0.5, 0.4, 0.012)
+	if got := st.DebounceMs(); got != 12 {
+		t.Errorf("Expected 12ms debounce, got %.2f", got)
+	}
+
+	// 10ms 的短脉冲 (高速发报的点) 被 12ms 的去抖吞掉
+	if marks := feedPulse(st, sampleRate, 10); len(marks) != 0 {
+		t.Errorf("Expected the pulse to be debounced, got %v", marks)
+	}
+
+	st = NewSchmittTrigger(sampleRate, 0.5, 0.4, 0.012)
+	st.SetDebounceMs(6)
+	if got := st.DebounceMs(); got != 6 {
+		t.Errorf("Expected 6ms debounce, got %.2f", got)
+	}
+	marks := feedPulse(st, sampleRate, 10)
+	if len(marks) != 1 || marks[0] < 9 || marks[0] > 11 {
+		t.Errorf("Expected one ~10ms mark, got %v", marks)
+	}
+}
+
+func TestSchmittTrigger_HysteresisRatio(t *testing.T) {
+	const sampleRate = 8000
+	st := NewSchmittTrigger(sampleRate, 0.2, 0.15, 0)
+	st.SetThreshold(0.5)
+	if high, low := st.Thresholds(); high != 0.5 || math.Abs(low-0.4) > 1e-12 {
+		t.Errorf("Expected default thresholds 0.5/0.4, got %.3f/%.3f", high, low)
+	}
+
+	// 下降沿在 0.45 和 0.55 之间抖动 40ms (弱信号的点)
+	feedWobble := func(st *SchmittTrigger) []float64 {
+		var marks []float64
+		feed := func(level float64, ms float64) {
+			for i := 0; i < int(ms/1000*sampleRate); i++ {
+				if tr := st.Feed(level); tr != nil && tr.FinishedState {
+					marks = append(marks, tr.DurationMs)
+				}
+			}
+		}
+		feed(0, 100)
+		feed(1, 50)
+		for i := 0; i < 4; i++ {
+			feed(0.45, 5)
+			feed(0.55, 5)
+		}
+		feed(0, 100)
+		return marks
+	}
+	// 默认迟滞 (低阈值 0.4) 把抖动当作同一个 Mark
+	if marks := feedWobble(st); len(marks) != 1 || math.Abs(marks[0]-90) > 1 {
+		t.Errorf("Expected one ~90ms mark with the default hysteresis, got %v", marks)
+	}
+
+	// 迟滞太窄 (低阈值 0.48) 时 Mark 被拆成好几段
+	st = NewSchmittTrigger(sampleRate, 0.2, 0.15, 0)
+	st.SetHysteresisRatio(0.02)
+	st.SetThreshold(0.5)
+	if marks := feedWobble(st); len(marks) < 4 {
+		t.Errorf("Expected the narrow hysteresis to chatter, got %v", marks)
+	}
+
+	st.SetHysteresisRatio(0)
+	st.SetThreshold(0.5)
+	if _, low := st.Thresholds(); math.Abs(low-0.4) > 1e-12 {
+		t.Errorf("Expected ratio 0 to restore the default, got low %.3f", low)
+	}
+
+	// 超过上限时按 MaxHysteresisRatio
+	st.SetHysteresisRatio(0.9)
+	st.SetThreshold(0.5)
+	if _, low := st.Thresholds(); math.Abs(low-0.5*(1-2*MaxHysteresisRatio)) > 1e-12 {
+		t.Errorf("Expected the ratio to be capped at %.2f, got low %.3f", MaxHysteresisRatio, low)
+	}
+}
@@ -0,0 +1,14 @@
+package Filters
+
+import "log/slog"
+
+// logger 诊断输出，默认丢弃 (见 cw.SetLogger)
+var logger = slog.New(slog.DiscardHandler)
+
+// SetLogger 设置诊断日志的输出，nil 表示关闭
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	logger = l
+}
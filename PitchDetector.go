@@ -1,18 +1,18 @@
 package cw
 
 import (
+	"fmt"
 	"math"
 	"math/cmplx"
 
 	"github.com/mjibson/go-dsp/fft"
-	"github.com/mjibson/go-dsp/window"
 )
 
 // PitchDetectorConfig 配置参数
 type PitchDetectorConfig struct {
 	SampleRate     float64
-	FFTSize        int     // 建议 1024 或 2048
-	MinFreq        float64 // 搜索下限，如 300Hz
+	FFTSize        int     // 建议 1024 或 2048。窗函数按此长度预先生成，运行时修改必须用 SetFFTSize
+	MinFreq        float64 // 搜索下限，如 300Hz (运行时可用 SetBand 修改)
 	MaxFreq        float64 // 搜索上限，如 1200Hz
 	SmoothingAlpha float64 // 平滑系数 (0.0-1.0)，越小越平滑，建议 0.1
 	MaxJumpHz      float64 // 允许的最大突变频率，超过此值视为干扰，建议 50Hz
@@ -31,7 +31,7 @@ type PitchDetector struct {
 func NewPitchDetector(cfg PitchDetectorConfig) *PitchDetector {
 	return &PitchDetector{
 		config:      cfg,
-		windowCache: window.Blackman(cfg.FFTSize),
+		windowCache: makeWindow(WindowBlackman, cfg.FFTSize),
 		hasLock:     false,
 	}
 }
@@ -42,6 +42,33 @@ func (pd *PitchDetector) Reset() {
 	pd.hasLock = false
 }
 
+// SetBand 修改搜索频段 (例如换到频段的另一部分后缩小搜索范围)，无需重新创建检测器。
+// 已锁定的频率不在新频段内时清除锁定。
+func (pd *PitchDetector) SetBand(minFreq, maxFreq float64) error {
+	if minFreq < 0 || maxFreq <= minFreq {
+		return fmt.Errorf("invalid band %.1f-%.1f Hz", minFreq, maxFreq)
+	}
+	if nyquist := pd.config.SampleRate / 2; maxFreq > nyquist {
+		return fmt.Errorf("band %.1f-%.1f Hz exceeds the Nyquist frequency %.1f Hz", minFreq, maxFreq, nyquist)
+	}
+	pd.config.MinFreq, pd.config.MaxFreq = minFreq, maxFreq
+	if pd.hasLock && (pd.lastFreq < minFreq || pd.lastFreq > maxFreq) {
+		pd.Reset()
+	}
+	return nil
+}
+
+// SetFFTSize 修改 FFT 点数并重新生成窗函数缓存 (窗函数长度必须与 FFTSize 一致)
+// 频率锁定保留，之后的 Detect 需要至少 size 个采样。
+func (pd *PitchDetector) SetFFTSize(size int) error {
+	if size < 2 {
+		return fmt.Errorf("invalid FFT size %d", size)
+	}
+	pd.config.FFTSize = size
+	pd.windowCache = makeWindow(WindowBlackman, size)
+	return nil
+}
+
 // Detect 输入音频切片，返回探测到的频率。
 // found: 是否找到有效信号
 func (pd *PitchDetector) Detect(samples []float64) (freq float64, found bool) {
@@ -116,3 +116,45 @@ func TestPitchDetector_Silence(t *testing.T) {
 		t.Error("Should not detect signal in silence/noise below threshold")
 	}
 }
+
+func TestPitchDetector_SetBand(t *testing.T) {
+	cfg := PitchDetectorConfig{
+		SampleRate:     testSampleRate,
+		FFTSize:        testFFTSize,
+		MinFreq:        300,
+		MaxFreq:        1200,
+		SmoothingAlpha: 1.0,
+		MaxJumpHz:      50.0,
+		NoiseThreshold: 0.1,
+	}
+	pd := NewPitchDetector(cfg)
+	pd.Detect(generateSineWave(600.0, 0.1, testSampleRate))
+
+	// 换到 1500Hz 附近：旧频段找不到，锁定也不在新频段内，应清除后重新锁定
+	if err := pd.SetBand(1400, 1800); err != nil {
+		t.Fatalf("SetBand: %v", err)
+	}
+	got, found := pd.Detect(generateSineWave(1500.0, 0.1, testSampleRate))
+	if !found || math.Abs(got-1500) > 1.0 {
+		t.Errorf("after SetBand: got %v (found=%v), want ~1500", got, found)
+	}
+
+	// 修改 FFT 点数后窗函数重新生成，检测仍然准确
+	if err := pd.SetFFTSize(1024); err != nil {
+		t.Fatalf("SetFFTSize: %v", err)
+	}
+	got, found = pd.Detect(generateSineWave(1500.0, 0.05, testSampleRate))
+	if !found || math.Abs(got-1500) > 1.0 {
+		t.Errorf("after SetFFTSize: got %v (found=%v), want ~1500", got, found)
+	}
+
+	if err := pd.SetBand(1800, 1400); err == nil {
+		t.Error("expected error for inverted band")
+	}
+	if err := pd.SetBand(1000, 30000); err == nil {
+		t.Error("expected error for band above Nyquist")
+	}
+	if err := pd.SetFFTSize(0); err == nil {
+		t.Error("expected error for zero FFT size")
+	}
+}
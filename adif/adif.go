@@ -0,0 +1,78 @@
+// Package adif 将解码得到的通联记录导出为 ADIF 3.x 格式
+package adif
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// ADIF 文件头参数
+const (
+	adifVersion = "3.1.4"
+	programID   = "cw"
+)
+
+// TimeLayout QSORecord.Time 使用的时间格式 (UTC)，导出时拆分为 QSO_DATE 和 TIME_ON
+const TimeLayout = "20060102 150405"
+
+// QSORecord 一条通联记录，空字段不会写入文件
+type QSORecord struct {
+	Call    string // 对方呼号 (必填)
+	Freq    string // 频率 (MHz)，例如 "7.025000"
+	Mode    string // 模式，例如 "CW"
+	Time    string // 通联时间 (UTC)，格式见 TimeLayout
+	RSTSent string // 发出的信号报告
+	RSTRcvd string // 收到的信号报告
+}
+
+// WriteADIF 将记录写成 ADIF 文件 (带文件头，每条记录以 <EOR> 结束)
+func WriteADIF(w io.Writer, records []QSORecord) error {
+	header := fmt.Sprintf("Generated by %s\n", programID) +
+		field("ADIF_VER", adifVersion) + field("PROGRAMID", programID) + "<EOH>\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	for i, r := range records {
+		line, err := r.encode()
+		if err != nil {
+			return fmt.Errorf("record %d: %v", i, err)
+		}
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encode 生成单条记录
+func (r QSORecord) encode() (string, error) {
+	if r.Call == "" {
+		return "", fmt.Errorf("missing call")
+	}
+
+	line := field("CALL", r.Call)
+	line += field("FREQ", r.Freq)
+	line += field("MODE", r.Mode)
+	if r.Time != "" {
+		t, err := time.Parse(TimeLayout, r.Time)
+		if err != nil {
+			return "", fmt.Errorf("invalid time %q: %v", r.Time, err)
+		}
+		line += field("QSO_DATE", t.Format("20060102"))
+		line += field("TIME_ON", t.Format("150405"))
+	}
+	line += field("RST_SENT", r.RSTSent)
+	line += field("RST_RCVD", r.RSTRcvd)
+	return line + "<EOR>\n", nil
+}
+
+// field 生成 <NAME:长度>值 形式的字段，值为空时返回空串
+// ADIF 的长度按字节计算
+func field(name, value string) string {
+	if value == "" {
+		return ""
+	}
+	return fmt.Sprintf("<%s:%d>%s ", name, len(value), value)
+}
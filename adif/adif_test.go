@@ -0,0 +1,45 @@
+package adif
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteADIF(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteADIF(&buf, []QSORecord{
+		{Call: "BG1ABC", Freq: "7.025000", Mode: "CW", Time: "20261017 083015", RSTSent: "599", RSTRcvd: "579"},
+		{Call: "JA1XYZ/P", Mode: "CW"},
+	})
+	if err != nil {
+		t.Fatalf("WriteADIF failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "<ADIF_VER:5>3.1.4 ") || !strings.Contains(out, "<EOH>\n") {
+		t.Errorf("Missing ADIF header:\n%s", out)
+	}
+
+	want := "<CALL:6>BG1ABC <FREQ:8>7.025000 <MODE:2>CW <QSO_DATE:8>20261017 <TIME_ON:6>083015 " +
+		"<RST_SENT:3>599 <RST_RCVD:3>579 <EOR>\n"
+	if !strings.Contains(out, want) {
+		t.Errorf("Expected record %q in:\n%s", want, out)
+	}
+	if !strings.Contains(out, "<CALL:8>JA1XYZ/P <MODE:2>CW <EOR>\n") {
+		t.Errorf("Empty fields should be omitted:\n%s", out)
+	}
+	if strings.Count(out, "<EOR>") != 2 {
+		t.Errorf("Expected 2 records, got:\n%s", out)
+	}
+}
+
+func TestWriteADIF_Invalid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteADIF(&buf, []QSORecord{{Mode: "CW"}}); err == nil {
+		t.Error("Expected error for record without call")
+	}
+	if err := WriteADIF(&buf, []QSORecord{{Call: "BG1ABC", Time: "yesterday"}}); err == nil {
+		t.Error("Expected error for invalid time")
+	}
+}
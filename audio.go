@@ -8,6 +8,9 @@ import (
 	"github.com/gen2brain/malgo"
 )
 
+// captureChannels 捕获声道数 (回调按交错排列的 float32 处理)
+const captureChannels = 1
+
 // AudioCallback 定义音频数据回调函数类型
 type AudioCallback func(samples []float32)
 
@@ -15,8 +18,47 @@ type AudioCallback func(samples []float32)
 type AudioCapture struct {
 	ctx        *malgo.AllocatedContext
 	device     *malgo.Device
-	SampleRate int
+	SampleRate int // 回调收到的音频的采样率 (即请求的采样率)
 	Callback   AudioCallback
+
+	// DeviceSampleRate 声卡实际运行的采样率。
+	// 有些 USB 声卡只支持 44100 或 96000，与 SampleRate 不同时音频先重采样到 SampleRate 再回调，
+	// 否则解码器的所有时间计算 (WPM、点划长度) 都会按错误的采样率进行。
+	DeviceSampleRate int
+	resampler        *linearResampler
+}
+
+// DeviceInfo 描述一个音频捕获设备
+type DeviceInfo struct {
+	Name      string // 设备名称，AudioDeviceName 按子串 (不区分大小写) 匹配它
+	ID        string // 后端的设备 ID (十六进制)
+	IsDefault bool   // 是否为系统默认捕获设备
+}
+
+// ListCaptureDevices 列出可用的音频捕获设备，供用户确定 AudioDeviceName 应填写的名称
+func ListCaptureDevices() ([]DeviceInfo, error) {
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init malgo context: %v", err)
+	}
+	defer func() {
+		_ = ctx.Uninit()
+		ctx.Free()
+	}()
+
+	infos, err := ctx.Devices(malgo.Capture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enumerate capture devices: %v", err)
+	}
+	devices := make([]DeviceInfo, 0, len(infos))
+	for _, info := range infos {
+		devices = append(devices, DeviceInfo{
+			Name:      info.Name(),
+			ID:        info.ID.String(),
+			IsDefault: info.IsDefault != 0,
+		})
+	}
+	return devices, nil
 }
 
 // NewAudioCapture 创建新的音频捕获实例
@@ -34,7 +76,7 @@ func NewAudioCapture(sampleRate int, targetDeviceName string, callback AudioCall
 
 	deviceConfig := malgo.DefaultDeviceConfig(malgo.Capture)
 	deviceConfig.Capture.Format = malgo.FormatF32
-	deviceConfig.Capture.Channels = 1
+	deviceConfig.Capture.Channels = captureChannels
 	deviceConfig.SampleRate = uint32(sampleRate)
 	deviceConfig.Alsa.NoMMap = 1
 
@@ -44,7 +86,7 @@ func NewAudioCapture(sampleRate int, targetDeviceName string, callback AudioCall
 			for _, info := range infos {
 				if strings.Contains(strings.ToLower(info.Name()), strings.ToLower(targetDeviceName)) {
 					deviceConfig.Capture.DeviceID = info.ID.Pointer()
-					fmt.Printf("Selected Audio Device: %s\n", info.Name())
+					logger.Info("selected audio device", "name", info.Name())
 					break
 				}
 			}
@@ -55,10 +97,13 @@ func NewAudioCapture(sampleRate int, targetDeviceName string, callback AudioCall
 		if ac.Callback == nil {
 			return
 		}
-		if len(pInputSamples) == 0 {
+		samples := inputSamples(pInputSamples, framecount, captureChannels)
+		if len(samples) == 0 {
 			return
 		}
-		samples := unsafe.Slice((*float32)(unsafe.Pointer(&pInputSamples[0])), int(framecount))
+		if ac.resampler != nil {
+			samples = ac.resampler.process(samples)
+		}
 		ac.Callback(samples)
 	}
 
@@ -73,14 +118,36 @@ func NewAudioCapture(sampleRate int, targetDeviceName string, callback AudioCall
 		return nil, fmt.Errorf("failed to init device: %v", err)
 	}
 	ac.device = device
-	
-	// 打印实际采样率
-	// 注意：malgo.Device 的方法可能因版本而异，这里只打印 SampleRate()
-	fmt.Printf("Audio Device Initialized. Rate: %d Hz\n", device.SampleRate())
+
+	ac.DeviceSampleRate = int(device.SampleRate())
+	if ac.DeviceSampleRate > 0 && ac.DeviceSampleRate != sampleRate {
+		logger.Warn("audio device sample rate differs from the requested rate, resampling",
+			"device_hz", ac.DeviceSampleRate, "requested_hz", sampleRate)
+		ac.resampler = newLinearResampler(ac.DeviceSampleRate, sampleRate)
+	}
+	logger.Info("audio device initialized", "rate_hz", device.SampleRate())
 
 	return ac, nil
 }
 
+// inputSamples 把后端传来的字节缓冲按 float32 解释。
+// 长度以缓冲区实际字节数为准，并限制在 framecount*channels 以内：
+// 有些后端的缓冲长度与 framecount 不一致 (声道数、不完整的帧)，直接按 framecount 切片会越界读。
+// 结尾不完整的帧丢弃。
+func inputSamples(buf []byte, framecount uint32, channels int) []float32 {
+	n := len(buf) / 4
+	if want := int(framecount) * channels; want < n {
+		n = want
+	}
+	if channels > 1 {
+		n -= n % channels
+	}
+	if n <= 0 {
+		return nil
+	}
+	return unsafe.Slice((*float32)(unsafe.Pointer(&buf[0])), n)
+}
+
 // Start 启动音频捕获
 func (ac *AudioCapture) Start() error {
 	if ac.device == nil {
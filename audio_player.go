@@ -0,0 +1,147 @@
+package cw
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"github.com/gen2brain/malgo"
+)
+
+// AudioPlayer 管理音频播放 (单声道 float32)，与 AudioCapture 对应
+// 用于把解码结果重新生成 CW 音频播放出来，让操作员用耳朵核对 (复读练习模式)。
+type AudioPlayer struct {
+	ctx        *malgo.AllocatedContext
+	device     *malgo.Device
+	SampleRate int
+
+	playMu  sync.Mutex    // 串行化 Play 调用
+	mu      sync.Mutex    // 保护 pending / done (播放回调线程与 Play 共享)
+	pending []float32     // 尚未送入声卡的采样
+	done    chan struct{} // pending 播完时关闭
+	closed  chan struct{} // Close 时关闭，唤醒等待中的 Play
+}
+
+// NewAudioPlayer 创建新的音频播放实例
+// targetDeviceName 为空时使用系统默认输出设备，否则按名称 (不区分大小写的子串) 选择。
+func NewAudioPlayer(sampleRate int, targetDeviceName string) (*AudioPlayer, error) {
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init malgo context: %v", err)
+	}
+
+	ap := &AudioPlayer{
+		ctx:        ctx,
+		SampleRate: sampleRate,
+		closed:     make(chan struct{}),
+	}
+
+	deviceConfig := malgo.DefaultDeviceConfig(malgo.Playback)
+	deviceConfig.Playback.Format = malgo.FormatF32
+	deviceConfig.Playback.Channels = 1
+	deviceConfig.SampleRate = uint32(sampleRate)
+	deviceConfig.Alsa.NoMMap = 1
+
+	if targetDeviceName != "" {
+		infos, err := ctx.Devices(malgo.Playback)
+		if err == nil {
+			for _, info := range infos {
+				if strings.Contains(strings.ToLower(info.Name()), strings.ToLower(targetDeviceName)) {
+					deviceConfig.Playback.DeviceID = info.ID.Pointer()
+					logger.Info("selected playback device", "name", info.Name())
+					break
+				}
+			}
+		}
+	}
+
+	onSendFrames := func(pOutputSample, pInputSamples []byte, framecount uint32) {
+		if len(pOutputSample) == 0 {
+			return
+		}
+		ap.fill(unsafe.Slice((*float32)(unsafe.Pointer(&pOutputSample[0])), int(framecount)))
+	}
+
+	device, err := malgo.InitDevice(ctx.Context, deviceConfig, malgo.DeviceCallbacks{Data: onSendFrames})
+	if err != nil {
+		_ = ctx.Uninit()
+		ctx.Free()
+		return nil, fmt.Errorf("failed to init playback device: %v", err)
+	}
+	ap.device = device
+
+	if err := device.Start(); err != nil {
+		ap.Close()
+		return nil, fmt.Errorf("failed to start playback device: %v", err)
+	}
+	return ap, nil
+}
+
+// fill 播放回调：把待播放的采样拷进声卡缓冲区，不足部分补静音
+func (ap *AudioPlayer) fill(out []float32) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+
+	n := copy(out, ap.pending)
+	for i := n; i < len(out); i++ {
+		out[i] = 0
+	}
+	ap.pending = ap.pending[n:]
+	if len(ap.pending) == 0 && ap.done != nil {
+		close(ap.done)
+		ap.done = nil
+	}
+}
+
+// Play 播放一段音频，阻塞直到全部送入声卡
+func (ap *AudioPlayer) Play(samples []float32) error {
+	ap.playMu.Lock()
+	defer ap.playMu.Unlock()
+
+	if len(samples) == 0 {
+		return nil
+	}
+	select {
+	case <-ap.closed:
+		return fmt.Errorf("audio player closed")
+	default:
+	}
+
+	done := make(chan struct{})
+	ap.mu.Lock()
+	ap.pending = samples
+	ap.done = done
+	ap.mu.Unlock()
+
+	select {
+	case <-done:
+		return nil
+	case <-ap.closed:
+		return fmt.Errorf("audio player closed during playback")
+	}
+}
+
+// PlayText 用 GenerateCW 把文本编码为 CW 音频并播放 (采样率使用播放器的采样率)
+func (ap *AudioPlayer) PlayText(text string, cfg AudioConfig) error {
+	cfg.SampleRate = ap.SampleRate
+	return ap.Play(GenerateCW(text, cfg))
+}
+
+// Close 停止播放并释放资源
+func (ap *AudioPlayer) Close() {
+	select {
+	case <-ap.closed:
+	default:
+		close(ap.closed)
+	}
+	if ap.device != nil {
+		ap.device.Uninit()
+		ap.device = nil
+	}
+	if ap.ctx != nil {
+		_ = ap.ctx.Uninit()
+		ap.ctx.Free()
+		ap.ctx = nil
+	}
+}
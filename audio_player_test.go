@@ -0,0 +1,59 @@
+package cw
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAudioPlayer_Fill(t *testing.T) {
+	// 不打开声卡，直接驱动播放回调
+	ap := &AudioPlayer{SampleRate: 8000, closed: make(chan struct{})}
+	errc := make(chan error, 1)
+	go func() { errc <- ap.Play([]float32{1, 2, 3, 4, 5}) }()
+
+	// 等 Play 把数据放进缓冲区
+	for i := 0; ; i++ {
+		ap.mu.Lock()
+		queued := ap.done != nil
+		ap.mu.Unlock()
+		if queued {
+			break
+		}
+		if i > 1000 {
+			t.Fatal("Play did not queue samples")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	out := make([]float32, 3)
+	ap.fill(out)
+	if out[0] != 1 || out[2] != 3 {
+		t.Errorf("Expected first 3 samples, got %v", out)
+	}
+	select {
+	case <-errc:
+		t.Fatal("Play returned before all samples were sent")
+	default:
+	}
+
+	ap.fill(out)
+	if out[0] != 4 || out[1] != 5 || out[2] != 0 {
+		t.Errorf("Expected remaining samples padded with silence, got %v", out)
+	}
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("Play failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Play did not return after the buffer drained")
+	}
+}
+
+func TestAudioPlayer_PlayAfterClose(t *testing.T) {
+	ap := &AudioPlayer{SampleRate: 8000, closed: make(chan struct{})}
+	ap.Close()
+	if err := ap.Play([]float32{1}); err == nil {
+		t.Error("Expected error when playing on a closed player")
+	}
+}
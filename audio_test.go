@@ -0,0 +1,51 @@
+package cw
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func float32Bytes(vals ...float32) []byte {
+	buf := make([]byte, 4*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func TestInputSamples_Boundaries(t *testing.T) {
+	buf := float32Bytes(0.1, 0.2, 0.3, 0.4)
+
+	// 正常情况：framecount 与缓冲长度一致
+	if got := inputSamples(buf, 4, 1); len(got) != 4 || got[3] != 0.4 {
+		t.Errorf("full buffer: got %v", got)
+	}
+
+	// 缓冲比 framecount 声明的短：只能读缓冲内的数据
+	if got := inputSamples(buf[:8], 4, 1); len(got) != 2 || got[1] != 0.2 {
+		t.Errorf("short buffer: got %v, want [0.1 0.2]", got)
+	}
+
+	// 末尾有不足 4 字节的残片
+	if got := inputSamples(buf[:10], 4, 1); len(got) != 2 {
+		t.Errorf("partial float: got %d samples, want 2", len(got))
+	}
+
+	// framecount 比缓冲小：不读多余的数据
+	if got := inputSamples(buf, 3, 1); len(got) != 3 {
+		t.Errorf("framecount limit: got %d samples, want 3", len(got))
+	}
+
+	// 双声道：丢弃结尾不完整的帧
+	if got := inputSamples(buf[:12], 2, 2); len(got) != 2 {
+		t.Errorf("stereo partial frame: got %d samples, want 2", len(got))
+	}
+
+	if got := inputSamples(nil, 4, 1); got != nil {
+		t.Errorf("empty buffer: got %v", got)
+	}
+	if got := inputSamples(buf[:3], 1, 1); got != nil {
+		t.Errorf("sub-sample buffer: got %v", got)
+	}
+}
@@ -0,0 +1,152 @@
+// Package bigram 从文本语料统计字符 bigram，生成 BeamDecoder.LanguageModel 使用的模型
+package bigram
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode"
+
+	"cw/BeamDecoder"
+)
+
+// Options 模型构建参数
+type Options struct {
+	// AddK add-k (Laplace) 平滑：每个组合的计数加 k，语料中没出现过的组合也有非零概率。
+	// 0 表示不平滑 (只输出出现过的组合)
+	AddK float64
+}
+
+// DefaultOptions 默认参数
+func DefaultOptions() Options {
+	return Options{AddK: 0.1}
+}
+
+// BuildBigramModel 读取语料，返回 log(P(next|curr))，使用 DefaultOptions
+// 结构: {"A": {"B": -2.5, "C": -4.1}, ...}，与 BeamDecoder.LanguageModel.LogProbs 一致 (对数概率，不是概率)，
+// 写成 JSON 后可以直接作为 ham_bigrams.json 加载。
+// key 是单个字符、空格 " " 或整个勤务符号 (例如 "<SK>")。
+// 另外输出 BeamDecoder.UnigramKey 行 (一元概率 log(P(next)))，供加载端回退使用。
+func BuildBigramModel(corpus io.Reader) (map[string]map[string]float64, error) {
+	return BuildBigramModelWithOptions(corpus, DefaultOptions())
+}
+
+// BuildBigramModelWithOptions 同 BuildBigramModel，可指定平滑参数
+func BuildBigramModelWithOptions(corpus io.Reader, opts Options) (map[string]map[string]float64, error) {
+	if opts.AddK < 0 {
+		return nil, fmt.Errorf("AddK must be non-negative, got %f", opts.AddK)
+	}
+	content, err := io.ReadAll(corpus)
+	if err != nil {
+		return nil, fmt.Errorf("read corpus: %w", err)
+	}
+
+	// 转大写，因为 CW 不分大小写；只保留 CW 能发的字符，连续空白合并为一个空格
+	tokens := tokenize(PreProcess(strings.ToUpper(string(content))))
+	if len(tokens) < 2 {
+		return nil, fmt.Errorf("corpus has no usable bigrams")
+	}
+
+	counts := make(map[string]map[string]int)
+	totals := make(map[string]int)
+	unigrams := make(map[string]int)
+	for i, curr := range tokens {
+		unigrams[curr]++
+		if i == len(tokens)-1 {
+			break
+		}
+		next := tokens[i+1]
+		if _, ok := counts[curr]; !ok {
+			counts[curr] = make(map[string]int)
+		}
+		counts[curr][next]++
+		totals[curr]++
+	}
+
+	logProbs := make(map[string]map[string]float64, len(counts)+1)
+	for curr, nextMap := range counts {
+		logProbs[curr] = smoothed(nextMap, totals[curr], unigrams, opts.AddK)
+	}
+	logProbs[BeamDecoder.UnigramKey] = smoothed(unigrams, len(tokens), unigrams, opts.AddK)
+	return logProbs, nil
+}
+
+// smoothed 计算 log((count+k) / (total+k*V))，V 为词表 (vocab) 大小
+// k > 0 时词表中的每个 token 都有一项；k == 0 时只输出出现过的组合
+func smoothed(counts map[string]int, total int, vocab map[string]int, k float64) map[string]float64 {
+	denom := math.Log(float64(total) + k*float64(len(vocab)))
+	out := make(map[string]float64, len(vocab))
+	for next := range vocab {
+		c := float64(counts[next]) + k
+		if c > 0 {
+			out[next] = math.Log(c) - denom
+		}
+	}
+	return out
+}
+
+// PreProcess 只保留 CW 能发的字符，连续空白合并为单个空格
+// 输入应已转成大写。允许的字符:
+// A-Z É Ñ Ü Ä Ö 0-9 . , ? ' ! / ( ) & : ; = + - _ " $ @，以及 <AR> <SK> 等勤务符号
+func PreProcess(input string) string {
+	var sb strings.Builder
+	lastWasSpace := false
+
+	// 除了 A-Z, 0-9 之外允许的字符
+	const specialChars = ".,?'!/()&:;=+-_\"$@ÉÑÜÄÖ"
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		// 勤务符号 <AR> <SK> 等整体保留
+		if p := matchProsign(runes[i:]); p != "" {
+			sb.WriteString(p)
+			i += len(p) - 1
+			lastWasSpace = false
+			continue
+		}
+
+		isValid := (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune(specialChars, r)
+
+		if isValid {
+			sb.WriteRune(r)
+			lastWasSpace = false
+		} else if unicode.IsSpace(r) {
+			if !lastWasSpace {
+				sb.WriteRune(' ') // 统一用单空格
+				lastWasSpace = true
+			}
+		}
+	}
+	return sb.String()
+}
+
+// prosigns 语料中以尖括号书写的勤务符号，作为单个 token 统计
+var prosigns = []string{"<AR>", "<SK>", "<BT>", "<KN>", "<AS>", "<BK>"}
+
+// matchProsign 如果 runes 以勤务符号开头，返回该符号
+func matchProsign(runes []rune) string {
+	for _, p := range prosigns {
+		if strings.HasPrefix(string(runes[:min(len(runes), len(p))]), p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// tokenize 将预处理后的文本切分为 token：单个字符或整个勤务符号
+func tokenize(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if p := matchProsign(runes[i:]); p != "" {
+			tokens = append(tokens, p)
+			i += len(p) - 1
+			continue
+		}
+		tokens = append(tokens, string(runes[i]))
+	}
+	return tokens
+}
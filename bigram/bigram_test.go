@@ -0,0 +1,119 @@
+package bigram
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"cw/BeamDecoder"
+)
+
+func TestPreProcess(t *testing.T) {
+	got := PreProcess(strings.ToUpper("cq  de\tbg1abc <SK>\n\n73 ~ café #1"))
+	if want := "CQ DE BG1ABC <SK> 73 CAFÉ 1"; got != want {
+		t.Errorf("PreProcess = %q, want %q", got, want)
+	}
+}
+
+func TestBuildBigramModel(t *testing.T) {
+	lm, err := BuildBigramModelWithOptions(strings.NewReader("qa qb <sk> qa"), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Q 后面: A 两次, B 一次
+	if got, want := lm["Q"]["A"], math.Log(2.0/3); math.Abs(got-want) > 1e-12 {
+		t.Errorf("log P(A|Q) = %f, want %f", got, want)
+	}
+	if got, want := lm["Q"]["B"], math.Log(1.0/3); math.Abs(got-want) > 1e-12 {
+		t.Errorf("log P(B|Q) = %f, want %f", got, want)
+	}
+	// 勤务符号作为单个 token
+	if got, want := lm[" "]["<SK>"], math.Log(1.0/3); math.Abs(got-want) > 1e-12 {
+		t.Errorf("log P(<SK>| ) = %f, want %f", got, want)
+	}
+	if _, ok := lm["<"]; ok {
+		t.Error("Prosign should not be split into characters")
+	}
+	// 不平滑时只有出现过的组合
+	if _, ok := lm["Q"]["Q"]; ok {
+		t.Error("Unseen pair should be absent without smoothing")
+	}
+	// 一元概率: 10 个 token 中 Q 出现 3 次
+	if got, want := lm[BeamDecoder.UnigramKey]["Q"], math.Log(3.0/10); math.Abs(got-want) > 1e-12 {
+		t.Errorf("log P(Q) = %f, want %f", got, want)
+	}
+
+	// 每个前驱的转移概率之和为 1
+	for curr, next := range lm {
+		sum := 0.0
+		for _, lp := range next {
+			sum += math.Exp(lp)
+		}
+		if math.Abs(sum-1) > 1e-9 {
+			t.Errorf("Transitions from %q sum to %f", curr, sum)
+		}
+	}
+}
+
+func TestBuildBigramModel_AddK(t *testing.T) {
+	lm, err := BuildBigramModelWithOptions(strings.NewReader("qa qb <sk> qa"), Options{AddK: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 词表: Q A B " " <SK> (V=5)，Q 后面共 3 次
+	if got, want := lm["Q"]["A"], math.Log(3.0/8); math.Abs(got-want) > 1e-12 {
+		t.Errorf("log P(A|Q) = %f, want %f", got, want)
+	}
+	// 没出现过的组合也有有限的概率
+	if got, want := lm["Q"]["Q"], math.Log(1.0/8); math.Abs(got-want) > 1e-12 {
+		t.Errorf("log P(Q|Q) = %f, want %f", got, want)
+	}
+	for curr, next := range lm {
+		if len(next) != 5 {
+			t.Errorf("Row %q has %d entries, want 5", curr, len(next))
+		}
+	}
+
+	if _, err := BuildBigramModelWithOptions(strings.NewReader("qa"), Options{AddK: -1}); err == nil {
+		t.Error("Expected error for negative AddK")
+	}
+}
+
+// 构建结果可以直接交给 LanguageModel：词表内的组合按平滑后的概率打分，词表外的回退到一元概率
+func TestBuildBigramModel_LoadedBackoff(t *testing.T) {
+	logProbs, err := BuildBigramModel(strings.NewReader("the quick brown fox 73"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lm := &BeamDecoder.LanguageModel{LogProbs: logProbs, DefaultProb: math.Log(1e-6)}
+
+	if got := lm.GetTransitionScore("T", "H"); got <= lm.GetTransitionScore("T", "Q") {
+		t.Errorf("Seen pair TH (%f) should score above unseen TQ", got)
+	}
+	if got := lm.GetTransitionScore("T", "Q"); math.IsInf(got, -1) || got <= lm.DefaultProb {
+		t.Errorf("Smoothed unseen pair should beat DefaultProb, got %f", got)
+	}
+	// Z 不在语料中作为前驱：回退到 P(O)
+	if got, want := lm.GetTransitionScore("Z", "O"), math.Log(0.4)+logProbs[BeamDecoder.UnigramKey]["O"]; math.Abs(got-want) > 1e-12 {
+		t.Errorf("Backoff score = %f, want %f", got, want)
+	}
+	// 完全没见过的字符仍然是 DefaultProb
+	if got := lm.GetTransitionScore("Z", "Z"); got != lm.DefaultProb {
+		t.Errorf("Unknown token should fall back to DefaultProb, got %f", got)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("disk error") }
+
+func TestBuildBigramModel_Errors(t *testing.T) {
+	if _, err := BuildBigramModel(failingReader{}); err == nil {
+		t.Error("Expected read error")
+	}
+	if _, err := BuildBigramModel(strings.NewReader("~#~")); err == nil {
+		t.Error("Expected error for a corpus without usable characters")
+	}
+}
@@ -89,3 +89,31 @@ func (f *ButterworthFilter) Process(in float64) float64 {
 	}
 	return out
 }
+
+// DCBlocker 一阶隔直高通滤波器：y[n] = x[n] - x[n-1] + R*y[n-1]
+// 去掉声卡输入的直流偏置和极低频的晃动 (截止频率以下)。
+type DCBlocker struct {
+	r      float64
+	prevX  float64
+	prevY  float64
+	primed bool
+}
+
+// NewDCBlocker 按截止频率 (Hz) 创建隔直滤波器，R = exp(-2π * cutoff / sampleRate)
+// 截止频率越高，直流阶跃消失得越快，但对低音调的衰减也越大；几 Hz 到几十 Hz 即可。
+func NewDCBlocker(sampleRate, cutoffFreq float64) *DCBlocker {
+	return &DCBlocker{r: math.Exp(-2 * math.Pi * cutoffFreq / sampleRate)}
+}
+
+// Process 处理单个采样点
+// 第一个采样点被当作已经持续存在的直流，输出从 0 开始：
+// 廉价 USB 声卡开机就带着偏置，如果从 0 开始算，偏置本身就是一个阶跃，会在包络上留下一个尖峰。
+func (f *DCBlocker) Process(in float64) float64 {
+	if !f.primed {
+		f.prevX = in
+		f.primed = true
+	}
+	out := in - f.prevX + f.r*f.prevY
+	f.prevX, f.prevY = in, out
+	return out
+}
@@ -0,0 +1,84 @@
+package cw
+
+import (
+	"math"
+	"sort"
+)
+
+// 噪声校准参数
+const (
+	calibDecimation      = 48    // 包络降采样倍率 (48kHz -> 1kHz)，足够描述噪声分布
+	calibSettleSec       = 0.1   // 丢弃开头的滤波器暂态 (秒)
+	calibThresholdMargin = 2.0   // 解码阈值 = 噪声包络 P95 * 此倍数 (约 6dB)
+	calibSquelchMargin   = 3.16  // 搜台静噪 = 噪声频谱峰值 * 此倍数 (约 10dB)
+	calibMinThreshold    = 0.001 // 解码阈值下限，防止数字静音时阈值为 0
+	calibMinSquelch      = 0.002 // 静噪门限下限，防止误触电路噪声
+)
+
+// NoiseStats 描述一次噪声校准测得的统计特征
+// 包络域 (Mean/StdDev/Median/P95/Threshold) 与 SDR 解调输出同尺度，
+// 频谱域 (SpectralPeak/Squelch) 与搜台时的归一化 FFT 幅度同尺度。
+type NoiseStats struct {
+	Mean   float64 // 噪声包络均值
+	StdDev float64 // 噪声包络标准差
+	Median float64 // 噪声包络中位数
+	P95    float64 // 噪声包络 95% 分位点
+
+	SpectralPeak float64 // 搜台频段内归一化 FFT 峰值的 95% 分位点
+
+	Threshold float64 // 推导出的解码器初始阈值 (包络域)
+	Squelch   float64 // 推导出的搜台静噪门限 (频谱域)
+	Samples   int     // 参与统计的包络点数
+}
+
+// computeNoiseStats 根据噪声包络样本和频谱峰值样本计算统计结果
+func computeNoiseStats(envelopes, spectralPeaks []float64) NoiseStats {
+	stats := NoiseStats{Samples: len(envelopes)}
+
+	if len(envelopes) > 0 {
+		sum := 0.0
+		for _, v := range envelopes {
+			sum += v
+		}
+		stats.Mean = sum / float64(len(envelopes))
+
+		varianceSum := 0.0
+		for _, v := range envelopes {
+			varianceSum += (v - stats.Mean) * (v - stats.Mean)
+		}
+		stats.StdDev = math.Sqrt(varianceSum / float64(len(envelopes)))
+
+		sorted := make([]float64, len(envelopes))
+		copy(sorted, envelopes)
+		sort.Float64s(sorted)
+		stats.Median = percentile(sorted, 0.5)
+		stats.P95 = percentile(sorted, 0.95)
+	}
+
+	if len(spectralPeaks) > 0 {
+		sorted := make([]float64, len(spectralPeaks))
+		copy(sorted, spectralPeaks)
+		sort.Float64s(sorted)
+		stats.SpectralPeak = percentile(sorted, 0.95)
+	}
+
+	stats.Threshold = stats.P95 * calibThresholdMargin
+	if stats.Threshold < calibMinThreshold {
+		stats.Threshold = calibMinThreshold
+	}
+	stats.Squelch = stats.SpectralPeak * calibSquelchMargin
+	if stats.Squelch < calibMinSquelch {
+		stats.Squelch = calibMinSquelch
+	}
+
+	return stats
+}
+
+// percentile 返回已排序数据中 p (0.0 - 1.0) 分位处的值
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * p)
+	return sorted[idx]
+}
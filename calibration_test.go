@@ -0,0 +1,46 @@
+package cw
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestComputeNoiseStats(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	envelopes := make([]float64, 5000)
+	for i := range envelopes {
+		envelopes[i] = 0.01 + math.Abs(rng.NormFloat64())*0.002
+	}
+	// 偶发的强干扰不应抬高静噪门限
+	peaks := make([]float64, 40)
+	for i := range peaks {
+		peaks[i] = 0.003
+	}
+	peaks[7] = 0.5
+
+	stats := computeNoiseStats(envelopes, peaks)
+
+	if stats.Samples != len(envelopes) {
+		t.Errorf("Expected %d samples, got %d", len(envelopes), stats.Samples)
+	}
+	if stats.Median < 0.01 || stats.Median > stats.P95 {
+		t.Errorf("Unexpected median %.5f (p95 %.5f)", stats.Median, stats.P95)
+	}
+	if math.Abs(stats.Threshold-stats.P95*calibThresholdMargin) > 1e-12 {
+		t.Errorf("Threshold %.5f should be p95 * margin", stats.Threshold)
+	}
+	if stats.Threshold <= stats.Mean+stats.StdDev {
+		t.Errorf("Threshold %.5f should sit above the noise (mean %.5f, std %.5f)", stats.Threshold, stats.Mean, stats.StdDev)
+	}
+	if math.Abs(stats.Squelch-0.003*calibSquelchMargin) > 1e-12 {
+		t.Errorf("Expected squelch %.5f, got %.5f", 0.003*calibSquelchMargin, stats.Squelch)
+	}
+}
+
+func TestComputeNoiseStats_Silence(t *testing.T) {
+	stats := computeNoiseStats(make([]float64, 100), nil)
+	if stats.Threshold != calibMinThreshold || stats.Squelch != calibMinSquelch {
+		t.Errorf("Digital silence should fall back to floors, got threshold %.5f squelch %.5f", stats.Threshold, stats.Squelch)
+	}
+}
@@ -0,0 +1,87 @@
+package cw
+
+import (
+	"math"
+	"math/rand"
+)
+
+// ChannelEffects 描述模拟信道的劣化参数
+type ChannelEffects struct {
+	SNRdB    float64 // 信噪比 (按整段信号的平均功率计算，不含干扰台)
+	QSBRate  float64 // 衰落频率 (Hz)，例如 0.5Hz
+	QSBDepth float64 // 衰落深度 (0.0 - 1.0)
+	Seed     int64   // 噪声的随机种子，非 0 时同样的输入总是得到同样的输出；0 使用全局随机源
+
+	// Frequency 主信号的音调频率 (Hz)，干扰台的频率以它为基准
+	Frequency float64
+	// Interferers 邻频干扰台 (QRM)，不受主信号的衰落影响
+	Interferers []InterfererConfig
+}
+
+// InterfererConfig 描述一个邻频 CW 干扰台
+type InterfererConfig struct {
+	FreqOffset float64 // 相对主信号的频率偏移 (Hz)，可为负
+	WPM        float64 // 干扰台的发送速度
+	LevelDB    float64 // 相对主信号峰值幅度的电平 (dB)，0 表示一样强
+	Text       string  // 干扰台发送的内容，比主信号长的部分被截断
+}
+
+// ApplyEffects 在纯净信号上叠加正弦衰落、邻频干扰台和高斯白噪声，返回新的切片
+func ApplyEffects(signal []float32, sampleRate int, fx ChannelEffects) []float32 {
+	normal := rand.NormFloat64
+	if fx.Seed != 0 {
+		normal = rand.New(rand.NewSource(fx.Seed)).NormFloat64
+	}
+
+	out := make([]float32, len(signal))
+	copy(out, signal)
+
+	// 信号平均功率 P_signal (简化为整体 RMS，包含点划间的静音) 和峰值幅度
+	var signalEnergy, peak float64
+	for _, s := range signal {
+		signalEnergy += float64(s * s)
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	if peak == 0 {
+		return out // 全是静音，没法按 SNR 加噪声，也没有干扰台的参考电平
+	}
+	pSignal := signalEnergy / float64(len(signal))
+
+	// SNR(dB) = 10 * log10(P_signal / P_noise)
+	pNoise := pSignal / math.Pow(10, fx.SNRdB/10.0)
+	noiseScale := math.Sqrt(pNoise)
+
+	// 先衰落：幅度在 (1-depth) 到 1.0 之间波动
+	if fx.QSBDepth > 0 {
+		qsbPhase := 0.0
+		qsbInc := 2.0 * math.Pi * fx.QSBRate / float64(sampleRate)
+		for i := range out {
+			fading := 1.0 - (fx.QSBDepth * (0.5 + 0.5*math.Sin(qsbPhase)))
+			out[i] *= float32(fading)
+			qsbPhase += qsbInc
+		}
+	}
+
+	for _, qrm := range fx.Interferers {
+		tone := GenerateCW(qrm.Text, AudioConfig{
+			WPM:        qrm.WPM,
+			SampleRate: sampleRate,
+			Frequency:  fx.Frequency + qrm.FreqOffset,
+		})
+		gain := float32(peak * math.Pow(10, qrm.LevelDB/20.0))
+		for i := 0; i < len(out) && i < len(tone); i++ {
+			out[i] += tone[i] * gain
+		}
+	}
+
+	for i := range out {
+		out[i] += float32(normal() * noiseScale)
+	}
+
+	return out
+}
+
+// noisyRecording 模拟一段录音：按 padSilence 首尾加静音，再按 fx 叠加信道劣化
+func noisyRecording(audio []float32, sampleRate int, tail float64, fx ChannelEffects) []float32 {
+	return ApplyEffects(padSilence(audio, sampleRate, tail), sampleRate, fx)
+}
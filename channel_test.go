@@ -0,0 +1,102 @@
+package cw
+
+import (
+	"math"
+	"testing"
+)
+
+func meanPower(s []float32) float64 {
+	var sum float64
+	for _, v := range s {
+		sum += float64(v) * float64(v)
+	}
+	return sum / float64(len(s))
+}
+
+func TestApplyEffects_Seed(t *testing.T) {
+	signal := GenerateCW("PARIS", AudioConfig{WPM: 20, SampleRate: 8000, Frequency: 700})
+	fx := ChannelEffects{SNRdB: 0, QSBRate: 0.5, QSBDepth: 0.5, Seed: 42}
+
+	a := ApplyEffects(signal, 8000, fx)
+	b := ApplyEffects(signal, 8000, fx)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Same seed gave different output at sample %d", i)
+		}
+	}
+
+	fx.Seed = 43
+	c := ApplyEffects(signal, 8000, fx)
+	same := true
+	for i := range a {
+		if a[i] != c[i] {
+			same = false
+			break
+		}
+	}
+	if same {
+		t.Error("Different seeds gave identical output")
+	}
+}
+
+func TestApplyEffects_SNR(t *testing.T) {
+	signal := GenerateCW("PARIS PARIS", AudioConfig{WPM: 20, SampleRate: 8000, Frequency: 700})
+	noisy := ApplyEffects(signal, 8000, ChannelEffects{SNRdB: 10, Seed: 1})
+
+	noise := make([]float32, len(signal))
+	for i := range noise {
+		noise[i] = noisy[i] - signal[i]
+	}
+	snr := 10 * math.Log10(meanPower(signal)/meanPower(noise))
+	if math.Abs(snr-10) > 0.5 {
+		t.Errorf("Expected SNR near 10 dB, got %.2f", snr)
+	}
+
+	// 全静音输入无法定义 SNR，原样返回
+	silence := make([]float32, 100)
+	for _, v := range ApplyEffects(silence, 8000, ChannelEffects{SNRdB: 0, Seed: 1}) {
+		if v != 0 {
+			t.Fatal("Expected silence to pass through unchanged")
+		}
+	}
+}
+
+// toneLevel 用 Goertzel 测量 s 中 freq 处的幅度
+func toneLevel(s []float32, sampleRate int, freq float64) float64 {
+	g := NewGoertzel(float64(sampleRate), freq)
+	for _, v := range s {
+		g.ProcessSample(float64(v))
+	}
+	return g.Detect()
+}
+
+func TestApplyEffects_Interferers(t *testing.T) {
+	const sampleRate = 8000
+	signal := GenerateCW("TTTT", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	fx := ChannelEffects{
+		SNRdB:     100,
+		Frequency: 700,
+		Interferers: []InterfererConfig{
+			{FreqOffset: 300, WPM: 20, LevelDB: 0, Text: "TTTTTTTTTTTTTTTT"},
+			{FreqOffset: -200, WPM: 25, LevelDB: -20, Text: "TTTTTTTTTTTTTTTT"},
+		},
+	}
+	out := ApplyEffects(signal, sampleRate, fx)
+	if len(out) != len(signal) {
+		t.Fatalf("Expected output length %d, got %d", len(signal), len(out))
+	}
+
+	want := toneLevel(signal, sampleRate, 700)
+	if got := toneLevel(out, sampleRate, 700); math.Abs(got-want)/want > 0.05 {
+		t.Errorf("Wanted signal changed: %.1f -> %.1f", want, got)
+	}
+	// 同样的码型、同样的电平，干扰台的幅度应与主信号相当；-20dB 的干扰台约弱 10 倍
+	strong := toneLevel(out, sampleRate, 1000)
+	weak := toneLevel(out, sampleRate, 500)
+	if strong < want*0.8 {
+		t.Errorf("Expected 0 dB interferer near %.1f at 1000 Hz, got %.1f", want, strong)
+	}
+	if ratio := strong / weak; ratio < 5 || ratio > 20 {
+		t.Errorf("Expected -20 dB interferer about 10x weaker, got ratio %.1f", ratio)
+	}
+}
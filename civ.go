@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"io"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/tarm/serial"
@@ -13,10 +15,45 @@ import (
 const (
 	CIV_PREAMBLE  = 0xFE
 	CIV_END       = 0xFD
+	CIV_NG        = 0xFA // 电台拒绝命令
+	CIV_OK        = 0xFB // 电台接受命令
 	CIV_ADDR_7300 = 0x94 // ICOM 7300 默认地址
+	CIV_ADDR_7610 = 0x98 // ICOM 7610 默认地址
+	CIV_ADDR_9700 = 0xA2 // ICOM 9700 默认地址
 	CIV_ADDR_PC   = 0xE0 // 控制器(PC) 默认地址
+
+	DefaultCIVReadTimeout = 2 * time.Second // 等待一帧完整响应的默认总时限
+
+	CIVMinFrequency = 30000    // IC-7300 接收下限 30 kHz (其他电台见 civFrequencyRanges)
+	CIVMaxFrequency = 74800000 // IC-7300 接收上限 74.8 MHz
+
+	CIVMinKeyerWPM = 6  // 内置电键最低速度
+	CIVMaxKeyerWPM = 48 // 内置电键最高速度
+
+	CIVMinCWPitch = 300 // CW 音调 (侧音) 下限 (Hz)
+	CIVMaxCWPitch = 900 // CW 音调 (侧音) 上限 (Hz)
 )
 
+// civModes CI-V 模式字节与名称的映射表
+var civModes = map[byte]string{
+	0x00: "LSB", 0x01: "USB", 0x02: "AM", 0x03: "CW",
+	0x04: "RTTY", 0x05: "FM", 0x07: "CW-R", 0x08: "RTTY-R",
+	0x17: "DV",
+}
+
+// CIVFrequencyRange 电台可接收的一段频率范围 (Hz，含两端)
+type CIVFrequencyRange struct {
+	Min, Max int
+}
+
+// civFrequencyRanges 按 CI-V 默认地址查找电台的接收范围
+// 表中没有的地址不在本地检查频率，由电台回复 NG
+var civFrequencyRanges = map[byte][]CIVFrequencyRange{
+	CIV_ADDR_7300: {{CIVMinFrequency, CIVMaxFrequency}},
+	CIV_ADDR_7610: {{30000, 60000000}},
+	CIV_ADDR_9700: {{144000000, 148000000}, {430000000, 450000000}, {1240000000, 1300000000}},
+}
+
 // SerialPort 定义串口操作接口，方便测试 Mock
 type SerialPort interface {
 	io.ReadWriteCloser
@@ -24,19 +61,49 @@ type SerialPort interface {
 
 // CIVClient 处理与 ICOM 电台的通信
 type CIVClient struct {
-	Port     string
-	BaudRate int
-	conn     SerialPort
+	Port         string
+	BaudRate     int
+	RadioAddress byte          // 电台 CI-V 地址，0 表示使用 CIV_ADDR_7300
+	ReadTimeout  time.Duration // 等待响应帧的总时限，0 表示使用 DefaultCIVReadTimeout
+	// FrequencyRanges SetFrequency 接受的频率范围，nil 表示按 RadioAddress 查默认表
+	// (电台改过 CI-V 地址时需要手动设置)
+	FrequencyRanges []CIVFrequencyRange
+	conn            SerialPort
+	rxBuf           []byte // 跨多次 Read 累积的接收数据
 }
 
-// NewCIVClient 创建新的 CI-V 客户端
+// NewCIVClient 创建新的 CI-V 客户端 (默认 IC-7300 地址)
 func NewCIVClient(port string, baudRate int) *CIVClient {
+	return NewCIVClientAddr(port, baudRate, CIV_ADDR_7300)
+}
+
+// NewCIVClientAddr 创建指定电台地址的 CI-V 客户端
+// addr 为电台菜单中设置的 CI-V Address，例如 IC-7610 为 0x98，IC-9700 为 0xA2
+func NewCIVClientAddr(port string, baudRate int, addr byte) *CIVClient {
 	return &CIVClient{
-		Port:     port,
-		BaudRate: baudRate,
+		Port:         port,
+		BaudRate:     baudRate,
+		RadioAddress: addr,
+		ReadTimeout:  DefaultCIVReadTimeout,
 	}
 }
 
+// radioAddr 返回实际使用的电台地址
+func (c *CIVClient) radioAddr() byte {
+	if c.RadioAddress == 0 {
+		return CIV_ADDR_7300
+	}
+	return c.RadioAddress
+}
+
+// frequencyRanges 返回 SetFrequency 使用的频率范围，nil 表示不检查
+func (c *CIVClient) frequencyRanges() []CIVFrequencyRange {
+	if c.FrequencyRanges != nil {
+		return c.FrequencyRanges
+	}
+	return civFrequencyRanges[c.radioAddr()]
+}
+
 // Open 打开串口连接
 func (c *CIVClient) Open() error {
 	config := &serial.Config{
@@ -66,7 +133,7 @@ func (c *CIVClient) SendCommand(cmd byte, subCmd []byte) error {
 		return fmt.Errorf("connection not open")
 	}
 	// 构造帧: FE FE [To] [From] [Cmd] [SubCmd...] FD
-	frame := []byte{CIV_PREAMBLE, CIV_PREAMBLE, CIV_ADDR_7300, CIV_ADDR_PC, cmd}
+	frame := []byte{CIV_PREAMBLE, CIV_PREAMBLE, c.radioAddr(), CIV_ADDR_PC, cmd}
 	if len(subCmd) > 0 {
 		frame = append(frame, subCmd...)
 	}
@@ -82,11 +149,11 @@ func (c *CIVClient) SendText(text string) error {
 	if len(text) > 30 {
 		return fmt.Errorf("text too long (max 30 chars)")
 	}
-	
+
 	// Cmd 0x17: Send CW Message
 	// 数据部分直接是 ASCII 字符
 	data := []byte(text)
-	
+
 	// 发送指令
 	// 注意：发送 CW 指令后，电台通常不会立即返回数据，除非配置了 Echo
 	// 我们这里只负责发送
@@ -108,7 +175,7 @@ func (c *CIVClient) ReadFrequency() (int, error) {
 	// 解析 BCD 编码的频率数据
 	// 响应格式: FE FE E0 94 03 [d1 d2 d3 d4 d5] FD
 	// 数据部分是 5 字节 BCD，低位在前
-	// 例如 7.050.00 MHz -> 00 00 50 07 00
+	// 例如 7.050.00 MHz -> 00 00 05 07 00
 	if len(resp) < 5 {
 		return 0, fmt.Errorf("invalid frequency data length")
 	}
@@ -143,70 +210,224 @@ func (c *CIVClient) ReadMode() (string, error) {
 		return "", fmt.Errorf("invalid mode data")
 	}
 
-	// 模式映射表
-	modes := map[byte]string{
-		0x00: "LSB", 0x01: "USB", 0x02: "AM", 0x03: "CW",
-		0x04: "RTTY", 0x05: "FM", 0x07: "CW-R", 0x08: "RTTY-R",
-		0x17: "DV",
-	}
-
 	modeByte := resp[0]
-	if name, ok := modes[modeByte]; ok {
+	if name, ok := civModes[modeByte]; ok {
 		return name, nil
 	}
 	return fmt.Sprintf("Unknown(0x%02X)", modeByte), nil
 }
 
-// readResponse 读取并解析响应
-func (c *CIVClient) readResponse(expectedCmd byte) ([]byte, error) {
-	if c.conn == nil {
-		return nil, fmt.Errorf("connection not open")
+// SetFrequency 设置当前频率 (Hz)
+func (c *CIVClient) SetFrequency(hz int) error {
+	if hz <= 0 {
+		return fmt.Errorf("invalid frequency %d Hz", hz)
 	}
-	buf := make([]byte, 1024)
-	n, err := c.conn.Read(buf)
-	if err != nil {
-		if err == io.EOF {
-			return nil, fmt.Errorf("connection closed")
+	if ranges := c.frequencyRanges(); ranges != nil && !inFrequencyRanges(hz, ranges) {
+		return fmt.Errorf("frequency %d Hz out of range for radio 0x%02X %v", hz, c.radioAddr(), ranges)
+	}
+
+	// Cmd 0x05: Set operating frequency
+	// 数据为 5 字节 BCD，低位在前，与 ReadFrequency 的响应格式相同
+	data := make([]byte, 5)
+	v := hz
+	for i := 0; i < 5; i++ {
+		data[i] = decimalToBCD(v % 100)
+		v /= 100
+	}
+
+	if err := c.SendCommand(0x05, data); err != nil {
+		return err
+	}
+	_, err := c.readResponse(CIV_OK)
+	return err
+}
+
+// inFrequencyRanges 判断 hz 是否落在任一范围内
+func inFrequencyRanges(hz int, ranges []CIVFrequencyRange) bool {
+	for _, r := range ranges {
+		if hz >= r.Min && hz <= r.Max {
+			return true
 		}
-		// 串口读取超时也可能返回 err，视库实现而定
-		// 这里简单处理
 	}
-	if n == 0 {
-		return nil, fmt.Errorf("timeout or no data")
+	return false
+}
+
+// SetMode 设置当前模式 (LSB, USB, CW, etc.)
+func (c *CIVClient) SetMode(mode string) error {
+	mode = strings.ToUpper(mode)
+
+	var modeByte byte
+	found := false
+	for b, name := range civModes {
+		if name == mode {
+			modeByte = b
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown mode %q", mode)
+	}
+
+	// Cmd 0x06: Set operating mode (不带滤波器字节时电台保持当前滤波器)
+	if err := c.SendCommand(0x06, []byte{modeByte}); err != nil {
+		return err
+	}
+	_, err := c.readResponse(CIV_OK)
+	return err
+}
+
+// SetKeyerSpeed 设置内置电键速度 (WPM)，影响 SendText 的发送速度
+// 超出 6-48 WPM 的值会被钳位到边界。
+// 电台使用 0-255 的电平值表示速度，线性映射:
+//
+//	level = round((wpm - 6) * 255 / (48 - 6))
+//
+// 电平以 2 字节 BCD 发送，高位在前，例如 128 -> 01 28。
+func (c *CIVClient) SetKeyerSpeed(wpm int) error {
+	if wpm < CIVMinKeyerWPM {
+		wpm = CIVMinKeyerWPM
+	}
+	if wpm > CIVMaxKeyerWPM {
+		wpm = CIVMaxKeyerWPM
 	}
 
-	data := buf[:n]
-	// 简单的帧查找逻辑
-	// 寻找 FE FE E0 94 [Cmd] ... FD
-	// 注意：串口可能会回显我们发送的指令，需要过滤
+	level := int(math.Round(float64(wpm-CIVMinKeyerWPM) * 255 / float64(CIVMaxKeyerWPM-CIVMinKeyerWPM)))
+	data := []byte{0x0C, decimalToBCD(level / 100), decimalToBCD(level % 100)}
 
-	// 查找目标帧头: FE FE [To=PC] [From=7300] [Cmd]
-	header := []byte{CIV_PREAMBLE, CIV_PREAMBLE, CIV_ADDR_PC, CIV_ADDR_7300, expectedCmd}
-	idx := bytes.Index(data, header)
-	if idx == -1 {
-		// 可能是回显，或者分包了（这里简化处理，假设一次读完或主要部分在buffer里）
-		// 实际生产代码需要更健壮的 buffer 缓存机制
-		return nil, fmt.Errorf("response header not found in: %s", hex.EncodeToString(data))
+	// Cmd 0x14 Sub 0x0C: Key speed
+	if err := c.SendCommand(0x14, data); err != nil {
+		return err
 	}
+	_, err := c.readResponse(CIV_OK)
+	return err
+}
 
-	// 截取从 header 开始的数据
-	frame := data[idx:]
-	endIdx := bytes.IndexByte(frame, CIV_END)
-	if endIdx == -1 {
-		return nil, fmt.Errorf("frame end not found")
+// ReadCWPitch 读取 CW 音调 (Hz)，即接收时 CW 信号在音频中的频率
+// 电台使用 0-255 的电平值表示 300-900Hz，以 5Hz 为步进:
+//
+//	pitch = 300 + round(level * 600 / 255 / 5) * 5
+func (c *CIVClient) ReadCWPitch() (int, error) {
+	// Cmd 0x14 Sub 0x09: CW pitch
+	if err := c.SendCommand(0x14, []byte{0x09}); err != nil {
+		return 0, err
+	}
+	resp, err := c.readResponse(0x14)
+	if err != nil {
+		return 0, err
+	}
+	// 响应数据: 09 [高位 BCD] [低位 BCD]
+	if len(resp) < 3 || resp[0] != 0x09 {
+		return 0, fmt.Errorf("invalid CW pitch data")
 	}
+	level := bcdToDecimal(resp[1])*100 + bcdToDecimal(resp[2])
+	if level > 255 {
+		return 0, fmt.Errorf("CW pitch level %d out of range", level)
+	}
+	step := math.Round(float64(level) * float64(CIVMaxCWPitch-CIVMinCWPitch) / 255 / 5)
+	return CIVMinCWPitch + int(step)*5, nil
+}
 
-	// 提取数据部分: Header(5 bytes) ... Data ... End(1 byte)
-	// Header: FE FE E0 94 Cmd
-	if endIdx <= 5 {
-		return []byte{}, nil // 无数据
+// readResponse 读取并解析响应
+// 慢速串口上一帧常被拆成多次 Read，这里把数据累积到 rxBuf 中，
+// 直到找到完整的 FE FE [To=PC] [From=Radio] [Cmd] ... FD 帧或超过 ReadTimeout。
+// 串口可能回显我们发送的指令 (PC -> Radio)，这类帧以及其他无关帧会被丢弃。
+func (c *CIVClient) readResponse(expectedCmd byte) ([]byte, error) {
+	if c.conn == nil {
+		return nil, fmt.Errorf("connection not open")
 	}
 
-	return frame[5:endIdx], nil
+	timeout := c.ReadTimeout
+	if timeout <= 0 {
+		timeout = DefaultCIVReadTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	buf := make([]byte, 256)
+	for {
+		// 1. 先尝试从已有数据中解析
+		if data, found, err := c.extractFrame(expectedCmd); found {
+			return data, err
+		}
+
+		if time.Now().After(deadline) {
+			if len(c.rxBuf) > 0 {
+				return nil, fmt.Errorf("timeout waiting for response, partial data: %s", hex.EncodeToString(c.rxBuf))
+			}
+			return nil, fmt.Errorf("timeout or no data")
+		}
+
+		// 2. 继续读取
+		n, err := c.conn.Read(buf)
+		if n > 0 {
+			c.rxBuf = append(c.rxBuf, buf[:n]...)
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if n == 0 {
+			// 串口读取超时 (或没有数据) 时稍等再试，避免空转
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+// extractFrame 从 rxBuf 中依次取出完整帧，直到找到期望命令的响应
+// found: 是否找到了需要返回给调用者的帧 (期望的响应或 NG)
+func (c *CIVClient) extractFrame(expectedCmd byte) (data []byte, found bool, err error) {
+	for {
+		start := bytes.Index(c.rxBuf, []byte{CIV_PREAMBLE, CIV_PREAMBLE})
+		if start == -1 {
+			// 没有帧头，只保留最后一个字节 (可能是半个帧头)
+			if len(c.rxBuf) > 1 {
+				c.rxBuf = c.rxBuf[len(c.rxBuf)-1:]
+			}
+			return nil, false, nil
+		}
+		c.rxBuf = c.rxBuf[start:]
+
+		end := bytes.IndexByte(c.rxBuf, CIV_END)
+		if end == -1 {
+			return nil, false, nil // 帧不完整，等待更多数据
+		}
+
+		frame := c.rxBuf[:end+1]
+		c.rxBuf = c.rxBuf[end+1:]
+
+		// 帧结构: FE FE [To] [From] [Cmd] [Data...] FD
+		if len(frame) < 6 {
+			continue // 残缺帧
+		}
+		// 跳过帧头中多余的 FE (部分电台会发送多个前导码)
+		for len(frame) > 6 && frame[2] == CIV_PREAMBLE {
+			frame = frame[1:]
+		}
+		if frame[2] != CIV_ADDR_PC || frame[3] != c.radioAddr() {
+			continue // 回显 (PC -> Radio) 或发给其他设备的帧
+		}
+
+		cmd := frame[4]
+		if cmd == CIV_NG {
+			return nil, true, fmt.Errorf("radio rejected command 0x%02X (NG)", expectedCmd)
+		}
+		if cmd != expectedCmd {
+			continue // 其他命令的响应 (例如电台主动上报的 transceive 数据)
+		}
+
+		// 提取数据部分: Header(5 bytes) ... Data ... End(1 byte)
+		payload := make([]byte, len(frame)-6)
+		copy(payload, frame[5:len(frame)-1])
+		return payload, true, nil
+	}
 }
 
 func bcdToDecimal(b byte) int {
-	return int((b >> 4) * 10 + (b & 0x0F))
+	return int((b>>4)*10 + (b & 0x0F))
+}
+
+// decimalToBCD 将 0-99 的整数编码为一个 BCD 字节
+func decimalToBCD(v int) byte {
+	return byte((v/10)<<4 | (v % 10))
 }
 
 // AutoDetectPort 尝试列出可能的串口 (仅作占位，实际需要系统调用或库支持)
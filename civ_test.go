@@ -3,6 +3,7 @@ package cw
 import (
 	"bytes"
 	"testing"
+	"time"
 )
 
 // MockSerialPort 模拟串口
@@ -10,6 +11,7 @@ type MockSerialPort struct {
 	ReadBuffer  *bytes.Buffer
 	WriteBuffer *bytes.Buffer
 	Closed      bool
+	ChunkSize   int // >0 时每次 Read 最多返回这么多字节，模拟慢速串口分包
 }
 
 func NewMockSerialPort() *MockSerialPort {
@@ -20,6 +22,9 @@ func NewMockSerialPort() *MockSerialPort {
 }
 
 func (m *MockSerialPort) Read(p []byte) (n int, err error) {
+	if m.ChunkSize > 0 && len(p) > m.ChunkSize {
+		p = p[:m.ChunkSize]
+	}
 	return m.ReadBuffer.Read(p)
 }
 
@@ -64,12 +69,12 @@ func TestReadFrequency(t *testing.T) {
 	mockPort := NewMockSerialPort()
 	client := &CIVClient{conn: mockPort}
 
-	// 模拟电台响应: 7.050.00 MHz -> 00 00 50 07 00 (BCD)
+	// 模拟电台响应: 7.050.00 MHz -> 00 00 05 07 00 (BCD)
 	// 注意：ReadFrequency 会先发送指令，然后读取响应
 	// 我们需要预先填充 ReadBuffer
-	
+
 	// 构造响应帧
-	freqData := []byte{0x00, 0x00, 0x50, 0x07, 0x00}
+	freqData := []byte{0x00, 0x00, 0x05, 0x07, 0x00}
 	respFrame := makeResponseFrame(0x03, freqData)
 	mockPort.ReadBuffer.Write(respFrame)
 
@@ -130,21 +135,21 @@ func TestReadResponse_EchoFilter(t *testing.T) {
 
 	// 模拟回显 + 真实响应
 	// 回显: FE FE 94 E0 03 FD (PC -> Radio)
-	// 响应: FE FE E0 94 03 00 00 50 07 00 FD (Radio -> PC)
-	
+	// 响应: FE FE E0 94 03 00 00 05 07 00 FD (Radio -> PC)
+
 	echoFrame := []byte{0xFE, 0xFE, 0x94, 0xE0, 0x03, 0xFD}
-	freqData := []byte{0x00, 0x00, 0x50, 0x07, 0x00}
+	freqData := []byte{0x00, 0x00, 0x05, 0x07, 0x00}
 	respFrame := makeResponseFrame(0x03, freqData)
-	
+
 	mockPort.ReadBuffer.Write(echoFrame)
 	mockPort.ReadBuffer.Write(respFrame)
 
 	// 直接测试 readResponse 内部逻辑比较困难，因为它不是公开的
 	// 但我们可以通过 ReadFrequency 间接测试
-	
+
 	// 注意：ReadFrequency 内部会先 Write 一次，这会清空我们上面的 WriteBuffer (如果我们在测试 SendCommand)
 	// 但这里我们只关心 ReadBuffer
-	
+
 	freq, err := client.ReadFrequency()
 	if err != nil {
 		t.Fatalf("ReadFrequency with echo failed: %v", err)
@@ -156,6 +161,226 @@ func TestReadResponse_EchoFilter(t *testing.T) {
 	}
 }
 
+func TestReadResponse_SplitFrames(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	mockPort.ChunkSize = 3 // 每次只读到 3 个字节
+	client := &CIVClient{conn: mockPort}
+
+	// 回显 + 真实响应，被拆成多次 Read
+	mockPort.ReadBuffer.Write([]byte{0xFE, 0xFE, 0x94, 0xE0, 0x04, 0xFD})
+	mockPort.ReadBuffer.Write(makeResponseFrame(0x04, []byte{0x03, 0x01}))
+
+	mode, err := client.ReadMode()
+	if err != nil {
+		t.Fatalf("ReadMode with split frames failed: %v", err)
+	}
+	if mode != "CW" {
+		t.Errorf("Expected mode CW, got %s", mode)
+	}
+}
+
+func TestReadResponse_SkipsUnrelatedFrames(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := &CIVClient{conn: mockPort}
+
+	// 电台主动上报的频率 (transceive) 在前，模式响应在后
+	mockPort.ReadBuffer.Write(makeResponseFrame(0x00, []byte{0x00, 0x00, 0x05, 0x07, 0x00}))
+	mockPort.ReadBuffer.Write(makeResponseFrame(0x04, []byte{0x01}))
+
+	mode, err := client.ReadMode()
+	if err != nil {
+		t.Fatalf("ReadMode failed: %v", err)
+	}
+	if mode != "USB" {
+		t.Errorf("Expected mode USB, got %s", mode)
+	}
+}
+
+func TestReadResponse_NG(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := &CIVClient{conn: mockPort}
+
+	mockPort.ReadBuffer.Write(makeResponseFrame(CIV_NG, nil))
+
+	if _, err := client.ReadMode(); err == nil {
+		t.Error("Expected error for NG response")
+	}
+}
+
+func TestReadResponse_Timeout(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := &CIVClient{conn: mockPort, ReadTimeout: 50 * time.Millisecond}
+
+	// 只有半帧数据，永远等不到 FD
+	mockPort.ReadBuffer.Write([]byte{0xFE, 0xFE, 0xE0, 0x94, 0x04})
+
+	start := time.Now()
+	if _, err := client.ReadMode(); err == nil {
+		t.Error("Expected timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ReadTimeout not honoured, took %v", elapsed)
+	}
+}
+
+func TestSetFrequency(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := &CIVClient{conn: mockPort}
+	mockPort.ReadBuffer.Write(makeResponseFrame(CIV_OK, nil))
+
+	if err := client.SetFrequency(14025500); err != nil {
+		t.Fatalf("SetFrequency failed: %v", err)
+	}
+
+	// 14.025.500 MHz -> 00 55 02 14 00 (BCD, 低位在前)
+	expected := []byte{0xFE, 0xFE, 0x94, 0xE0, 0x05, 0x00, 0x55, 0x02, 0x14, 0x00, 0xFD}
+	if !bytes.Equal(mockPort.WriteBuffer.Bytes(), expected) {
+		t.Errorf("Expected command frame %X, got %X", expected, mockPort.WriteBuffer.Bytes())
+	}
+}
+
+func TestSetFrequency_OutOfRange(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := &CIVClient{conn: mockPort}
+
+	for _, hz := range []int{0, CIVMinFrequency - 1, CIVMaxFrequency + 1} {
+		if err := client.SetFrequency(hz); err == nil {
+			t.Errorf("Expected error for %d Hz", hz)
+		}
+	}
+	if mockPort.WriteBuffer.Len() != 0 {
+		t.Errorf("Nothing should be sent for invalid frequency, got %X", mockPort.WriteBuffer.Bytes())
+	}
+}
+
+func TestSetFrequency_PerRadio(t *testing.T) {
+	// IC-9700: 2m 波段可以设置，HF 和 70MHz 不行
+	mockPort := NewMockSerialPort()
+	client := NewCIVClientAddr("", 0, CIV_ADDR_9700)
+	client.conn = mockPort
+	mockPort.ReadBuffer.Write([]byte{0xFE, 0xFE, CIV_ADDR_PC, CIV_ADDR_9700, CIV_OK, 0xFD})
+	if err := client.SetFrequency(144050000); err != nil {
+		t.Fatalf("SetFrequency(144.050 MHz) on IC-9700 failed: %v", err)
+	}
+	// 144.050.000 MHz -> 00 00 05 44 01 (BCD, 低位在前)
+	expected := []byte{0xFE, 0xFE, 0xA2, 0xE0, 0x05, 0x00, 0x00, 0x05, 0x44, 0x01, 0xFD}
+	if !bytes.Equal(mockPort.WriteBuffer.Bytes(), expected) {
+		t.Errorf("Expected command frame %X, got %X", expected, mockPort.WriteBuffer.Bytes())
+	}
+	for _, hz := range []int{14025500, 74000000, 200000000} {
+		if err := client.SetFrequency(hz); err == nil {
+			t.Errorf("Expected IC-9700 to reject %d Hz", hz)
+		}
+	}
+	for _, hz := range []int{432100000, 1296200000} {
+		mockPort.ReadBuffer.Write([]byte{0xFE, 0xFE, CIV_ADDR_PC, CIV_ADDR_9700, CIV_OK, 0xFD})
+		if err := client.SetFrequency(hz); err != nil {
+			t.Errorf("SetFrequency(%d) on IC-9700 failed: %v", hz, err)
+		}
+	}
+
+	// IC-7300 不接受 VHF
+	if err := (&CIVClient{conn: NewMockSerialPort()}).SetFrequency(144050000); err == nil {
+		t.Error("Expected IC-7300 to reject 144.050 MHz")
+	}
+
+	// 改过地址的电台可以手动指定范围
+	custom := NewCIVClientAddr("", 0, 0x70)
+	custom.conn = NewMockSerialPort()
+	custom.FrequencyRanges = civFrequencyRanges[CIV_ADDR_9700]
+	if err := custom.SetFrequency(14025500); err == nil {
+		t.Error("Expected custom ranges to be used")
+	}
+}
+
+func TestSetMode(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := &CIVClient{conn: mockPort}
+	mockPort.ReadBuffer.Write(makeResponseFrame(CIV_OK, nil))
+
+	if err := client.SetMode("cw-r"); err != nil {
+		t.Fatalf("SetMode failed: %v", err)
+	}
+
+	expected := []byte{0xFE, 0xFE, 0x94, 0xE0, 0x06, 0x07, 0xFD}
+	if !bytes.Equal(mockPort.WriteBuffer.Bytes(), expected) {
+		t.Errorf("Expected command frame %X, got %X", expected, mockPort.WriteBuffer.Bytes())
+	}
+}
+
+func TestSetMode_Errors(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := &CIVClient{conn: mockPort}
+
+	if err := client.SetMode("PSK31"); err == nil {
+		t.Error("Expected error for unknown mode")
+	}
+
+	// 电台回复 NG
+	mockPort.ReadBuffer.Write(makeResponseFrame(CIV_NG, nil))
+	if err := client.SetMode("USB"); err == nil {
+		t.Error("Expected error when radio replies NG")
+	}
+}
+
+func TestSetKeyerSpeed(t *testing.T) {
+	tests := []struct {
+		wpm      int
+		expected []byte // 0x0C + 2 字节 BCD 电平
+	}{
+		{6, []byte{0x0C, 0x00, 0x00}},
+		{27, []byte{0x0C, 0x01, 0x28}}, // (27-6)*255/42 = 127.5 -> 128
+		{48, []byte{0x0C, 0x02, 0x55}},
+		{3, []byte{0x0C, 0x00, 0x00}},  // 低于下限钳位
+		{60, []byte{0x0C, 0x02, 0x55}}, // 高于上限钳位
+	}
+
+	for _, tt := range tests {
+		mockPort := NewMockSerialPort()
+		client := &CIVClient{conn: mockPort}
+		mockPort.ReadBuffer.Write(makeResponseFrame(CIV_OK, nil))
+
+		if err := client.SetKeyerSpeed(tt.wpm); err != nil {
+			t.Fatalf("SetKeyerSpeed(%d) failed: %v", tt.wpm, err)
+		}
+
+		expected := append([]byte{0xFE, 0xFE, 0x94, 0xE0, 0x14}, tt.expected...)
+		expected = append(expected, 0xFD)
+		if !bytes.Equal(mockPort.WriteBuffer.Bytes(), expected) {
+			t.Errorf("SetKeyerSpeed(%d): expected frame %X, got %X", tt.wpm, expected, mockPort.WriteBuffer.Bytes())
+		}
+	}
+}
+
+func TestRadioAddress(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	client := NewCIVClientAddr("", 0, CIV_ADDR_7610)
+	client.conn = mockPort
+
+	// IC-7300 地址的帧应被忽略，只接受 IC-7610 的响应
+	mockPort.ReadBuffer.Write(makeResponseFrame(0x04, []byte{0x01}))
+	mockPort.ReadBuffer.Write([]byte{0xFE, 0xFE, CIV_ADDR_PC, CIV_ADDR_7610, 0x04, 0x03, 0xFD})
+
+	mode, err := client.ReadMode()
+	if err != nil {
+		t.Fatalf("ReadMode failed: %v", err)
+	}
+	if mode != "CW" {
+		t.Errorf("Expected mode CW from 0x98, got %s", mode)
+	}
+
+	expected := []byte{0xFE, 0xFE, 0x98, 0xE0, 0x04, 0xFD}
+	if !bytes.Equal(mockPort.WriteBuffer.Bytes(), expected) {
+		t.Errorf("Expected command frame %X, got %X", expected, mockPort.WriteBuffer.Bytes())
+	}
+}
+
+func TestNewCIVClient_DefaultAddress(t *testing.T) {
+	if addr := NewCIVClient("", 0).RadioAddress; addr != CIV_ADDR_7300 {
+		t.Errorf("Expected default address 0x94, got 0x%02X", addr)
+	}
+}
+
 func TestClose(t *testing.T) {
 	mockPort := NewMockSerialPort()
 	client := &CIVClient{conn: mockPort}
@@ -169,3 +394,32 @@ func TestClose(t *testing.T) {
 		t.Error("Expected port to be closed")
 	}
 }
+
+func TestReadCWPitch(t *testing.T) {
+	tests := []struct {
+		level []byte // 2 字节 BCD 电平
+		pitch int
+	}{
+		{[]byte{0x00, 0x00}, 300},
+		{[]byte{0x01, 0x28}, 600}, // 300 + 128*600/255 (301.2，按 5Hz 取整为 300)
+		{[]byte{0x02, 0x55}, 900},
+	}
+
+	for _, tt := range tests {
+		mockPort := NewMockSerialPort()
+		client := &CIVClient{conn: mockPort}
+		mockPort.ReadBuffer.Write(makeResponseFrame(0x14, append([]byte{0x09}, tt.level...)))
+
+		pitch, err := client.ReadCWPitch()
+		if err != nil {
+			t.Fatalf("ReadCWPitch failed: %v", err)
+		}
+		if pitch != tt.pitch {
+			t.Errorf("level %X: expected %d Hz, got %d", tt.level, tt.pitch, pitch)
+		}
+		expected := []byte{0xFE, 0xFE, 0x94, 0xE0, 0x14, 0x09, 0xFD}
+		if !bytes.Equal(mockPort.WriteBuffer.Bytes(), expected) {
+			t.Errorf("Expected frame %X, got %X", expected, mockPort.WriteBuffer.Bytes())
+		}
+	}
+}
@@ -2,9 +2,12 @@ package cw
 
 import (
 	"bufio"
+	"cw/BeamDecoder"
+	"cw/Filters"
 	"fmt"
 	"math"
 	"os"
+	"sort"
 )
 
 // ClusterDecoder 使用 K-Means 聚类算法进行高精度 CW 解码
@@ -33,14 +36,25 @@ type ClusterDecoder struct {
 	dashLen    float64
 	elemGapLen float64
 	charGapLen float64
+	wordGapLen float64 // 从间隔统计中学到的单词间隔，0 = 尚未学到 (按 WordGapRatio 判定)
+
+	markConfidence float64 // 点划聚类的置信度，见 MarkConfidence
+	dotSigma       float64 // 点长的标准差 (秒)，用于计算点划的后验概率
+	dashSigma      float64 // 划长的标准差 (秒)
 
 	// 输出
 	symbolBuffer string
+	markDurs     []float64 // 当前字符每个 Mark 的原始时长 (与 symbolBuffer 一一对应)，模糊的点划在字符结束时重新判定
+	lastEmitted  string    // 上一次输出的文本，用于合并连续空格
 	OnDecoded    func(string)
+	OnSymbol     func(sym string, durationMs float64)  // 码元回调 (见 SymbolNotifier)
+	OnConfidence func(char string, confidence float64) // 字符置信度回调 (见 ConfidenceNotifier)
 
 	// Debug
 	debugFile   *os.File
 	debugWriter *bufio.Writer
+
+	muteGate // Mute: 发射期间丢弃输入音频
 }
 
 // NewClusterDecoder 创建实例
@@ -49,35 +63,54 @@ func NewClusterDecoder(sampleRate, targetFreq float64, cfg *Config) *ClusterDeco
 		cfg = DefaultConfig()
 	}
 
-	// 创建调试文件
-	f, err := os.Create("debug_signal.txt")
+	// 按需创建调试文件，创建失败不影响解码
+	var f *os.File
 	var bw *bufio.Writer
-	if err != nil {
-		fmt.Printf("Error creating debug_signal.txt: %v\n", err)
-	} else {
-		bw = bufio.NewWriter(f)
+	if path := cfg.Decoder.DebugSignalFile; path != "" {
+		var err error
+		if f, err = os.Create(path); err != nil {
+			logger.Warn("cannot create debug signal file", "path", path, "err", err)
+			f = nil
+		} else {
+			bw = bufio.NewWriter(f)
+		}
 	}
 
-	return &ClusterDecoder{
+	d := &ClusterDecoder{
 		cfg:           cfg,
 		sdr:           NewSDRDemodulator(sampleRate, targetFreq, cfg),
 		ThresholdHigh: 0.05,
 		ThresholdLow:  0.03,
 		signalPeak:    0.05,
-		markBuffer:    NewWindowBuffer(cfg.Decoder.MarkWindowSize),
-		spaceBuffer:   NewWindowBuffer(cfg.Decoder.SpaceWindowSize),
-		// 初始默认值 (20 WPM)
-		dotLen:      0.06,
-		dashLen:     0.18,
-		elemGapLen:  0.06,
-		charGapLen:  0.18,
-		debugFile:   f,
-		debugWriter: bw,
+		debugFile:     f,
+		debugWriter:   bw,
+		muteGate:      newMuteGate(sampleRate),
 	}
+	d.Reset()
+	return d
+}
+
+// Reset 清空缓冲的码元和聚类统计，点划长度回到 20 WPM 的初始值 (见 StreamDecoder)
+// AGC 跟踪的信号峰值和阈值保持不变。
+func (d *ClusterDecoder) Reset() {
+	d.markBuffer = NewWindowBuffer(d.cfg.Decoder.MarkWindowSize)
+	d.spaceBuffer = NewWindowBuffer(d.cfg.Decoder.SpaceWindowSize)
+	d.dotLen = 0.06
+	d.dashLen = 0.18
+	d.elemGapLen = 0.06
+	d.charGapLen = 0.18
+	d.wordGapLen = 0
+	d.dotSigma = 0.06 * clusterSigmaMin
+	d.dashSigma = 0.06 * clusterSigmaMin
+	d.markConfidence = 0
+	d.symbolBuffer = ""
+	d.markDurs = nil
+	d.lastEmitted = ""
 }
 
 // ProcessAudioChunk 处理音频块
 func (d *ClusterDecoder) ProcessAudioChunk(samples []float32) {
+	samples = d.muteGate.apply(samples)
 	for _, s := range samples {
 		d.processSample(float64(s))
 	}
@@ -111,7 +144,7 @@ func (d *ClusterDecoder) processSample(sample float64) {
 		}
 
 		d.ThresholdHigh = newHigh
-		d.ThresholdLow = newHigh * d.cfg.Decoder.AgcLowRatio
+		d.ThresholdLow = newHigh * Filters.HysteresisLowRatio(d.cfg.Decoder.HysteresisRatio)
 	}
 	// ---------------------------
 
@@ -166,17 +199,14 @@ func (d *ClusterDecoder) processSample(sample float64) {
 		durationSamples := d.samplesProcessed - d.stateStartSample
 		durationSec := float64(durationSamples) / d.sdr.sampleRate
 
-		// 单词间隔阈值
-		wordGapThreshold := d.charGapLen * d.cfg.Decoder.WordGapRatio
-		if wordGapThreshold < 0.2 {
-			wordGapThreshold = 0.2
-		}
+		wordGapThreshold := d.wordGapThreshold()
 
 		if durationSec > wordGapThreshold && d.symbolBuffer == "" {
 			// 已经处理过或 buffer 为空，不做操作
 		} else if durationSec > wordGapThreshold && d.symbolBuffer != "" {
 			// 强制输出单词间隔
-			fmt.Printf("[DEBUG] Word Gap Detected (%.4fs > %.4fs)\n", durationSec, wordGapThreshold)
+			logger.Debug("word gap detected", "duration_s", durationSec, "threshold_s", wordGapThreshold)
+			d.emitSymbol("/", durationSec)
 			d.decodeBuffer()
 			d.emit(" ")
 		}
@@ -197,19 +227,27 @@ func (d *ClusterDecoder) handleMarkEnd(duration float64) {
 	d.updateMarkClusters()
 
 	// 3. 判定当前符号
-	// 阈值 = (点 + 划) / 2
-	threshold := (d.dotLen + d.dashLen) / 2.0
-
+	// 开启模糊判定时按后验概率选择，否则阈值 = (点 + 划) / 2
 	var symbol string
-	if duration < threshold {
-		symbol = "."
+	if d.cfg.Decoder.AmbiguousMarkProb > 0 {
+		symbol = "-"
+		if d.dotProbability(duration) >= 0.5 {
+			symbol = "."
+		}
 	} else {
+		threshold := (d.dotLen + d.dashLen) / 2.0
 		symbol = "-"
+		if duration < threshold {
+			symbol = "."
+		}
 	}
 
-	//fmt.Printf("[DEBUG] Mark: %.4fs -> %s (Th: %.4f, Dot: %.4f, Dash: %.4f)\n", duration, symbol, threshold, d.dotLen, d.dashLen)
+	//fmt.Printf("[DEBUG] Mark: %.4fs -> %s (Dot: %.4f, Dash: %.4f)\n", duration, symbol, d.dotLen, d.dashLen)
 
+	// 码元流按当前判定输出，模糊的点划可能在字符结束时被改判
+	d.emitSymbol(symbol, duration)
 	d.symbolBuffer += symbol
+	d.markDurs = append(d.markDurs, duration)
 
 	if len(d.symbolBuffer) > 7 {
 		d.decodeBuffer()
@@ -223,11 +261,12 @@ func (d *ClusterDecoder) handleSpaceEnd(duration float64) {
 		return
 	}
 
-	// 1. 加入统计缓冲区
-	d.spaceBuffer.Add(duration)
-
+	// 1. 加入统计缓冲区 (换手之间的长时间静音不是间隔时长的样本，否则会把单词间隔拉长)
 	// 2. 重新聚类计算间隔长度
-	d.updateSpaceClusters()
+	if duration <= d.dotLen*spaceMaxUnits {
+		d.spaceBuffer.Add(duration)
+		d.updateSpaceClusters()
+	}
 
 	// 3. 判定间隔类型
 	// 字符分割阈值 = (元素间隔 + 字符间隔) / 2
@@ -242,80 +281,175 @@ func (d *ClusterDecoder) handleSpaceEnd(duration float64) {
 	//fmt.Printf("[DEBUG] Space: %.4fs (Th: %.4f, Elem: %.4f, Char: %.4f)\n", duration, charThreshold, d.elemGapLen, d.charGapLen)
 
 	if duration > charThreshold {
-		// 字符间隔 -> 解码当前 buffer
+		// 字符间隔 -> 解码当前 buffer (单词间隔时 buffer 已在静音期间清空)
+		if d.symbolBuffer != "" {
+			d.emitSymbol(" ", duration)
+		}
 		d.decodeBuffer()
 	} else {
 		// 元素间隔 -> 不做操作，等待下一个点划
 	}
 }
 
-// updateMarkClusters 使用 K-Means (K=2) 更新点划长度估计
+// 点划聚类参数
+const (
+	// 两类均值之比低于此值视为只有一类 (窗口里全是点或全是划)。标准划/点比为 3，手键通常不低于 2
+	markMinClusterRatio = 1.8
+	// 单类情况下的置信度上限
+	markSingleClusterConfidence = 0.2
+
+	// 点划标准差的限幅 (以点长为单位)，与 BeamDecoder 机器键的 sigma 限幅一致
+	clusterSigmaMin = 0.35
+	clusterSigmaMax = 5.0
+	// 一个字符中最多推迟判定的点划个数 (枚举 2^n 种组合)
+	maxAmbiguousMarks = 4
+)
+
+// updateMarkClusters 使用一维 2-Means 的精确解更新点划长度估计
+// 窗口里只有一类时不凭空捏造划长：只更新离得近的那个中心，并报告低置信度。
 func (d *ClusterDecoder) updateMarkClusters() {
 	data := d.markBuffer.GetData()
 	if len(data) < 2 {
 		return
 	}
 
-	// 简单的 K-Means
-	c1 := data[0] // Dot
-	c2 := data[0] // Dash
-	for _, v := range data {
-		if v < c1 {
-			c1 = v
-		}
-		if v > c2 {
-			c2 = v
+	dot, dah := splitClusterStats(data)
+	lo, hi := dot.Mean, dah.Mean
+	if lo > 0 && hi/lo >= markMinClusterRatio {
+		d.dotLen, d.dotSigma = lo, dot.StdDev
+		d.dashLen, d.dashSigma = hi, dah.StdDev
+		// 划/点比达到 3 时置信度为 1
+		d.markConfidence = math.Min(1.0, (hi/lo-1.0)/2.0)
+	} else {
+		all := meanStdDev(data)
+		if math.Abs(all.Mean-d.dotLen) <= math.Abs(all.Mean-d.dashLen) {
+			d.dotLen, d.dotSigma = all.Mean, all.StdDev
+		} else {
+			d.dashLen, d.dashSigma = all.Mean, all.StdDev
 		}
+		d.markConfidence = math.Min(markSingleClusterConfidence, (hi/lo-1.0)/2.0)
+	}
+
+	// 限制范围
+	if d.dotLen < d.cfg.Decoder.MinDotLen {
+		d.dotLen = d.cfg.Decoder.MinDotLen
+	}
+	if d.dotLen > d.cfg.Decoder.MaxDotLen {
+		d.dotLen = d.cfg.Decoder.MaxDotLen
+	}
+	// 点和划的中心不能交叉 (例如速度变慢后点已经接近旧的划长)，按当前点长重置划长
+	if d.dashLen < d.dotLen*2.0 {
+		d.dashLen = d.dotLen * 3.0
 	}
+	d.dotSigma = clampSigma(d.dotSigma, d.dotLen)
+	d.dashSigma = clampSigma(d.dashSigma, d.dotLen)
+}
+
+// clampSigma 将标准差限制在 [clusterSigmaMin, clusterSigmaMax] 个点长之间
+// 防止样本过于整齐时 sigma 趋近 0，使稍有偏差的时长也被判为毫无疑问
+func clampSigma(sigma, dotLen float64) float64 {
+	return math.Max(dotLen*clusterSigmaMin, math.Min(dotLen*clusterSigmaMax, sigma))
+}
 
-	if c2 < c1*1.5 {
-		c2 = c1 * 3.0
+// dotProbability 时长为 duration 的 Mark 是点的后验概率 (点划先验相同)
+// 对数似然与 BeamDecoder 的发射分相同: -(x-μ)^2 / (2σ^2)
+func (d *ClusterDecoder) dotProbability(duration float64) float64 {
+	zDot := (duration - d.dotLen) / d.dotSigma
+	zDash := (duration - d.dashLen) / d.dashSigma
+	return 1.0 / (1.0 + math.Exp((zDot*zDot-zDash*zDash)/2.0))
+}
+
+// markProbability 时长为 duration 的 Mark 被判为 symbol 的概率
+func (d *ClusterDecoder) markProbability(duration float64, symbol byte) float64 {
+	p := d.dotProbability(duration)
+	if symbol == '-' {
+		return 1.0 - p
 	}
+	return p
+}
 
-	for i := 0; i < 5; i++ {
-		sum1, count1 := 0.0, 0.0
-		sum2, count2 := 0.0, 0.0
+// MarkConfidence 点划聚类的置信度 (0.0 - 1.0)
+// 窗口里只有一种 Mark (例如连续发 "EISH5") 时很低，此时的划长只是沿用旧值或按点长推算
+func (d *ClusterDecoder) MarkConfidence() float64 {
+	return d.markConfidence
+}
 
-		for _, v := range data {
-			dist1 := math.Abs(v - c1)
-			dist2 := math.Abs(v - c2)
-			if dist1 < dist2 {
-				sum1 += v
-				count1++
-			} else {
-				sum2 += v
-				count2++
-			}
-		}
+// splitClusters 一维数据的 2-Means 精确解
+// 排序后枚举所有切分点，取类间方差最大 (等价于类内平方误差最小) 的切分，不依赖初值。
+// 返回较小和较大一类的均值。
+func splitClusters(data []float64) (lo, hi float64) {
+	dot, dah := splitClusterStats(data)
+	return dot.Mean, dah.Mean
+}
 
-		if count1 > 0 {
-			c1 = sum1 / count1
-		}
-		if count2 > 0 {
-			c2 = sum2 / count2
+// splitClusterStats 与 splitClusters 相同，同时返回两类的标准差和样本数
+func splitClusterStats(data []float64) (lo, hi BeamDecoder.SignalStats) {
+	sorted := make([]float64, len(data))
+	copy(sorted, data)
+	sort.Float64s(sorted)
+
+	n := len(sorted)
+	total := 0.0
+	for _, v := range sorted {
+		total += v
+	}
+
+	bestScore := -1.0
+	split := 0
+	prefix := 0.0
+	for i := 1; i < n; i++ {
+		prefix += sorted[i-1]
+		m1 := prefix / float64(i)
+		m2 := (total - prefix) / float64(n-i)
+		// 类间方差 (省略常数因子)
+		score := float64(i) * float64(n-i) * (m2 - m1) * (m2 - m1)
+		if score > bestScore {
+			bestScore = score
+			split = i
 		}
 	}
-
-	if c1 > c2 {
-		c1, c2 = c2, c1
+	if split == 0 {
+		return lo, hi
 	}
+	return meanStdDev(sorted[:split]), meanStdDev(sorted[split:])
+}
 
-	d.dotLen = c1
-	d.dashLen = c2
-
-	// 限制范围
-	if d.dotLen < d.cfg.Decoder.MinDotLen {
-		d.dotLen = d.cfg.Decoder.MinDotLen
+// meanStdDev 计算样本的均值和 (总体) 标准差
+func meanStdDev(data []float64) BeamDecoder.SignalStats {
+	if len(data) == 0 {
+		return BeamDecoder.SignalStats{}
 	}
-	if d.dotLen > d.cfg.Decoder.MaxDotLen {
-		d.dotLen = d.cfg.Decoder.MaxDotLen
+	mean := 0.0
+	for _, v := range data {
+		mean += v
 	}
-	if d.dashLen < d.dotLen*2.0 {
-		d.dashLen = d.dotLen * 3.0
+	mean /= float64(len(data))
+	variance := 0.0
+	for _, v := range data {
+		variance += (v - mean) * (v - mean)
+	}
+	return BeamDecoder.SignalStats{
+		Mean:   mean,
+		StdDev: math.Sqrt(variance / float64(len(data))),
+		Count:  len(data),
 	}
 }
 
-// updateSpaceClusters 使用 K-Means (K=2) 更新间隔长度估计
+// 单词间隔学习参数
+const (
+	// 第三类 (单词间隔) 与字符间隔的均值之比低于此值时，视为窗口里没有单词间隔。
+	// 标准间隔为 7:3，发得很紧凑的单词间隔也有 5:3
+	wordMinClusterRatio = 1.4
+	// 尚未学到单词间隔时按 WordGapRatio 判定的最小阈值 (秒)
+	wordGapFallbackMin = 0.2
+	// 长于此值 (以点长为单位) 的静音不计入间隔统计
+	spaceMaxUnits = 25.0
+)
+
+// updateSpaceClusters 使用 K-Means 更新间隔长度估计
+// 尚未学到单词间隔时先按 K=2 分出元素间隔和字符间隔，再试一次 K=3：第三类明显长于字符间隔时
+// 作为单词间隔。学到之后一直按 K=3 更新，窗口里暂时没有某类间隔 (例如全是短单词，没有字符间隔)
+// 时该类保持原值，字符间隔也不会再被单词间隔拉长。
 func (d *ClusterDecoder) updateSpaceClusters() {
 	data := d.spaceBuffer.GetData()
 	if len(data) < 2 {
@@ -330,50 +464,174 @@ func (d *ClusterDecoder) updateSpaceClusters() {
 		c2 = d.dotLen * 3.0
 	}
 
-	for i := 0; i < 5; i++ {
-		sum1, count1 := 0.0, 0.0
-		sum2, count2 := 0.0, 0.0
-
+	var c3 float64
+	if d.wordGapLen > 0 {
+		c2, c3 = d.charGapLen, d.wordGapLen
+	} else {
+		centers, _ := kMeans1D(data, []float64{c1, c2})
+		d.elemGapLen = centers[0]
+		d.charGapLen = centers[1]
+		c1, c2 = centers[0], centers[1]
+		// 第三类从最长的间隔出发，紧凑的单词间隔 (5t) 也不会被字符间隔吸走
 		for _, v := range data {
-			dist1 := math.Abs(v - c1)
-			dist2 := math.Abs(v - c2)
-			if dist1 < dist2 {
-				sum1 += v
-				count1++
-			} else {
-				sum2 += v
-				count2++
-			}
+			c3 = math.Max(c3, v)
 		}
+	}
 
-		if count1 > 0 {
-			c1 = sum1 / count1
+	centers, counts := kMeans1D(data, []float64{c1, c2, c3})
+	// 中间一类必须仍是字符间隔：没有单词间隔时 K=3 可能把元素间隔拆成两类，此时第三类其实是字符间隔
+	if centers[1] < centers[0]*markMinClusterRatio || centers[2] < centers[1]*wordMinClusterRatio {
+		return
+	}
+	if d.wordGapLen == 0 && (counts[1] == 0 || counts[2] == 0) {
+		return
+	}
+	d.elemGapLen = centers[0]
+	d.charGapLen = centers[1]
+	d.wordGapLen = centers[2]
+}
+
+// kMeans1D 一维 K-Means，迭代 5 次，返回升序排列的各类中心和样本数
+// 没有分到样本的类保留初始中心。
+func kMeans1D(data []float64, init []float64) ([]float64, []int) {
+	centers := make([]float64, len(init))
+	copy(centers, init)
+	counts := make([]int, len(init))
+	sums := make([]float64, len(init))
+
+	for iter := 0; iter < 5; iter++ {
+		for k := range centers {
+			sums[k], counts[k] = 0, 0
 		}
-		if count2 > 0 {
-			c2 = sum2 / count2
+		for _, v := range data {
+			best := 0
+			for k := 1; k < len(centers); k++ {
+				if math.Abs(v-centers[k]) < math.Abs(v-centers[best]) {
+					best = k
+				}
+			}
+			sums[best] += v
+			counts[best]++
+		}
+		for k := range centers {
+			if counts[k] > 0 {
+				centers[k] = sums[k] / float64(counts[k])
+			}
 		}
 	}
 
-	if c1 > c2 {
-		c1, c2 = c2, c1
+	// 按中心排序 (样本数跟随)
+	order := make([]int, len(centers))
+	for k := range order {
+		order[k] = k
 	}
+	sort.Slice(order, func(a, b int) bool { return centers[order[a]] < centers[order[b]] })
+	sortedCenters := make([]float64, len(centers))
+	sortedCounts := make([]int, len(centers))
+	for i, k := range order {
+		sortedCenters[i] = centers[k]
+		sortedCounts[i] = counts[k]
+	}
+	return sortedCenters, sortedCounts
+}
 
-	d.elemGapLen = c1
-	d.charGapLen = c2
+// wordGapThreshold 判定单词间隔的静音时长 (秒)
+// 学到单词间隔后取字符间隔和单词间隔的中点；否则为 charGapLen * WordGapRatio (不低于 0.2s)
+func (d *ClusterDecoder) wordGapThreshold() float64 {
+	if d.wordGapLen > 0 {
+		return (d.charGapLen + d.wordGapLen) / 2.0
+	}
+	return math.Max(wordGapFallbackMin, d.charGapLen*d.cfg.Decoder.WordGapRatio)
 }
 
-func (d *ClusterDecoder) decodeBuffer() {
+// decodeBuffer 解码并输出缓冲中的码型，返回输出的字符
+func (d *ClusterDecoder) decodeBuffer() string {
 	if d.symbolBuffer == "" {
-		return
-	}
-	fmt.Printf("[DEBUG] Decoding Buffer: [%s]\n", d.symbolBuffer)
-	if char, ok := MorseCodeMap[d.symbolBuffer]; ok {
-		d.emit(char)
+		return ""
 	}
+	logger.Debug("decoding buffer", "symbols", d.symbolBuffer)
+	code, confidence := d.resolveAmbiguous()
+	char, ok := decodeMorse(code, d.cfg.Decoder.UnknownChar)
 	d.symbolBuffer = ""
+	d.markDurs = d.markDurs[:0]
+	if !ok {
+		return ""
+	}
+	d.emit(char)
+	if d.OnConfidence != nil {
+		d.OnConfidence(char, confidence)
+	}
+	return char
+}
+
+// resolveAmbiguous 字符结束时确定最终码型，并返回其置信度 (各点划判定概率之积)
+// 后验概率低于 AmbiguousMarkProb 的点划是模糊的：枚举它们的点/划组合，
+// 在码表中存在的码型里取概率最大的一个；都不存在时保留逐个判定的结果。
+func (d *ClusterDecoder) resolveAmbiguous() (string, float64) {
+	code := d.symbolBuffer
+	if len(d.markDurs) != len(code) {
+		// 码型不是由 handleMarkEnd 逐个生成的，没有时长信息
+		return code, 1.0
+	}
+
+	var ambiguous []int
+	for i, dur := range d.markDurs {
+		if d.markProbability(dur, code[i]) < d.cfg.Decoder.AmbiguousMarkProb {
+			ambiguous = append(ambiguous, i)
+		}
+	}
+
+	best, bestProb := code, d.codeProbability(code)
+	if len(ambiguous) == 0 || len(ambiguous) > maxAmbiguousMarks {
+		return best, bestProb
+	}
+	if _, ok := MorseCodeMap[code]; !ok {
+		bestProb = -1
+	}
+	candidate := []byte(code)
+	for mask := 1; mask < 1<<len(ambiguous); mask++ {
+		for j, idx := range ambiguous {
+			candidate[idx] = code[idx]
+			if mask&(1<<j) != 0 {
+				candidate[idx] = flipSymbol(code[idx])
+			}
+		}
+		if _, ok := MorseCodeMap[string(candidate)]; !ok {
+			continue
+		}
+		if p := d.codeProbability(string(candidate)); p > bestProb {
+			best, bestProb = string(candidate), p
+		}
+	}
+	if bestProb < 0 {
+		// 没有任何组合能解码，按原判定交给 UnknownChar 策略
+		return code, d.codeProbability(code)
+	}
+	return best, bestProb
+}
+
+// codeProbability 当前字符的时长被判为 code 的概率
+func (d *ClusterDecoder) codeProbability(code string) float64 {
+	p := 1.0
+	for i, dur := range d.markDurs {
+		p *= d.markProbability(dur, code[i])
+	}
+	return p
+}
+
+func flipSymbol(sym byte) byte {
+	if sym == '.' {
+		return '-'
+	}
+	return '.'
 }
 
 func (d *ClusterDecoder) emit(text string) {
+	// 逐字符输出：空格只在有文字之后且上一次不是空格时才输出
+	if d.cfg.Decoder.CollapseSpaces && text == " " && (d.lastEmitted == "" || d.lastEmitted == " ") {
+		return
+	}
+	d.lastEmitted = text
 	if d.OnDecoded != nil {
 		d.OnDecoded(text)
 	} else {
@@ -383,14 +641,52 @@ func (d *ClusterDecoder) emit(text string) {
 
 // 接口实现
 func (d *ClusterDecoder) UpdateTargetFreq(freq float64) { d.sdr.SetTargetFreq(freq) }
+
+// SetSidebandInvert 翻转 SDR 前端的 I/Q 方向 (CW-R)
+func (d *ClusterDecoder) SetSidebandInvert(invert bool) { d.sdr.SetSidebandInvert(invert) }
 func (d *ClusterDecoder) SetThreshold(t float64) {
 	// 初始设置，后续会被 AGC 覆盖
 	d.ThresholdHigh = t
-	d.ThresholdLow = t * d.cfg.Decoder.AgcLowRatio
+	d.ThresholdLow = t * Filters.HysteresisLowRatio(d.cfg.Decoder.HysteresisRatio)
 	d.signalPeak = t * 2.0
 }
 func (d *ClusterDecoder) SetOnDecoded(cb func(string)) { d.OnDecoded = cb }
 
+// SetOnSymbol 设置码元回调 (见 SymbolNotifier)
+func (d *ClusterDecoder) SetOnSymbol(cb func(sym string, durationMs float64)) { d.OnSymbol = cb }
+
+// SetOnCharConfidence 设置字符置信度回调 (见 ConfidenceNotifier)
+func (d *ClusterDecoder) SetOnCharConfidence(cb func(char string, confidence float64)) {
+	d.OnConfidence = cb
+}
+
+func (d *ClusterDecoder) emitSymbol(sym string, durationSec float64) {
+	if d.OnSymbol != nil {
+		d.OnSymbol(sym, durationSec*1000)
+	}
+}
+
+// Flush 输出缓冲中未完成的字符并返回该字符 (见 StreamDecoder)
+func (d *ClusterDecoder) Flush() string {
+	return d.decodeBuffer()
+}
+
+// ClusterDecoder 可以用在任何需要 CWDecoder 的地方
+var _ CWDecoder = (*ClusterDecoder)(nil)
+
+// Stop 输出缓冲中未完成的字符并关闭调试文件，可以重复调用
+func (d *ClusterDecoder) Stop() {
+	d.Flush()
+	if d.debugWriter != nil {
+		d.debugWriter.Flush()
+		d.debugWriter = nil
+	}
+	if d.debugFile != nil {
+		d.debugFile.Close()
+		d.debugFile = nil
+	}
+}
+
 // --- 辅助类: 滑动窗口缓冲区 ---
 
 type WindowBuffer struct {
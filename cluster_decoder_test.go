@@ -0,0 +1,319 @@
+package cw
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestClusterDecoder(t *testing.T) *ClusterDecoder {
+	d := NewClusterDecoder(8000, 700, nil)
+	d.SetOnDecoded(func(string) {})
+	t.Cleanup(d.Stop)
+	return d
+}
+
+func TestClusterDecoder_DebugSignalFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// 默认关闭：不在当前目录留下任何文件
+	d := NewClusterDecoder(8000, 700, nil)
+	d.Stop()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no debug file by default, found %d entries", len(entries))
+	}
+
+	// 配置了路径则逐样本写出状态
+	cfg := DefaultConfig()
+	cfg.Decoder.DebugSignalFile = filepath.Join(dir, "signal.txt")
+	d = NewClusterDecoder(8000, 700, cfg)
+	d.SetOnDecoded(func(string) {})
+	d.ProcessAudioChunk(make([]float32, 100))
+	d.Stop()
+	data, err := os.ReadFile(cfg.Decoder.DebugSignalFile)
+	if err != nil {
+		t.Fatalf("Expected debug file: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 100 {
+		t.Errorf("Expected 100 lines, got %d", lines)
+	}
+
+	// 无法写入时仍能正常创建解码器
+	cfg.Decoder.DebugSignalFile = filepath.Join(dir, "missing", "signal.txt")
+	if d = NewClusterDecoder(8000, 700, cfg); d == nil {
+		t.Fatal("Expected decoder even when the debug file cannot be created")
+	}
+	d.Stop()
+}
+
+func TestClusterDecoder_Stop(t *testing.T) {
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.Decoder.DebugSignalFile = filepath.Join(dir, "signal.txt")
+	var dec CWDecoder = NewClusterDecoder(8000, 700, cfg)
+	var out string
+	dec.SetOnDecoded(func(s string) { out += s })
+	dec.ProcessAudioChunk(make([]float32, 10))
+
+	// 缓冲中尚未结束的字符在 Stop 时输出
+	d := dec.(*ClusterDecoder)
+	d.symbolBuffer = ".-"
+	dec.Stop()
+	if out != "A" {
+		t.Errorf("Expected buffered A on Stop, got %q", out)
+	}
+	if d.debugFile != nil || d.debugWriter != nil {
+		t.Error("Expected the debug file to be closed")
+	}
+	// 重复调用不出错，也不重复输出
+	dec.Stop()
+	if out != "A" {
+		t.Errorf("Second Stop should not emit again, got %q", out)
+	}
+}
+
+func TestClusterDecoder_UnknownChar(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Decoder.UnknownChar = UnknownCharRaw
+	d := NewClusterDecoder(8000, 700, cfg)
+	defer d.Stop()
+	var out string
+	d.SetOnDecoded(func(s string) { out += s })
+
+	d.symbolBuffer = "........"
+	d.decodeBuffer()
+	if out != "[........]" {
+		t.Errorf("Expected raw pattern, got %q", out)
+	}
+}
+
+func TestClusterDecoder_HysteresisRatio(t *testing.T) {
+	cfg := DefaultConfig()
+	d := NewClusterDecoder(8000, 700, cfg)
+	defer d.Stop()
+	d.SetThreshold(0.5)
+	if math.Abs(d.ThresholdLow-0.4) > 1e-12 {
+		t.Errorf("Expected default low threshold 0.4, got %.4f", d.ThresholdLow)
+	}
+
+	// 与其他解码器共用 Decoder.HysteresisRatio
+	cfg.Decoder.HysteresisRatio = 0.2
+	d.SetThreshold(0.5)
+	if math.Abs(d.ThresholdLow-0.3) > 1e-12 {
+		t.Errorf("Expected low threshold 0.3 with HysteresisRatio 0.2, got %.4f", d.ThresholdLow)
+	}
+}
+
+func TestSplitClusters(t *testing.T) {
+	lo, hi := splitClusters([]float64{0.18, 0.06, 0.065, 0.17, 0.055, 0.19})
+	if math.Abs(lo-0.06) > 1e-9 || math.Abs(hi-0.18) > 1e-9 {
+		t.Errorf("Expected 0.06 / 0.18, got %.3f / %.3f", lo, hi)
+	}
+}
+
+func TestClusterDecoder_OnlyDits(t *testing.T) {
+	d := newTestClusterDecoder(t)
+	var symbols strings.Builder
+	d.SetOnSymbol(func(sym string, durationMs float64) { symbols.WriteString(sym) })
+
+	// 16 个几乎相同的点 (12 WPM)，窗口里没有任何划
+	for i := 0; i < 16; i++ {
+		d.handleMarkEnd(0.1 + 0.004*float64(i%3-1))
+	}
+
+	if got := symbols.String(); strings.Contains(got, "-") {
+		t.Errorf("Expected only dits, got %q", got)
+	}
+	if math.Abs(d.dotLen-0.1) > 0.005 {
+		t.Errorf("Expected dot length ~0.1s, got %.3f", d.dotLen)
+	}
+	if c := d.MarkConfidence(); c > markSingleClusterConfidence {
+		t.Errorf("Single cluster should report low confidence, got %.2f", c)
+	}
+}
+
+func TestClusterDecoder_DitsAndDahs(t *testing.T) {
+	d := newTestClusterDecoder(t)
+	for i := 0; i < 8; i++ {
+		d.handleMarkEnd(0.05)
+		d.handleMarkEnd(0.15)
+	}
+	if math.Abs(d.dotLen-0.05) > 1e-9 || math.Abs(d.dashLen-0.15) > 1e-9 {
+		t.Errorf("Expected 0.05 / 0.15, got %.3f / %.3f", d.dotLen, d.dashLen)
+	}
+	if c := d.MarkConfidence(); c < 0.99 {
+		t.Errorf("Expected full confidence for a 1:3 ratio, got %.2f", c)
+	}
+}
+
+// trainClusterDecoder 用带抖动的点 (50ms) 和划 (150ms) 填满统计窗口，并清空已缓冲的码型
+func trainClusterDecoder(d *ClusterDecoder) {
+	for i := 0; i < 8; i++ {
+		jitter := 0.005 * float64(i%3-1)
+		d.handleMarkEnd(0.05 + jitter)
+		d.handleMarkEnd(0.15 + 2*jitter)
+	}
+	d.symbolBuffer = ""
+	d.markDurs = d.markDurs[:0]
+}
+
+func TestClusterDecoder_AmbiguousMark(t *testing.T) {
+	d := newTestClusterDecoder(t)
+	trainClusterDecoder(d)
+	var out string
+	var conf float64
+	d.SetOnDecoded(func(s string) { out += s })
+	d.SetOnCharConfidence(func(char string, c float64) { conf = c })
+
+	// 第三个 Mark 略长于点划中点，单独判定为划得到 "-.-.-" (不在码表中)，
+	// 推迟判定后改为点得到 "-...-"
+	for _, dur := range []float64{0.15, 0.05, 0.102, 0.05, 0.15} {
+		d.handleMarkEnd(dur)
+	}
+	d.decodeBuffer()
+	if out != "=" {
+		t.Errorf("Expected ambiguous mark resolved to =, got %q", out)
+	}
+	if conf <= 0 || conf >= 0.6 {
+		t.Errorf("Expected low confidence for a borderline mark, got %.3f", conf)
+	}
+
+	// 规整的时长置信度接近 1
+	out = ""
+	for _, dur := range []float64{0.05, 0.15} {
+		d.handleMarkEnd(dur)
+	}
+	d.decodeBuffer()
+	if out != "A" || conf < 0.99 {
+		t.Errorf("Expected A with high confidence, got %q (%.3f)", out, conf)
+	}
+}
+
+func TestClusterDecoder_AmbiguousMarkDisabled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Decoder.AmbiguousMarkProb = 0
+	d := NewClusterDecoder(8000, 700, cfg)
+	defer d.Stop()
+	trainClusterDecoder(d)
+	var out string
+	d.SetOnDecoded(func(s string) { out += s })
+
+	// 关闭时按中点硬判决，"-.-.-" 不在码表中被丢弃
+	for _, dur := range []float64{0.15, 0.05, 0.102, 0.05, 0.15} {
+		d.handleMarkEnd(dur)
+	}
+	d.decodeBuffer()
+	if out != "" {
+		t.Errorf("Expected unknown pattern dropped, got %q", out)
+	}
+}
+
+func TestClusterDecoder_FlushReset(t *testing.T) {
+	d := newTestClusterDecoder(t)
+	trainClusterDecoder(d)
+	var out string
+	d.SetOnDecoded(func(s string) { out += s })
+
+	d.handleMarkEnd(0.15)
+	d.handleMarkEnd(0.05)
+	if got := d.Flush(); got != "N" || out != "N" {
+		t.Errorf("Expected Flush to emit and return N, got %q (emitted %q)", got, out)
+	}
+
+	d.handleMarkEnd(0.05)
+	d.Reset()
+	if d.Flush() != "" || d.dotLen != 0.06 || len(d.markBuffer.GetData()) != 0 {
+		t.Errorf("Expected Reset to drop the buffer and timing, got dot %.3f", d.dotLen)
+	}
+}
+
+// feedSpacing 按给定的字符/单词间隔 (以点长为单位) 把 text 的时序直接喂给解码器，
+// 返回被 wordGapThreshold 判错的字符间隔和单词间隔个数 (第一个单词作为预热不计)
+func feedSpacing(d *ClusterDecoder, text string, dot, charUnits, wordUnits float64) (charErrs, wordErrs int) {
+	for w, word := range strings.Fields(text) {
+		if w > 0 {
+			gap := dot * wordUnits
+			if w > 1 && gap <= d.wordGapThreshold() {
+				wordErrs++
+			}
+			d.handleSpaceEnd(gap)
+			d.decodeBuffer()
+		}
+		for c, char := range word {
+			if c > 0 {
+				gap := dot * charUnits
+				if w > 0 && gap > d.wordGapThreshold() {
+					charErrs++
+				}
+				d.handleSpaceEnd(gap)
+			}
+			code := morseEncodeTable[char]
+			for i, sym := range code {
+				if i > 0 {
+					d.handleSpaceEnd(dot)
+				}
+				if sym == '.' {
+					d.handleMarkEnd(dot)
+				} else {
+					d.handleMarkEnd(dot * 3)
+				}
+			}
+		}
+	}
+	return charErrs, wordErrs
+}
+
+func TestClusterDecoder_WordGapLearning(t *testing.T) {
+	const text = "CQ CQ DE BG1ABC BG1ABC K TNX FER CALL UR RST 599 5NN BK"
+	tests := []struct {
+		name                 string
+		dot                  float64
+		charUnits, wordUnits float64
+	}{
+		{"standard", 0.06, 3, 7},
+		{"tight", 0.04, 3, 5}, // 快速发报，单词间隔被压缩
+		{"loose", 0.1, 5, 15}, // 慢速发报，字符和单词间隔都被拉长
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestClusterDecoder(t)
+			charErrs, wordErrs := feedSpacing(d, text, tt.dot, tt.charUnits, tt.wordUnits)
+			if d.wordGapLen == 0 {
+				t.Fatal("Expected a learned word gap")
+			}
+			if math.Abs(d.wordGapLen-tt.dot*tt.wordUnits) > tt.dot {
+				t.Errorf("Expected word gap ~%.3fs, got %.3fs", tt.dot*tt.wordUnits, d.wordGapLen)
+			}
+			if charErrs > 0 || wordErrs > 0 {
+				t.Errorf("Misclassified %d char gaps and %d word gaps", charErrs, wordErrs)
+			}
+		})
+	}
+}
+
+func TestClusterDecoder_WordGapWithoutWords(t *testing.T) {
+	d := newTestClusterDecoder(t)
+	// 只有一个长单词：窗口里只有元素间隔和字符间隔，不能把字符间隔当成单词间隔
+	feedSpacing(d, "PARISPARISPARIS", 0.06, 3, 7)
+	if d.wordGapLen != 0 {
+		t.Errorf("Expected no learned word gap, got %.3fs", d.wordGapLen)
+	}
+	if got, want := d.wordGapThreshold(), d.charGapLen*d.cfg.Decoder.WordGapRatio; math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected fallback threshold %.3f, got %.3f", want, got)
+	}
+}
+
+func TestClusterDecoder_LongPauseIgnored(t *testing.T) {
+	d := newTestClusterDecoder(t)
+	feedSpacing(d, "CQ CQ DE BG1ABC BG1ABC K", 0.06, 3, 7)
+	learned := d.wordGapLen
+
+	// 换手时的 5 秒静音不能把单词间隔拉长
+	d.handleSpaceEnd(5.0)
+	if d.wordGapLen != learned {
+		t.Errorf("Expected word gap %.3fs unchanged by a long pause, got %.3fs", learned, d.wordGapLen)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"cw"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// display 终端显示层：所有光标控制转义序列只在这里输出
+// 输出不是终端 (重定向到文件或管道) 时退化为纯文本，每行一次解码结果。
+type display struct {
+	w           io.Writer
+	tty         bool
+	incremental bool   // 解码器每次输出新增片段 (见 CWSystem.IncrementalOutput)
+	expand      bool   // 在缩写 / Q 简语后注释含义 (见 cw.ExpandAbbreviations)
+	word        string // 逐字符输出时当前尚未结束的单词
+}
+
+func newDisplay(f *os.File, incremental, expand bool) *display {
+	return &display{w: f, tty: isTerminal(f), incremental: incremental, expand: expand}
+}
+
+// isTerminal 判断 f 是否为字符设备 (终端)
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Clear 清屏并将光标移到左上角
+func (d *display) Clear() {
+	if d.tty {
+		fmt.Fprint(d.w, "\033[2J\033[H")
+	}
+}
+
+// Show 显示一次解码输出
+// 逐字符输出直接追加；完整文本在终端上覆盖显示在固定位置，非终端时逐行输出
+func (d *display) Show(text string) {
+	switch {
+	case d.incremental && d.expand:
+		fmt.Fprint(d.w, d.annotateIncremental(text))
+	case d.incremental:
+		fmt.Fprint(d.w, text)
+	case d.tty:
+		if d.expand {
+			text = cw.ExpandAbbreviations(text)
+		}
+		fmt.Fprint(d.w, "\033[s\033[H\033[8B "+text+"\r\n\033[u")
+	default:
+		if d.expand {
+			text = cw.ExpandAbbreviations(text)
+		}
+		fmt.Fprintln(d.w, text)
+	}
+}
+
+// annotateIncremental 逐字符输出时，在单词结束 (遇到空格) 后补上含义
+func (d *display) annotateIncremental(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		if r == ' ' {
+			if m, ok := cw.AbbreviationMeaning(d.word); ok {
+				sb.WriteString("(" + m + ")")
+			}
+			d.word = ""
+		} else {
+			d.word += string(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -3,11 +3,15 @@ package main
 import (
 	"bufio"
 	"cw"
+	"cw/httpserver"
+	"cw/publish"
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 )
@@ -15,32 +19,151 @@ import (
 func main() {
 	// 1. 解析命令行参数
 	recordAudio := flag.Bool("record", false, "Record audio to capture.wav")
-	inputFile := flag.String("file", "", "Input wav file for replay testing")
+	inputFile := flag.String("file", "", "Input wav file for replay testing ('-' reads a wav stream from stdin)")
+	replaySpeed := flag.Float64("speed", 1, "Replay speed factor for -file (e.g. 4 to fast-forward, 0.5 to slow down)")
+	channel := flag.String("channel", cw.ChannelLeft.String(), "Channel of a multi-channel replay wav: left, right or mix")
+	calibrate := flag.Duration("calibrate", 0, "Measure band noise for this long before decoding (e.g. 2s)")
+	civAddr := flag.Uint("civaddr", cw.CIV_ADDR_7300, "Radio CI-V address (IC-7300: 0x94, IC-7610: 0x98, IC-9700: 0xA2)")
+	decoderType := flag.String("decoder", cw.DecoderExperimental.String(), "Decoder: experimental, cluster, adaptive or goertzel")
+	adifFile := flag.String("adif", "", "Export decoded callsigns to this ADIF file on exit")
+	wpm := flag.Float64("wpm", 0, "Sender speed hint in WPM; 0 auto-detects starting from the default speed")
+	outFile := flag.String("out", "", "Append decoded text with timestamps to this file")
+	wsAddr := flag.String("ws", "", "Publish decoded words to browsers at this address (e.g. :8080, websocket at /ws)")
+	httpAddr := flag.String("http", "", "Serve decoded text, WPM/SNR and spectrum as JSON at this address (e.g. :8081, see /api/...)")
+	configFile := flag.String("config", "", "Load decoder parameters from this JSON file (unset fields keep their defaults)")
+	expand := flag.Bool("expand", false, "Annotate CW abbreviations and Q-codes in the decoded text (e.g. TNX(thanks))")
+	squelch := flag.Bool("squelch", false, "Suppress decoding when the audio does not look like CW (noise, voice, empty band)")
+	device := flag.String("device", "", "Capture device name (case-insensitive substring, see -list-devices)")
+	listDevices := flag.Bool("list-devices", false, "List available audio capture devices and exit")
+	logLevel := flag.String("log-level", "info", "Diagnostic log level on stderr: debug, info, warn, error or off")
+	selfTest := flag.Bool("selftest", false, "Decode a generated PARIS test at -wpm (default 20) and -snr, print the error rate and exit")
+	selfTestSNR := flag.Float64("snr", 10, "Self-test signal-to-noise ratio in dB")
+	truthFile := flag.String("truth", "", "Score -file against this text file of its known content, print the error rate and alignment, and exit")
 	flag.Parse()
+	if err := setupLogging(*logLevel); err != nil {
+		log.Fatal(err)
+	}
+
+	if *listDevices {
+		listCaptureDevices()
+		return
+	}
+	if *selfTest {
+		runSelfTest(*wpm, *selfTestSNR)
+		return
+	}
+	if *truthFile != "" {
+		runReplayScore(*inputFile, *truthFile)
+		return
+	}
 
 	// 2. 初始化系统
-	system := cw.NewCWSystem()
+	decoder, err := cw.ParseDecoderType(*decoderType)
+	if err != nil {
+		log.Fatal(err)
+	}
+	system := cw.NewCWSystemWithDecoder(decoder)
+	if *configFile != "" {
+		cfg, err := cw.LoadConfig(*configFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		system.SetConfig(cfg)
+	}
+	if *wpm < 0 {
+		log.Fatalf("Invalid WPM: %v", *wpm)
+	}
+	if *wpm > 0 {
+		system.SetInitialWPM(*wpm)
+	}
+	if *civAddr == 0 || *civAddr > 0xFF {
+		log.Fatalf("Invalid CI-V address: 0x%X", *civAddr)
+	}
+	system.RadioAddress = byte(*civAddr)
+	if *device != "" {
+		system.AudioDeviceName = *device
+	}
+	system.SetSquelchMode(*squelch)
+	if system.ReplayChannel, err = cw.ParseChannelSelect(*channel); err != nil {
+		log.Fatal(err)
+	}
 	//a := "/Users/leilei/work/goProject/src/cw/testData/test1.wav"
 	//inputFile = &a
-	if *inputFile != "" {
+	if *inputFile == "-" {
+		system.SetReplayStream(os.Stdin)
+	} else if *inputFile != "" {
 		system.SetReplayFile(*inputFile)
 	}
+	if *replaySpeed <= 0 {
+		log.Fatalf("Invalid replay speed: %v", *replaySpeed)
+	}
+	system.SetReplaySpeed(*replaySpeed)
 	if *recordAudio {
 		system.EnableRecording("capture.wav")
 	}
+	// 解码文本的显示 (及可选的文本记录) 都在这里完成，库本身不输出终端控制符
+	display := newDisplay(os.Stdout, system.IncrementalOutput(), *expand)
+	var transcript *cw.TranscriptLog
+	if *outFile != "" {
+		f, err := os.OpenFile(*outFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		transcript = cw.NewTranscriptLog(f, system.IncrementalOutput())
+	}
+	var published *cw.TranscriptLog
+	if *wsAddr != "" {
+		var ws *publish.WebSocketServer
+		published, ws = startPublisher(*wsAddr, system.IncrementalOutput())
+		defer ws.Close()
+	}
+	var api *httpserver.Server
+	if *httpAddr != "" {
+		api = startHTTPServer(*httpAddr, system)
+	}
+	system.OnTextDecoded = func(text string) {
+		if transcript != nil {
+			transcript.Observe(text)
+		}
+		if published != nil {
+			published.Observe(text)
+		}
+		if api != nil {
+			api.Observe(text)
+		}
+		display.Show(text)
+	}
+
+	// 回放到文件末尾时正常退出 (保存文本记录和 ADIF)
+	sigChan := make(chan os.Signal, 1)
+	system.OnReplayEnd = func() {
+		select {
+		case sigChan <- os.Interrupt:
+		default:
+		}
+	}
 
 	// 3. 启动系统
+	display.Clear()
 	if err := system.Start(); err != nil {
 		log.Fatalf("System start failed: %v", err)
 	}
-	defer system.Stop()
+
+	if *calibrate > 0 {
+		if _, err := system.Calibrate(*calibrate); err != nil {
+			log.Printf("Calibration failed: %v", err)
+		}
+	}
 
 	// 4. 主循环 (处理信号和控制台输入)
-	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// 启动控制台输入监听
+	// 启动控制台输入监听 (stdin 被用作音频流时跳过)
 	go func() {
+		if *inputFile == "-" {
+			return
+		}
 		scanner := bufio.NewScanner(os.Stdin)
 		fmt.Println("System Ready. (Type 'exit' to quit)")
 
@@ -56,6 +179,11 @@ func main() {
 				return
 			}
 
+			if *inputFile != "" && handleReplayCommand(system, input) {
+				fmt.Print("> ")
+				continue
+			}
+
 			// 将输入传递给系统处理
 			system.HandleInput(input)
 			fmt.Print("> ")
@@ -65,4 +193,125 @@ func main() {
 	// 阻塞等待退出信号
 	<-sigChan
 	fmt.Println("\nShutting down...")
+	system.Stop()
+	if transcript != nil {
+		if err := transcript.Flush(); err != nil {
+			log.Printf("Writing %s failed: %v", *outFile, err)
+		}
+	}
+	if published != nil {
+		published.Flush()
+	}
+
+	if *adifFile != "" {
+		if err := exportADIF(system, *adifFile); err != nil {
+			log.Printf("ADIF export failed: %v", err)
+		} else {
+			fmt.Printf("Saved %d QSO(s) to %s\n", len(system.QSORecords()), *adifFile)
+		}
+	}
+}
+
+// runReplayScore 两遍解码录音并与标注文本对比，打印错误率和对齐结果
+func runReplayScore(wavPath, truthPath string) {
+	if wavPath == "" || wavPath == "-" {
+		log.Fatal("-truth requires a wav file given with -file")
+	}
+	cer, aligned, err := cw.ReplayAndScore(wavPath, truthPath)
+	if err != nil {
+		log.Fatalf("Replay failed: %v", err)
+	}
+	fmt.Printf("%s: CER %.1f%%\n%s\n", wavPath, cer*100, aligned)
+}
+
+// runSelfTest 运行解码自检并打印字符错误率，出错或未能完全解码时以非零状态退出
+func runSelfTest(wpm, snrDB float64) {
+	if wpm <= 0 {
+		wpm = 20
+	}
+	cer, err := cw.SelfTest(wpm, snrDB)
+	if err != nil {
+		log.Fatalf("Self-test failed: %v", err)
+	}
+	fmt.Printf("Self-test %.0f WPM @ %.1f dB: CER %.1f%%\n", wpm, snrDB, cer*100)
+	if cer > 0 {
+		os.Exit(1)
+	}
+}
+
+// setupLogging 把库的诊断日志输出到 stderr (解码文本在 stdout)，level 为 off 时关闭
+func setupLogging(level string) error {
+	if strings.EqualFold(level, "off") {
+		cw.SetLogger(nil)
+		return nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", level, err)
+	}
+	cw.SetLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l})))
+	return nil
+}
+
+// listCaptureDevices 打印可用的音频捕获设备，名称可直接用作 -device 的参数
+func listCaptureDevices() {
+	devices, err := cw.ListCaptureDevices()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(devices) == 0 {
+		fmt.Println("No capture devices found")
+		return
+	}
+	for i, d := range devices {
+		mark := " "
+		if d.IsDefault {
+			mark = "*"
+		}
+		fmt.Printf("%s %d: %s [%s]\n", mark, i, d.Name, d.ID)
+	}
+}
+
+// exportADIF 将识别到的呼号写入 ADIF 文件
+func exportADIF(system *cw.CWSystem, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := system.ExportADIF(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// handleReplayCommand 处理回放模式下的控制台命令: pause / resume / seek <秒> / speed <倍率>
+// 不是回放命令时返回 false，交给 HandleInput 处理
+func handleReplayCommand(system *cw.CWSystem, input string) bool {
+	fields := strings.Fields(strings.ToLower(input))
+	switch {
+	case fields[0] == "pause" && len(fields) == 1:
+		system.Pause()
+	case fields[0] == "resume" && len(fields) == 1:
+		system.Resume()
+	case fields[0] == "seek" && len(fields) == 2:
+		sec, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			fmt.Printf("Invalid seek position: %s\n", fields[1])
+			return true
+		}
+		if err := system.Seek(sec); err != nil {
+			fmt.Printf("Seek failed: %v\n", err)
+		}
+	case fields[0] == "speed" && len(fields) == 2:
+		factor, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || factor <= 0 {
+			fmt.Printf("Invalid replay speed: %s\n", fields[1])
+			return true
+		}
+		system.SetReplaySpeed(factor)
+	default:
+		return false
+	}
+	return true
 }
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"cw"
+	"cw/httpserver"
+	"cw/publish"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+)
+
+// dashboardPage 最简单的浏览器面板：连接 /ws，把收到的每一行追加到页面上
+const dashboardPage = `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>CW Decoder</title>
+<style>body{font:18px monospace;background:#111;color:#9f9;margin:1em}</style></head>
+<body><div id="log"></div><script>
+const log = document.getElementById("log");
+function connect() {
+  const ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + "/ws");
+  ws.onmessage = e => { const d = document.createElement("div"); d.textContent = e.data; log.appendChild(d); window.scrollTo(0, document.body.scrollHeight); };
+  ws.onclose = () => setTimeout(connect, 2000);
+}
+connect();
+</script></body></html>
+`
+
+// startPublisher 在 addr 上提供浏览器面板 (/) 和 WebSocket (/ws)，
+// 返回的 TranscriptLog 按单词发送已确定的文本 (与 -out 相同的格式)，退出前调用 Flush
+func startPublisher(addr string, incremental bool) (*cw.TranscriptLog, *publish.WebSocketServer) {
+	ws := publish.NewWebSocketServer()
+	mux := http.NewServeMux()
+	mux.Handle("/ws", ws)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, dashboardPage)
+	})
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("Publisher listen failed: %v", err)
+	}
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			log.Printf("Publisher stopped: %v", err)
+		}
+	}()
+	fmt.Printf("Publishing decoded text on http://%s/ (websocket /ws)\n", ln.Addr())
+	return cw.NewTranscriptLog(publish.Writer(ws), incremental), ws
+}
+
+// startHTTPServer 在 addr 上提供 httpserver 的 JSON 接口和 SSE 推送
+func startHTTPServer(addr string, system *cw.CWSystem) *httpserver.Server {
+	api := httpserver.New(system, system.IncrementalOutput())
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("HTTP server listen failed: %v", err)
+	}
+	go func() {
+		if err := http.Serve(ln, api); err != nil {
+			log.Printf("HTTP server stopped: %v", err)
+		}
+	}()
+	fmt.Printf("Serving decoder status on http://%s/api/ (text, status, spectrum, events)\n", ln.Addr())
+	return api
+}
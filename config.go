@@ -1,6 +1,11 @@
 package cw
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
 
 // Config 结构体用于集中管理解码器的所有可调参数和阈值
 type Config struct {
@@ -10,22 +15,32 @@ type Config struct {
 		Enabled        bool          // 是否启用后台频谱监控 (true: 开启, false: 关闭)
 		UpdateInterval time.Duration // 分析周期 (例如 200ms)，决定了频率更新的频率
 		FFTSize        int           // FFT 点数 (例如 4096)，决定了频率分辨率。越大分辨率越高，但计算量越大
-		MinFrequency   float64       // 频率搜索下限 (Hz)，用于屏蔽低频底噪 (例如 600Hz)
+		Window         WindowType    // Welch 分段使用的窗函数。Blackman / FlatTop 泄漏更小，适合两个信号靠得很近的情况
+		MinFrequency   float64       // 频率搜索下限 (Hz)，用于屏蔽低频底噪 (例如 500Hz)。同时也是开机搜台的频段
 		MaxFrequency   float64       // 频率搜索上限 (Hz)，用于限制搜索范围 (例如 900Hz)
+		InitialFreq    float64       // 锁定信号之前解码器使用的音调频率 (Hz)。0 = 搜索频段的中心
 		RequiredSNR    float64       // 触发频率更新所需的最小信噪比 (线性值)。例如 10.0 代表信号功率需是底噪的 10 倍 (10dB)
 		AlphaBase      float64       // 频率平滑的基础学习率 (0.0 - 1.0)。值越小，频率变化越平滑；值越大，响应越快
 		AlphaGain      float64       // 频率平滑的学习率增益。随 SNR 增加而增加，使强信号能更快拉动频率
 		AlphaMax       float64       // 频率平滑的最大学习率，防止频率跳变过快
+		PeakMinSpacing float64       // FindPeaks 中两个信号峰的最小间距 (Hz)，小于此间距视为同一个信号
+		LockTolerance  float64       // Lock 手动锁定后允许微调的范围 (±Hz)
+		LockLossAfter  time.Duration // SNR 持续低于 RequiredSNR 超过此时长视为信号丢失，系统回到搜台状态 (例如换台后)。0 = 关闭
 	}
 
 	// --- SDR 解调 ---
 	// 负责将音频信号混频、滤波并提取包络
 	SDR struct {
-		LpfAlpha    float64 // I/Q 低通滤波器的系数 (0.0 - 1.0)。值越小，平滑度越高，抗噪越好，但对快速信号响应变慢。0.05 适合 40WPM
-		AfcEnabled  bool    // 是否启用 AFC (自动频率控制)，用于微调相位漂移
-		AfcGain     float64 // AFC 增益，决定了 AFC 跟踪频率的速度
-		AfcDeadband float64 // AFC 死区 (Hz)，频率误差小于此值时不进行调整，防止抖动
-		FilterBW    float64 // 低通滤波器截止频率 (Hz)。决定了接收带宽 (BW = 2 * Cutoff)。例如 50.0 代表 100Hz 带宽
+		Frontend    SDRFrontend // 包络检测前端：FrontendIQ (默认) 或 FrontendHilbert (不需要音调频率，适合尚未锁定音调时)
+		LpfAlpha    float64     // I/Q 低通滤波器的系数 (0.0 - 1.0)。值越小，平滑度越高，抗噪越好，但对快速信号响应变慢。0.05 适合 40WPM
+		AfcEnabled  bool        // 是否启用 AFC (自动频率控制)，用于微调相位漂移
+		AfcGain     float64     // AFC 增益，决定了 AFC 跟踪频率的速度
+		AfcDeadband float64     // AFC 死区 (Hz)，频率误差小于此值时不进行调整，防止抖动
+		FilterBW    float64     // 低通滤波器截止频率 (Hz)。决定了接收带宽 (BW = 2 * Cutoff)。例如 50.0 代表 100Hz 带宽
+
+		DCBlockCutoff  float64 // 混频前隔直高通滤波器的截止频率 (Hz)，去掉声卡的直流偏置和低频晃动。0 = 关闭
+		LoInitialPhase float64 // 本振 (LO) 的初始相位 (弧度)，默认 0
+		SidebandInvert bool    // 翻转 I/Q 方向 (loQ 取反)，用于 CW-R 或边带相反的电台；电台报告 CW-R 时自动开启
 	}
 
 	// --- 解码逻辑 (ClusterDecoder) ---
@@ -36,8 +51,15 @@ type Config struct {
 		AgcPeakDecay float64 // 信号峰值的衰减率 (例如 0.99995)。值越接近 1，峰值保持时间越长
 		AgcPeakFloor float64 // 信号峰值的最低值，防止在完全静音时阈值降得太低
 		AgcHighRatio float64 // 动态阈值高位 = 峰值 * 此比例 (例如 0.5)。施密特触发器的开启阈值
-		AgcLowRatio  float64 // 动态阈值低位 = 高位 * 此比例 (例如 0.85)。施密特触发器的关闭阈值，较高的值有助于防止字符粘连
 		AgcMinHigh   float64 // 动态阈值高位的最小值，防止锁定到微弱底噪
+		// 施密特触发器的迟滞比例 (上下各占动态范围的比例)：所有解码器的低阈值 = 高阈值 * (1 - 2 * 此值)，
+		// AdaptiveThresholder 的迟滞宽度也按此值。太小时弱信号的点在边沿来回抖动被拆开，
+		// 太大时靠得很近的元素粘在一起。0 = Filters.DefaultHysteresisRatio (0.1，即低阈值 = 高阈值 * 0.8)
+		HysteresisRatio float64
+		// ExperimentalDecoder 在阈值判定前用 RobustAGC (跟踪包络的 95% 分位点) 归一化包络。
+		// 偶尔有强脉冲噪声 (雷电) 时比峰值保持更稳定。false = 直接使用原始包络 (默认)
+		// 注意：第一个信号出现之前，底噪也会被归一化到满幅，开头的一个字符可能丢失
+		RobustAGC bool
 
 		// 统计和聚类
 		MarkWindowSize  int     // Mark (信号) 统计窗口大小 (例如 16)。用于 K-Means 聚类的样本数量
@@ -51,7 +73,30 @@ type Config struct {
 		DotDashRatio  float64 // 点划分割阈值系数。Threshold = dotLen * 此比例 (例如 2.2)。小于为点，大于为划
 		CharGapRatio  float64 // 字符分割阈值系数。Threshold = dotLen * 此比例 (例如 1.5)。大于此间隔被视为字符结束
 		CharGapMinMs  int     // 最小字符分割时长 (毫秒)。硬性兜底，防止在高码率下字符粘连 (例如 60ms)
-		WordGapRatio  float64 // 单词分割阈值系数。Threshold = dotLen * 此比例 (例如 5.0)。大于此间隔输出空格
+		WordGapRatio  float64 // 单词分割阈值系数。ClusterDecoder 尚未从间隔统计中学到单词间隔时，Threshold = 字符间隔 * 此比例 (例如 5.0)
+		InitialWPM    float64 // 已知的发送速度 (WPM)，解码器直接从该速度开始。0 = 自动 (从默认速度出发，按前几个 Mark 估计)
+		// Beam 解码器使用的发报者点划比例，通常由 ExperimentalDecoder.CalibrateWeighting 测得 (见 BeamDecoder.Weighting)
+		DahRatio float64 // 划/点比，0 = 3
+		GapRatio float64 // 字符内元素间隔/点比，0 = 1
+		// ExperimentalDecoder 按 AUTO-TUNE 测得的信噪比 (见 CurrentSNR) 放宽束搜索的剪枝：信号越差保留的候选越多。
+		// false = 固定束宽 (默认)；开启后噪声大时每步计算量最多增加到 40 条路径 (默认 20)
+		AdaptiveBeam bool
+		// 束搜索中语言模型的权重 (见 BeamDecoder.BeamConfig.LMWeight)：小于 1 时更相信信号本身，大于 1 时更相信语言模型。0 = 1
+		LMWeight float64
+		// ClusterDecoder 点划判定的后验概率低于此值时视为模糊 (例如 0.9)，推迟到字符结束时按码表判定。0 = 关闭 (按中点硬判决)
+		AmbiguousMarkProb float64
+
+		// 输出
+		CollapseSpaces bool              // 是否将连续空格合并为一个并去掉开头的空格 (长停顿时避免 "CQ    DE")
+		UnknownChar    UnknownCharPolicy // 无法识别的码型 (例如 "........") 的处理：丢弃、输出 "?" 或输出原始码型
+		OutputCase     OutputCase        // CWSystem 输出文本的大小写：CaseUpper (默认) 或 CaseLower (见 OutputFormatter)
+		WordSeparator  string            // CWSystem 输出中单词边界的分隔符 (例如 " / " 或 "\n")，空 = 空格
+		// Beam 解码器把与标点码型相同的勤务符号输出为 <AR> <BT> <KN> <AS>，false = 输出 + = ( & (默认，见 BeamDecoder.BeamConfig.BracketProsigns)
+		BracketProsigns bool
+
+		// 调试
+		DebugSignalFile string // ClusterDecoder 逐样本写出 Mark/Space 状态的文件 (例如 "debug_signal.txt")，空 = 关闭
+		DebugCSVFile    string // ExperimentalDecoder 逐样本写出输入、包络、阈值和状态的 CSV (例如 "debug_session_01.csv")，空 = 关闭
 	}
 }
 
@@ -63,12 +108,16 @@ func DefaultConfig() *Config {
 	cfg.Monitor.Enabled = true // 默认开启，以自动锁定频率
 	cfg.Monitor.UpdateInterval = 200 * time.Millisecond
 	cfg.Monitor.FFTSize = 4096
-	cfg.Monitor.MinFrequency = 600.0
+	cfg.Monitor.Window = WindowHanning
+	cfg.Monitor.MinFrequency = 500.0
 	cfg.Monitor.MaxFrequency = 900.0
 	cfg.Monitor.RequiredSNR = 40.0 // 10dB
 	cfg.Monitor.AlphaBase = 0.02
 	cfg.Monitor.AlphaGain = 0.005
 	cfg.Monitor.AlphaMax = 0.5
+	cfg.Monitor.PeakMinSpacing = 25.0
+	cfg.Monitor.LockTolerance = 15.0
+	cfg.Monitor.LockLossAfter = 30 * time.Second
 
 	// --- SDR 解调 ---
 	cfg.SDR.LpfAlpha = 0.05
@@ -76,13 +125,13 @@ func DefaultConfig() *Config {
 	cfg.SDR.AfcGain = 0.0002
 	cfg.SDR.AfcDeadband = 1.0
 	cfg.SDR.FilterBW = 50.0 // 恢复为 50Hz 截止频率 (100Hz 带宽)
+	cfg.SDR.DCBlockCutoff = 10.0
 
 	// --- 解码逻辑 ---
 	cfg.Decoder.AgcEnabled = true
 	cfg.Decoder.AgcPeakDecay = 0.9995
 	cfg.Decoder.AgcPeakFloor = 0.01
 	cfg.Decoder.AgcHighRatio = 0.5
-	cfg.Decoder.AgcLowRatio = 0.85
 	cfg.Decoder.AgcMinHigh = 0.005
 
 	cfg.Decoder.MarkWindowSize = 16
@@ -96,6 +145,70 @@ func DefaultConfig() *Config {
 	cfg.Decoder.CharGapRatio = 1.5
 	cfg.Decoder.CharGapMinMs = 60 // 60ms, 对应 50 WPM
 	cfg.Decoder.WordGapRatio = 5.0
+	cfg.Decoder.AmbiguousMarkProb = 0.9
+
+	cfg.Decoder.CollapseSpaces = true
+	cfg.Decoder.UnknownChar = UnknownCharDrop
 
 	return cfg
 }
+
+// initialFreq 返回锁定信号之前使用的音调频率 (Monitor.InitialFreq，0 表示搜索频段的中心)
+func (c *Config) initialFreq() float64 {
+	if c.Monitor.InitialFreq > 0 {
+		return c.Monitor.InitialFreq
+	}
+	return (c.Monitor.MinFrequency + c.Monitor.MaxFrequency) / 2
+}
+
+// copyConfig 复制一份配置 (nil 返回 DefaultConfig)，避免解码器修改调用方的配置
+func copyConfig(cfg *Config) *Config {
+	if cfg == nil {
+		return DefaultConfig()
+	}
+	c := *cfg
+	return &c
+}
+
+// LoadConfig 从 JSON 文件加载配置
+// 文件中没有出现的字段保留 DefaultConfig 的值，因此只需写出想要修改的参数，例如:
+//
+//	{"Decoder": {"DotDashRatio": 2.5}, "SDR": {"FilterBW": 80}}
+//
+// time.Duration 字段 (Monitor.UpdateInterval) 以纳秒为单位。
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+	// 在默认配置上解码，即完成与默认值的合并
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate 检查解码器参数 (束搜索参数、点划比例等) 是否合法
+// 不合法的配置创建 ExperimentalDecoder / GoertzelDecoder 时会 panic，而不是悄悄换成默认值。
+func (c *Config) Validate() error {
+	if err := beamDecoderConfig(c).Validate(); err != nil {
+		return fmt.Errorf("invalid decoder config: %w", err)
+	}
+	return nil
+}
+
+// Save 将配置以 JSON 格式写入文件 (包含所有字段，可作为修改的模板)
+func (c *Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write config %s: %w", path, err)
+	}
+	return nil
+}
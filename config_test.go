@@ -0,0 +1,67 @@
+package cw
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConfig_MergesDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cw.json")
+	data := `{"Decoder": {"DotDashRatio": 2.5, "CollapseSpaces": false}, "SDR": {"FilterBW": 80}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	want := DefaultConfig()
+	want.Decoder.DotDashRatio = 2.5
+	want.Decoder.CollapseSpaces = false
+	want.SDR.FilterBW = 80
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Expected defaults with overrides\ngot  %+v\nwant %+v", cfg, want)
+	}
+}
+
+func TestConfig_SaveRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cw.json")
+	cfg := DefaultConfig()
+	cfg.Monitor.Window = WindowBlackman
+	cfg.Decoder.UnknownChar = UnknownCharRaw
+	if err := cfg.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if !reflect.DeepEqual(loaded, cfg) {
+		t.Errorf("Round trip mismatch\ngot  %+v\nwant %+v", loaded, cfg)
+	}
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte("{not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(bad); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+	// 不合法的参数在加载时报错，而不是创建解码器时悄悄换成默认值
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"Decoder": {"DahRatio": 0.5}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(invalid); err == nil {
+		t.Error("Expected error for an invalid dah ratio")
+	}
+}
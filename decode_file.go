@@ -0,0 +1,159 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"cw/Filters"
+	"fmt"
+	"io"
+)
+
+// decodeFileChunk 离线解码时每次从文件读取的采样点数
+const decodeFileChunk = 4096
+
+// DecodeWAVFile 离线解码整个 WAV 文件并返回识别出的文本
+// 一次性把采样喂给 ExperimentalDecoder (不模拟实时节奏)，结束时用 Flush 结算最后一个字符。
+func DecodeWAVFile(path string, targetFreq float64) (string, error) {
+	return decodeWAVFile(path, targetFreq, BeamDecoder.NewLanguageModel())
+}
+
+func decodeWAVFile(path string, targetFreq float64, lm *BeamDecoder.LanguageModel) (string, error) {
+	reader, err := NewWavReader(path)
+	if err != nil {
+		return "", fmt.Errorf("open wav %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	dec := newExperimentalDecoder(float64(reader.SampleRate), targetFreq, nil, lm)
+	defer dec.Stop()
+
+	for {
+		samples, err := reader.ReadSamples(decodeFileChunk)
+		if len(samples) > 0 {
+			dec.ProcessAudioChunk(samples)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("read wav %s: %w", path, err)
+		}
+	}
+	// Flush 返回完整的最优路径
+	return dec.Flush(), nil
+}
+
+// twoPassParams 第一遍分析整段录音得到的解码参数
+type twoPassParams struct {
+	freq      float64 // 音调频率 (Hz)
+	threshold float64 // 包络判决阈值 (施密特触发器 High)
+	wpm       float64 // 估计速度，0 表示无法估计
+}
+
+// DecodeWAVTwoPass 两遍离线解码，适合不在乎延迟的存档录音：
+// 第一遍分析整个文件，估计音调频率、包络阈值 (底噪/峰值) 和速度；
+// 第二遍用这些固定参数解码 (关闭自动阈值和启动估速)，
+// 不会像实时解码那样在开头几秒参数还没收敛时丢字。
+func DecodeWAVTwoPass(path string) (string, error) {
+	return decodeWAVTwoPass(path, BeamDecoder.NewLanguageModel())
+}
+
+func decodeWAVTwoPass(path string, lm *BeamDecoder.LanguageModel) (string, error) {
+	samples, sampleRate, err := readWAVFile(path)
+	if err != nil {
+		return "", err
+	}
+	cfg := DefaultConfig()
+	params, err := analyzeRecording(samples, float64(sampleRate), cfg)
+	if err != nil {
+		return "", fmt.Errorf("analyze %s: %w", path, err)
+	}
+
+	cfg.Decoder.InitialWPM = params.wpm
+	dec := newExperimentalDecoder(float64(sampleRate), params.freq, cfg, lm)
+	defer dec.Stop()
+	dec.SetAutoThreshold(false)
+	dec.SetThreshold(params.threshold)
+	return decodeSamples(dec, samples), nil
+}
+
+// decodeSamples 按 decodeFileChunk 分块喂给解码器，返回 Flush 的结果
+func decodeSamples(dec StreamDecoder, samples []float32) string {
+	for i := 0; i < len(samples); i += decodeFileChunk {
+		dec.ProcessAudioChunk(samples[i:min(i+decodeFileChunk, len(samples))])
+	}
+	return dec.Flush()
+}
+
+// readWAVFile 读出整个 WAV 文件的采样
+func readWAVFile(path string) ([]float32, int, error) {
+	reader, err := NewWavReader(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open wav %s: %w", path, err)
+	}
+	defer reader.Close()
+
+	var all []float32
+	for {
+		samples, err := reader.ReadSamples(decodeFileChunk)
+		all = append(all, samples...)
+		if err == io.EOF {
+			return all, reader.SampleRate, nil
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("read wav %s: %w", path, err)
+		}
+	}
+}
+
+// analyzeRecording 第一遍：在整段录音上估计解码参数
+//  1. 频率：在 Monitor 频段内逐帧 FFT，取最强一帧的峰值频率
+//  2. 阈值：在该频率上解调出整段包络，按 HistoryOptimizer 的分位点 (底噪 10%、峰值 95%) 计算
+//  3. 速度：用该阈值切出所有 Mark，点划聚类得到点长
+func analyzeRecording(samples []float32, sampleRate float64, cfg *Config) (twoPassParams, error) {
+	var params twoPassParams
+	if len(samples) == 0 {
+		return params, fmt.Errorf("empty recording")
+	}
+
+	analyzer := NewSpectrumAnalyzer(sampleRate, 4096, WindowHanning)
+	var bestMag float64
+	for i := 0; i < len(samples); i += decodeFileChunk {
+		chunk := toFloat64(samples[i:min(i+decodeFileChunk, len(samples))])
+		freq, mag := analyzer.FindDominantFrequency(chunk, cfg.Monitor.MinFrequency, cfg.Monitor.MaxFrequency)
+		if mag > bestMag {
+			params.freq, bestMag = freq, mag
+		}
+	}
+	if bestMag == 0 {
+		return params, fmt.Errorf("no tone found in %.0f-%.0f Hz", cfg.Monitor.MinFrequency, cfg.Monitor.MaxFrequency)
+	}
+
+	sdr := NewSDRDemodulator(sampleRate, params.freq, cfg)
+	history := Filters.NewHistoryOptimizer(float64(len(samples))/sampleRate+1, sampleRate)
+	envelope := make([]float64, len(samples))
+	for i, v := range samples {
+		envelope[i] = sdr.Process(float64(v))
+		history.Push(envelope[i])
+	}
+	params.threshold, _, _ = history.SuggestThreshold()
+
+	trigger := Filters.NewSchmittTrigger(sampleRate, params.threshold, params.threshold*0.8, maxDebounceMs/1000)
+	trigger.SetHysteresisRatio(cfg.Decoder.HysteresisRatio)
+	trigger.SetThreshold(params.threshold)
+	var marks []float64
+	for _, e := range envelope {
+		if tr := trigger.Feed(e); tr != nil && tr.FinishedState {
+			marks = append(marks, tr.DurationMs)
+		}
+	}
+	if len(marks) >= 2 {
+		stats := BeamDecoder.NewAnalyzer(len(marks))
+		for _, m := range marks {
+			stats.AddObservation(m)
+		}
+		if result := stats.Analyze(); result.Valid && result.DitStats.Mean > 0 {
+			params.wpm = 1200 / result.DitStats.Mean
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,96 @@
+package cw
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDecodeWAVFile(t *testing.T) {
+	const sampleRate = 8000
+	dir := t.TempDir()
+	path := filepath.Join(dir, "paris.wav")
+
+	w, err := NewWavWriter(path, sampleRate)
+	if err != nil {
+		t.Fatalf("NewWavWriter: %v", err)
+	}
+	audio := padSilence(GenerateCW("PARIS PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700}), sampleRate, 1)
+	if err := w.WriteSamples(audio); err != nil {
+		t.Fatalf("WriteSamples: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got, err := decodeWAVFile(path, 700, newTestLanguageModel())
+	if err != nil {
+		t.Fatalf("decodeWAVFile: %v", err)
+	}
+	if got = strings.TrimSpace(got); got != "PARIS PARIS PARIS" {
+		t.Errorf("Expected PARIS PARIS PARIS, got %q", got)
+	}
+}
+
+func TestDecodeWAVFile_MissingFile(t *testing.T) {
+	if _, err := decodeWAVFile(filepath.Join(t.TempDir(), "none.wav"), 700, newTestLanguageModel()); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+func TestDecodeWAVTwoPass(t *testing.T) {
+	const sampleRate = 8000
+	const text = "CQ CQ DE BG1ABC BG1ABC K"
+	dir := t.TempDir()
+	path := filepath.Join(dir, "weak.wav")
+
+	// 电平很低的录音：实时解码要等第一次 AUTO-TUNE (约 3 秒) 之后阈值才对，开头的字会丢
+	audio := GenerateCW(text, AudioConfig{WPM: 12, SampleRate: sampleRate, Frequency: 640})
+	audio = noisyRecording(audio, sampleRate, 1, ChannelEffects{SNRdB: 8, Seed: 3})
+	for i := range audio {
+		audio[i] *= 0.1
+	}
+	w, err := NewWavWriter(path, sampleRate)
+	if err != nil {
+		t.Fatalf("NewWavWriter: %v", err)
+	}
+	if err := w.WriteSamples(audio); err != nil {
+		t.Fatalf("WriteSamples: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	params, err := analyzeRecording(audio, sampleRate, DefaultConfig())
+	if err != nil {
+		t.Fatalf("analyzeRecording: %v", err)
+	}
+	if math.Abs(params.freq-640) > 5 || math.Abs(params.wpm-12) > 1 {
+		t.Errorf("analyzeRecording = %+v, want ~640 Hz, ~12 WPM", params)
+	}
+
+	single, err := decodeWAVFile(path, 640, newTestLanguageModel())
+	if err != nil {
+		t.Fatalf("decodeWAVFile: %v", err)
+	}
+	twoPass, err := decodeWAVTwoPass(path, newTestLanguageModel())
+	if err != nil {
+		t.Fatalf("decodeWAVTwoPass: %v", err)
+	}
+	singleCER := CharacterErrorRate(text, single)
+	twoPassCER := CharacterErrorRate(text, twoPass)
+	t.Logf("single-pass CER %.2f (%q), two-pass CER %.2f (%q)", singleCER, single, twoPassCER, twoPass)
+	if twoPassCER > 0.05 || twoPassCER >= singleCER {
+		t.Errorf("two-pass CER %.2f should be near 0 and better than single-pass %.2f", twoPassCER, singleCER)
+	}
+}
+
+func TestDecodeWAVTwoPass_Silence(t *testing.T) {
+	if _, err := analyzeRecording(make([]float32, 8000), 8000, DefaultConfig()); err == nil {
+		t.Error("Expected error for a recording without a tone")
+	}
+	if _, err := decodeWAVTwoPass(filepath.Join(t.TempDir(), "none.wav"), newTestLanguageModel()); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
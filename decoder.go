@@ -14,6 +14,8 @@ var MorseCodeMap = map[string]string{
 	".--.": "P", "--.-": "Q", ".-.": "R", "...": "S", "-": "T",
 	"..-": "U", "...-": "V", ".--": "W", "-..-": "X", "-.--": "Y",
 	"--..": "Z",
+	// 带重音的字母 (ITU)
+	"..-..": "É", "--.--": "Ñ", "..--": "Ü", ".-.-": "Ä", "---.": "Ö",
 	// 数字
 	".----": "1", "..---": "2", "...--": "3", "....-": "4", ".....": "5",
 	"-....": "6", "--...": "7", "---..": "8", "----.": "9", "-----": "0",
@@ -42,15 +44,77 @@ var MorseCodeMap = map[string]string{
 	"-...-.-": "<BK>", // Break
 }
 
+// UnknownCharPolicy 码型不在 MorseCodeMap 中时的处理方式
+type UnknownCharPolicy int
+
+const (
+	UnknownCharDrop     UnknownCharPolicy = iota // 丢弃 (默认)
+	UnknownCharQuestion                          // 输出 "?"
+	UnknownCharRaw                               // 输出方括号包住的原始码型，例如 "[........]"
+)
+
+// decodeMorse 按 MorseCodeMap 查表，查不到时按 policy 处理
+// 返回 false 表示不输出任何内容
+func decodeMorse(code string, policy UnknownCharPolicy) (string, bool) {
+	if char, ok := MorseCodeMap[code]; ok {
+		return char, true
+	}
+	switch policy {
+	case UnknownCharQuestion:
+		return "?", true
+	case UnknownCharRaw:
+		return "[" + code + "]", true
+	}
+	return "", false
+}
+
+// StreamDecoder 所有解码器共同的流式解码接口
+// OnDecoded 的文本有两种形式 (见 CWSystem.IncrementalOutput)：ClusterDecoder/AdaptiveCWDecoder
+// 每次给出新增的片段，ExperimentalDecoder/GoertzelDecoder 每次给出完整的最优路径。
+type StreamDecoder interface {
+	ProcessAudioChunk(samples []float32)
+	SetOnDecoded(func(string))
+	// Flush 立即结算尚未结束的字符 (如同遇到了足够长的静音)，经 OnDecoded 输出，
+	// 并返回与 OnDecoded 形式相同的当前文本：逐字符解码器返回本次冲刷出的片段 (可能为空)，
+	// Beam Search 解码器返回完整的最优路径。
+	Flush() string
+	// Reset 清空已解码的文本、缓冲的码元和速度统计，保留配置、阈值和回调
+	Reset()
+}
+
 // CWDecoder 接口定义通用解码器行为
 type CWDecoder interface {
-	ProcessAudioChunk(samples []float32)
+	StreamDecoder
 	UpdateTargetFreq(freq float64)
 	SetThreshold(threshold float64)
-	SetOnDecoded(func(string))
+	// Stop 调用 Flush 输出最后一个字符并释放资源 (调试文件等)
 	Stop()
 }
 
+// SymbolNotifier 可选接口：在组装字符之前逐个报告已分类的码元，供练习/调试查看原始莫尔斯码
+// sym 为 "." / "-" (Mark) 或 " " / "/" (字符间隔 / 单词间隔)，durationMs 为该码元或间隔的时长。
+// 对照码元流即可判断解码错误出在点划分类还是字符分组。
+type SymbolNotifier interface {
+	SetOnSymbol(func(sym string, durationMs float64))
+}
+
+// SpeedReporter 可选接口：报告当前估计的发送速度 (WPM)，供界面显示
+type SpeedReporter interface {
+	CurrentWPM() float64
+}
+
+// SNRReporter 可选接口：报告当前测得的信噪比 (dB)，供界面显示
+type SNRReporter interface {
+	CurrentSNR() float64
+}
+
+// ConfidenceNotifier 可选接口：报告每个输出字符的置信度 (0.0 - 1.0)
+// 置信度是字符中各个点划时长在高斯模型 (与 BeamDecoder 的发射分相同) 下被判为该码型的概率之积，
+// 时长落在点划中间时接近 0.5，规整的信号接近 1。
+type ConfidenceNotifier interface {
+	SetOnCharConfidence(func(char string, confidence float64))
+}
+
 // AdaptiveClassifier 自适应贝叶斯分类器
 type AdaptiveClassifier struct {
 	MeanDot  float64
@@ -127,7 +191,11 @@ type AdaptiveCWDecoder struct {
 
 	classifier *AdaptiveClassifier
 
-	OnDecoded func(string)
+	OnDecoded   func(string)
+	OnSymbol    func(sym string, durationMs float64)
+	UnknownChar UnknownCharPolicy // 无法识别的码型如何输出
+
+	muteGate // Mute: 发射期间丢弃输入音频
 }
 
 func NewAdaptiveCWDecoder(sampleRate, targetFreq float64, wpm float64) *AdaptiveCWDecoder {
@@ -152,6 +220,7 @@ func NewAdaptiveCWDecoder(sampleRate, targetFreq float64, wpm float64) *Adaptive
 		Wpm:        wpm,
 		filter:     filter,
 		classifier: NewAdaptiveClassifier(wpm),
+		muteGate:   newMuteGate(sampleRate),
 	}
 }
 
@@ -169,8 +238,40 @@ func (d *AdaptiveCWDecoder) SetOnDecoded(callback func(string)) {
 	d.OnDecoded = callback
 }
 
+// SetOnSymbol 设置码元回调 (见 SymbolNotifier)
+func (d *AdaptiveCWDecoder) SetOnSymbol(callback func(sym string, durationMs float64)) {
+	d.OnSymbol = callback
+}
+
+func (d *AdaptiveCWDecoder) emitSymbol(sym string, durationSec float64) {
+	if d.OnSymbol != nil {
+		d.OnSymbol(sym, durationSec*1000)
+	}
+}
+
+// Flush 以字符间隔结束当前符号 (不追加空格)，返回冲刷出的字符
+func (d *AdaptiveCWDecoder) Flush() string {
+	if d.currentSymbol == "" {
+		return ""
+	}
+	d.emitSymbol(" ", d.classifier.MeanDot*3.0)
+	return d.decodeSymbol()
+}
+
+// Reset 清空当前符号并把分类器恢复到初始速度
+func (d *AdaptiveCWDecoder) Reset() {
+	d.currentSymbol = ""
+	d.classifier = NewAdaptiveClassifier(d.Wpm)
+}
+
+// Stop 输出尚未结束的字符
+func (d *AdaptiveCWDecoder) Stop() {
+	d.Flush()
+}
+
 // ProcessAudioChunk 处理音频块
 func (d *AdaptiveCWDecoder) ProcessAudioChunk(samples []float32) {
+	samples = d.muteGate.apply(samples)
 	thresholdLow := d.Threshold * 0.6
 	thresholdHigh := d.Threshold
 
@@ -240,6 +341,7 @@ func (d *AdaptiveCWDecoder) ProcessAudioChunk(samples []float32) {
 func (d *AdaptiveCWDecoder) handleSignal(durationSec float64) {
 	symbol := d.classifier.ClassifyAndTrain(durationSec)
 	if symbol != "" {
+		d.emitSymbol(symbol, durationSec)
 		d.currentSymbol += symbol
 		// fmt.Print(symbol)
 	}
@@ -250,24 +352,35 @@ func (d *AdaptiveCWDecoder) handleSilence(durationSec float64) {
 
 	if durationSec > meanDot*2.0 {
 		if d.currentSymbol != "" {
-			if char, ok := MorseCodeMap[d.currentSymbol]; ok {
-				if d.OnDecoded != nil {
-					d.OnDecoded(char)
-				} else {
-					fmt.Printf("%s", char)
-				}
+			if durationSec >= meanDot*5.0 {
+				d.emitSymbol("/", durationSec)
 			} else {
-				// fmt.Printf("?")
+				d.emitSymbol(" ", durationSec)
 			}
-			d.currentSymbol = ""
+			d.decodeSymbol()
 		}
 
 		if durationSec >= meanDot*5.0 {
-			if d.OnDecoded != nil {
-				d.OnDecoded(" ")
-			} else {
-				fmt.Print(" ")
-			}
+			d.emit(" ")
 		}
 	}
 }
+
+// decodeSymbol 解码并输出当前符号，返回输出的字符
+func (d *AdaptiveCWDecoder) decodeSymbol() string {
+	char, ok := decodeMorse(d.currentSymbol, d.UnknownChar)
+	d.currentSymbol = ""
+	if !ok {
+		return ""
+	}
+	d.emit(char)
+	return char
+}
+
+func (d *AdaptiveCWDecoder) emit(text string) {
+	if d.OnDecoded != nil {
+		d.OnDecoded(text)
+	} else {
+		fmt.Print(text)
+	}
+}
@@ -0,0 +1,58 @@
+package cw
+
+import "testing"
+
+func TestDecodeMorse_UnknownCharPolicy(t *testing.T) {
+	tests := []struct {
+		code   string
+		policy UnknownCharPolicy
+		want   string
+		ok     bool
+	}{
+		{".-", UnknownCharDrop, "A", true},
+		{".-", UnknownCharRaw, "A", true},
+		{"........", UnknownCharDrop, "", false},
+		{"........", UnknownCharQuestion, "?", true},
+		{"........", UnknownCharRaw, "[........]", true},
+	}
+	for _, tt := range tests {
+		if got, ok := decodeMorse(tt.code, tt.policy); got != tt.want || ok != tt.ok {
+			t.Errorf("decodeMorse(%q, %d) = %q, %v; want %q, %v", tt.code, tt.policy, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAdaptiveCWDecoder_UnknownChar(t *testing.T) {
+	d := NewAdaptiveCWDecoder(8000, 700, 20)
+	var out string
+	d.SetOnDecoded(func(s string) { out += s })
+	d.UnknownChar = UnknownCharQuestion
+
+	// 8 个点之后的字符间隔
+	d.currentSymbol = "........"
+	d.handleSilence(d.classifier.MeanDot * 3)
+	if out != "?" {
+		t.Errorf("Expected ?, got %q", out)
+	}
+}
+
+func TestAdaptiveCWDecoder_FlushReset(t *testing.T) {
+	d := NewAdaptiveCWDecoder(8000, 700, 20)
+	var out string
+	d.SetOnDecoded(func(s string) { out += s })
+
+	d.currentSymbol = ".-"
+	if got := d.Flush(); got != "A" || out != "A" {
+		t.Errorf("Expected Flush to emit and return A, got %q (emitted %q)", got, out)
+	}
+	if got := d.Flush(); got != "" {
+		t.Errorf("Expected empty second Flush, got %q", got)
+	}
+
+	d.currentSymbol = "..."
+	d.classifier.MeanDot = 0.2
+	d.Reset()
+	if d.currentSymbol != "" || d.classifier.MeanDot != 1.2/20 {
+		t.Errorf("Expected Reset to clear symbol and speed, got %q / %.3f", d.currentSymbol, d.classifier.MeanDot)
+	}
+}
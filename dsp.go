@@ -1,43 +1,86 @@
 package cw
 
 import (
-	"math"
 	"math/cmplx"
 
 	"github.com/mjibson/go-dsp/fft"
+	"github.com/mjibson/go-dsp/window"
 )
 
+// WindowType FFT 前使用的窗函数
+type WindowType int
+
+const (
+	WindowHanning  WindowType = iota // 汉宁窗 (默认)，分辨率和泄漏比较均衡
+	WindowHamming                    // 汉明窗，第一旁瓣更低
+	WindowBlackman                   // 布莱克曼窗，泄漏更低，主瓣更宽
+	WindowFlatTop                    // 平顶窗，幅度最准，主瓣最宽
+)
+
+// makeWindow 生成长度为 n 的窗函数系数，未知类型按汉宁窗处理
+func makeWindow(t WindowType, n int) []float64 {
+	switch t {
+	case WindowHamming:
+		return window.Hamming(n)
+	case WindowBlackman:
+		return window.Blackman(n)
+	case WindowFlatTop:
+		return window.FlatTop(n)
+	default:
+		// 公式: 0.5 * (1 - cos(2*PI*n / (N-1)))
+		return window.Hann(n)
+	}
+}
+
 // SpectrumAnalyzer 用于频谱分析和峰值检测
 type SpectrumAnalyzer struct {
 	SampleRate float64
 	FFTSize    int
+	WindowType WindowType
 	Window     []float64
+
+	buffer []float64 // 尚未凑满一帧的样本 (以及与下一帧重叠的后半帧)
 }
 
 // NewSpectrumAnalyzer 创建新的频谱分析器
-func NewSpectrumAnalyzer(sampleRate float64, fftSize int) *SpectrumAnalyzer {
-	// 创建汉宁窗 (Hanning Window)
-	// 公式: 0.5 * (1 - cos(2*PI*n / (N-1)))
-	window := make([]float64, fftSize)
-	for i := 0; i < fftSize; i++ {
-		window[i] = 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/float64(fftSize-1)))
-	}
-
+// windowType: 窗函数类型，两个信号靠得很近时 Blackman / FlatTop 的泄漏更小
+func NewSpectrumAnalyzer(sampleRate float64, fftSize int, windowType WindowType) *SpectrumAnalyzer {
 	return &SpectrumAnalyzer{
 		SampleRate: sampleRate,
 		FFTSize:    fftSize,
-		Window:     window,
+		WindowType: windowType,
+		Window:     makeWindow(windowType, fftSize),
 	}
 }
 
-// FindDominantFrequency 计算当前音频块的主频
-// 返回主频 (Hz) 和 对应的幅度
+// FindDominantFrequency 输入一块音频，返回主频 (Hz) 和 对应的幅度
+// 音频块可以比 FFTSize 短：样本先存入内部缓冲区，凑满 FFTSize 才做一次 FFT，帧间 50% 重叠 (步进 FFTSize/2)。
+// 本次调用处理了多帧时返回幅度最大的一帧；一帧都没凑满时返回 (0, 0)。
 // minFreq, maxFreq: 限制搜索范围，避开低频噪声
 func (sa *SpectrumAnalyzer) FindDominantFrequency(samples []float64, minFreq, maxFreq float64) (float64, float64) {
-	if len(samples) < sa.FFTSize {
-		return 0, 0
+	sa.buffer = append(sa.buffer, samples...)
+	hop := max(sa.FFTSize/2, 1)
+
+	var bestFreq, bestMag float64
+	start := 0
+	for ; start+sa.FFTSize <= len(sa.buffer); start += hop {
+		freq, mag := sa.analyzeFrame(sa.buffer[start:start+sa.FFTSize], minFreq, maxFreq)
+		if mag > bestMag {
+			bestFreq, bestMag = freq, mag
+		}
 	}
+	// 保留未处理的样本 (包括与下一帧重叠的部分)
+	sa.buffer = append(sa.buffer[:0], sa.buffer[start:]...)
+	return bestFreq, bestMag
+}
+
+// Reset 丢弃缓冲区中的样本，下一帧从新输入的音频开始
+func (sa *SpectrumAnalyzer) Reset() {
+	sa.buffer = sa.buffer[:0]
+}
 
+// analyzeFrame 对一帧 (FFTSize 个样本) 做 FFT 并寻找峰值
+func (sa *SpectrumAnalyzer) analyzeFrame(samples []float64, minFreq, maxFreq float64) (float64, float64) {
 	// 1. 应用窗函数
 	input := make([]complex128, sa.FFTSize)
 	for i := 0; i < sa.FFTSize; i++ {
@@ -101,3 +144,12 @@ func (sa *SpectrumAnalyzer) FindDominantFrequency(samples []float64, minFreq, ma
 
 	return freq, maxMag
 }
+
+// toFloat64 把音频块转换为 FFT 使用的 float64
+func toFloat64(samples []float32) []float64 {
+	out := make([]float64, len(samples))
+	for i, v := range samples {
+		out[i] = float64(v)
+	}
+	return out
+}
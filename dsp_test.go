@@ -0,0 +1,70 @@
+package cw
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestSpectrumAnalyzer_WindowTypes(t *testing.T) {
+	const sampleRate = 8000
+	samples := make([]float64, 4096)
+	for i := range samples {
+		samples[i] = math.Sin(2 * math.Pi * 703.3 * float64(i) / sampleRate)
+	}
+
+	for _, wt := range []WindowType{WindowHanning, WindowHamming, WindowBlackman, WindowFlatTop} {
+		sa := NewSpectrumAnalyzer(sampleRate, 4096, wt)
+		if len(sa.Window) != 4096 || sa.WindowType != wt {
+			t.Fatalf("window %d: unexpected analyzer %+v", wt, sa.WindowType)
+		}
+		// 对称窗
+		if math.Abs(sa.Window[100]-sa.Window[4095-100]) > 1e-9 {
+			t.Errorf("window %d: expected symmetric coefficients", wt)
+		}
+		freq, mag := sa.FindDominantFrequency(samples, 300, 1200)
+		if math.Abs(freq-703.3) > 1.0 || mag <= 0 {
+			t.Errorf("window %d: expected ~703.3Hz, got %.2fHz (mag %.2f)", wt, freq, mag)
+		}
+	}
+
+	// 默认的汉宁窗保持原来的公式
+	hann := makeWindow(WindowHanning, 8)
+	for i, v := range hann {
+		want := 0.5 * (1 - math.Cos(2*math.Pi*float64(i)/7))
+		if math.Abs(v-want) > 1e-12 {
+			t.Errorf("hann[%d] = %f, want %f", i, v, want)
+		}
+	}
+}
+
+func TestSpectrumAnalyzer_ChunkedOverlap(t *testing.T) {
+	const sampleRate, fftSize, chunk = 8000, 4096, 1024
+	sa := NewSpectrumAnalyzer(sampleRate, fftSize, WindowHanning)
+	tone := make([]float64, chunk)
+
+	var frames []int
+	for n := 0; n < 10; n++ {
+		for i := range tone {
+			tone[i] = math.Sin(2 * math.Pi * 703.3 * float64(n*chunk+i) / sampleRate)
+		}
+		freq, mag := sa.FindDominantFrequency(tone, 300, 1200)
+		if mag == 0 {
+			continue
+		}
+		frames = append(frames, n)
+		if math.Abs(freq-703.3) > 1.0 {
+			t.Errorf("chunk %d: expected ~703.3Hz, got %.2fHz", n, freq)
+		}
+	}
+	// 第一帧在 4096 个样本 (第 4 块) 后完成，之后每 2048 个样本一帧
+	if want := []int{3, 5, 7, 9}; fmt.Sprint(frames) != fmt.Sprint(want) {
+		t.Errorf("Expected frames after chunks %v, got %v", want, frames)
+	}
+
+	// Reset 丢弃缓冲的半帧
+	sa.Reset()
+	if _, mag := sa.FindDominantFrequency(make([]float64, fftSize/2), 300, 1200); mag != 0 {
+		t.Error("Expected no frame from half an FFT after Reset")
+	}
+}
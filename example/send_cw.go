@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 )
 
@@ -26,6 +27,7 @@ func main() {
 	}
 	defer client.Close()
 	fmt.Println("Connected. Type text and press Enter to send CW.")
+	fmt.Println("Type '/wpm N' to set keyer speed (6-48).")
 	fmt.Println("Type 'exit' or 'quit' to stop.")
 
 	// 4. 循环读取控制台输入
@@ -47,6 +49,23 @@ func main() {
 			break
 		}
 
+		// 调整电台内置电键速度
+		if strings.HasPrefix(strings.ToLower(input), "/wpm") {
+			wpm, err := strconv.Atoi(strings.TrimSpace(input[len("/wpm"):]))
+			if err != nil {
+				fmt.Println("Usage: /wpm N")
+				continue
+			}
+			// 电台只支持 6-48 WPM，SetKeyerSpeed 会钳位，这里按实际设置的值显示
+			wpm = min(max(wpm, cw.CIVMinKeyerWPM), cw.CIVMaxKeyerWPM)
+			if err := client.SetKeyerSpeed(wpm); err != nil {
+				log.Printf("Error setting keyer speed: %v\n", err)
+			} else {
+				fmt.Printf("Keyer speed set to %d WPM\n", wpm)
+			}
+			continue
+		}
+
 		// 转换为大写 (CW 通常只支持大写)
 		textToSend := strings.ToUpper(input)
 
@@ -4,18 +4,24 @@ import (
 	"cw/BeamDecoder"
 	"cw/Filters"
 	"fmt"
+	"math"
 )
 
 // ExperimentalDecoder implements the new decoding logic:
 // 1. SDR-based Demodulation (I/Q)
 // 2. Beam Search Decoder (Logic & WPM Tracking)
 type ExperimentalDecoder struct {
+	cfg              *Config
+	sampleRate       float64
 	sdr              *SDRDemodulator
 	beam             *BeamDecoder.CWDecoder
-	agc              *Filters.MedianAGC
+	agc              Filters.AGC // 仅在 Decoder.RobustAGC 时作用于包络
 	samplesProcessed int64
+	beamStartSample  int64 // 上次 Reset 时的 samplesProcessed：Beam 解码器的时间轴 (FlushIfIdle) 从这里开始
 	// Callback
-	OnDecoded func(string)
+	OnDecoded   func(string)
+	onDecodedAt func(text string, sampleOffset int64) // 带采样偏移的解码回调
+	onTune      func(noise, peak, thresh float64)     // AUTO-TUNE 回调
 
 	debugger      SignalDebugger
 	trigger       *Filters.SchmittTrigger
@@ -23,23 +29,89 @@ type ExperimentalDecoder struct {
 
 	historyOpt   *Filters.HistoryOptimizer // 历史分析器
 	processedCnt int                       // 用于定期触发计算的计数器
+
+	multiSenderWarned bool // 是否已经提示过多发信方
+
+	// 最近一次自动调整时的包络峰值和底噪，见 CurrentSNR
+	tunePeak  float64
+	tuneNoise float64
+
+	muteGate // Mute: 发射期间丢弃输入音频
+
+	// 时长记录 (ms)，供 DumpTimingHistogram 导出
+	markDurations  []float64
+	spaceDurations []float64
 }
 
+// beamDecoderConfig 返回 Beam 解码器的参数
+// Decoder.InitialWPM > 0 时直接从该速度开始并关闭启动估速；为 0 时从 30 WPM 开始，用前 8 个 Mark 自动估计速度。
+func beamDecoderConfig(cfg *Config) BeamDecoder.DecoderConfig {
+	bc := BeamDecoder.DecoderConfig{
+		InitialWPM:        30, // 初始假设
+		GlitchThresholdMs: 0,  // 过滤极短噪声 (随速度自适应)
+		UpdateAlpha:       0.25,
+		BootstrapMarks:    8, // 先用前 8 个 Mark 估计实际速度
+	}
+	if cfg.Decoder.InitialWPM > 0 {
+		bc.InitialWPM = cfg.Decoder.InitialWPM
+		bc.BootstrapMarks = 0
+	}
+	bc.Weighting = BeamDecoder.Weighting{DahRatio: cfg.Decoder.DahRatio, GapRatio: cfg.Decoder.GapRatio}
+	// 未设置的束搜索参数由 NewCWDecoder 逐个取默认值
+	bc.Beam.LMWeight = cfg.Decoder.LMWeight
+	bc.Beam.BracketProsigns = cfg.Decoder.BracketProsigns
+	if cfg.Decoder.AdaptiveBeam {
+		bc.Beam.MaxBeamWidth = adaptiveMaxBeamWidth
+		bc.Beam.LowSNRPruneThreshold = adaptiveLowSNRPruneThreshold
+	}
+	return bc
+}
+
+// newBeamDecoder 按配置创建 Beam 解码器
+// 配置不合法时 panic：LoadConfig 和 CWSystem.Start 已经检查过，自己构造配置时先调用 Config.Validate。
+func newBeamDecoder(cfg *Config, lm *BeamDecoder.LanguageModel) *BeamDecoder.CWDecoder {
+	d, err := BeamDecoder.NewCWDecoder(beamDecoderConfig(cfg), lm)
+	if err != nil {
+		panic(fmt.Sprintf("cw: %v (check the config with Config.Validate)", err))
+	}
+	return d
+}
+
+// 开启 AdaptiveBeam 时信噪比最差情况下的束宽和剪枝阈值 (信号好时仍是默认的 20 条路径)
+const (
+	adaptiveMaxBeamWidth         = 40
+	adaptiveLowSNRPruneThreshold = 20.0
+)
+
+// timingHistoryLimit DumpTimingHistogram 每类最多保留的时长个数 (超出后丢弃最旧的)
+const timingHistoryLimit = 20000
+
 // NewExperimentalDecoder creates the new decoder instance
 func NewExperimentalDecoder(sampleRate, targetFreq float64) *ExperimentalDecoder {
-	// Use DefaultConfig for the experimental decoder
-	cfg := DefaultConfig()
-	dbg, _ := NewCsvFileDebugger("debug_session_01.csv")
+	return NewExperimentalDecoderWithConfig(sampleRate, targetFreq, nil)
+}
+
+// NewExperimentalDecoderWithConfig 使用指定配置创建解码器 (nil 表示 DefaultConfig)
+// 配置会被复制一份，之后修改 cfg 不影响解码器。
+func NewExperimentalDecoderWithConfig(sampleRate, targetFreq float64, cfg *Config) *ExperimentalDecoder {
+	return newExperimentalDecoder(sampleRate, targetFreq, cfg, BeamDecoder.NewLanguageModel())
+}
+
+func newExperimentalDecoder(sampleRate, targetFreq float64, cfg *Config, lmodel *BeamDecoder.LanguageModel) *ExperimentalDecoder {
+	cfg = copyConfig(cfg)
 
-	// Debounce window: 5ms
-	debounceMs := 0.012
 	// 【解耦点】初始化施密特触发器
-	// 阈值 0.5/0.4, 去抖 12ms
-	trigger := Filters.NewSchmittTrigger(sampleRate, 0.2, 0.15, debounceMs)
-	lmodel := BeamDecoder.NewLanguageModel()
+	// 阈值 0.2/0.15, 去抖窗口随估计的速度调整 (见 debounceForWPM)
+	cwDecoder := newBeamDecoder(cfg, lmodel)
+	trigger := Filters.NewSchmittTrigger(sampleRate, 0.2, 0.15, debounceForWPM(cwDecoder.WPM())/1000)
+	trigger.SetHysteresisRatio(cfg.Decoder.HysteresisRatio)
 	// 衰减系数 0.99995 (假设48kHz采样) 意味着峰值大约在 1-2秒内衰减一半
 	// 适合 CW 这种时断时续的信号
-	agc := Filters.NewMedianAGC()
+	var agc Filters.AGC = Filters.NewMedianAGC()
+	if cfg.Decoder.RobustAGC {
+		// 跟踪 95% 分位点：CW 的占空比约 40%，分位点落在 Mark 的幅度上
+		agc = Filters.NewRobustAGC(0.95)
+	}
 	sdr := NewSDRDemodulator(sampleRate, targetFreq, cfg)
 	// 初始化历史优化器，记录最近 30 秒
 	historyOpt := Filters.NewHistoryOptimizer(30.0, sampleRate)
@@ -53,28 +125,25 @@ func NewExperimentalDecoder(sampleRate, targetFreq float64) *ExperimentalDecoder
 		MaxJumpHz:      50,
 		NoiseThreshold: 8,
 	})
-	cwDecoder := BeamDecoder.NewCWDecoder(BeamDecoder.DecoderConfig{
-		InitialWPM:        30,   // 初始假设
-		GlitchThresholdMs: 20.0, // 过滤极短噪声
-		UpdateAlpha:       0.25,
-	},
-		lmodel,
-	)
 	return &ExperimentalDecoder{
-		sdr:  sdr,
-		beam: cwDecoder,
+		cfg:        cfg,
+		sampleRate: sampleRate,
+		sdr:        sdr,
+		beam:       cwDecoder,
 
 		agc:     agc,
 		trigger: trigger,
 
-		debugger:      dbg,
+		debugger:      newSignalDebugger(cfg.Decoder.DebugCSVFile),
 		pitchDetector: pitch,
 		historyOpt:    historyOpt,
+		muteGate:      newMuteGate(sampleRate),
 	}
 }
 
 // ProcessAudioChunk processes a block of audio samples
 func (d *ExperimentalDecoder) ProcessAudioChunk(samples []float32) {
+	samples = d.muteGate.apply(samples)
 	sampe64 := make([]float64, len(samples))
 	for i, s := range samples {
 		// 转一次 float64 即可，避免重复转换
@@ -88,6 +157,11 @@ func (d *ExperimentalDecoder) ProcessAudioChunk(samples []float32) {
 		sampe64[i] = val
 	}
 
+	// 触发器要等下一个 Mark 开始才报告空窗：信号停止后，静音超过单词间隔就主动结算最后一个字符
+	if text := d.beam.FlushIfIdle(float64(d.samplesProcessed-d.beamStartSample) * 1000 / d.sampleRate); text != "" {
+		d.emit(text)
+	}
+
 	//a, b := d.pitchDetector.Detect(sampe64)
 	//if b == true {
 	//	d.UpdateTargetFreq(a)
@@ -101,35 +175,43 @@ func (d *ExperimentalDecoder) processSample(sample float64) {
 	d.processedCnt++
 
 	// 1.Orthogonal Down-Conversion + Butterworth Filter
-	rawEnvelope := d.sdr.Process(sample)
-	// 注意：这里不需要再过 d.agc.Update 了，
-	// 因为我们要用历史统计来做更有智慧的 AGC。
+	filtered := d.sdr.Process(sample)
+	rawEnvelope := filtered
+	// 默认不过 d.agc.Update，因为我们要用历史统计来做更有智慧的 AGC，
 	// 直接把 rawEnvelope 喂给历史分析器即可。
+	// 开启 RobustAGC 时，先按分位点归一化，历史分析器和触发器都工作在 0 - 1 的包络上。
+	if d.cfg.Decoder.RobustAGC {
+		rawEnvelope = d.agc.Update(rawEnvelope)
+	}
 	d.historyOpt.Push(rawEnvelope)
 
 	// 2. 定期更新阈值 (例如每 2 秒更新一次)
 	// 48000 * 2 = 96000
-	if d.processedCnt > 24000 {
+	// 关闭自动阈值 (AgcEnabled = false) 时保持 SetThreshold 设置的固定阈值
+	if d.processedCnt > 24000 && d.cfg.Decoder.AgcEnabled {
 		d.processedCnt = 0
 
 		// ★ 核心魔法：从历史中获取智慧
 		bestThresh, peak, noise := d.historyOpt.SuggestThreshold()
+		d.tunePeak, d.tuneNoise = peak, noise
 
 		if bestThresh > 0.001 {
-			d.trigger.SetThresholds(bestThresh, bestThresh*0.8)
+			d.trigger.SetThreshold(bestThresh)
+		}
+		// 信噪比交给束搜索，用于自适应剪枝 (AdaptiveBeam 关闭时无效果)
+		if peak > 0 {
+			d.beam.SetSNR(d.CurrentSNR())
 		}
 		// 更新施密特触发器的阈值
 		// High = 最佳阈值
-		// Low  = 最佳阈值 * 0.8 (防止抖动)
-		//d.trigger.SetThresholds(bestThresh, bestThresh*0.8)
+		// Low  = 最佳阈值 * 0.8 (防止抖动，比例见 Decoder.HysteresisRatio)
 
-		// 可选：打印调试信息，看看现在的决策是基于什么数据
-		fmt.Printf("[AUTO-TUNE] Noise: %.4f | Peak: %.4f | Set Thresh: %.4f\n", noise, peak, bestThresh)
+		// 通知调用方本次决策基于什么数据 (未设置时不输出)
+		if d.onTune != nil {
+			d.onTune(noise, peak, bestThresh)
+		}
 	}
 
-	// 2. AGC Normalization (关键步骤)
-	//envelope := d.agc.Update(rawEnvelope)
-
 	// 【调试插桩】如果还不工作，取消下面这行的注释，看看 envelope 到底是多少
 	//if d.samplesProcessed%1000 == 0 {
 	// fmt.Printf("Env: %.4f | Thr: %.4f\n", envelope, d.ThresholdHigh)
@@ -137,6 +219,8 @@ func (d *ExperimentalDecoder) processSample(sample float64) {
 
 	// 3. 状态检测 (委托给 SchmittTrigger)
 	transition := d.trigger.Feed(rawEnvelope)
+	high, _ := d.trigger.Thresholds()
+	d.debugger.Record(sample, filtered, rawEnvelope, high, d.trigger.GetCurrentState())
 
 	if transition != nil {
 		// 映射 bool -> BeamDecoder 枚举
@@ -153,8 +237,24 @@ func (d *ExperimentalDecoder) processSample(sample float64) {
 		// 打印调试信息
 		//fmt.Printf("\033[s\033[H\033[10B [DEBUG]-> Feed: %s | %.2f ms\033[u\n", stateStr, transition.DurationMs)
 
+		if transition.FinishedState {
+			d.markDurations = appendTiming(d.markDurations, transition.DurationMs)
+		} else {
+			d.spaceDurations = appendTiming(d.spaceDurations, transition.DurationMs)
+		}
+
 		// 输入到 Beam Decoder
 		decodedText := d.beam.FeedNew(transition.DurationMs, finishedState)
+		d.trigger.SetDebounceMs(debounceForWPM(d.beam.WPM()))
+
+		if suspected := d.beam.MultipleSendersSuspected(); suspected != d.multiSenderWarned {
+			d.multiSenderWarned = suspected
+			if suspected {
+				logger.Warn("element timing is inconsistent with a single sender (interleaved beacons?)")
+			} else {
+				logger.Info("element timing consistent again")
+			}
+		}
 
 		if decodedText != "" {
 			d.emit(decodedText)
@@ -162,12 +262,67 @@ func (d *ExperimentalDecoder) processSample(sample float64) {
 	}
 }
 
+// 触发器去抖窗口：取单位时长的 debounceUnitRatio，不超过 maxDebounceMs
+// 30 WPM 及以下为 12ms，40 WPM -> 9ms，60 WPM -> 6ms
+const (
+	maxDebounceMs     = 12.0
+	debounceUnitRatio = 0.3
+)
+
+// debounceForWPM 按速度计算去抖窗口 (ms)
+func debounceForWPM(wpm float64) float64 {
+	return min(maxDebounceMs, 1200.0/wpm*debounceUnitRatio)
+}
+
+// CurrentWPM 当前估计的字符速度 (WPM)，只能在处理音频的线程中调用 (跨线程读取见 CWSystem.CurrentWPM)
+func (d *ExperimentalDecoder) CurrentWPM() float64 {
+	return d.beam.WPM()
+}
+
+// CurrentSNR 最近一次自动调整时的信噪比 (dB)：20*log10(峰值/底噪)
+// 峰值取包络的 95% 分位点，底噪取 10% 分位点 (见 HistoryOptimizer)。
+// 尚未完成自动调整 (或关闭了自动阈值) 时返回 0；底噪为 0 (纯数字静音) 时返回 +Inf
+func (d *ExperimentalDecoder) CurrentSNR() float64 {
+	if d.tunePeak <= 0 {
+		return 0
+	}
+	return 20 * math.Log10(d.tunePeak/d.tuneNoise)
+}
+
+// appendTiming 追加一个时长，超过 2 倍上限时只保留最近的 timingHistoryLimit 个
+func appendTiming(durations []float64, ms float64) []float64 {
+	durations = append(durations, ms)
+	if len(durations) > 2*timingHistoryLimit {
+		durations = append(durations[:0], durations[len(durations)-timingHistoryLimit:]...)
+	}
+	return durations
+}
+
+// DumpTimingHistogram 返回触发器输出的 Mark 和 Space 时长 (ms，按时间顺序)
+// 用于画出点/划的双峰分布，检查自动阈值是否切分正确。返回的是副本。
+func (d *ExperimentalDecoder) DumpTimingHistogram() ([]float64, []float64) {
+	marks := make([]float64, len(d.markDurations))
+	copy(marks, d.markDurations)
+	spaces := make([]float64, len(d.spaceDurations))
+	copy(spaces, d.spaceDurations)
+	return marks, spaces
+}
+
+// formatText 按配置整理输出文本 (合并连续空格)
+func (d *ExperimentalDecoder) formatText(text string) string {
+	if d.cfg.Decoder.CollapseSpaces {
+		text = CollapseSpaces(text)
+	}
+	return text
+}
+
 func (d *ExperimentalDecoder) emit(text string) {
+	text = d.formatText(text)
+	if d.onDecodedAt != nil {
+		d.onDecodedAt(text, d.samplesProcessed)
+	}
 	if d.OnDecoded != nil {
 		d.OnDecoded(text)
-	} else {
-		fmt.Print("\033[s\033[H\033[8B " + text + "\r\n\033[u")
-		//fmt.Print(text)
 	}
 }
 
@@ -176,16 +331,74 @@ func (d *ExperimentalDecoder) UpdateTargetFreq(freq float64) {
 	d.sdr.SetTargetFreq(freq)
 }
 
+// SetThreshold 设置施密特触发器的阈值 (Low 按 Decoder.HysteresisRatio，默认 High * 0.8)
+// 自动阈值开启时这只是初始值，之后会被 AUTO-TUNE 的历史统计结果覆盖；
+// 用 SetAutoThreshold(false) 关闭后该阈值会一直保持。
 func (d *ExperimentalDecoder) SetThreshold(threshold float64) {
-	//d.ThresholdHigh = threshold
-	//d.ThresholdLow = threshold * 0.85
+	d.trigger.SetThreshold(threshold)
+}
+
+// SetAutoThreshold 开启/关闭基于历史统计的周期性阈值调整
+// 干净的录音用固定阈值比 30 秒自适应更可靠。
+func (d *ExperimentalDecoder) SetAutoThreshold(enabled bool) {
+	d.cfg.Decoder.AgcEnabled = enabled
+	d.processedCnt = 0
+}
+
+// SetSidebandInvert 翻转 SDR 前端的 I/Q 方向 (CW-R)
+func (d *ExperimentalDecoder) SetSidebandInvert(invert bool) {
+	d.sdr.SetSidebandInvert(invert)
+}
+
+// SetOnTune 设置 AUTO-TUNE 回调，每次根据历史统计调整阈值时给出噪声、峰值和新阈值
+func (d *ExperimentalDecoder) SetOnTune(callback func(noise, peak, thresh float64)) {
+	d.onTune = callback
 }
 
 func (d *ExperimentalDecoder) SetOnDecoded(callback func(string)) {
 	d.OnDecoded = callback
 }
 
+// SetOnDecodedAt 设置带时间戳的解码回调
+// text 与 OnDecoded 相同 (当前完整的最优路径)，sampleOffset 是解码出这段文本时已处理的采样点数，
+// 除以采样率即为录音中的时间，可用来生成 SRT/VTT 字幕。
+func (d *ExperimentalDecoder) SetOnDecodedAt(callback func(text string, sampleOffset int64)) {
+	d.onDecodedAt = callback
+}
+
+// SetOnSymbol 设置码元回调 (见 SymbolNotifier)
+func (d *ExperimentalDecoder) SetOnSymbol(callback func(sym string, durationMs float64)) {
+	d.beam.SetOnSymbol(callback)
+}
+
+// Flush 结算最后一个字符，返回当前完整的最优路径 (见 StreamDecoder)
+func (d *ExperimentalDecoder) Flush() string {
+	if text := d.beam.CheckTimeout(); text != "" {
+		d.emit(text)
+	}
+	return d.formatText(d.beam.GetBestPath())
+}
+
+// Commit 把已经确定的完整单词定为结论 (见 BeamDecoder.CWDecoder.Commit)，24 小时值守时定期调用，
+// 避免最优路径无限增长。与 ProcessAudioChunk 在同一线程调用。
+// 返回提交的文本 (已按配置整理)；之后 OnDecoded / OnDecodedAt / Flush 给出的完整文本只包含尚未提交的部分，
+// 显示全文的调用方把每次的返回值按顺序拼在前面。
+func (d *ExperimentalDecoder) Commit() string {
+	return d.formatText(d.beam.Commit())
+}
+
+// Reset 清空解码文本、速度统计和时长记录，阈值和 SDR 前端保持不变 (见 StreamDecoder)
+func (d *ExperimentalDecoder) Reset() {
+	d.beam.Reset()
+	d.beamStartSample = d.samplesProcessed
+	d.trigger.SetDebounceMs(debounceForWPM(d.beam.WPM()))
+	d.markDurations = nil
+	d.spaceDurations = nil
+	d.multiSenderWarned = false
+}
+
+// Stop 输出最后一个字符并关闭调试文件
 func (d *ExperimentalDecoder) Stop() {
-	d.emit(d.beam.CheckTimeout())
+	d.Flush()
 	d.debugger.Close()
 }
@@ -0,0 +1,353 @@
+package cw
+
+import (
+	"cw/Filters"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExperimentalDecoder_ManualThreshold(t *testing.T) {
+	const sampleRate = 8000
+
+	audio := GenerateCW("PARIS PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+
+	auto := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	auto.SetOnDecoded(func(string) {})
+	auto.SetThreshold(0.5)
+	auto.ProcessAudioChunk(audio)
+	if high, _ := auto.trigger.Thresholds(); high == 0.5 {
+		t.Error("Expected auto-tune to replace the initial threshold")
+	}
+	auto.Stop()
+
+	fixed := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	fixed.SetOnDecoded(func(string) {})
+	fixed.SetAutoThreshold(false)
+	fixed.SetThreshold(0.5)
+	fixed.ProcessAudioChunk(audio)
+	if high, low := fixed.trigger.Thresholds(); high != 0.5 || low != 0.4 {
+		t.Errorf("Expected pinned thresholds 0.5/0.4, got %.4f/%.4f", high, low)
+	}
+	fixed.Stop()
+
+	// 加宽迟滞：低阈值 = 高阈值 * (1 - 2 * 0.2)
+	cfg := DefaultConfig()
+	cfg.Decoder.HysteresisRatio = 0.2
+	wide := newExperimentalDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+	wide.SetThreshold(0.5)
+	if high, low := wide.trigger.Thresholds(); high != 0.5 || math.Abs(low-0.3) > 1e-12 {
+		t.Errorf("Expected thresholds 0.5/0.3 with HysteresisRatio 0.2, got %.4f/%.4f", high, low)
+	}
+	wide.Stop()
+}
+
+func TestExperimentalDecoder_DebugCSVFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+
+	// 默认关闭：不在当前目录留下任何文件
+	d := newExperimentalDecoder(8000, 700, nil, newTestLanguageModel())
+	d.SetOnDecoded(func(string) {})
+	d.ProcessAudioChunk(make([]float32, 100))
+	d.Stop()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no debug file by default, found %d entries", len(entries))
+	}
+
+	// 配置了路径则写出表头和数据
+	cfg := DefaultConfig()
+	cfg.Decoder.DebugCSVFile = filepath.Join(dir, "session.csv")
+	d = newExperimentalDecoder(8000, 700, cfg, newTestLanguageModel())
+	d.SetOnDecoded(func(string) {})
+	d.ProcessAudioChunk(make([]float32, 100))
+	d.Stop()
+	data, err := os.ReadFile(cfg.Decoder.DebugCSVFile)
+	if err != nil {
+		t.Fatalf("Expected debug file: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "RawInput,") {
+		t.Errorf("Expected a CSV header, got %q", string(data[:min(len(data), 40)]))
+	}
+	// 每个采样一行
+	if rows := strings.Count(string(data), "\n") - 1; rows != 100 {
+		t.Errorf("Expected 100 data rows, got %d", rows)
+	}
+
+	// 无法创建时退回空调试器，Stop 不会 panic
+	cfg.Decoder.DebugCSVFile = filepath.Join(dir, "missing", "session.csv")
+	d = newExperimentalDecoder(8000, 700, cfg, newTestLanguageModel())
+	d.Stop()
+}
+
+func TestExperimentalDecoder_Commit(t *testing.T) {
+	const sampleRate = 8000
+	audio := padSilence(GenerateCW("CQ CQ DE BG1ABC K", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700}), sampleRate, 1)
+
+	cfg := DefaultConfig()
+	cfg.Decoder.InitialWPM = 20
+	dec := newExperimentalDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+	var latest string
+	dec.SetOnDecoded(func(text string) { latest = text })
+
+	// 每个音频块之后提交一次：提交的文本 + OnDecoded 收到的尾部 = 完整结果
+	var committed strings.Builder
+	for i := 0; i < len(audio); i += decodeFileChunk {
+		dec.ProcessAudioChunk(audio[i:min(i+decodeFileChunk, len(audio))])
+		committed.WriteString(dec.Commit())
+	}
+	tail := dec.Flush()
+	if tail != latest {
+		t.Errorf("Flush %q should match the last OnDecoded text %q", tail, latest)
+	}
+	if got := committed.String() + tail; got != "CQ CQ DE BG1ABC K" {
+		t.Errorf("Expected committed text + tail to be the full message, got %q + %q", committed.String(), tail)
+	}
+	if committed.Len() == 0 || strings.HasPrefix(tail, "CQ") {
+		t.Errorf("Expected settled words to be committed, tail %q", tail)
+	}
+}
+
+func TestExperimentalDecoder_OnTune(t *testing.T) {
+	const sampleRate = 8000
+
+	dec := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetOnDecoded(func(string) {})
+	var tunes int
+	var lastPeak, lastThresh float64
+	dec.SetOnTune(func(noise, peak, thresh float64) {
+		tunes++
+		lastPeak, lastThresh = peak, thresh
+	})
+	dec.ProcessAudioChunk(GenerateCW("PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700}))
+	dec.Stop()
+
+	if tunes == 0 {
+		t.Fatal("Expected OnTune to be called")
+	}
+	if high, _ := dec.trigger.Thresholds(); high != lastThresh {
+		t.Errorf("Expected trigger threshold %.4f from last tune, got %.4f", lastThresh, high)
+	}
+	if lastThresh <= 0 || lastThresh >= lastPeak {
+		t.Errorf("Expected threshold between 0 and peak %.4f, got %.4f", lastPeak, lastThresh)
+	}
+}
+
+func TestExperimentalDecoder_DumpTimingHistogram(t *testing.T) {
+	const sampleRate = 8000
+
+	dec := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetOnDecoded(func(string) {})
+	audio := GenerateCW("PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	dec.ProcessAudioChunk(padSilence(audio, sampleRate, 0))
+	dec.Stop()
+
+	marks, spaces := dec.DumpTimingHistogram()
+	// PARIS = 10 个点 + 4 个划，两遍共 28 个
+	if len(marks) != 28 {
+		t.Fatalf("Expected 28 marks, got %d", len(marks))
+	}
+	// 20 WPM: 点 60ms，划 180ms
+	var dits, dahs int
+	for _, m := range marks {
+		switch {
+		case m > 40 && m < 90:
+			dits++
+		case m > 150 && m < 220:
+			dahs++
+		default:
+			t.Errorf("Unexpected mark duration %.1fms", m)
+		}
+	}
+	if dits != 20 || dahs != 8 {
+		t.Errorf("Expected 20 dits and 8 dahs, got %d / %d", dits, dahs)
+	}
+	if len(spaces) == 0 {
+		t.Error("Expected space durations")
+	}
+
+	// 返回的是副本
+	marks[0] = -1
+	if again, _ := dec.DumpTimingHistogram(); again[0] == -1 {
+		t.Error("DumpTimingHistogram should return a copy")
+	}
+}
+
+func TestExperimentalDecoder_OnDecodedAt(t *testing.T) {
+	const sampleRate = 8000
+
+	dec := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	var texts []string
+	var offsets []int64
+	dec.SetOnDecodedAt(func(text string, sampleOffset int64) {
+		texts = append(texts, text)
+		offsets = append(offsets, sampleOffset)
+	})
+
+	audio := GenerateCW("PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	firstWord := int64(len(GenerateCW("PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})))
+	audio = append(audio, make([]float32, sampleRate)...)
+	dec.ProcessAudioChunk(audio)
+	dec.Stop()
+
+	if len(texts) < 2 {
+		t.Fatalf("Expected several timestamped updates, got %q", texts)
+	}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] < offsets[i-1] {
+			t.Errorf("Offsets should not go backwards: %v", offsets)
+		}
+	}
+	// 第一个字符在第一个单词结束前就已解码
+	if offsets[0] >= firstWord {
+		t.Errorf("Expected first update within the first word (%d samples), got %d (%q)", firstWord, offsets[0], texts[0])
+	}
+	// Stop 冲刷出的最后一段文本对应整段录音的末尾
+	if last := len(offsets) - 1; offsets[last] != int64(len(audio)) {
+		t.Errorf("Expected final offset %d, got %d (%q)", len(audio), offsets[last], texts[last])
+	}
+}
+
+func TestExperimentalDecoder_ConfigIsCopied(t *testing.T) {
+	cfg := DefaultConfig()
+	dec := newExperimentalDecoder(8000, 700, cfg, newTestLanguageModel())
+	defer dec.Stop()
+
+	dec.SetAutoThreshold(false)
+	if !cfg.Decoder.AgcEnabled {
+		t.Error("SetAutoThreshold should not modify the caller's config")
+	}
+}
+
+func TestExperimentalDecoder_RobustAGC(t *testing.T) {
+	const sampleRate = 8000
+
+	audio := GenerateCW("PARIS PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	audio = noisyRecording(audio, sampleRate, 2, ChannelEffects{SNRdB: 20, Seed: 1})
+	// 两次 10ms 的强脉冲 (约 30 倍信号幅度)
+	for _, at := range []int{sampleRate * 2, sampleRate * 4} {
+		for i := 0; i < 80; i++ {
+			audio[at+i] = 30 * float32(1-2*(i%2))
+		}
+	}
+
+	cfg := DefaultConfig()
+	cfg.Decoder.InitialWPM = 20
+	cfg.Decoder.RobustAGC = true
+	dec := newExperimentalDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+	defer dec.Stop()
+	if _, ok := dec.agc.(*Filters.RobustAGC); !ok {
+		t.Fatalf("Expected RobustAGC, got %T", dec.agc)
+	}
+	var lastThresh float64
+	dec.SetOnTune(func(noise, peak, thresh float64) { lastThresh = thresh })
+	for i := 0; i < len(audio); i += 512 {
+		dec.ProcessAudioChunk(audio[i:min(i+512, len(audio))])
+	}
+
+	// 开头的字符可能在 AGC 稳定前丢失，之后的内容不受强脉冲影响
+	if got := dec.Flush(); !strings.HasSuffix(got, "PARIS PARIS") {
+		t.Errorf("Expected ...PARIS PARIS, got %q", got)
+	}
+	// 阈值工作在归一化后的包络上
+	if lastThresh <= 0 || lastThresh >= 1 {
+		t.Errorf("Expected a normalized threshold in (0, 1), got %.4f", lastThresh)
+	}
+
+	def := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	defer def.Stop()
+	if _, ok := def.agc.(*Filters.MedianAGC); !ok {
+		t.Error("Expected MedianAGC by default")
+	}
+}
+
+func TestExperimentalDecoder_DebounceFollowsWPM(t *testing.T) {
+	const sampleRate = 8000
+
+	// 40 WPM: 点长 30ms，点间隔也只有 30ms
+	const text = "PARIS PARIS PARIS"
+	audio := padSilence(GenerateCW(text, AudioConfig{WPM: 40, SampleRate: sampleRate, Frequency: 700}), sampleRate, 2)
+
+	cfg := DefaultConfig()
+	cfg.Decoder.InitialWPM = 40
+	dec := newExperimentalDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+	defer dec.Stop()
+	if got := dec.trigger.DebounceMs(); got >= maxDebounceMs {
+		t.Errorf("Expected debounce below %.0fms at 40 WPM, got %.2fms", maxDebounceMs, got)
+	}
+	dec.SetAutoThreshold(false)
+	dec.SetThreshold(0.3)
+	dec.ProcessAudioChunk(audio)
+
+	// 每个点都保留下来：PARIS 共 14 个 Mark
+	marks, _ := dec.DumpTimingHistogram()
+	if len(marks) != 3*14 {
+		t.Errorf("Expected %d marks, got %d", 3*14, len(marks))
+	}
+	if got := strings.TrimSpace(dec.Flush()); got != text {
+		t.Errorf("Expected %q, got %q", text, got)
+	}
+
+	if got := debounceForWPM(20); got != maxDebounceMs {
+		t.Errorf("Expected %.0fms debounce at 20 WPM, got %.2f", maxDebounceMs, got)
+	}
+}
+
+func TestExperimentalDecoder_CurrentSNR(t *testing.T) {
+	const sampleRate = 8000
+
+	measure := func(snrDB float64) float64 {
+		audio := GenerateCW("PARIS PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+		audio = ApplyEffects(audio, sampleRate, ChannelEffects{SNRdB: snrDB, Seed: 1})
+		dec := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+		defer dec.Stop()
+		dec.SetOnDecoded(func(string) {})
+		if got := dec.CurrentSNR(); got != 0 {
+			t.Errorf("Expected 0 before the first auto-tune, got %.1f", got)
+		}
+		var peak, noise float64
+		dec.SetOnTune(func(n, p, thresh float64) { noise, peak = n, p })
+		dec.ProcessAudioChunk(audio)
+		if want := 20 * math.Log10(peak/noise); dec.CurrentSNR() != want {
+			t.Errorf("Expected %.2f dB from the last tune, got %.2f", want, dec.CurrentSNR())
+		}
+		return dec.CurrentSNR()
+	}
+
+	strong, weak := measure(20), measure(0)
+	if strong <= weak {
+		t.Errorf("Expected higher SNR for the stronger signal: %.1f dB vs %.1f dB", strong, weak)
+	}
+	if weak <= 0 {
+		t.Errorf("Expected a positive envelope SNR, got %.1f dB", weak)
+	}
+}
+
+func TestExperimentalDecoder_FlushesWhenIdle(t *testing.T) {
+	const sampleRate = 8000
+
+	dec := newExperimentalDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	var last string
+	dec.SetOnDecoded(func(text string) { last = text })
+	dec.SetAutoThreshold(false)
+	dec.SetThreshold(0.3)
+
+	// 信号之后是 1 秒静音，不调用 Flush：最后的 K 也应自动输出
+	audio := padSilence(GenerateCW("TEST K", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700}), sampleRate, 0)
+	for i := 0; i < len(audio); i += 512 {
+		dec.ProcessAudioChunk(audio[i:min(i+512, len(audio))])
+	}
+	if strings.HasSuffix(last, "K") {
+		t.Fatalf("K should still be pending right after the signal, got %q", last)
+	}
+	silence := make([]float32, 512)
+	for i := 0; i < sampleRate/512; i++ {
+		dec.ProcessAudioChunk(silence)
+	}
+	if got := strings.TrimSpace(last); got != "TEST K" {
+		t.Errorf("Expected TEST K after the idle timeout, got %q", last)
+	}
+	dec.Stop()
+}
@@ -0,0 +1,174 @@
+package cw
+
+import (
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// AudioConfig 描述 GenerateCW 生成音频的参数
+type AudioConfig struct {
+	WPM           float64 // 字符速度 (Words Per Minute)，决定点划长度
+	FarnsworthWPM float64 // 整体速度，用于字符/单词间隔；0 或 >= WPM 时使用标准间隔
+	SampleRate    int     // 采样率，例如 48000
+	Frequency     float64 // 音调频率，例如 700Hz
+	JitterPct     float64 // 0.0 - 1.0，点划长度随机抖动 (模拟手键误差)
+	DahRatio      float64 // 划/点比 (模拟机械键或调过比重的电键)，0 = 3
+}
+
+// genRampTime 升余弦包络的上升/下降时间 (秒)，避免 Click 声
+const genRampTime = 0.005
+
+// morseEncodeTable 由 MorseCodeMap 反查得到的字符 -> 码型表 (不含 <SK> 等多字符勤务符号)
+var morseEncodeTable = func() map[rune]string {
+	table := make(map[rune]string)
+	for code, char := range MorseCodeMap {
+		r := []rune(char)
+		if len(r) == 1 {
+			table[r[0]] = code
+		}
+	}
+	return table
+}()
+
+// farnsworthGaps 返回字符间隔和单词间隔 (秒)
+// 使用 ARRL 的 Farnsworth 公式: ta = (60c - 37.2s) / (sc)，
+// 字符间隔 = 3ta/19，单词间隔 = 7ta/19 (c 为字符速度，s 为整体速度)。
+func farnsworthGaps(cfg AudioConfig) (charGap, wordGap float64) {
+	dotLen := 1.2 / cfg.WPM
+	c, s := cfg.WPM, cfg.FarnsworthWPM
+	if s <= 0 || s >= c {
+		return dotLen * 3, dotLen * 7
+	}
+	ta := (60*c - 37.2*s) / (s * c)
+	return 3 * ta / 19, 7 * ta / 19
+}
+
+// padSilence 在音频前面加 0.5 秒、后面加 tail 秒静音：
+// 让解码器的阈值先稳定下来，结尾的字符也能被超时冲刷出来
+func padSilence(audio []float32, sampleRate int, tail float64) []float32 {
+	out := make([]float32, sampleRate/2, sampleRate/2+len(audio)+int(tail*float64(sampleRate)))
+	out = append(out, audio...)
+	return append(out, make([]float32, int(tail*float64(sampleRate)))...)
+}
+
+// GenerateCW 将文本编码为带升余弦包络的 CW 音频
+// 无法编码的字符会被跳过，空格产生单词间隔。
+func GenerateCW(text string, cfg AudioConfig) []float32 {
+	if cfg.WPM <= 0 || cfg.SampleRate <= 0 {
+		return nil
+	}
+
+	// 基础时序 (PARIS 标准: 50 个点 = 1 个单词)
+	dotLen := 1.2 / cfg.WPM
+	dahLen := dotLen * 3
+	if cfg.DahRatio > 0 {
+		dahLen = dotLen * cfg.DahRatio
+	}
+	charGap, wordGap := farnsworthGaps(cfg)
+	sampleRate := float64(cfg.SampleRate)
+
+	var buffer []float32
+
+	appendSilence := func(duration float64) {
+		buffer = append(buffer, make([]float32, int(duration*sampleRate))...)
+	}
+
+	appendTone := func(duration float64) {
+		if cfg.JitterPct > 0 {
+			duration *= 1 + (rand.Float64()*2-1)*cfg.JitterPct
+		}
+		buffer = appendToneSamples(buffer, int(duration*sampleRate), cfg)
+	}
+
+	// 上一个输出是否为字符 (决定空格前是否已有字符间隔)
+	pendingCharGap := false
+	for _, char := range strings.ToUpper(text) {
+		if char == ' ' {
+			if pendingCharGap {
+				appendSilence(wordGap)
+				pendingCharGap = false
+			}
+			continue
+		}
+
+		code, ok := morseEncodeTable[char]
+		if !ok {
+			continue
+		}
+		if pendingCharGap {
+			appendSilence(charGap)
+		}
+
+		for i, symbol := range code {
+			if symbol == '.' {
+				appendTone(dotLen)
+			} else {
+				appendTone(dahLen)
+			}
+			if i < len(code)-1 {
+				appendSilence(dotLen)
+			}
+		}
+		pendingCharGap = true
+	}
+	if pendingCharGap {
+		appendSilence(charGap)
+	}
+
+	return buffer
+}
+
+// appendToneSamples 在 buffer 末尾追加 numSamples 个采样的音调 (cfg.Frequency)，两端为升余弦包络
+func appendToneSamples(buffer []float32, numSamples int, cfg AudioConfig) []float32 {
+	omega := 2.0 * math.Pi * cfg.Frequency / float64(cfg.SampleRate)
+	ramp := min(int(genRampTime*float64(cfg.SampleRate)), numSamples/2)
+	for i := 0; i < numSamples; i++ {
+		envelope := 1.0
+		if i < ramp {
+			envelope = 0.5 - 0.5*math.Cos(math.Pi*float64(i)/float64(ramp))
+		} else if i >= numSamples-ramp {
+			envelope = 0.5 - 0.5*math.Cos(math.Pi*float64(numSamples-1-i)/float64(ramp))
+		}
+		buffer = append(buffer, float32(math.Sin(omega*float64(i))*envelope))
+	}
+	return buffer
+}
+
+// CWDuration 估算以 wpm 速度发送 text 所需的时间 (标准间隔，与 GenerateCW 的时序一致)
+func CWDuration(text string, wpm float64) time.Duration {
+	if wpm <= 0 {
+		return 0
+	}
+	units := 0 // 以点长为单位
+	pendingCharGap := false
+	for _, char := range strings.ToUpper(text) {
+		if char == ' ' {
+			if pendingCharGap {
+				units += 7
+				pendingCharGap = false
+			}
+			continue
+		}
+		code, ok := morseEncodeTable[char]
+		if !ok {
+			continue
+		}
+		if pendingCharGap {
+			units += 3
+		}
+		for i, symbol := range code {
+			if symbol == '.' {
+				units++
+			} else {
+				units += 3
+			}
+			if i < len(code)-1 {
+				units++
+			}
+		}
+		pendingCharGap = true
+	}
+	return time.Duration(float64(units) * 1.2 / wpm * float64(time.Second))
+}
@@ -0,0 +1,126 @@
+package cw
+
+import (
+	"math"
+	"testing"
+)
+
+// toneRuns 返回音频中有声段和静音段的长度 (采样点)，true 表示有声
+// 短于 1ms 的零值段 (包络端点/正弦过零) 并入相邻的有声段
+func toneRuns(samples []float32) (runs []int, on []bool) {
+	const eps = 1e-6
+	for i := 0; i < len(samples); {
+		state := math.Abs(float64(samples[i])) > eps
+		j := i
+		for j < len(samples) && (math.Abs(float64(samples[j])) > eps) == state {
+			j++
+		}
+		length := j - i
+		i = j
+
+		if !state && length < 48 && i < len(samples) {
+			state = true // 夹在有声段之间的短零值
+		}
+		if len(runs) > 0 && on[len(on)-1] == state {
+			runs[len(runs)-1] += length
+			continue
+		}
+		runs = append(runs, length)
+		on = append(on, state)
+	}
+	return runs, on
+}
+
+func TestGenerateCW_Timing(t *testing.T) {
+	cfg := AudioConfig{WPM: 20, SampleRate: 48000, Frequency: 750}
+	samples := GenerateCW("A", cfg)
+
+	// 20 WPM: 点 60ms = 2880 点。"A" = 点 + 间隔 + 划 + 字符间隔
+	dot := 2880
+	expectedLen := dot + dot + 3*dot + 3*dot
+	if len(samples) != expectedLen {
+		t.Fatalf("Expected %d samples, got %d", expectedLen, len(samples))
+	}
+
+	runs, on := toneRuns(samples)
+	if len(runs) != 4 || !on[0] || on[1] || !on[2] || on[3] {
+		t.Fatalf("Unexpected run pattern %v %v", runs, on)
+	}
+	// 包络两端各有一个采样为 0，允许少量误差
+	for i, want := range []int{dot, dot, 3 * dot, 3 * dot} {
+		if math.Abs(float64(runs[i]-want)) > 4 {
+			t.Errorf("Run %d: expected ~%d samples, got %d", i, want, runs[i])
+		}
+	}
+
+	// 升余弦包络：起点为 0，包络结束后达到满幅
+	if samples[0] != 0 {
+		t.Errorf("Envelope should start at 0, got %f", samples[0])
+	}
+	peak := 0.0
+	for _, v := range samples[:dot] {
+		peak = math.Max(peak, math.Abs(float64(v)))
+	}
+	if peak < 0.99 || peak > 1.0 {
+		t.Errorf("Expected peak amplitude ~1.0, got %f", peak)
+	}
+}
+
+func TestGenerateCW_Farnsworth(t *testing.T) {
+	std := AudioConfig{WPM: 20, SampleRate: 8000, Frequency: 700}
+	fw := std
+	fw.FarnsworthWPM = 10
+
+	text := "PARIS PARIS"
+	a := GenerateCW(text, std)
+	b := GenerateCW(text, fw)
+	if len(b) <= len(a) {
+		t.Fatalf("Farnsworth spacing should lengthen output: %d vs %d", len(b), len(a))
+	}
+
+	// 点划长度不变，只有间隔变长
+	runsA, onA := toneRuns(a)
+	runsB, onB := toneRuns(b)
+	if len(runsA) != len(runsB) {
+		t.Fatalf("Run count differs: %d vs %d", len(runsA), len(runsB))
+	}
+	for i := range runsA {
+		if onA[i] != onB[i] {
+			t.Fatalf("Run %d state differs", i)
+		}
+		if onA[i] && runsA[i] != runsB[i] {
+			t.Errorf("Tone run %d changed length: %d vs %d", i, runsA[i], runsB[i])
+		}
+	}
+
+	// "PARIS " 在 Farnsworth 下整体速度应接近 10 WPM (6 秒/词)
+	one := GenerateCW("PARIS ", fw)
+	if sec := float64(len(one)) / 8000; math.Abs(sec-6.0) > 0.05 {
+		t.Errorf("Expected PARIS at 10 WPM overall to take ~6s, got %.3fs", sec)
+	}
+}
+
+func TestGenerateCW_SkipsUnknown(t *testing.T) {
+	cfg := AudioConfig{WPM: 20, SampleRate: 8000, Frequency: 700}
+	if len(GenerateCW("E#", cfg)) != len(GenerateCW("E", cfg)) {
+		t.Error("Unknown characters should be skipped")
+	}
+	if GenerateCW("E", AudioConfig{}) != nil {
+		t.Error("Invalid config should produce no audio")
+	}
+}
+
+func TestGenerateCW_AccentedLetters(t *testing.T) {
+	cfg := AudioConfig{WPM: 20, SampleRate: 8000, Frequency: 700}
+	if len(GenerateCW("É", cfg)) == 0 {
+		t.Fatal("Expected audio for É")
+	}
+	if len(GenerateCW("é", cfg)) != len(GenerateCW("É", cfg)) {
+		t.Error("Lowercase é should be encoded like É")
+	}
+	for _, r := range "ÉÑÜÄÖ" {
+		if _, ok := morseEncodeTable[r]; !ok {
+			t.Errorf("Expected %c in the encode table", r)
+		}
+	}
+}
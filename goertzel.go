@@ -62,3 +62,10 @@ func (g *Goertzel) Detect() float64 {
 	}
 	return math.Sqrt(magnitudeSquared)
 }
+
+// SetTargetFreq 修改检测频率并清空当前块的状态
+func (g *Goertzel) SetTargetFreq(targetFreq float64) {
+	g.targetFreq = targetFreq
+	g.coeff = 2.0 * math.Cos(2.0*math.Pi*targetFreq/g.sampleRate)
+	g.Reset()
+}
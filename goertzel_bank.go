@@ -0,0 +1,85 @@
+package cw
+
+import "math"
+
+// GoertzelBank 在 [minFreq, maxFreq] 上等间距排列的一组 Goertzel 检测器，用于宽范围寻找 CW 音调
+// 比 FFT 便宜，适合低功耗设备上代替 SpectrumMonitor 的 FFT 做频率跟踪。
+// 按块检测：每凑满 BlockSize 个采样，所有检测器出一次结果并清零。
+// 块长取 sampleRate / 检测器间距，使每个检测器的带宽刚好覆盖到相邻检测器，频段内没有盲区。
+type GoertzelBank struct {
+	detectors []*Goertzel
+	freqs     []float64
+	spacing   float64
+	blockSize int
+	count     int // 当前块已处理的采样数
+
+	bestFreq float64 // 最近一块的结果，块内保持
+	energy   float64
+}
+
+// NewGoertzelBank 创建 n 个检测器 (n < 2 时按 2 个处理)
+func NewGoertzelBank(sampleRate, minFreq, maxFreq float64, n int) *GoertzelBank {
+	n = max(n, 2)
+	spacing := (maxFreq - minFreq) / float64(n-1)
+	b := &GoertzelBank{
+		spacing:   spacing,
+		blockSize: max(int(math.Round(sampleRate/spacing)), 1),
+	}
+	for i := 0; i < n; i++ {
+		f := minFreq + float64(i)*spacing
+		b.freqs = append(b.freqs, f)
+		b.detectors = append(b.detectors, NewGoertzel(sampleRate, f))
+	}
+	return b
+}
+
+// BlockSize 返回每次检测的块长 (采样点数)
+func (b *GoertzelBank) BlockSize() int {
+	return b.blockSize
+}
+
+// Process 处理一个采样点，返回最近一个完整块中最强的频率 (在相邻检测器间抛物线插值) 及其幅度。
+// 幅度按块长归一化，与输入正弦波的振幅同尺度；第一个块完成之前返回 0, 0。
+func (b *GoertzelBank) Process(sample float64) (bestFreq, energy float64) {
+	for _, g := range b.detectors {
+		g.ProcessSample(sample)
+	}
+	b.count++
+	if b.count >= b.blockSize {
+		b.detect()
+	}
+	return b.bestFreq, b.energy
+}
+
+// detect 结算当前块：找出最强的检测器并清零所有检测器
+func (b *GoertzelBank) detect() {
+	mags := make([]float64, len(b.detectors))
+	best := 0
+	for i, g := range b.detectors {
+		mags[i] = g.Detect()
+		if mags[i] > mags[best] {
+			best = i
+		}
+		g.Reset()
+	}
+	b.count = 0
+
+	freq := b.freqs[best]
+	if best > 0 && best < len(mags)-1 {
+		l, c, r := mags[best-1], mags[best], mags[best+1]
+		if d := l - 2*c + r; d < 0 {
+			freq += 0.5 * (l - r) / d * b.spacing
+		}
+	}
+	b.bestFreq = freq
+	b.energy = mags[best] * 2 / float64(b.blockSize)
+}
+
+// Reset 清空当前块和最近的结果
+func (b *GoertzelBank) Reset() {
+	for _, g := range b.detectors {
+		g.Reset()
+	}
+	b.count = 0
+	b.bestFreq, b.energy = 0, 0
+}
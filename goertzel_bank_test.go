@@ -0,0 +1,47 @@
+package cw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGoertzelBank_FindsTone(t *testing.T) {
+	const sampleRate = 8000
+	for _, tone := range []float64{520, 700, 712, 885} {
+		bank := NewGoertzelBank(sampleRate, 500, 900, 41) // 10Hz 间距
+		if bank.BlockSize() != 800 {
+			t.Fatalf("Expected block size 800, got %d", bank.BlockSize())
+		}
+
+		var freq, energy float64
+		for i := 0; i < 4*bank.BlockSize(); i++ {
+			freq, energy = bank.Process(0.5 * math.Sin(2*math.Pi*tone*float64(i)/sampleRate))
+		}
+		if math.Abs(freq-tone) > 3 {
+			t.Errorf("tone %.0fHz: detected %.1fHz", tone, freq)
+		}
+		// 矩形窗在两个检测器中间最多损失约 4dB
+		if energy < 0.3 || energy > 0.55 {
+			t.Errorf("tone %.0fHz: energy %.3f, want about 0.5", tone, energy)
+		}
+	}
+}
+
+func TestGoertzelBank_HoldsResultWithinBlock(t *testing.T) {
+	bank := NewGoertzelBank(8000, 500, 900, 41)
+	if f, e := bank.Process(0); f != 0 || e != 0 {
+		t.Errorf("Expected no result before the first block, got %.1f %.3f", f, e)
+	}
+	for i := 1; i < bank.BlockSize(); i++ {
+		bank.Process(math.Sin(2 * math.Pi * 600 * float64(i) / 8000))
+	}
+	f, _ := bank.Process(0)
+	if g, _ := bank.Process(0); g != f || f == 0 {
+		t.Errorf("Expected the block result to be held, got %.1f then %.1f", f, g)
+	}
+
+	bank.Reset()
+	if f, e := bank.Process(0); f != 0 || e != 0 {
+		t.Errorf("Expected Reset to clear the result, got %.1f %.3f", f, e)
+	}
+}
@@ -0,0 +1,167 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"cw/Filters"
+)
+
+// Goertzel 前端参数
+const (
+	goertzelBlockMs      = 5.0   // 每块时长 (ms)，对应约 200Hz 检测带宽
+	goertzelDebounceMs   = 12.0  // 去抖时长，与 ExperimentalDecoder 一致
+	goertzelTuneInterval = 0.5   // 自动阈值更新周期 (秒)
+	goertzelMinThreshold = 0.001 // 自动阈值下限
+)
+
+// GoertzelDecoder 使用分块 Goertzel 能量代替 SDR I/Q 解调作为前端
+// 每块只需一次乘加递推，比 FFT + Butterworth 便宜得多，适合树莓派等低功耗设备。
+// 后端与 ExperimentalDecoder 相同：施密特触发器 + Beam Search 解码。
+type GoertzelDecoder struct {
+	cfg        *Config
+	goertzel   *Goertzel
+	blockSize  int
+	blockCount int // 当前块已累积的采样点数
+
+	trigger    *Filters.SchmittTrigger
+	historyOpt *Filters.HistoryOptimizer
+	beam       *BeamDecoder.CWDecoder
+	tuneBlocks int // 每隔多少块更新一次阈值
+	blocksSeen int
+
+	OnDecoded func(string)
+
+	muteGate // Mute: 发射期间丢弃输入音频
+}
+
+// NewGoertzelDecoder 创建基于 Goertzel 的解码器
+func NewGoertzelDecoder(sampleRate, targetFreq float64) *GoertzelDecoder {
+	return NewGoertzelDecoderWithConfig(sampleRate, targetFreq, nil)
+}
+
+// NewGoertzelDecoderWithConfig 使用指定配置创建解码器 (nil 表示 DefaultConfig)
+func NewGoertzelDecoderWithConfig(sampleRate, targetFreq float64, cfg *Config) *GoertzelDecoder {
+	return newGoertzelDecoder(sampleRate, targetFreq, cfg, BeamDecoder.NewLanguageModel())
+}
+
+func newGoertzelDecoder(sampleRate, targetFreq float64, cfg *Config, lm *BeamDecoder.LanguageModel) *GoertzelDecoder {
+	cfg = copyConfig(cfg)
+	blockSize := int(sampleRate * goertzelBlockMs / 1000.0)
+	if blockSize < 1 {
+		blockSize = 1
+	}
+	// 触发器和历史统计都工作在块速率上
+	blockRate := sampleRate / float64(blockSize)
+
+	trigger := Filters.NewSchmittTrigger(blockRate, 0.2, 0.15, goertzelDebounceMs/1000.0)
+	trigger.SetHysteresisRatio(cfg.Decoder.HysteresisRatio)
+	return &GoertzelDecoder{
+		cfg:        cfg,
+		goertzel:   NewGoertzel(sampleRate, targetFreq),
+		blockSize:  blockSize,
+		trigger:    trigger,
+		historyOpt: Filters.NewHistoryOptimizer(30.0, blockRate),
+		beam:       newBeamDecoder(cfg, lm),
+		tuneBlocks: int(goertzelTuneInterval * blockRate),
+		muteGate:   newMuteGate(sampleRate),
+	}
+}
+
+// ProcessAudioChunk 处理一段音频，按块计算目标频率的幅度
+func (d *GoertzelDecoder) ProcessAudioChunk(samples []float32) {
+	samples = d.muteGate.apply(samples)
+	for _, s := range samples {
+		d.goertzel.ProcessSample(float64(s))
+		d.blockCount++
+		if d.blockCount < d.blockSize {
+			continue
+		}
+
+		// 幅度归一化到与 SDR 包络相同的尺度 (满幅正弦 -> 1.0)
+		envelope := d.goertzel.Detect() * 2.0 / float64(d.blockSize)
+		d.goertzel.Reset()
+		d.blockCount = 0
+		d.processBlock(envelope)
+	}
+}
+
+func (d *GoertzelDecoder) processBlock(envelope float64) {
+	d.historyOpt.Push(envelope)
+
+	d.blocksSeen++
+	if d.blocksSeen >= d.tuneBlocks {
+		d.blocksSeen = 0
+		if bestThresh, _, _ := d.historyOpt.SuggestThreshold(); bestThresh > goertzelMinThreshold {
+			d.trigger.SetThreshold(bestThresh)
+		}
+	}
+
+	transition := d.trigger.Feed(envelope)
+	if transition == nil {
+		return
+	}
+
+	finishedState := BeamDecoder.StateOff
+	if transition.FinishedState {
+		finishedState = BeamDecoder.StateOn
+	}
+	if text := d.beam.FeedNew(transition.DurationMs, finishedState); text != "" {
+		d.emit(text)
+	}
+}
+
+// formatText 按配置整理输出文本 (合并连续空格)
+func (d *GoertzelDecoder) formatText(text string) string {
+	if d.cfg.Decoder.CollapseSpaces {
+		text = CollapseSpaces(text)
+	}
+	return text
+}
+
+func (d *GoertzelDecoder) emit(text string) {
+	text = d.formatText(text)
+	if text != "" && d.OnDecoded != nil {
+		d.OnDecoded(text)
+	}
+}
+
+// CurrentWPM 当前估计的字符速度 (WPM)，只能在处理音频的线程中调用 (跨线程读取见 CWSystem.CurrentWPM)
+func (d *GoertzelDecoder) CurrentWPM() float64 {
+	return d.beam.WPM()
+}
+
+// UpdateTargetFreq 切换检测频率 (丢弃当前未完成的块)
+func (d *GoertzelDecoder) UpdateTargetFreq(freq float64) {
+	d.goertzel.SetTargetFreq(freq)
+	d.blockCount = 0
+}
+
+// SetThreshold 设置施密特触发器的初始阈值 (Low 按 Decoder.HysteresisRatio，默认 High * 0.8)
+// 之后会被历史统计结果覆盖
+func (d *GoertzelDecoder) SetThreshold(threshold float64) {
+	d.trigger.SetThreshold(threshold)
+}
+
+func (d *GoertzelDecoder) SetOnDecoded(callback func(string)) {
+	d.OnDecoded = callback
+}
+
+// SetOnSymbol 设置码元回调 (见 SymbolNotifier)
+func (d *GoertzelDecoder) SetOnSymbol(callback func(sym string, durationMs float64)) {
+	d.beam.SetOnSymbol(callback)
+}
+
+// Flush 结算最后一个字符，返回当前完整的最优路径 (见 StreamDecoder)
+func (d *GoertzelDecoder) Flush() string {
+	d.emit(d.beam.CheckTimeout())
+	return d.formatText(d.beam.GetBestPath())
+}
+
+// Reset 清空解码文本和速度统计，阈值保持不变 (见 StreamDecoder)
+func (d *GoertzelDecoder) Reset() {
+	d.beam.Reset()
+}
+
+// Stop 输出最后一个字符
+func (d *GoertzelDecoder) Stop() {
+	d.Flush()
+}
@@ -0,0 +1,185 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"testing"
+)
+
+// decodeWithGoertzel 将生成的音频分块喂给解码器，返回最终的解码文本
+func decodeWithGoertzel(dec *GoertzelDecoder, audio []float32, sampleRate int) string {
+	// OnDecoded 每次给出当前完整的最优路径文本，保留最后一次即可
+	var out string
+	dec.SetOnDecoded(func(s string) { out = s })
+
+	// 首尾留出静音，分块喂入模拟实时音频
+	audio = padSilence(audio, sampleRate, 2)
+	for i := 0; i < len(audio); i += 512 {
+		end := i + 512
+		if end > len(audio) {
+			end = len(audio)
+		}
+		dec.ProcessAudioChunk(audio[i:end])
+	}
+	dec.Stop()
+	return strings.TrimSpace(out)
+}
+
+func TestGoertzelDecoder_RoundTrip(t *testing.T) {
+	const sampleRate = 8000
+	dec := newGoertzelDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+
+	audio := GenerateCW("PARIS PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	if got := decodeWithGoertzel(dec, audio, sampleRate); got != "PARIS PARIS PARIS" {
+		t.Errorf("Expected round-trip PARIS PARIS PARIS, got %q", got)
+	}
+}
+
+func TestGoertzelDecoder_FlushReset(t *testing.T) {
+	const sampleRate = 8000
+	dec := newGoertzelDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+	cfg := AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700}
+
+	// 音频在最后一个字符之后立刻结束，由 Flush 结算
+	dec.ProcessAudioChunk(padSilence(GenerateCW("PARIS", cfg), sampleRate, 0))
+	if got := strings.TrimSpace(dec.Flush()); got != "PARIS" {
+		t.Errorf("Expected Flush to return PARIS, got %q", got)
+	}
+
+	// Reset 之后不再包含之前的文本
+	dec.Reset()
+	dec.ProcessAudioChunk(padSilence(GenerateCW("TEST", cfg), sampleRate, 0))
+	if got := strings.TrimSpace(dec.Flush()); got != "TEST" {
+		t.Errorf("Expected only TEST after Reset, got %q", got)
+	}
+}
+
+func TestGoertzelDecoder_NoTerminalOutput(t *testing.T) {
+	const sampleRate = 8000
+	dec := newGoertzelDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+	audio := GenerateCW("PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	audio = append(audio, make([]float32, sampleRate*2)...)
+
+	// 未设置 OnDecoded 时解码器不能自己往终端输出
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	dec.ProcessAudioChunk(audio)
+	dec.Stop()
+	os.Stdout = stdout
+	w.Close()
+
+	out, _ := io.ReadAll(r)
+	if len(out) != 0 {
+		t.Errorf("Expected no stdout output, got %q", out)
+	}
+}
+
+func TestGoertzelDecoder_AccentedRoundTrip(t *testing.T) {
+	const sampleRate = 8000
+	dec := newGoertzelDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+
+	audio := GenerateCW("PARIS CAFÉ ÑÜÄÖ", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	if got := decodeWithGoertzel(dec, audio, sampleRate); got != "PARIS CAFÉ ÑÜÄÖ" {
+		t.Errorf("Expected round-trip PARIS CAFÉ ÑÜÄÖ, got %q", got)
+	}
+}
+
+func TestGoertzelDecoder_InitialWPM(t *testing.T) {
+	const sampleRate = 8000
+	cfg := DefaultConfig()
+	cfg.Decoder.InitialWPM = 12
+	dec := newGoertzelDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+
+	audio := GenerateCW("TEST", AudioConfig{WPM: 12, SampleRate: sampleRate, Frequency: 700})
+	if got := decodeWithGoertzel(dec, audio, sampleRate); got != "TEST" {
+		t.Errorf("Expected TEST with a 12 WPM hint, got %q", got)
+	}
+}
+
+func TestBeamDecoderConfig_InitialWPM(t *testing.T) {
+	cfg := DefaultConfig()
+	if bc := beamDecoderConfig(cfg); bc.InitialWPM != 30 || bc.BootstrapMarks != 8 {
+		t.Errorf("Expected auto-detect defaults, got %+v", bc)
+	}
+	cfg.Decoder.InitialWPM = 18
+	if bc := beamDecoderConfig(cfg); bc.InitialWPM != 18 || bc.BootstrapMarks != 0 {
+		t.Errorf("Expected 18 WPM without bootstrap, got %+v", bc)
+	}
+}
+
+func TestBeamDecoderConfig_AdaptiveBeam(t *testing.T) {
+	cfg := DefaultConfig()
+	if bc := beamDecoderConfig(cfg); bc.Beam.LowSNRPruneThreshold != 0 {
+		t.Errorf("Adaptive pruning should be off by default, got %+v", bc.Beam)
+	}
+	cfg.Decoder.AdaptiveBeam = true
+	bc := beamDecoderConfig(cfg)
+	if bc.Beam.LowSNRPruneThreshold <= BeamDecoder.PruneThreshold || bc.Beam.MaxBeamWidth <= BeamDecoder.DefaultBeamWidth {
+		t.Errorf("Expected a wider low-SNR beam, got %+v", bc.Beam)
+	}
+	if err := bc.Validate(); err != nil {
+		t.Errorf("Adaptive beam config should be valid: %v", err)
+	}
+}
+
+func TestBeamDecoderConfig_LMWeight(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Decoder.LMWeight = 0.5
+	bc := beamDecoderConfig(cfg)
+	if bc.Beam.LMWeight != 0.5 {
+		t.Errorf("Expected LM weight 0.5, got %+v", bc.Beam)
+	}
+	if err := bc.Validate(); err != nil {
+		t.Errorf("LM weight config should be valid: %v", err)
+	}
+}
+
+func TestGoertzelDecoder_UpdateTargetFreq(t *testing.T) {
+	const sampleRate = 8000
+	dec := newGoertzelDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+	dec.UpdateTargetFreq(1000)
+
+	audio := GenerateCW("CQ TEST", AudioConfig{WPM: 25, SampleRate: sampleRate, Frequency: 1000})
+	if got := decodeWithGoertzel(dec, audio, sampleRate); got != "CQ TEST" {
+		t.Errorf("Expected CQ TEST after retuning to 1000Hz, got %q", got)
+	}
+}
+
+// newTestLanguageModel 返回不依赖模型文件的空语言模型
+func newTestLanguageModel() *BeamDecoder.LanguageModel {
+	return &BeamDecoder.LanguageModel{
+		LogProbs:    make(map[string]map[string]float64),
+		DefaultProb: math.Log(1e-6),
+	}
+}
+
+func TestGoertzelDecoder_SymbolStream(t *testing.T) {
+	const sampleRate = 8000
+	dec := newGoertzelDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+
+	var notifier SymbolNotifier = dec
+	var symbols strings.Builder
+	notifier.SetOnSymbol(func(sym string, durationMs float64) { symbols.WriteString(sym) })
+
+	audio := GenerateCW("CQ DE", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	if got := decodeWithGoertzel(dec, audio, sampleRate); got != "CQ DE" {
+		t.Fatalf("Expected CQ DE, got %q", got)
+	}
+	if got := symbols.String(); got != "-.-. --.-/-.. ." {
+		t.Errorf("Expected symbol stream -.-. --.-/-.. ., got %q", got)
+	}
+}
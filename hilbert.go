@@ -0,0 +1,61 @@
+package cw
+
+import "math"
+
+// SDRFrontend 包络检测前端 (Config.SDR.Frontend)
+type SDRFrontend int
+
+const (
+	FrontendIQ      SDRFrontend = iota // I/Q 混频 (默认)：需要已知音调频率，带宽窄、抗噪好
+	FrontendHilbert                    // 希尔伯特变换：取解析信号的幅度，不需要音调频率，但整个音频带的噪声都会进来
+)
+
+// HilbertEnvelope 基于希尔伯特变换的包络检测器
+// 原信号 (延迟对齐) 与其 90° 移相版本构成解析信号，幅度即包络，与音调频率无关。
+// 适合宽带或尚未锁定音调时的第一级检测；之后再用低通滤波器平滑掉 FIR 近似带来的纹波。
+type HilbertEnvelope struct {
+	taps  []float64 // FIR 希尔伯特变换器系数 (奇数个，与中心相距偶数的为 0)
+	delay []float64 // 环形延迟线
+	pos   int
+	lpf   *ButterworthFilter
+}
+
+// hilbertMinFreq 希尔伯特变换器能准确移相的最低频率 (Hz)，决定 FIR 的长度
+const hilbertMinFreq = 100.0
+
+// NewHilbertEnvelope 创建包络检测器
+// smoothBW: 包络低通滤波器的截止频率 (Hz)，与 SDR.FilterBW 含义相同
+func NewHilbertEnvelope(sampleRate, smoothBW float64) *HilbertEnvelope {
+	// 长度约为最低频率的一个周期，取奇数
+	n := int(sampleRate/hilbertMinFreq) | 1
+	n = max(n, 31)
+	m := n / 2
+	hamming := makeWindow(WindowHamming, n)
+	taps := make([]float64, n)
+	for i := range taps {
+		k := i - m
+		if k%2 != 0 {
+			taps[i] = 2 / (math.Pi * float64(k)) * hamming[i]
+		}
+	}
+	return &HilbertEnvelope{
+		taps:  taps,
+		delay: make([]float64, n),
+		lpf:   NewButterworthLowpass(4, sampleRate, smoothBW),
+	}
+}
+
+// Process 处理单个采样点并返回包络 (输入为幅度 A 的正弦时约为 A)，延迟为 FIR 长度的一半
+func (h *HilbertEnvelope) Process(sample float64) float64 {
+	n := len(h.taps)
+	h.delay[h.pos] = sample
+	// delay[pos] 是最新的采样，taps[i] 对应 i 个采样之前
+	m := n / 2
+	var im float64
+	for i := (m + 1) % 2; i < n; i += 2 {
+		im += h.taps[i] * h.delay[(h.pos-i+n)%n]
+	}
+	re := h.delay[(h.pos-m+n)%n]
+	h.pos = (h.pos + 1) % n
+	return h.lpf.Process(math.Sqrt(re*re + im*im))
+}
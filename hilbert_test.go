@@ -0,0 +1,46 @@
+package cw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHilbertEnvelope_Amplitude(t *testing.T) {
+	for _, sampleRate := range []float64{8000, 48000} {
+		for _, freq := range []float64{300, 700, 1500} {
+			h := NewHilbertEnvelope(sampleRate, 50)
+			lo, hi := math.Inf(1), 0.0
+			for i := 0; i < int(sampleRate); i++ {
+				env := h.Process(0.5 * math.Sin(2*math.Pi*freq*float64(i)/sampleRate))
+				if i > int(sampleRate)/2 {
+					lo, hi = math.Min(lo, env), math.Max(hi, env)
+				}
+			}
+			if lo < 0.48 || hi > 0.52 {
+				t.Errorf("%.0f Hz @ %.0f: envelope %.3f - %.3f, want ~0.5", freq, sampleRate, lo, hi)
+			}
+		}
+	}
+}
+
+func TestExperimentalDecoder_HilbertFrontend(t *testing.T) {
+	const sampleRate = 8000
+	// 实际音调 1100 Hz，解码器以为是 700 Hz (尚未锁定)
+	audio := GenerateCW("PARIS PARIS", AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 1100})
+	audio = noisyRecording(audio, sampleRate, 1, ChannelEffects{SNRdB: 20, Seed: 1})
+
+	decode := func(frontend SDRFrontend) string {
+		cfg := DefaultConfig()
+		cfg.SDR.Frontend = frontend
+		cfg.Decoder.InitialWPM = 20
+		dec := newExperimentalDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+		defer dec.Stop()
+		return decodeSamples(dec, audio)
+	}
+	if got := decode(FrontendIQ); got == "PARIS PARIS" {
+		t.Errorf("I/Q front-end should miss a tone 400 Hz off target, got %q", got)
+	}
+	if got := decode(FrontendHilbert); got != "PARIS PARIS" {
+		t.Errorf("Hilbert front-end: got %q, want PARIS PARIS", got)
+	}
+}
@@ -0,0 +1,215 @@
+// Package httpserver 为无人值守的解码器 (例如树莓派) 提供 HTTP 接口：
+// 当前解码文本、实时速度和信噪比、频谱快照 (JSON)，以及解码字符的 SSE 推送。
+// 放在独立的子包中，核心库 cw 不依赖任何网络代码。
+package httpserver
+
+import (
+	"cw"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Source 状态数据的来源，*cw.CWSystem 实现了这个接口
+type Source interface {
+	CurrentWPM() float64
+	CurrentSNR() float64
+	SpectrumMonitor() *cw.SpectrumMonitor
+}
+
+// 接口参数
+const (
+	maxTextRunes = 4096 // /api/text 最多返回的字符数 (只保留最近的部分)
+	eventQueue   = 64   // 每个 SSE 客户端最多排队的事件数，满了说明客户端太慢，直接断开
+)
+
+// Server 提供以下接口:
+//
+//	GET /api/text      {"text": "..."}                      当前解码文本 (最近 4096 个字符)
+//	GET /api/status    {"wpm": 20.1, "snr_db": 18.5}         实时速度和信噪比，未知时为 null
+//	GET /api/spectrum  {"bin_hz": 11.7, "power": [...]}      最近一次频谱分析的平均功率谱
+//	GET /api/events    SSE: "char" 事件为新增的字符，"text" 事件为被修正后的完整文本
+//
+// 解码文本由调用方通过 Observe 送入 (接到 CWSystem.OnTextDecoded 上)。
+type Server struct {
+	src         Source
+	incremental bool // 与 cw.TranscriptLog 相同：true 表示解码器每次输出新增片段
+	mux         *http.ServeMux
+
+	mu   sync.Mutex
+	text string                  // 当前文本 (完整文本模式下为解码器最近一次的输出)
+	subs map[chan event]struct{} // SSE 客户端
+}
+
+// event 一个 SSE 事件
+type event struct {
+	name string // "char" 或 "text"
+	data string
+}
+
+// New 创建服务，incremental 见 cw.CWSystem.IncrementalOutput
+func New(src Source, incremental bool) *Server {
+	s := &Server{
+		src:         src,
+		incremental: incremental,
+		mux:         http.NewServeMux(),
+		subs:        make(map[chan event]struct{}),
+	}
+	s.mux.HandleFunc("GET /api/text", s.handleText)
+	s.mux.HandleFunc("GET /api/status", s.handleStatus)
+	s.mux.HandleFunc("GET /api/spectrum", s.handleSpectrum)
+	s.mux.HandleFunc("GET /api/events", s.handleEvents)
+	return s
+}
+
+// ServeHTTP 实现 http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Observe 处理一次解码输出并推送给 SSE 客户端，可在任意线程调用
+// 完整文本模式下，新文本是旧文本的延续时只推送新增的字符，末尾被修正时推送完整文本。
+func (s *Server) Observe(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ev event
+	switch {
+	case s.incremental:
+		s.text = trimRunes(s.text+text, maxTextRunes)
+		ev = event{"char", text}
+	case strings.HasPrefix(text, s.text):
+		ev = event{"char", text[len(s.text):]}
+		s.text = text
+	default:
+		s.text = text
+		ev = event{"text", trimRunes(text, maxTextRunes)}
+	}
+	if ev.data == "" && ev.name == "char" {
+		return
+	}
+	for ch := range s.subs {
+		select {
+		case ch <- ev:
+		default:
+			// 客户端太慢，断开 (handleEvents 发现通道关闭后返回)
+			delete(s.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Text 当前解码文本 (最近 4096 个字符)
+func (s *Server) Text() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return trimRunes(s.text, maxTextRunes)
+}
+
+func (s *Server) handleText(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, struct {
+		Text string `json:"text"`
+	}{s.Text()})
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, struct {
+		WPM *float64 `json:"wpm"`
+		SNR *float64 `json:"snr_db"`
+	}{known(s.src.CurrentWPM()), known(s.src.CurrentSNR())})
+}
+
+func (s *Server) handleSpectrum(w http.ResponseWriter, r *http.Request) {
+	sm := s.src.SpectrumMonitor()
+	var power []float64
+	if sm != nil {
+		power = sm.Snapshot()
+	}
+	if power == nil {
+		http.Error(w, "spectrum not available yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, struct {
+		BinHz float64   `json:"bin_hz"`
+		Power []float64 `json:"power"`
+	}{sm.BinToFreq(1), power})
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ch := make(chan event, eventQueue)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	// 先发一次完整文本，新连接的页面不必再请求 /api/text
+	ch <- event{"text", trimRunes(s.text, maxTextRunes)}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if _, ok := s.subs[ch]; ok {
+			delete(s.subs, ch)
+			close(ch)
+		}
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent 按 SSE 格式写出一个事件，数据中的换行拆成多个 data 行
+func writeEvent(w http.ResponseWriter, ev event) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "event: %s\n", ev.name)
+	for _, line := range strings.Split(ev.data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// known 0 和非有限值 (JSON 无法表示) 视为未知，编码为 null
+func known(v float64) *float64 {
+	if v == 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// trimRunes 只保留 s 的最后 n 个字符
+func trimRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[len(r)-n:])
+}
@@ -0,0 +1,160 @@
+package httpserver
+
+import (
+	"bufio"
+	"cw"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeSource struct {
+	wpm, snr float64
+	sm       *cw.SpectrumMonitor
+}
+
+func (f *fakeSource) CurrentWPM() float64                  { return f.wpm }
+func (f *fakeSource) CurrentSNR() float64                  { return f.snr }
+func (f *fakeSource) SpectrumMonitor() *cw.SpectrumMonitor { return f.sm }
+
+func getJSON(t *testing.T, h http.Handler, path string, v any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: invalid JSON %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestServer_TextAndStatus(t *testing.T) {
+	src := &fakeSource{wpm: 22.5, snr: math.NaN()}
+	s := New(src, false)
+
+	s.Observe("CQ CQ")
+	s.Observe("CQ CQ DE")
+	var text struct{ Text string }
+	if code := getJSON(t, s, "/api/text", &text); code != http.StatusOK || text.Text != "CQ CQ DE" {
+		t.Errorf("Expected text %q, got %d %q", "CQ CQ DE", code, text.Text)
+	}
+
+	var status map[string]*float64
+	getJSON(t, s, "/api/status", &status)
+	if status["wpm"] == nil || *status["wpm"] != 22.5 {
+		t.Errorf("Expected wpm 22.5, got %v", status["wpm"])
+	}
+	if status["snr_db"] != nil {
+		t.Errorf("Unknown SNR should be null, got %v", *status["snr_db"])
+	}
+
+	// 增量模式下累加，超出上限时只保留最近的部分
+	s = New(src, true)
+	s.Observe(strings.Repeat("E", maxTextRunes))
+	s.Observe("K")
+	if got := s.Text(); len(got) != maxTextRunes || !strings.HasSuffix(got, "EK") {
+		t.Errorf("Expected the last %d chars ending in EK, got %d chars", maxTextRunes, len(got))
+	}
+}
+
+func TestServer_Spectrum(t *testing.T) {
+	src := &fakeSource{}
+	s := New(src, false)
+	if code := getJSON(t, s, "/api/spectrum", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a monitor, got %d", code)
+	}
+
+	cfg := cw.DefaultConfig()
+	cfg.Monitor.UpdateInterval = 10 * time.Millisecond
+	src.sm = cw.NewSpectrumMonitor(8000, cfg, nil)
+	if code := getJSON(t, s, "/api/spectrum", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 before the first analysis, got %d", code)
+	}
+
+	src.sm.Start()
+	defer src.sm.Stop()
+	tone := make([]float32, 8000)
+	for i := range tone {
+		tone[i] = float32(0.3 * math.Sin(2*math.Pi*750*float64(i)/8000))
+	}
+	src.sm.PushAudioData(tone)
+
+	var spec struct {
+		BinHz float64 `json:"bin_hz"`
+		Power []float64
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for getJSON(t, s, "/api/spectrum", &spec) != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatal("Spectrum never became available")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(spec.Power) != cfg.Monitor.FFTSize/2+1 || spec.BinHz != 8000/float64(cfg.Monitor.FFTSize) {
+		t.Fatalf("Unexpected spectrum: %d bins, %.2fHz/bin", len(spec.Power), spec.BinHz)
+	}
+	peak := 0
+	for i, p := range spec.Power {
+		if p > spec.Power[peak] {
+			peak = i
+		}
+	}
+	if f := float64(peak) * spec.BinHz; math.Abs(f-750) > 2*spec.BinHz {
+		t.Errorf("Expected the strongest bin near 750Hz, got %.1fHz", f)
+	}
+}
+
+func TestServer_Events(t *testing.T) {
+	s := New(&fakeSource{}, false)
+	s.Observe("CQ")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() (name, data string) {
+		t.Helper()
+		var lines []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("read event: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				return name, strings.Join(lines, "\n")
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				lines = append(lines, strings.TrimPrefix(line, "data: "))
+			}
+		}
+	}
+
+	// 连接后先收到当前的完整文本
+	if name, data := readEvent(); name != "text" || data != "CQ" {
+		t.Fatalf("Expected initial text event CQ, got %s %q", name, data)
+	}
+	s.Observe("CQ DE")
+	if name, data := readEvent(); name != "char" || data != " DE" {
+		t.Errorf("Expected char event %q, got %s %q", " DE", name, data)
+	}
+	// 末尾被修正：推送完整文本，换行拆成多个 data 行
+	s.Observe("CQ DF\nK")
+	if name, data := readEvent(); name != "text" || data != "CQ DF\nK" {
+		t.Errorf("Expected text event, got %s %q", name, data)
+	}
+}
@@ -0,0 +1,154 @@
+package cw
+
+import (
+	"math"
+	"time"
+)
+
+// IambicMode 自动键 (iambic keyer) 的工作模式
+type IambicMode int
+
+const (
+	IambicModeA IambicMode = iota // 松开键杆后发完当前元素即停
+	IambicModeB                   // 当前元素期间按过另一侧键杆时，即使已经松开，也再补发一个相反的元素 (Curtis B)
+)
+
+// Paddle 一段保持不变的双键杆状态
+type Paddle struct {
+	Dit, Dah bool          // 点键杆、划键杆是否按下
+	Duration time.Duration // 保持时长
+}
+
+// keyedElement 自动键发出的一个元素 (秒)
+type keyedElement struct {
+	start, duration float64
+	dah             bool
+}
+
+// GenerateIambic 模拟自动键：按键杆的操作序列 paddles 生成 CW 音频，而不是从文本生成。
+// 自动键的元素长度和元素间隔都是精确的整数点长 (cfg.JitterPct 不起作用)，字符和单词间隔则取决于
+// 操作者何时再次按下键杆。同时按下两侧 (squeeze) 时点划交替发出，Mode A 和 Mode B 的区别见 IambicMode。
+// 序列结束后视为松开，音频末尾至少留一个字符间隔的静音。
+func GenerateIambic(paddles []Paddle, mode IambicMode, cfg AudioConfig) []float32 {
+	if cfg.WPM <= 0 || cfg.SampleRate <= 0 {
+		return nil
+	}
+	dotLen := 1.2 / cfg.WPM
+	dahLen := dotLen * 3
+	if cfg.DahRatio > 0 {
+		dahLen = dotLen * cfg.DahRatio
+	}
+	sampleRate := float64(cfg.SampleRate)
+
+	// 按绝对时间换算采样位置，长序列不会累积取整误差
+	var buffer []float32
+	at := func(sec float64) int { return int(math.Round(sec * sampleRate)) }
+	end := 0.0
+	for _, e := range iambicKeying(paddles, mode, dotLen, dahLen) {
+		buffer = append(buffer, make([]float32, at(e.start)-len(buffer))...)
+		buffer = appendToneSamples(buffer, at(e.start+e.duration)-at(e.start), cfg)
+		end = e.start + e.duration + 3*dotLen
+	}
+	end = max(end, paddleTimeline(paddles).end())
+	return append(buffer, make([]float32, max(at(end)-len(buffer), 0))...)
+}
+
+// iambicKeying 按键杆序列计算自动键发出的元素
+// 每个元素 (连同其后一个点长的间隔) 结束时检查键杆：两侧都按下时发与上一个相反的元素，只按一侧时发该侧的元素；
+// Mode B 下元素期间按过另一侧键杆也发相反的元素。都没有按下时空闲，等到下一次按下键杆时立即开始
+// (此时两侧同时按下的从点开始)。
+func iambicKeying(paddles []Paddle, mode IambicMode, dotLen, dahLen float64) []keyedElement {
+	timeline := paddleTimeline(paddles)
+	var elems []keyedElement
+	t := 0.0
+	keying := false  // 是否紧接在上一个元素之后 (不是空闲)
+	lastDah := false // 上一个元素是否为划
+	memory := false  // Mode B: 上一个元素期间按过另一侧键杆
+	for {
+		dit, dah := timeline.at(t)
+		var sendDah bool
+		switch {
+		case keying && (dit && dah || memory):
+			sendDah = !lastDah
+		case dit || dah:
+			sendDah = !dit
+		default:
+			// 空闲，等待下一次按下键杆
+			next, ok := timeline.nextPress(t)
+			if !ok {
+				return elems
+			}
+			t, keying = next, false
+			continue
+		}
+
+		e := keyedElement{start: t, duration: dotLen, dah: sendDah}
+		if sendDah {
+			e.duration = dahLen
+		}
+		elems = append(elems, e)
+		keying, lastDah = true, sendDah
+		t = e.start + e.duration + dotLen
+		memory = mode == IambicModeB && timeline.pressedDuring(e.start, t, !sendDah)
+	}
+}
+
+// paddleSpan 键杆状态保持不变的一段时间 [start, end) (秒)
+type paddleSpan struct {
+	start, end float64
+	dit, dah   bool
+}
+
+type paddleSpans []paddleSpan
+
+func paddleTimeline(paddles []Paddle) paddleSpans {
+	spans := make(paddleSpans, 0, len(paddles))
+	t := 0.0
+	for _, p := range paddles {
+		d := p.Duration.Seconds()
+		if d <= 0 {
+			continue
+		}
+		spans = append(spans, paddleSpan{t, t + d, p.Dit, p.Dah})
+		t += d
+	}
+	return spans
+}
+
+// end 序列的总时长 (秒)
+func (s paddleSpans) end() float64 {
+	if len(s) == 0 {
+		return 0
+	}
+	return s[len(s)-1].end
+}
+
+// at 时刻 t 的键杆状态，序列结束后视为松开
+func (s paddleSpans) at(t float64) (dit, dah bool) {
+	for _, sp := range s {
+		if t >= sp.start && t < sp.end {
+			return sp.dit, sp.dah
+		}
+	}
+	return false, false
+}
+
+// nextPress t 之后 (含 t) 第一次有键杆按下的时刻
+func (s paddleSpans) nextPress(t float64) (float64, bool) {
+	for _, sp := range s {
+		if sp.end > t && (sp.dit || sp.dah) {
+			return max(sp.start, t), true
+		}
+	}
+	return 0, false
+}
+
+// pressedDuring [t0, t1) 内是否按下过划键杆 (dah = true) 或点键杆
+func (s paddleSpans) pressedDuring(t0, t1 float64, dah bool) bool {
+	for _, sp := range s {
+		if sp.start < t1 && sp.end > t0 && (dah && sp.dah || !dah && sp.dit) {
+			return true
+		}
+	}
+	return false
+}
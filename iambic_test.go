@@ -0,0 +1,118 @@
+package cw
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keyedPattern 把自动键发出的元素写成点划 ("-.-.")
+func keyedPattern(elems []keyedElement) string {
+	var sb strings.Builder
+	for _, e := range elems {
+		if e.dah {
+			sb.WriteByte('-')
+		} else {
+			sb.WriteByte('.')
+		}
+	}
+	return sb.String()
+}
+
+// paddlesFor 模拟单侧操作的发报者：每个元素只短按对应的键杆，字符和单词间隔按标准时长等待
+func paddlesFor(text string, wpm float64) []Paddle {
+	dot := time.Duration(1.2 / wpm * float64(time.Second))
+	var paddles []Paddle
+	release := func(d time.Duration) {
+		if n := len(paddles); n > 0 && !paddles[n-1].Dit && !paddles[n-1].Dah {
+			paddles[n-1].Duration += d
+			return
+		}
+		paddles = append(paddles, Paddle{Duration: d})
+	}
+	for _, r := range strings.ToUpper(text) {
+		if r == ' ' {
+			release(4 * dot)
+			continue
+		}
+		for _, sym := range morseEncodeTable[r] {
+			// 元素开始后很快松开，自动键会把元素和其后的间隔发完
+			length := dot
+			if sym == '-' {
+				length = 3 * dot
+			}
+			paddles = append(paddles, Paddle{Dit: sym == '.', Dah: sym == '-', Duration: dot / 2})
+			release(length + dot - dot/2)
+		}
+		release(2 * dot)
+	}
+	return paddles
+}
+
+func TestIambicKeying_Modes(t *testing.T) {
+	const dot = 0.06 // 20 WPM
+	ms := time.Millisecond
+
+	// 按住点键杆 250ms：0、120、240ms 各发一个点，360ms 时已松开
+	hold := []Paddle{{Dit: true, Duration: 250 * ms}}
+	if got := keyedPattern(iambicKeying(hold, IambicModeA, dot, 3*dot)); got != "..." {
+		t.Errorf("Holding the dit paddle: expected ..., got %q", got)
+	}
+
+	// 先按划再同时按下 (squeeze) 发 "C"，在第 4 个元素 (点，600-660ms) 期间松开
+	squeeze := []Paddle{
+		{Dah: true, Duration: 20 * ms},
+		{Dit: true, Dah: true, Duration: 600 * ms},
+		{Duration: 500 * ms},
+	}
+	if got := keyedPattern(iambicKeying(squeeze, IambicModeA, dot, 3*dot)); got != "-.-." {
+		t.Errorf("Mode A squeeze: expected -.-., got %q", got)
+	}
+	// Mode B 在点期间还按着划键杆，松开后补发一个划
+	if got := keyedPattern(iambicKeying(squeeze, IambicModeB, dot, 3*dot)); got != "-.-.-" {
+		t.Errorf("Mode B squeeze: expected -.-.-, got %q", got)
+	}
+
+	// 元素的起点都落在整数点长上
+	for _, e := range iambicKeying(squeeze, IambicModeB, dot, 3*dot) {
+		if units := e.start / dot; math.Abs(units-math.Round(units)) > 1e-9 {
+			t.Errorf("Element at %.4fs is off the dot grid", e.start)
+		}
+	}
+}
+
+func TestGenerateIambic_Timing(t *testing.T) {
+	cfg := AudioConfig{WPM: 20, SampleRate: 48000, Frequency: 750}
+	samples := GenerateIambic([]Paddle{{Dit: true, Dah: true, Duration: 150 * time.Millisecond}}, IambicModeA, cfg)
+
+	// 同时按下时从点开始："A" = 点 + 间隔 + 划 + 字符间隔 (与 GenerateCW 相同)
+	dot := 2880
+	runs, on := toneRuns(samples)
+	if len(runs) != 4 || !on[0] || on[1] || !on[2] || on[3] {
+		t.Fatalf("Unexpected run pattern %v %v", runs, on)
+	}
+	for i, want := range []int{dot, dot, 3 * dot, 3 * dot} {
+		if math.Abs(float64(runs[i]-want)) > 4 {
+			t.Errorf("Run %d: expected ~%d samples, got %d", i, want, runs[i])
+		}
+	}
+	if got := GenerateIambic(nil, IambicModeA, cfg); len(got) != 0 {
+		t.Errorf("Expected no audio without paddle presses, got %d samples", len(got))
+	}
+}
+
+func TestGenerateIambic_Decodes(t *testing.T) {
+	const sampleRate = 8000
+	const message = "CQ DE BG1ABC K"
+	audio := GenerateIambic(paddlesFor(message, 20), IambicModeB, AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700})
+	audio = noisyRecording(audio, sampleRate, 1, ChannelEffects{SNRdB: 20, Seed: 1})
+
+	cfg := DefaultConfig()
+	cfg.Decoder.InitialWPM = 20
+	dec := newExperimentalDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+	defer dec.Stop()
+	if got := decodeSamples(dec, audio); got != message {
+		t.Errorf("Expected %q, got %q", message, got)
+	}
+}
@@ -0,0 +1,63 @@
+package cw
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Koch 训练参数
+const (
+	KochMinLesson = 1
+	KochMaxLesson = 40
+	kochGroupSize = 5 // 每组字符数
+)
+
+// kochOrder 标准 Koch 字符引入顺序 (G4FON)，第 n 课使用前 n+1 个字符
+// 第 1 课为 K、M，第 40 课最后引入 = (BT)。
+var kochOrder = []rune("KMRSUAPTLOWI.NJEF0Y,VG5/Q9ZH38B?427C1D6X=")
+
+// KochGenerator 生成 Koch 法练习用的随机字符组
+type KochGenerator struct {
+	lesson int
+	chars  []rune
+	rng    *rand.Rand
+}
+
+// NewKochGenerator 创建指定课程的练习生成器
+func NewKochGenerator(lesson int) *KochGenerator {
+	g := &KochGenerator{rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	g.SetLesson(lesson)
+	return g
+}
+
+// SetLesson 切换课程 (超出 1-40 的值会被钳位)
+func (g *KochGenerator) SetLesson(n int) {
+	if n < KochMinLesson {
+		n = KochMinLesson
+	}
+	if n > KochMaxLesson {
+		n = KochMaxLesson
+	}
+	g.lesson = n
+	g.chars = kochOrder[:n+1]
+}
+
+// Lesson 返回当前课程
+func (g *KochGenerator) Lesson() int {
+	return g.lesson
+}
+
+// Characters 返回当前课程可用的字符
+func (g *KochGenerator) Characters() string {
+	return string(g.chars)
+}
+
+// NextGroup 返回一组 5 个随机字符
+func (g *KochGenerator) NextGroup() string {
+	var sb strings.Builder
+	for i := 0; i < kochGroupSize; i++ {
+		sb.WriteRune(g.chars[g.rng.Intn(len(g.chars))])
+	}
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package cw
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKochOrder_Encodable(t *testing.T) {
+	if len(kochOrder) != KochMaxLesson+1 {
+		t.Fatalf("Expected %d Koch characters, got %d", KochMaxLesson+1, len(kochOrder))
+	}
+	for _, r := range kochOrder {
+		if _, ok := morseEncodeTable[r]; !ok {
+			t.Errorf("Koch character %q has no Morse code", r)
+		}
+	}
+}
+
+func TestKochGenerator_Lessons(t *testing.T) {
+	g := NewKochGenerator(1)
+	if g.Characters() != "KM" {
+		t.Errorf("Lesson 1 should use K and M, got %q", g.Characters())
+	}
+
+	for _, lesson := range []int{1, 5, 40} {
+		g.SetLesson(lesson)
+		allowed := g.Characters()
+		for i := 0; i < 50; i++ {
+			group := g.NextGroup()
+			if len([]rune(group)) != 5 {
+				t.Fatalf("Lesson %d: expected 5 characters, got %q", lesson, group)
+			}
+			for _, r := range group {
+				if !strings.ContainsRune(allowed, r) {
+					t.Errorf("Lesson %d: unexpected character %q in %q", lesson, r, group)
+				}
+			}
+		}
+	}
+}
+
+func TestKochGenerator_Clamp(t *testing.T) {
+	g := NewKochGenerator(0)
+	if g.Lesson() != KochMinLesson {
+		t.Errorf("Expected lesson clamped to %d, got %d", KochMinLesson, g.Lesson())
+	}
+	g.SetLesson(99)
+	if g.Lesson() != KochMaxLesson || g.Characters() != string(kochOrder) {
+		t.Errorf("Expected lesson clamped to %d with all characters, got %d %q", KochMaxLesson, g.Lesson(), g.Characters())
+	}
+}
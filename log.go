@@ -0,0 +1,21 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"cw/Filters"
+	"log/slog"
+)
+
+// logger 库的诊断输出 (状态、警告、调试信息)，默认丢弃，库本身不往终端写任何东西
+var logger = slog.New(slog.DiscardHandler)
+
+// SetLogger 设置诊断日志的输出，同时作用于 BeamDecoder 和 Filters 子包；nil 表示关闭。
+// 应在 Start 之前调用。解码文本不经过日志，仍然只通过 OnTextDecoded 等回调输出。
+func SetLogger(l *slog.Logger) {
+	if l == nil {
+		l = slog.New(slog.DiscardHandler)
+	}
+	logger = l
+	BeamDecoder.SetLogger(l)
+	Filters.SetLogger(l)
+}
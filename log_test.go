@@ -0,0 +1,43 @@
+package cw
+
+import (
+	"bytes"
+	"cw/BeamDecoder"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer SetLogger(nil)
+
+	// 本包的诊断输出
+	s := NewCWSystem()
+	s.HandleInput("TEST")
+	if !strings.Contains(buf.String(), "level=WARN") || !strings.Contains(buf.String(), "radio not connected") {
+		t.Errorf("expected a warning from HandleInput, got %q", buf.String())
+	}
+
+	// SetLogger 同时作用于子包
+	buf.Reset()
+	// 200 WPM (点长 6ms) 低于解码器允许的下限，估速时会报错并回到 20 WPM
+	dec, err := BeamDecoder.NewCWDecoder(BeamDecoder.DecoderConfig{InitialWPM: 200}, newTestLanguageModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec.FeedNew(6, BeamDecoder.StateOn)
+	dec.FeedNew(100, BeamDecoder.StateOff)
+	if !strings.Contains(buf.String(), "unit time below 10 ms") {
+		t.Errorf("expected BeamDecoder warning in the shared logger, got %q", buf.String())
+	}
+
+	// 关闭后不再输出
+	SetLogger(nil)
+	buf.Reset()
+	s.HandleInput("TEST")
+	if buf.Len() != 0 {
+		t.Errorf("expected no output after SetLogger(nil), got %q", buf.String())
+	}
+}
@@ -0,0 +1,49 @@
+package cw
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Muter 可选接口：在一段时间内丢弃输入音频 (例如本机发射期间的侧音 / 射频泄漏)
+type Muter interface {
+	Mute(d time.Duration)
+}
+
+// muteGate 嵌入到解码器中实现 Muter
+// 静音期间的音频被替换成静音而不是直接丢掉，解码器内部的时间基准保持连续 (静音期表现为一段长空窗)。
+type muteGate struct {
+	sampleRate float64
+	remaining  atomic.Int64 // 还需要静音的采样点数 (Mute 在任意线程写，音频线程读)
+}
+
+func newMuteGate(sampleRate float64) muteGate {
+	return muteGate{sampleRate: sampleRate}
+}
+
+// Mute 从现在起静音 d 时长的输入音频；d <= 0 取消静音。可在任意线程调用。
+func (m *muteGate) Mute(d time.Duration) {
+	n := int64(d.Seconds() * m.sampleRate)
+	if n < 0 {
+		n = 0
+	}
+	m.remaining.Store(n)
+}
+
+// apply 返回静音处理后的音频：仍在静音期内的部分置零 (不修改调用方的切片)
+func (m *muteGate) apply(samples []float32) []float32 {
+	remaining := m.remaining.Load()
+	if remaining <= 0 {
+		return samples
+	}
+	n := int64(len(samples))
+	if n > remaining {
+		n = remaining
+	}
+	// 只有在 Mute 期间没有被重新设置时才扣减
+	m.remaining.CompareAndSwap(remaining, remaining-n)
+
+	out := make([]float32, len(samples))
+	copy(out[n:], samples[n:])
+	return out
+}
@@ -0,0 +1,57 @@
+package cw
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMuteGate(t *testing.T) {
+	m := newMuteGate(1000)
+	in := []float32{1, 1, 1, 1}
+	if out := m.apply(in); &out[0] != &in[0] {
+		t.Error("Expected samples to pass through untouched when not muted")
+	}
+
+	m.Mute(6 * time.Millisecond) // 6 个采样点
+	out := m.apply(in)
+	if out[0] != 0 || out[3] != 0 || in[0] != 1 {
+		t.Errorf("Expected first chunk silenced without touching input, got %v (input %v)", out, in)
+	}
+	out = m.apply(in)
+	if out[1] != 0 || out[2] != 1 {
+		t.Errorf("Expected mute to end after 6 samples, got %v", out)
+	}
+
+	m.Mute(time.Second)
+	m.Mute(0)
+	if out := m.apply(in); out[0] != 1 {
+		t.Error("Mute(0) should cancel muting")
+	}
+}
+
+func TestGoertzelDecoder_Mute(t *testing.T) {
+	const sampleRate = 8000
+	cfg := AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700}
+	dec := newGoertzelDecoder(sampleRate, 700, nil, newTestLanguageModel())
+	dec.SetThreshold(0.3)
+
+	// 模拟发射期间听到自己的 "TEST"，之后收到对方的 PARIS
+	tx := GenerateCW("TEST", cfg)
+	// decodeWithGoertzel 会在开头补 0.5 秒静音
+	dec.Mute(500*time.Millisecond + CWDuration("TEST", cfg.WPM) + txMuteTail)
+	audio := append(tx, make([]float32, sampleRate/2)...)
+	audio = append(audio, GenerateCW("PARIS", cfg)...)
+	if got := decodeWithGoertzel(dec, audio, sampleRate); got != "PARIS" {
+		t.Errorf("Expected only PARIS after muting the transmission, got %q", got)
+	}
+}
+
+func TestCWDuration(t *testing.T) {
+	// PARIS = 50 个点长 (含 7 个点长的单词间隔)
+	if got, want := CWDuration("PARIS", 20), 43*60*time.Millisecond; got != want {
+		t.Errorf("CWDuration(PARIS) = %v, want %v", got, want)
+	}
+	if got := CWDuration("PARIS PARIS", 20); got != (50+43)*60*time.Millisecond {
+		t.Errorf("CWDuration(PARIS PARIS) = %v", got)
+	}
+}
@@ -0,0 +1,244 @@
+package cw
+
+import (
+	"strings"
+	"unicode"
+)
+
+// CollapseSpaces 输出级过滤：把连续的空格合并为一个，并去掉开头的空格
+// 只处理最终文本，不影响 Beam 搜索内部的路径
+func CollapseSpaces(text string) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	lastWasSpace := true // 视开头为空格，从而去掉前导空格
+	for _, r := range text {
+		if r == ' ' {
+			if lastWasSpace {
+				continue
+			}
+			lastWasSpace = true
+		} else {
+			lastWasSpace = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// OutputCase 输出文本的大小写 (Config.Decoder.OutputCase)
+type OutputCase int
+
+const (
+	CaseUpper OutputCase = iota // 大写 (默认，与码表一致)
+	CaseLower                   // 小写，勤务符号 (例如 "<AR>") 保持大写
+)
+
+// OutputFormatter 输出级格式化：按配置转换大小写，并把单词边界 (空格) 换成指定的分隔符。
+// CWSystem 在把解码文本交给 OnTextDecoded 之前统一调用，所有解码器共用；逐字符处理、没有状态，
+// 对增量片段和完整文本的结果一致。连续空格是否合并由解码器的 CollapseSpaces 决定：
+// 关闭时长停顿产生的每个空格各输出一个分隔符，与字符间隔 (不输出任何内容) 仍然可以区分。
+type OutputFormatter struct {
+	Case          OutputCase
+	WordSeparator string // 单词边界的分隔符，空 = " "
+}
+
+// NewOutputFormatter 按 cfg.Decoder 的 OutputCase 和 WordSeparator 创建格式化器
+func NewOutputFormatter(cfg *Config) *OutputFormatter {
+	return &OutputFormatter{Case: cfg.Decoder.OutputCase, WordSeparator: cfg.Decoder.WordSeparator}
+}
+
+// Format 返回格式化后的文本
+func (f *OutputFormatter) Format(text string) string {
+	sep := f.WordSeparator
+	if sep == "" {
+		sep = " "
+	}
+	if f.Case == CaseUpper && sep == " " {
+		return text
+	}
+	var sb strings.Builder
+	sb.Grow(len(text))
+	inProsign := false
+	for _, r := range text {
+		switch {
+		case r == ' ':
+			sb.WriteString(sep)
+			continue
+		case r == '<':
+			inProsign = true
+		case r == '>':
+			inProsign = false
+		case f.Case == CaseLower && !inProsign:
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// abbreviations 常见的 CW 缩写、Q 简语和勤务符号的含义 (供 ExpandAbbreviations 注释)
+var abbreviations = map[string]string{
+	// 问候与礼貌
+	"GM": "good morning", "GA": "good afternoon", "GE": "good evening", "GN": "good night",
+	"TNX": "thanks", "TKS": "thanks", "TU": "thank you", "PSE": "please", "SRI": "sorry",
+	"73": "best regards", "88": "love and kisses", "GL": "good luck", "CUL": "see you later",
+	"CUAGN": "see you again", "HPE": "hope", "DR": "dear", "FB": "excellent",
+	// 称呼
+	"OM": "fellow ham", "YL": "young lady", "XYL": "wife", "OP": "operator",
+	// 通联流程
+	"CQ": "calling any station", "DE": "from", "K": "over", "R": "received", "AGN": "again",
+	"BK": "break", "BTU": "back to you", "CPY": "copy", "HR": "here", "UR": "your", "ES": "and",
+	"FER": "for", "WID": "with", "ABT": "about", "HW": "how", "HW?": "how do you copy?",
+	"RST": "signal report", "5NN": "599 signal report", "WX": "weather", "RIG": "radio",
+	"ANT": "antenna", "PWR": "power", "NR": "number", "TEST": "contest",
+	// Q 简语
+	"QTH": "location", "QRZ": "who is calling", "QSB": "fading", "QRM": "interference",
+	"QRN": "static noise", "QSL": "confirm / received", "QSY": "change frequency",
+	"QRS": "send slower", "QRQ": "send faster", "QRP": "low power", "QRO": "high power",
+	"QRT": "stop sending", "QRL": "frequency in use", "QRV": "ready", "QRX": "wait",
+	"QSO": "contact", "QRU": "nothing for you", "QSK": "full break-in",
+	// 勤务符号
+	"<AR>": "end of message", "<SK>": "end of contact", "<KN>": "over, named station only",
+	"<BT>": "break / new paragraph", "<AS>": "wait", "<BK>": "break",
+}
+
+// AbbreviationMeaning 返回单词 (例如 "TNX"、"QTH?"，不区分大小写) 的含义，不是已知缩写时返回 false
+// 结尾的 "?" 视为询问，按去掉问号后的缩写查找
+func AbbreviationMeaning(word string) (string, bool) {
+	word = strings.ToUpper(word)
+	if m, ok := abbreviations[word]; ok {
+		return m, true
+	}
+	if base, found := strings.CutSuffix(word, "?"); found && base != "" {
+		if m, ok := abbreviations[base]; ok {
+			return m + "?", true
+		}
+	}
+	return "", false
+}
+
+// ExpandAbbreviations 输出级注释：在已知的缩写和 Q 简语后面用括号标出含义，方便新手阅读，
+// 例如 "GM OM TNX" -> "GM(good morning) OM(fellow ham) TNX(thanks)"。
+// 只处理最终文本，空格原样保留，不影响解码。
+func ExpandAbbreviations(text string) string {
+	words := strings.Split(text, " ")
+	for i, w := range words {
+		if m, ok := AbbreviationMeaning(w); ok {
+			words[i] = w + "(" + m + ")"
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// cutNumbers 比赛中常用的"缩略数字"：用短的字母代替长的数字 (T = 0，N = 9 ...)
+var cutNumbers = map[rune]rune{
+	'T': '0', 'O': '0', 'A': '1', 'U': '2', 'V': '3', 'E': '5', 'B': '7', 'D': '8', 'N': '9',
+}
+
+// NumericRegion 文本中按数字字段解释的区间 (字节偏移，左闭右开)，例如比赛交换中的序号
+type NumericRegion struct {
+	Start, End int
+}
+
+// ApplyCutNumbers 输出级后处理：numericContext 为 true 时把整段文本视为数字字段 (例如单独解码的序号)，
+// 由数字和缩略字母组成的单词转换为数字，例如 "5NN TT1" -> "599 001"；其他单词不变。
+// numericContext 为 false 时原样返回。只有部分文本是数字字段时用 ApplyCutNumbersIn。
+func ApplyCutNumbers(text string, numericContext bool) string {
+	if !numericContext {
+		return text
+	}
+	return ApplyCutNumbersIn(text, []NumericRegion{{0, len(text)}})
+}
+
+// ApplyCutNumbersIn 只转换完全落在 regions 内的单词 (区间可由 ExchangeRegions 得到)
+func ApplyCutNumbersIn(text string, regions []NumericRegion) string {
+	var sb strings.Builder
+	sb.Grow(len(text))
+	start := 0
+	for start <= len(text) {
+		end := strings.IndexByte(text[start:], ' ')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += start
+		}
+		word := text[start:end]
+		if inNumericRegion(start, end, regions) && isCutNumberWord(word) {
+			word = cutNumberDigits(word)
+		}
+		sb.WriteString(word)
+		if end < len(text) {
+			sb.WriteByte(' ')
+		}
+		start = end + 1
+	}
+	return sb.String()
+}
+
+// ExchangeRegions 标出比赛交换中的数字字段：信号报告 (5NN、599、57N 等) 和紧跟其后的序号，
+// 例如 "TU 5NN TT1 K" 中的 "5NN TT1"。信号报告必须以数字开头，避免把普通单词当成报告；
+// 报告后面是常见缩写 (DE、TU 等) 时不当作序号。
+func ExchangeRegions(text string) []NumericRegion {
+	var regions []NumericRegion
+	words := strings.Split(text, " ")
+	offset := 0
+	for i, w := range words {
+		if isRSTReport(w) {
+			end := offset + len(w)
+			if i+1 < len(words) && isSerialNumber(words[i+1]) {
+				end += 1 + len(words[i+1])
+			}
+			regions = append(regions, NumericRegion{offset, end})
+		}
+		offset += len(w) + 1
+	}
+	return regions
+}
+
+// isSerialNumber 信号报告后面的单词是否像序号：只由数字和缩略字母组成，且不是常见缩写
+func isSerialNumber(word string) bool {
+	_, abbr := abbreviations[word]
+	return isCutNumberWord(word) && !abbr
+}
+
+// inNumericRegion 单词 [start, end) 是否完全落在某个区间内
+func inNumericRegion(start, end int, regions []NumericRegion) bool {
+	for _, r := range regions {
+		if start >= r.Start && end <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// isCutNumberWord 单词是否只由数字和缩略字母组成
+func isCutNumberWord(word string) bool {
+	if word == "" {
+		return false
+	}
+	for _, r := range word {
+		if _, ok := cutNumbers[r]; !ok && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// cutNumberDigits 把缩略字母替换为数字 (调用前用 isCutNumberWord 检查)
+func cutNumberDigits(word string) string {
+	return strings.Map(func(r rune) rune {
+		if d, ok := cutNumbers[r]; ok {
+			return d
+		}
+		return r
+	}, word)
+}
+
+// isRSTReport 是否为信号报告：三位，首位是数字 1-5，其余两位 (可用缩略字母) 为 1-9
+func isRSTReport(word string) bool {
+	if len(word) != 3 || word[0] < '1' || word[0] > '5' || !isCutNumberWord(word) {
+		return false
+	}
+	digits := cutNumberDigits(word)
+	return digits[1] != '0' && digits[2] != '0'
+}
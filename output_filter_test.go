@@ -0,0 +1,131 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"testing"
+)
+
+func TestCollapseSpaces(t *testing.T) {
+	tests := map[string]string{
+		"":               "",
+		"   ":            "",
+		"  CQ   CQ  DE ": "CQ CQ DE ",
+		"CQ DE BG1ABC":   "CQ DE BG1ABC",
+	}
+	for in, want := range tests {
+		if got := CollapseSpaces(in); got != want {
+			t.Errorf("CollapseSpaces(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCollapseSpaces_LongPauses(t *testing.T) {
+	dec, err := BeamDecoder.NewCWDecoder(BeamDecoder.DecoderConfig{InitialWPM: 20, GlitchThresholdMs: 15}, newTestLanguageModel())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "E  (长停顿)  T  (长停顿)  E"，每个停顿都远超单词间隔
+	// 像 ExperimentalDecoder 一样保留最后一次非空输出
+	var out string
+	feed := func(dur float64, state BeamDecoder.SignalState) {
+		if s := dec.FeedNew(dur, state); s != "" {
+			out = s
+		}
+	}
+	feed(3000, BeamDecoder.StateOff)
+	feed(60, BeamDecoder.StateOn)
+	for i := 0; i < 5; i++ {
+		feed(2000, BeamDecoder.StateOff)
+	}
+	feed(180, BeamDecoder.StateOn)
+	for i := 0; i < 5; i++ {
+		feed(2000, BeamDecoder.StateOff)
+	}
+	feed(60, BeamDecoder.StateOn)
+	feed(2000, BeamDecoder.StateOff)
+	if s := dec.CheckTimeout(); s != "" {
+		out = s
+	}
+
+	if got := CollapseSpaces(out); got != "E T E" {
+		t.Errorf("Expected exactly one space between words, got %q (raw %q)", got, out)
+	}
+}
+
+func TestOutputFormatter(t *testing.T) {
+	tests := []struct {
+		f        OutputFormatter
+		in, want string
+	}{
+		{OutputFormatter{}, "CQ  DE <AR>", "CQ  DE <AR>"},
+		{OutputFormatter{Case: CaseLower}, "CQ DE BG1ABC <AR> [........]", "cq de bg1abc <AR> [........]"},
+		{OutputFormatter{WordSeparator: " / "}, "CQ  DE", "CQ /  / DE"},
+		{OutputFormatter{Case: CaseLower, WordSeparator: "\n"}, "TNX 73 ", "tnx\n73\n"},
+	}
+	for _, tt := range tests {
+		if got := tt.f.Format(tt.in); got != tt.want {
+			t.Errorf("%+v.Format(%q) = %q, want %q", tt.f, tt.in, got, tt.want)
+		}
+	}
+
+	// 逐字符处理：分片格式化后拼接与整体格式化相同 (增量输出的解码器，勤务符号总是整个输出)
+	f := OutputFormatter{Case: CaseLower, WordSeparator: "_"}
+	if got := f.Format("CQ <AR>") + f.Format(" K"); got != f.Format("CQ <AR> K") {
+		t.Errorf("Chunked formatting differs: %q", got)
+	}
+}
+
+func TestExpandAbbreviations(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"GM OM TNX", "GM(good morning) OM(fellow ham) TNX(thanks)"},
+		{"UR QTH?", "UR(your) QTH?(location?)"},
+		{"CQ  DE BG1ABC <KN>", "CQ(calling any station)  DE(from) BG1ABC <KN>(over, named station only)"},
+		{"HELLO WORLD", "HELLO WORLD"},
+		{"gm om <AR>", "gm(good morning) om(fellow ham) <AR>(end of message)"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ExpandAbbreviations(tt.in); got != tt.want {
+			t.Errorf("ExpandAbbreviations(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	if _, ok := AbbreviationMeaning("?"); ok {
+		t.Error("A lone question mark should not match")
+	}
+}
+
+func TestApplyCutNumbers(t *testing.T) {
+	if got := ApplyCutNumbers("TU 5NN TT1", false); got != "TU 5NN TT1" {
+		t.Errorf("non-numeric context should be unchanged, got %q", got)
+	}
+	// 整段都是数字字段：纯字母单词 (例如 "TEST") 不会被转换
+	if got := ApplyCutNumbers("ANT 5NN TEST", true); got != "190 599 TEST" {
+		t.Errorf("ApplyCutNumbers = %q", got)
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"TU 5NN TT1 K", "TU 599 001 K"},
+		{"BG1ABC 5NN ETN", "BG1ABC 599 509"},
+		{"R 57N 1T4 TU", "R 579 104 TU"},
+		{"CQ TEST DE BG1ABC", "CQ TEST DE BG1ABC"}, // 没有信号报告
+		{"5NN DE BG1ABC", "599 DE BG1ABC"},         // 报告后面不是序号
+		{"TEN ANT", "TEN ANT"},                     // 首位不是数字，不当作报告
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ApplyCutNumbersIn(tt.in, ExchangeRegions(tt.in)); got != tt.want {
+			t.Errorf("ApplyCutNumbersIn(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// 调用方自己标记区间 (例如已知交换格式的第二个字段)
+	text := "BG1ABC NT"
+	if got := ApplyCutNumbersIn(text, []NumericRegion{{7, 9}}); got != "BG1ABC 90" {
+		t.Errorf("explicit region: got %q", got)
+	}
+}
@@ -0,0 +1,30 @@
+// Package publish 把解码文本发送到网络 (远程监听、浏览器面板)
+// 放在独立的子包中，核心库 cw 不依赖任何网络代码。
+package publish
+
+import (
+	"io"
+	"strings"
+)
+
+// Publisher 解码文本的发送目标
+type Publisher interface {
+	Publish(text string) error
+}
+
+// Writer 把 p 包装成 io.Writer：每次 Write 发送一条消息 (去掉末尾的换行)
+// 配合 cw.TranscriptLog 使用，即可按单词发送已经确定的文本 (Beam Search 解码器末尾的修正不会被发出去)。
+func Writer(p Publisher) io.Writer {
+	return publisherWriter{p}
+}
+
+type publisherWriter struct {
+	p Publisher
+}
+
+func (w publisherWriter) Write(b []byte) (int, error) {
+	if err := w.p.Publish(strings.TrimRight(string(b), "\r\n")); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
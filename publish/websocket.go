@@ -0,0 +1,276 @@
+package publish
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WebSocket 帧的操作码 (RFC 6455)
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+const (
+	// wsGUID 握手时与客户端的 Sec-WebSocket-Key 拼接后求 SHA-1
+	wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// wsSendQueue 每个客户端最多排队的消息数，满了说明客户端太慢，直接断开，不拖慢解码线程
+	wsSendQueue = 64
+	// wsMaxFramePayload 客户端帧的最大长度；浏览器只会发 ping/close，更大的帧视为协议错误
+	wsMaxFramePayload = 4096
+)
+
+// ErrClosed WebSocketServer 已关闭
+var ErrClosed = errors.New("publisher closed")
+
+// WebSocketServer 把解码文本以 WebSocket 文本消息广播给所有连接的客户端
+// 本身是一个 http.Handler，挂到任意路径上即可，例如:
+//
+//	ws := publish.NewWebSocketServer()
+//	http.Handle("/ws", ws)
+//	go http.ListenAndServe(":8080", nil)
+//
+// 浏览器端: new WebSocket("ws://pi.local:8080/ws").onmessage = e => console.log(e.data)
+// 只支持服务器到客户端的单向发送，客户端发来的数据帧会被忽略。
+type WebSocketServer struct {
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+	closed  bool
+}
+
+type wsClient struct {
+	conn net.Conn
+	send chan []byte // 已编码好的帧
+	once sync.Once
+}
+
+// NewWebSocketServer 创建一个没有客户端的广播服务
+func NewWebSocketServer() *WebSocketServer {
+	return &WebSocketServer{clients: make(map[*wsClient]struct{})}
+}
+
+// ServeHTTP 完成 WebSocket 握手并登记客户端
+func (s *WebSocketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") ||
+		r.Header.Get("Sec-WebSocket-Version") != "13" || key == "" {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+
+	resp := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return
+	}
+
+	c := &wsClient{conn: conn, send: make(chan []byte, wsSendQueue)}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+
+	go s.writeLoop(c)
+	go s.readLoop(c, rw.Reader)
+}
+
+// Publish 把 text 作为一条文本消息发给所有客户端 (不等待发送完成)
+// 发送队列已满的客户端会被断开。没有客户端时什么也不做。
+func (s *WebSocketServer) Publish(text string) error {
+	frame := encodeFrame(opText, []byte(text))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	for c := range s.clients {
+		select {
+		case c.send <- frame:
+		default:
+			s.dropLocked(c)
+		}
+	}
+	return nil
+}
+
+// Clients 当前连接的客户端数
+func (s *WebSocketServer) Clients() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.clients)
+}
+
+// Close 断开所有客户端，之后 Publish 返回 ErrClosed
+func (s *WebSocketServer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for c := range s.clients {
+		s.dropLocked(c)
+	}
+	return nil
+}
+
+// dropLocked 移除客户端并关闭发送队列 (writeLoop 随后关闭连接)，调用方持有 s.mu
+func (s *WebSocketServer) dropLocked(c *wsClient) {
+	if _, ok := s.clients[c]; !ok {
+		return
+	}
+	delete(s.clients, c)
+	close(c.send)
+}
+
+func (s *WebSocketServer) drop(c *wsClient) {
+	s.mu.Lock()
+	s.dropLocked(c)
+	s.mu.Unlock()
+}
+
+// writeLoop 按顺序写出队列中的帧；队列关闭时发送关闭帧并断开
+func (s *WebSocketServer) writeLoop(c *wsClient) {
+	defer c.close()
+	for frame := range c.send {
+		if _, err := c.conn.Write(frame); err != nil {
+			s.drop(c)
+			// 排空队列，等 drop 关闭它
+			for range c.send {
+			}
+			return
+		}
+	}
+	c.conn.Write(encodeFrame(opClose, nil))
+}
+
+// readLoop 处理客户端发来的帧：回应 ping，收到 close 或连接出错时移除客户端
+func (s *WebSocketServer) readLoop(c *wsClient, r *bufio.Reader) {
+	for {
+		op, payload, err := readFrame(r)
+		if err != nil || op == opClose {
+			s.drop(c)
+			c.close()
+			return
+		}
+		if op == opPing {
+			s.mu.Lock()
+			if _, ok := s.clients[c]; ok {
+				select {
+				case c.send <- encodeFrame(opPong, payload):
+				default:
+				}
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (c *wsClient) close() {
+	c.once.Do(func() { c.conn.Close() })
+}
+
+// acceptKey 计算握手响应的 Sec-WebSocket-Accept
+func acceptKey(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains 头部 name 的逗号分隔值中是否包含 token (不区分大小写)
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encodeFrame 编码一个服务器到客户端的完整帧 (FIN，不加掩码)
+func encodeFrame(op byte, payload []byte) []byte {
+	n := len(payload)
+	frame := []byte{0x80 | op}
+	switch {
+	case n < 126:
+		frame = append(frame, byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	return append(frame, payload...)
+}
+
+// readFrame 读取客户端的一个帧并去掉掩码
+func readFrame(r *bufio.Reader) (op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		return 0, nil, err
+	}
+	op = head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	n := uint64(head[1] & 0x7F)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxFramePayload {
+		return 0, nil, fmt.Errorf("websocket frame too large: %d bytes", n)
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return op, payload, nil
+}
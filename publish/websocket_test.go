@@ -0,0 +1,132 @@
+package publish
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// dialWebSocket 完成握手并返回连接和读取器
+func dialWebSocket(t *testing.T, url string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req := "GET /ws HTTP/1.1\r\nHost: test\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RFC 6455 中的示例
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected handshake response: %s %v", resp.Status, resp.Header)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return conn, r
+}
+
+// waitClients 等待服务器登记 n 个客户端 (握手响应先于登记发出)
+func waitClients(t *testing.T, s *WebSocketServer, n int) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); s.Clients() != n; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, got %d", n, s.Clients())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// clientFrame 编码一个客户端到服务器的帧 (必须加掩码)
+func clientFrame(op byte, payload []byte) []byte {
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | op, 0x80 | byte(len(payload))}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+func TestWebSocketServer_Publish(t *testing.T) {
+	ws := NewWebSocketServer()
+	srv := httptest.NewServer(ws)
+	defer srv.Close()
+
+	conn, r := dialWebSocket(t, srv.URL)
+	waitClients(t, ws, 1)
+
+	long := strings.Repeat("CQ ", 100) // 超过 125 字节，使用 16 位长度
+	for _, msg := range []string{"CQ DE BG1ABC", long} {
+		if err := ws.Publish(msg); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		op, payload, err := readFrame(r)
+		if err != nil {
+			t.Fatalf("readFrame: %v", err)
+		}
+		if op != opText || string(payload) != msg {
+			t.Errorf("Got op %d %q, want text %q", op, payload, msg)
+		}
+	}
+
+	// ping 得到同样内容的 pong
+	if _, err := conn.Write(clientFrame(opPing, []byte("hi"))); err != nil {
+		t.Fatal(err)
+	}
+	if op, payload, err := readFrame(r); err != nil || op != opPong || string(payload) != "hi" {
+		t.Errorf("Expected pong \"hi\", got op %d %q (%v)", op, payload, err)
+	}
+
+	// 客户端关闭后从列表中移除
+	if _, err := conn.Write(clientFrame(opClose, nil)); err != nil {
+		t.Fatal(err)
+	}
+	waitClients(t, ws, 0)
+
+	ws.Close()
+	if err := ws.Publish("73"); err != ErrClosed {
+		t.Errorf("Expected ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestWebSocketServer_RejectsPlainHTTP(t *testing.T) {
+	srv := httptest.NewServer(NewWebSocketServer())
+	defer srv.Close()
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a plain GET, got %d", resp.StatusCode)
+	}
+}
+
+type recorder struct{ msgs []string }
+
+func (r *recorder) Publish(text string) error {
+	r.msgs = append(r.msgs, text)
+	return nil
+}
+
+func TestWriter(t *testing.T) {
+	rec := &recorder{}
+	w := Writer(rec)
+	if n, err := w.Write([]byte("2026-10-17T08:30:15Z CQ DE BG1ABC\n")); err != nil || n != 34 {
+		t.Fatalf("Write = %d, %v", n, err)
+	}
+	if len(rec.msgs) != 1 || rec.msgs[0] != "2026-10-17T08:30:15Z CQ DE BG1ABC" {
+		t.Errorf("Expected one message without the newline, got %q", rec.msgs)
+	}
+}
@@ -0,0 +1,144 @@
+package cw
+
+import (
+	"cw/adif"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// QSOLog 从解码文本中收集疑似呼号，生成可导出的通联记录
+// 启发式规则：完整的大写单词，同时包含字母和数字 (排除 5NN 之类的信号报告)。
+type QSOLog struct {
+	mu          sync.Mutex
+	incremental bool   // true: 解码器每次输出新增的片段; false: 每次输出完整文本
+	transcript  string // 当前的解码文本
+	seen        map[string]bool
+	records     []adif.QSORecord
+
+	// FreqFunc 返回当前频率 (Hz)，为 nil 或出错时记录中不写频率
+	FreqFunc func() (int, error)
+	// RSTFunc 返回对方信号的估计报告 (例如 "579")，写入 RSTSent；为 nil 或返回空串时不写
+	RSTFunc func() string
+}
+
+// NewQSOLog 创建记录器
+// incremental 取决于解码器的输出方式：ClusterDecoder/AdaptiveCWDecoder 逐字符输出，
+// ExperimentalDecoder/GoertzelDecoder 每次输出 Beam Search 的完整最优文本。
+func NewQSOLog(incremental bool) *QSOLog {
+	return &QSOLog{
+		incremental: incremental,
+		seen:        make(map[string]bool),
+	}
+}
+
+// Observe 处理一次解码输出
+func (l *QSOLog) Observe(text string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.incremental {
+		l.transcript += text
+	} else {
+		l.transcript = text
+	}
+
+	// 只看已经结束的单词 (后面跟着空格)，最后一个单词可能还在接收中
+	words := strings.Fields(l.transcript)
+	if len(words) > 0 && !strings.HasSuffix(l.transcript, " ") {
+		words = words[:len(words)-1]
+	}
+	for _, w := range words {
+		l.consider(w)
+	}
+
+	// 逐字符模式下只保留未结束的单词，避免无限增长
+	if l.incremental {
+		if i := strings.LastIndexByte(l.transcript, ' '); i >= 0 {
+			l.transcript = l.transcript[i+1:]
+		}
+	}
+}
+
+// Flush 将最后一个未结束的单词也视为完整单词 (会话结束时调用)
+func (l *QSOLog) Flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, w := range strings.Fields(l.transcript) {
+		l.consider(w)
+	}
+}
+
+// Records 返回已收集的通联记录
+func (l *QSOLog) Records() []adif.QSORecord {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	out := make([]adif.QSORecord, len(l.records))
+	copy(out, l.records)
+	return out
+}
+
+// consider 检查单词是否像呼号，是新呼号则生成记录
+func (l *QSOLog) consider(word string) {
+	if !looksLikeCallsign(word) || l.seen[word] {
+		return
+	}
+	l.seen[word] = true
+
+	rec := adif.QSORecord{
+		Call: word,
+		Mode: "CW",
+		Time: time.Now().UTC().Format(adif.TimeLayout),
+	}
+	if l.FreqFunc != nil {
+		if hz, err := l.FreqFunc(); err == nil && hz > 0 {
+			rec.Freq = formatMHz(hz)
+		}
+	}
+	if l.RSTFunc != nil {
+		rec.RSTSent = l.RSTFunc()
+	}
+	l.records = append(l.records, rec)
+}
+
+// looksLikeCallsign 大写字母数字 (允许 /)，同时含字母和数字，且不是信号报告
+func looksLikeCallsign(word string) bool {
+	if len(word) < 3 || len(word) > 12 {
+		return false
+	}
+	hasLetter, hasDigit := false, false
+	for _, r := range word {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case r == '/':
+		default:
+			return false
+		}
+	}
+	return hasLetter && hasDigit && !isRST(word)
+}
+
+// isRST 是否为 599 / 5NN 这类信号报告 (N 是 9 的简写)
+func isRST(word string) bool {
+	if len(word) != 3 || word[0] < '1' || word[0] > '5' {
+		return false
+	}
+	for _, c := range word[1:] {
+		if !(c >= '1' && c <= '9') && c != 'N' {
+			return false
+		}
+	}
+	return true
+}
+
+// formatMHz 将 Hz 转为 ADIF 使用的 MHz 字符串
+func formatMHz(hz int) string {
+	return fmt.Sprintf("%.6f", float64(hz)/1e6)
+}
@@ -0,0 +1,69 @@
+package cw
+
+import "testing"
+
+func TestLooksLikeCallsign(t *testing.T) {
+	tests := map[string]bool{
+		"BG1ABC":   true,
+		"JA1XYZ/P": true,
+		"K1A":      true,
+		"CQ":       false,
+		"599":      false,
+		"5NN":      false,
+		"73":       false,
+		"bg1abc":   false,
+		"<SK>":     false,
+		"PARIS":    false,
+	}
+	for word, want := range tests {
+		if got := looksLikeCallsign(word); got != want {
+			t.Errorf("looksLikeCallsign(%q) = %v, want %v", word, got, want)
+		}
+	}
+}
+
+func TestQSOLog_FullText(t *testing.T) {
+	l := NewQSOLog(false)
+	l.FreqFunc = func() (int, error) { return 7025000, nil }
+	l.RSTFunc = func() string { return "579" }
+
+	// Beam Search 每次给出完整文本，最后一个单词尚未结束
+	l.Observe("CQ DE BG1A")
+	l.Observe("CQ DE BG1ABC")
+	if n := len(l.Records()); n != 0 {
+		t.Fatalf("Unfinished word should not be recorded, got %d records", n)
+	}
+	l.Observe("CQ DE BG1ABC BG1ABC K")
+	l.Observe("CQ DE BG1ABC BG1ABC K ")
+
+	recs := l.Records()
+	if len(recs) != 1 {
+		t.Fatalf("Expected 1 record, got %+v", recs)
+	}
+	if recs[0].Call != "BG1ABC" || recs[0].Freq != "7.025000" || recs[0].Mode != "CW" || recs[0].Time == "" || recs[0].RSTSent != "579" {
+		t.Errorf("Unexpected record %+v", recs[0])
+	}
+}
+
+func TestQSOLog_Incremental(t *testing.T) {
+	l := NewQSOLog(true)
+	for _, c := range "BG1ABC DE JA1XYZ 5NN" {
+		l.Observe(string(c))
+	}
+	if n := len(l.Records()); n != 2 {
+		t.Errorf("Expected 2 records before flush, got %d", n)
+	}
+	l.Observe(" ")
+	l.Observe("K")
+	l.Observe("2")
+	l.Observe("X")
+	l.Flush()
+
+	recs := l.Records()
+	if len(recs) != 3 || recs[0].Call != "BG1ABC" || recs[1].Call != "JA1XYZ" || recs[2].Call != "K2X" {
+		t.Errorf("Unexpected records %+v", recs)
+	}
+	if recs[0].Freq != "" {
+		t.Errorf("Freq should be empty without FreqFunc, got %q", recs[0].Freq)
+	}
+}
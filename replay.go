@@ -0,0 +1,104 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ReplayAndScore 用真实录音做回归测试：两遍解码 wavPath (见 DecodeWAVTwoPass)，
+// 与 truthPath 中的已知内容 (纯文本，大小写和换行不计) 逐字符对齐，
+// 返回字符错误率 (同 CharacterErrorRate) 和对齐结果 (见 AlignText)。
+// 把一个目录的录音各配一个同名 .txt 文件，就是一个真实信号的测试集。
+func ReplayAndScore(wavPath, truthPath string) (cer float64, aligned string, err error) {
+	return replayAndScore(wavPath, truthPath, BeamDecoder.NewLanguageModel())
+}
+
+func replayAndScore(wavPath, truthPath string, lm *BeamDecoder.LanguageModel) (float64, string, error) {
+	data, err := os.ReadFile(truthPath)
+	if err != nil {
+		return 0, "", fmt.Errorf("read truth %s: %w", truthPath, err)
+	}
+	truth := normalizeTranscript(string(data))
+	if truth == "" {
+		return 0, "", fmt.Errorf("truth file %s is empty", truthPath)
+	}
+
+	text, err := decodeWAVTwoPass(wavPath, lm)
+	if err != nil {
+		return 0, "", err
+	}
+	edits, aligned := AlignText(truth, normalizeTranscript(text))
+	return float64(edits) / float64(len([]rune(truth))), aligned, nil
+}
+
+// normalizeTranscript 转大写并把连续空白 (含换行) 合并为一个空格
+func normalizeTranscript(s string) string {
+	return strings.Join(strings.Fields(strings.ToUpper(s)), " ")
+}
+
+// AlignText 按编辑距离 (Levenshtein) 对齐参考文本和解码结果，返回编辑次数和三行对齐结果：
+//
+//	REF: THE QUICK
+//	HYP: T*E QUICK
+//	      D
+//
+// 第一、二行中 '*' 表示对方多出的字符；第三行 S = 替换，D = 丢字，I = 多字，空格 = 正确 (完全正确时为空行)。
+// 与 CharacterErrorRate 一致，首尾空白不计。
+func AlignText(reference, hypothesis string) (edits int, aligned string) {
+	ref := []rune(strings.TrimSpace(reference))
+	hyp := []rune(strings.TrimSpace(hypothesis))
+
+	// 完整的动态规划矩阵，用于回溯
+	dist := make([][]int, len(ref)+1)
+	for i := range dist {
+		dist[i] = make([]int, len(hyp)+1)
+		dist[i][0] = i
+	}
+	for j := range dist[0] {
+		dist[0][j] = j
+	}
+	for i := 1; i <= len(ref); i++ {
+		for j := 1; j <= len(hyp); j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			dist[i][j] = min(dist[i-1][j]+1, dist[i][j-1]+1, dist[i-1][j-1]+cost)
+		}
+	}
+
+	// 从右下角回溯，优先走对角线 (匹配/替换)
+	var refLine, hypLine, opLine []rune
+	for i, j := len(ref), len(hyp); i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && ref[i-1] == hyp[j-1] && dist[i][j] == dist[i-1][j-1]:
+			refLine, hypLine, opLine = append(refLine, ref[i-1]), append(hypLine, hyp[j-1]), append(opLine, ' ')
+			i, j = i-1, j-1
+		case i > 0 && j > 0 && dist[i][j] == dist[i-1][j-1]+1:
+			refLine, hypLine, opLine = append(refLine, ref[i-1]), append(hypLine, hyp[j-1]), append(opLine, 'S')
+			i, j = i-1, j-1
+		case i > 0 && dist[i][j] == dist[i-1][j]+1:
+			refLine, hypLine, opLine = append(refLine, ref[i-1]), append(hypLine, '*'), append(opLine, 'D')
+			i--
+		default:
+			refLine, hypLine, opLine = append(refLine, '*'), append(hypLine, hyp[j-1]), append(opLine, 'I')
+			j--
+		}
+	}
+	reverseRunes(refLine)
+	reverseRunes(hypLine)
+	reverseRunes(opLine)
+
+	aligned = "REF: " + string(refLine) + "\n" +
+		"HYP: " + string(hypLine) + "\n" +
+		strings.TrimRight("     "+string(opLine), " ")
+	return dist[len(ref)][len(hyp)], aligned
+}
+
+func reverseRunes(r []rune) {
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+}
@@ -0,0 +1,87 @@
+package cw
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAlignText(t *testing.T) {
+	tests := []struct {
+		ref, hyp string
+		edits    int
+		aligned  string
+	}{
+		{"CQ DE", "CQ DE", 0, "REF: CQ DE\nHYP: CQ DE\n"},
+		{"THE", "TE", 1, "REF: THE\nHYP: T*E\n      D"},
+		{"THE", "TIE", 1, "REF: THE\nHYP: TIE\n      S"},
+		{"K", "KN", 1, "REF: K*\nHYP: KN\n      I"},
+		{"", "E", 1, "REF: *\nHYP: E\n     I"},
+	}
+	for _, tt := range tests {
+		edits, aligned := AlignText(tt.ref, tt.hyp)
+		if edits != tt.edits || aligned != tt.aligned {
+			t.Errorf("AlignText(%q, %q) = %d, %q; want %d, %q", tt.ref, tt.hyp, edits, aligned, tt.edits, tt.aligned)
+		}
+		if tt.ref != "" {
+			if cer := CharacterErrorRate(tt.ref, tt.hyp); cer != float64(edits)/float64(len(tt.ref)) {
+				t.Errorf("AlignText(%q, %q) disagrees with CharacterErrorRate %.2f", tt.ref, tt.hyp, cer)
+			}
+		}
+	}
+}
+
+func TestReplayAndScore(t *testing.T) {
+	const sampleRate = 8000
+	dir := t.TempDir()
+	wavPath := filepath.Join(dir, "qso.wav")
+	truthPath := filepath.Join(dir, "qso.txt")
+
+	audio := GenerateCW("CQ CQ DE BG1ABC K", AudioConfig{WPM: 15, SampleRate: sampleRate, Frequency: 700})
+	audio = noisyRecording(audio, sampleRate, 1, ChannelEffects{SNRdB: 20, Seed: 1})
+	w, err := NewWavWriter(wavPath, sampleRate)
+	if err != nil {
+		t.Fatalf("NewWavWriter: %v", err)
+	}
+	if err := w.WriteSamples(audio); err != nil {
+		t.Fatalf("WriteSamples: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// 标注文件的大小写和换行不计
+	if err := os.WriteFile(truthPath, []byte("cq cq de\nbg1abc k\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cer, aligned, err := replayAndScore(wavPath, truthPath, newTestLanguageModel())
+	if err != nil {
+		t.Fatalf("replayAndScore: %v", err)
+	}
+	if cer != 0 {
+		t.Errorf("Expected a clean recording to score 0, got CER %.2f\n%s", cer, aligned)
+	}
+
+	// 标注与内容不符时给出错误率和位置
+	if err := os.WriteFile(truthPath, []byte("CQ CQ DE BG1ABD K"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cer, aligned, err = replayAndScore(wavPath, truthPath, newTestLanguageModel())
+	if err != nil {
+		t.Fatalf("replayAndScore: %v", err)
+	}
+	if want := "REF: CQ CQ DE BG1ABD K\nHYP: CQ CQ DE BG1ABC K\n" + strings.Repeat(" ", 19) + "S"; cer == 0 || aligned != want {
+		t.Errorf("Expected one substitution, got CER %.2f\n%s", cer, aligned)
+	}
+
+	if err := os.WriteFile(truthPath, []byte(" \n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := replayAndScore(wavPath, truthPath, newTestLanguageModel()); err == nil {
+		t.Error("Expected error for an empty truth file")
+	}
+	if _, _, err := replayAndScore(wavPath, filepath.Join(dir, "none.txt"), newTestLanguageModel()); err == nil {
+		t.Error("Expected error for a missing truth file")
+	}
+}
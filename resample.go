@@ -0,0 +1,46 @@
+package cw
+
+// linearResampler 线性插值重采样，用于声卡实际采样率与请求的不一致时把音频转换回请求的采样率。
+// CW 音频集中在 3kHz 以下，远低于常见采样率的奈奎斯特频率，线性插值不做抗混叠滤波也足够。
+// 跨块保持状态，分块输入与一次性输入的结果相同。
+type linearResampler struct {
+	step    float64 // 每个输出采样在输入上前进的采样数 (inRate / outRate)
+	pos     float64 // 下一个输出在 [prev, 当前块...] 上的位置
+	prev    float32 // 上一块的最后一个采样
+	started bool
+}
+
+// newLinearResampler 创建从 inRate 到 outRate 的重采样器
+func newLinearResampler(inRate, outRate int) *linearResampler {
+	return &linearResampler{step: float64(inRate) / float64(outRate)}
+}
+
+// process 重采样一块音频，返回新分配的切片
+func (r *linearResampler) process(samples []float32) []float32 {
+	n := len(samples)
+	if n == 0 {
+		return nil
+	}
+	if !r.started {
+		// 第一个输出对齐第一个输入采样
+		r.pos = 1
+		r.started = true
+	}
+	at := func(i int) float32 {
+		if i == 0 {
+			return r.prev
+		}
+		return samples[i-1]
+	}
+
+	out := make([]float32, 0, int(float64(n)/r.step)+1)
+	for int(r.pos) < n {
+		i := int(r.pos)
+		f := float32(r.pos - float64(i))
+		out = append(out, at(i)*(1-f)+at(i+1)*f)
+		r.pos += r.step
+	}
+	r.pos -= float64(n)
+	r.prev = samples[n-1]
+	return out
+}
@@ -0,0 +1,58 @@
+package cw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLinearResampler_Rate(t *testing.T) {
+	const inRate, outRate = 44100, 48000
+	const freq = 700.0
+	in := make([]float32, inRate)
+	for i := range in {
+		in[i] = float32(math.Sin(2 * math.Pi * freq * float64(i) / inRate))
+	}
+
+	// 分块输入 (块长与比例无关)，结果应与一次性处理相同
+	whole := newLinearResampler(inRate, outRate).process(in)
+	r := newLinearResampler(inRate, outRate)
+	var chunked []float32
+	for i := 0; i < len(in); i += 1000 {
+		chunked = append(chunked, r.process(in[i:min(i+1000, len(in))])...)
+	}
+	if len(whole) != len(chunked) {
+		t.Fatalf("chunked length %d, whole length %d", len(chunked), len(whole))
+	}
+	for i := range whole {
+		if math.Abs(float64(whole[i]-chunked[i])) > 1e-6 {
+			t.Fatalf("sample %d: chunked %v, whole %v", i, chunked[i], whole[i])
+		}
+	}
+
+	// 1 秒输入应得到约 1 秒的输出，且按输出采样率仍是同一个音调
+	if math.Abs(float64(len(whole)-outRate)) > 2 {
+		t.Errorf("got %d samples, want ~%d", len(whole), outRate)
+	}
+	var maxErr float64
+	for i, v := range whole {
+		want := math.Sin(2 * math.Pi * freq * float64(i) / outRate)
+		maxErr = math.Max(maxErr, math.Abs(float64(v)-want))
+	}
+	if maxErr > 0.01 {
+		t.Errorf("max interpolation error %.4f, want < 0.01", maxErr)
+	}
+}
+
+func TestLinearResampler_Downsample(t *testing.T) {
+	r := newLinearResampler(96000, 48000)
+	out := r.process([]float32{0, 1, 2, 3, 4, 5, 6, 7})
+	want := []float32{0, 2, 4, 6}
+	if len(out) != len(want) {
+		t.Fatalf("got %v, want %v", out, want)
+	}
+	for i := range want {
+		if out[i] != want[i] {
+			t.Fatalf("got %v, want %v", out, want)
+		}
+	}
+}
@@ -2,7 +2,6 @@ package cw
 
 import (
 	"cw/Filters"
-	"fmt"
 	"math"
 )
 
@@ -11,6 +10,10 @@ type SDRDemodulator struct {
 	sampleRate float64
 	targetFreq float64 // [新增] 记录目标频率
 	afcEnabled bool    // [新增] 记录 AFC 开关状态
+	invert     bool    // I/Q 方向翻转 (CW-R)
+
+	dcBlock *DCBlocker       // nil = 不隔直 (SDR.DCBlockCutoff = 0)
+	hilbert *HilbertEnvelope // 非 nil 时用希尔伯特变换求包络，跳过混频 (SDR.Frontend = FrontendHilbert)
 
 	lpfI  *ButterworthFilter
 	lpfQ  *ButterworthFilter
@@ -26,11 +29,19 @@ func NewSDRDemodulator(sampleRate, targetFreq float64, cfg *Config) *SDRDemodula
 		sampleRate: sampleRate,
 		targetFreq: targetFreq,         // [记录]
 		afcEnabled: cfg.SDR.AfcEnabled, // [记录] 听从 config 指挥
+		invert:     cfg.SDR.SidebandInvert,
+		phase:      cfg.SDR.LoInitialPhase,
 
 		lpfI: NewButterworthLowpass(4, sampleRate, cfg.SDR.FilterBW),
 		lpfQ: NewButterworthLowpass(4, sampleRate, cfg.SDR.FilterBW),
 		afc:  Filters.NewAFC(sampleRate, targetFreq),
 	}
+	if cfg.SDR.DCBlockCutoff > 0 {
+		sdr.dcBlock = NewDCBlocker(sampleRate, cfg.SDR.DCBlockCutoff)
+	}
+	if cfg.SDR.Frontend == FrontendHilbert {
+		sdr.hilbert = NewHilbertEnvelope(sampleRate, cfg.SDR.FilterBW)
+	}
 	return sdr
 }
 
@@ -39,17 +50,34 @@ func (s *SDRDemodulator) SetTargetFreq(freq float64) {
 	// 只有当新检测到的频率偏差超过 5Hz 时，才调整 SDR
 	// 避免因为 1-2Hz 的检测误差导致 SDR 反复重置相位
 	if math.Abs(freq-s.targetFreq) > 5.0 {
-		fmt.Printf("[Auto-Tune] Following signal to %.1f Hz\n", freq)
+		logger.Debug("sdr following signal", "freq_hz", freq)
 		s.afc.UpdateTargetFreq(freq)
 	}
 
 	// 滤波器重置代码已被正确移除，保持现状
 }
 
+// SetSidebandInvert 翻转 I/Q 方向 (CW-R)
+func (s *SDRDemodulator) SetSidebandInvert(invert bool) {
+	s.invert = invert
+}
+
 func (s *SDRDemodulator) Process(sample float64) float64 {
+	// 0. DC Blocking
+	if s.dcBlock != nil {
+		sample = s.dcBlock.Process(sample)
+	}
+	// 希尔伯特前端与音调频率无关，不需要本振
+	if s.hilbert != nil {
+		return s.hilbert.Process(sample)
+	}
+
 	// 1. LO generation
 	loI := math.Cos(s.phase)
 	loQ := math.Sin(s.phase)
+	if s.invert {
+		loQ = -loQ
+	}
 
 	// 2. Mixing
 	mixI := sample * loI
@@ -66,7 +94,13 @@ func (s *SDRDemodulator) Process(sample float64) float64 {
 	var phaseInc float64
 	if s.afcEnabled {
 		// 只有开启时才询问 AFC
-		phaseInc = s.afc.Update(float64(filteredI), float64(filteredQ), envelope)
+		// AFC 假设信号高于本振时基带相位递增；cos/sin 混频得到的相位是递减的 (Q = -sin(Δt)/2)，
+		// 需要取反，翻转 (CW-R) 时方向本身已经相反。方向错了频率误差的符号会反，越修越偏。
+		afcQ := -filteredQ
+		if s.invert {
+			afcQ = filteredQ
+		}
+		phaseInc = s.afc.Update(float64(filteredI), float64(afcQ), envelope)
 	} else {
 		// 关闭时，直接计算固定的相位增量 (死锁频率)
 		// Inc = 2 * PI * Freq / SampleRate
@@ -0,0 +1,86 @@
+package cw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSDRDemodulator_AFCTracksInBothOrientations(t *testing.T) {
+	const sampleRate = 8000
+	for _, invert := range []bool{false, true} {
+		for _, signal := range []float64{690, 710} {
+			cfg := DefaultConfig()
+			cfg.SDR.AfcEnabled = true
+			cfg.SDR.SidebandInvert = invert
+			sdr := NewSDRDemodulator(sampleRate, 700, cfg)
+
+			// 信号偏离目标 10Hz，AFC 应向信号收敛而不是反向跑偏
+			for i := 0; i < sampleRate*3; i++ {
+				sdr.Process(0.5 * math.Sin(2*math.Pi*signal*float64(i)/sampleRate))
+			}
+			if got := sdr.afc.CurrentFreq; math.Abs(got-signal) > 3 {
+				t.Errorf("invert=%v: expected AFC near %.0fHz, got %.1fHz", invert, signal, got)
+			}
+		}
+	}
+}
+
+func TestSDRDemodulator_InitialPhase(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SDR.LoInitialPhase = math.Pi / 2
+	if sdr := NewSDRDemodulator(8000, 700, cfg); sdr.phase != math.Pi/2 {
+		t.Errorf("Expected LO to start at pi/2, got %.3f", sdr.phase)
+	}
+}
+
+func TestDCBlocker(t *testing.T) {
+	const sampleRate = 8000
+	f := NewDCBlocker(sampleRate, 10)
+	// 直流偏置 + 阶跃：稳态输出回到 0
+	for i := 0; i < sampleRate; i++ {
+		x := 0.3
+		if i >= sampleRate/2 {
+			x = -0.2
+		}
+		if y := f.Process(x); i == 0 && y != 0 {
+			t.Errorf("Expected the initial bias to be absorbed, got %.3f", y)
+		}
+	}
+	if y := f.Process(-0.2); math.Abs(y) > 0.001 {
+		t.Errorf("Expected DC to decay to 0, got %.4f", y)
+	}
+
+	// 700 Hz 的音调几乎不受影响
+	peak := 0.0
+	for i := 0; i < sampleRate; i++ {
+		y := f.Process(math.Sin(2 * math.Pi * 700 * float64(i) / sampleRate))
+		if i > sampleRate/2 {
+			peak = math.Max(peak, y)
+		}
+	}
+	if peak < 0.99 || peak > 1.01 {
+		t.Errorf("Expected a 700 Hz tone to pass at unity gain, got peak %.3f", peak)
+	}
+}
+
+func TestSDRDemodulator_DCBlockStartup(t *testing.T) {
+	// 声卡从第一个采样起就带 0.5 的直流偏置和 50 Hz 交流声，前 0.25 秒没有信号
+	noiseFloor := func(cutoff float64) float64 {
+		cfg := DefaultConfig()
+		cfg.SDR.DCBlockCutoff = cutoff
+		sdr := NewSDRDemodulator(8000, 700, cfg)
+		peak := 0.0
+		for i := 0; i < 2000; i++ {
+			x := 0.5 + 0.2*math.Sin(2*math.Pi*50*float64(i)/8000)
+			peak = math.Max(peak, sdr.Process(x))
+		}
+		return peak
+	}
+	without, with := noiseFloor(0), noiseFloor(10)
+	if without < 0.01 {
+		t.Fatalf("Expected the startup DC step to leak into the envelope without blocking, got %.4f", without)
+	}
+	if with > without/10 {
+		t.Errorf("Expected the DC blocker to suppress the startup transient: %.4f with vs %.4f without", with, without)
+	}
+}
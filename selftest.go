@@ -0,0 +1,69 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"fmt"
+	"strings"
+)
+
+// 自检参数
+const (
+	selfTestText       = "PARIS PARIS PARIS"
+	selfTestSampleRate = 8000
+	selfTestFreq       = 700.0
+	selfTestSeed       = 1 // 固定噪声种子，同样的参数总是得到同样的结果
+)
+
+// SelfTest 解码质量自检：以 wpm 速度生成 "PARIS PARIS PARIS"，按 snrDB 叠加噪声后
+// 用 ExperimentalDecoder 解码，返回字符错误率 (0 表示完全正确，见 CharacterErrorRate)
+// 可用来确认构建和解码器工作正常，也可作为回归测试的入口。
+func SelfTest(wpm, snrDB float64) (cer float64, err error) {
+	return selfTest(wpm, snrDB, BeamDecoder.NewLanguageModel())
+}
+
+func selfTest(wpm, snrDB float64, lm *BeamDecoder.LanguageModel) (float64, error) {
+	if wpm <= 0 {
+		return 0, fmt.Errorf("invalid self-test WPM: %v", wpm)
+	}
+
+	audio := GenerateCW(selfTestText, AudioConfig{WPM: wpm, SampleRate: selfTestSampleRate, Frequency: selfTestFreq})
+	audio = noisyRecording(audio, selfTestSampleRate, 2, ChannelEffects{SNRdB: snrDB, Seed: selfTestSeed})
+
+	cfg := DefaultConfig()
+	cfg.Decoder.InitialWPM = wpm
+	dec := newExperimentalDecoder(selfTestSampleRate, selfTestFreq, cfg, lm)
+	defer dec.Stop()
+	return CharacterErrorRate(selfTestText, decodeSamples(dec, audio)), nil
+}
+
+// CharacterErrorRate 计算字符错误率：编辑距离 (Levenshtein) 除以参考文本长度
+// 首尾空白不计；参考文本为空时，假设也为空返回 0，否则返回 1。
+func CharacterErrorRate(reference, hypothesis string) float64 {
+	ref := []rune(strings.TrimSpace(reference))
+	hyp := []rune(strings.TrimSpace(hypothesis))
+	if len(ref) == 0 {
+		if len(hyp) == 0 {
+			return 0
+		}
+		return 1
+	}
+
+	// 只保留两行的动态规划
+	prev := make([]int, len(hyp)+1)
+	cur := make([]int, len(hyp)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ref); i++ {
+		cur[0] = i
+		for j := 1; j <= len(hyp); j++ {
+			cost := 1
+			if ref[i-1] == hyp[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return float64(prev[len(hyp)]) / float64(len(ref))
+}
@@ -0,0 +1,50 @@
+package cw
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCharacterErrorRate(t *testing.T) {
+	tests := []struct {
+		ref, hyp string
+		want     float64
+	}{
+		{"PARIS", "PARIS", 0},
+		{"PARIS", " PARIS ", 0},
+		{"PARIS", "PARTS", 0.2},
+		{"PARIS", "PAIS", 0.2},
+		{"PARIS", "", 1},
+		{"", "", 0},
+		{"", "E", 1},
+	}
+	for _, tt := range tests {
+		if got := CharacterErrorRate(tt.ref, tt.hyp); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("CharacterErrorRate(%q, %q) = %v, want %v", tt.ref, tt.hyp, got, tt.want)
+		}
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	lm := newTestLanguageModel()
+	for _, wpm := range []float64{15, 20, 30} {
+		cer, err := selfTest(wpm, 10, lm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cer != 0 {
+			t.Errorf("%v WPM at 10 dB: expected clean decode, got CER %v", wpm, cer)
+		}
+	}
+
+	// 噪声确实被加上了，且固定种子下结果可重复
+	cer1, _ := selfTest(20, -5, lm)
+	cer2, _ := selfTest(20, -5, lm)
+	if cer1 == 0 || cer1 != cer2 {
+		t.Errorf("Expected reproducible errors at -5 dB, got %v and %v", cer1, cer2)
+	}
+
+	if _, err := selfTest(0, 10, lm); err == nil {
+		t.Error("Expected error for zero WPM")
+	}
+}
@@ -2,10 +2,10 @@ package cw
 
 import (
 	"context"
-	"fmt"
 	"math"
 	"math/cmplx"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/mjibson/go-dsp/fft"
@@ -25,7 +25,9 @@ type SpectrumMonitor struct {
 
 	// 通信
 	audioInChan       chan []float32     // 从主线程接收音频数据
+	centerChan        chan float64       // SetCenterFreq -> 后台线程
 	OnFrequencyUpdate func(freq float64) // 回调函数，通知系统更新频率
+	OnLockLost        func()             // 信号丢失超过 Monitor.LockLossAfter 时回调 (后台线程调用)
 
 	// 内部状态
 	analyzer   *SpectrumAnalyzer // 复用现有的频谱分析器
@@ -37,6 +39,22 @@ type SpectrumMonitor struct {
 	// 频率平滑状态
 	smoothedFreq float64 // 当前平滑后的频率
 	hasLock      bool    // 是否已经锁定过一次频率
+	weakUpdates  int     // 锁定后连续低于静噪门限的分析次数
+
+	// 手动锁定 (Lock/Unlock 在任意线程调用，后台线程读)
+	lockMu   sync.Mutex
+	locked   bool
+	lockFreq float64
+
+	// 最近一次 Welch 分析的平均谱 (后台线程写，FindPeaks / Snapshot 在任意线程读)
+	spectrumMu sync.Mutex
+	spectrum   []float64
+	noiseFloor float64
+
+	// 最近一次 Welch 分析的信噪比 (后台线程写，EstimateReadability 在任意线程读)
+	snrMu  sync.Mutex
+	snrDB  float64
+	hasSNR bool
 }
 
 // NewSpectrumMonitor 创建实例
@@ -59,8 +77,9 @@ func NewSpectrumMonitor(sampleRate float64, cfg *Config, onUpdate func(float64))
 		overlap:           overlap,
 		updateInterval:    cfg.Monitor.UpdateInterval,
 		audioInChan:       make(chan []float32, 100),
+		centerChan:        make(chan float64, 1),
 		OnFrequencyUpdate: onUpdate,
-		analyzer:          NewSpectrumAnalyzer(sampleRate, fftSize),
+		analyzer:          NewSpectrumAnalyzer(sampleRate, fftSize, cfg.Monitor.Window),
 		ringBuffer:        make([]float64, bufferSize),
 		ctx:               ctx,
 		cancel:            cancel,
@@ -93,6 +112,40 @@ func (sm *SpectrumMonitor) PushAudioData(samples []float32) {
 	}
 }
 
+// SetCenterFreq 用外部校准的结果设定起始频率 (例如搜台锁定的频率)
+// 之后的跟踪从这里平滑出发，而不是从默认的 700Hz 或第一次 Welch 结果跳变。可在任意线程调用。
+func (sm *SpectrumMonitor) SetCenterFreq(freq float64) {
+	select {
+	case <-sm.centerChan: // 丢弃尚未生效的旧值
+	default:
+	}
+	sm.centerChan <- freq
+}
+
+// Lock 把跟踪锁定在 freq 附近：之后只在 ±Monitor.LockTolerance Hz 内做微调，
+// 不会跳到别处更强的信号 (避免 QSO 中途被相邻的大信号抢走)。可在任意线程调用。
+func (sm *SpectrumMonitor) Lock(freq float64) {
+	sm.lockMu.Lock()
+	sm.locked = true
+	sm.lockFreq = freq
+	sm.lockMu.Unlock()
+	sm.SetCenterFreq(freq)
+}
+
+// Unlock 解除手动锁定，恢复全频段自动跟踪
+func (sm *SpectrumMonitor) Unlock() {
+	sm.lockMu.Lock()
+	sm.locked = false
+	sm.lockMu.Unlock()
+}
+
+// lockState 返回当前是否手动锁定以及锁定的频率
+func (sm *SpectrumMonitor) lockState() (float64, bool) {
+	sm.lockMu.Lock()
+	defer sm.lockMu.Unlock()
+	return sm.lockFreq, sm.locked
+}
+
 // run 是后台运行的主循环
 func (sm *SpectrumMonitor) run() {
 	ticker := time.NewTicker(sm.updateInterval)
@@ -103,63 +156,154 @@ func (sm *SpectrumMonitor) run() {
 		case <-sm.ctx.Done():
 			return // 收到停止信号
 		case samples := <-sm.audioInChan:
-			// 将新数据写入环形缓冲区
-			for _, s := range samples {
-				sm.ringBuffer[sm.ringPos] = float64(s)
-				sm.ringPos = (sm.ringPos + 1) % len(sm.ringBuffer)
-			}
+			sm.ingest(samples)
 		case <-ticker.C:
 			// 时间到了，执行 Welch 分析
-			freq, mag, noiseFloor := sm.calculateWelch()
-
-			// --- 自适应静噪 (Adaptive Squelch) ---
-			requiredSNR := sm.cfg.Monitor.RequiredSNR
-
-			if mag > noiseFloor*requiredSNR && mag > 0.001 {
-				// --- 频率平滑更新 (Weighted Smoothing) ---
-				snr := mag / noiseFloor
-				// 计算学习率 alpha
-				alpha := sm.cfg.Monitor.AlphaBase + db(snr)/requiredSNR*sm.cfg.Monitor.AlphaGain
-				if alpha > sm.cfg.Monitor.AlphaMax {
-					alpha = sm.cfg.Monitor.AlphaMax
-				}
-
-				// 如果是第一次锁定，直接跳转，不平滑
-				if !sm.hasLock {
-					sm.smoothedFreq = freq
-					sm.hasLock = true
-					fmt.Printf("[MONITOR] Initial Lock: %.1f Hz (SNR: %.1f)\n", freq, db(snr))
-				} else {
-					// 计算频率偏差
-					diff := abs(freq - sm.smoothedFreq)
-					// 1. 如果偏差超过 20Hz (硬门限)，视为干扰或错误，直接忽略本次更新
-					//    除非 SNR 极高 (说明真的换台了)，否则保持不动
-					if diff > 20.0 && snr < 100.0 { // 100.0 linear approx 20dB
-						// 可选：打印日志调试
-						// fmt.Printf("[MONITOR] Ignored Jump: %.1f -> %.1f (Diff: %.1f)\n", sm.smoothedFreq, freq, diff)
-						return // 跳过本次循环，不更新 smoothedFreq
-					}
-					// 2. 如果偏差很小 (例如 < 2Hz)，可能是插值抖动，强制降低学习率，让数值更稳
-					currentAlpha := alpha
-					if diff < 2.0 {
-						currentAlpha = alpha * 0.1 // 极慢速微调
-					}
-
-					oldFreq := sm.smoothedFreq
-					sm.smoothedFreq = sm.smoothedFreq*(1-currentAlpha) + freq*currentAlpha
-					// 只有当频率变化超过一定阈值时才打印，避免刷屏
-					if abs(sm.smoothedFreq-oldFreq) > 2.0 {
-						fmt.Printf("[MONITOR] Frequency Update: %.1f Hz %.1f Hz -> %.1f Hz (SNR: %.1f)\n", oldFreq, freq, sm.smoothedFreq, db(snr))
-					}
-				}
-
-				if sm.OnFrequencyUpdate != nil {
-					sm.OnFrequencyUpdate(sm.smoothedFreq)
-				}
-			}
+			sm.update()
 		}
 	}
 }
+
+// ingest 将新数据写入环形缓冲区
+func (sm *SpectrumMonitor) ingest(samples []float32) {
+	for _, s := range samples {
+		sm.ringBuffer[sm.ringPos] = float64(s)
+		sm.ringPos = (sm.ringPos + 1) % len(sm.ringBuffer)
+	}
+}
+
+// update 执行一次 Welch 分析并平滑更新频率，满足条件时回调 OnFrequencyUpdate
+func (sm *SpectrumMonitor) update() {
+	select {
+	case freq := <-sm.centerChan:
+		sm.smoothedFreq = freq
+		sm.hasLock = true
+		sm.weakUpdates = 0
+	default:
+	}
+
+	freq, mag, noiseFloor := sm.calculateWelch()
+	if mag > 0 {
+		sm.snrMu.Lock()
+		sm.snrDB = db(mag / noiseFloor)
+		sm.hasSNR = true
+		sm.snrMu.Unlock()
+	}
+
+	// --- 自适应静噪 (Adaptive Squelch) ---
+	requiredSNR := sm.cfg.Monitor.RequiredSNR
+	if mag <= noiseFloor*requiredSNR || mag <= 0.001 {
+		sm.checkLockLoss()
+		return
+	}
+	sm.weakUpdates = 0
+
+	// --- 频率平滑更新 (Weighted Smoothing) ---
+	snr := mag / noiseFloor
+	// 计算学习率 alpha
+	alpha := sm.cfg.Monitor.AlphaBase + db(snr)/requiredSNR*sm.cfg.Monitor.AlphaGain
+	if alpha > sm.cfg.Monitor.AlphaMax {
+		alpha = sm.cfg.Monitor.AlphaMax
+	}
+
+	// 如果是第一次锁定，直接跳转，不平滑
+	if !sm.hasLock {
+		sm.smoothedFreq = freq
+		sm.hasLock = true
+		logger.Info("monitor initial lock", "freq_hz", freq, "snr_db", db(snr))
+	} else {
+		// 计算频率偏差
+		diff := abs(freq - sm.smoothedFreq)
+		// 1. 如果偏差超过 20Hz (硬门限)，视为干扰或错误，直接忽略本次更新
+		//    除非 SNR 极高 (说明真的换台了)，否则保持不动
+		if diff > 20.0 && snr < 100.0 { // 100.0 linear approx 20dB
+			// 可选：打印日志调试
+			// fmt.Printf("[MONITOR] Ignored Jump: %.1f -> %.1f (Diff: %.1f)\n", sm.smoothedFreq, freq, diff)
+			return // 跳过本次更新，不更新 smoothedFreq
+		}
+		// 2. 如果偏差很小 (例如 < 2Hz)，可能是插值抖动，强制降低学习率，让数值更稳
+		currentAlpha := alpha
+		if diff < 2.0 {
+			currentAlpha = alpha * 0.1 // 极慢速微调
+		}
+
+		oldFreq := sm.smoothedFreq
+		sm.smoothedFreq = sm.smoothedFreq*(1-currentAlpha) + freq*currentAlpha
+		// 只有当频率变化超过一定阈值时才打印，避免刷屏
+		if abs(sm.smoothedFreq-oldFreq) > 2.0 {
+			logger.Debug("monitor frequency update", "old_hz", oldFreq, "peak_hz", freq, "new_hz", sm.smoothedFreq, "snr_db", db(snr))
+		}
+	}
+
+	if sm.OnFrequencyUpdate != nil {
+		sm.OnFrequencyUpdate(sm.smoothedFreq)
+	}
+}
+
+// checkLockLoss 在一次没有信号的分析后调用：锁定后持续 LockLossAfter 没有信号则回调 OnLockLost
+// 手动锁定 (Lock) 时不判定丢失，等待锁定的电台回来
+func (sm *SpectrumMonitor) checkLockLoss() {
+	timeout := sm.cfg.Monitor.LockLossAfter
+	if !sm.hasLock || timeout <= 0 {
+		return
+	}
+	if _, locked := sm.lockState(); locked {
+		return
+	}
+	sm.weakUpdates++
+	if time.Duration(sm.weakUpdates)*sm.updateInterval < timeout {
+		return
+	}
+	// 等重新搜台后 SetCenterFreq 再开始判定
+	sm.hasLock = false
+	sm.weakUpdates = 0
+	logger.Info("monitor signal lost", "after", timeout)
+	if sm.OnLockLost != nil {
+		sm.OnLockLost()
+	}
+}
+
+// RST 估计的分档 (dB)
+// Welch 的 SNR 是峰值频点与中位数频点的功率比，频点只有约 2Hz 宽，数值比按 SSB 带宽测得的 SNR 高得多，
+// 因此分档整体偏高，结果只是粗略参考。
+const (
+	rstMinSNR      = 10.0 // 低于此值：R1 S1
+	rstReadStepDB  = 6.0  // R 每升一级需要的 dB
+	rstSUnitStepDB = 6.0  // S 每升一级需要的 dB (一个 S 单位 = 6dB)
+)
+
+// EstimateReadability 根据最近一次测得的 SNR 估计 RST 中的 R (1-5) 和 S (1-9)
+// 尚未完成过分析时返回 0, 0
+func (sm *SpectrumMonitor) EstimateReadability() (int, int) {
+	sm.snrMu.Lock()
+	snr, ok := sm.snrDB, sm.hasSNR
+	sm.snrMu.Unlock()
+	if !ok {
+		return 0, 0
+	}
+	return readabilityFromSNR(snr), strengthFromSNR(snr)
+}
+
+// readabilityFromSNR R1 (<10dB) 到 R5 (>=28dB)
+func readabilityFromSNR(snrDB float64) int {
+	return clampInt(1+int(math.Floor((snrDB-rstMinSNR+rstReadStepDB)/rstReadStepDB)), 1, 5)
+}
+
+// strengthFromSNR S1 (<16dB) 到 S9 (>=58dB)
+func strengthFromSNR(snrDB float64) int {
+	return clampInt(1+int(math.Floor((snrDB-rstMinSNR)/rstSUnitStepDB)), 1, 9)
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
 func abs(x float64) float64 {
 	if x < 0 {
 		return -x
@@ -173,6 +317,37 @@ func db(x float64) float64 {
 // calculateWelch 执行 Welch 平均周期图法
 // 返回: 峰值频率, 峰值功率, 噪声基底功率
 func (sm *SpectrumMonitor) calculateWelch() (float64, float64, float64) {
+	avgSpectrum, noiseFloor := sm.welchSpectrum()
+	if avgSpectrum == nil {
+		return 0, 0, 0
+	}
+
+	// 保存本次的平均谱，供 FindPeaks / Snapshot 在其他线程使用
+	sm.spectrumMu.Lock()
+	sm.spectrum = avgSpectrum
+	sm.noiseFloor = noiseFloor
+	sm.spectrumMu.Unlock()
+
+	// 在平均谱中寻找峰值，手动锁定时只看锁定频率附近
+	minFreq, maxFreq := sm.cfg.Monitor.MinFrequency, sm.cfg.Monitor.MaxFrequency
+	if lockFreq, locked := sm.lockState(); locked {
+		minFreq, maxFreq = lockFreq-sm.cfg.Monitor.LockTolerance, lockFreq+sm.cfg.Monitor.LockTolerance
+	}
+	maxMag := 0.0
+	maxIndex := 0
+	startIndex, endIndex := sm.binRange(minFreq, maxFreq, len(avgSpectrum))
+	for i := startIndex; i < endIndex; i++ {
+		if avgSpectrum[i] > maxMag {
+			maxMag = avgSpectrum[i]
+			maxIndex = i
+		}
+	}
+
+	return sm.interpolatePeak(avgSpectrum, maxIndex), maxMag, noiseFloor
+}
+
+// welchSpectrum 计算环形缓冲区的平均功率谱和噪声基底 (数据不足时返回 nil)
+func (sm *SpectrumMonitor) welchSpectrum() ([]float64, float64) {
 	numSegments := 0
 	avgSpectrum := make([]float64, sm.fftSize/2+1)
 	step := sm.fftSize - sm.overlap
@@ -199,7 +374,7 @@ func (sm *SpectrumMonitor) calculateWelch() (float64, float64, float64) {
 	}
 
 	if numSegments == 0 {
-		return 0, 0, 0
+		return nil, 0
 	}
 
 	// 4. 计算平均功率谱
@@ -220,48 +395,126 @@ func (sm *SpectrumMonitor) calculateWelch() (float64, float64, float64) {
 		noiseFloor = 1e-9
 	}
 
-	// 6. 在平均谱中寻找峰值
-	maxMag := 0.0
-	maxIndex := 0
-	binWidth := sm.sampleRate / float64(sm.fftSize)
+	return avgSpectrum, noiseFloor
+}
 
-	// 限制搜索范围
-	minFreq := sm.cfg.Monitor.MinFrequency
-	maxFreq := sm.cfg.Monitor.MaxFrequency
+// searchRange 返回 [MinFrequency, MaxFrequency) 对应的频点范围
+func (sm *SpectrumMonitor) searchRange(numBins int) (int, int) {
+	return sm.binRange(sm.cfg.Monitor.MinFrequency, sm.cfg.Monitor.MaxFrequency, numBins)
+}
 
+// binRange 返回 [minFreq, maxFreq) 对应的频点范围
+func (sm *SpectrumMonitor) binRange(minFreq, maxFreq float64, numBins int) (int, int) {
+	binWidth := sm.sampleRate / float64(sm.fftSize)
 	startIndex := int(minFreq / binWidth)
 	endIndex := int(maxFreq / binWidth)
 
 	if startIndex < 0 {
 		startIndex = 0
 	}
-	if endIndex > len(avgSpectrum) {
-		endIndex = len(avgSpectrum)
-	}
-
-	for i := startIndex; i < endIndex; i++ {
-		if avgSpectrum[i] > maxMag {
-			maxMag = avgSpectrum[i]
-			maxIndex = i
-		}
+	if endIndex > numBins {
+		endIndex = numBins
 	}
+	return startIndex, endIndex
+}
 
-	// 简单的抛物线插值，提高频率精度
-	var freq float64
-	if maxIndex > 0 && maxIndex < len(avgSpectrum)-1 {
-		alpha := avgSpectrum[maxIndex-1]
-		beta := avgSpectrum[maxIndex]
-		gamma := avgSpectrum[maxIndex+1]
+// interpolatePeak 简单的抛物线插值，提高频率精度
+func (sm *SpectrumMonitor) interpolatePeak(spectrum []float64, index int) float64 {
+	binWidth := sm.sampleRate / float64(sm.fftSize)
+	if index > 0 && index < len(spectrum)-1 {
+		alpha := spectrum[index-1]
+		beta := spectrum[index]
+		gamma := spectrum[index+1]
 		denom := alpha - 2*beta + gamma
 		if denom != 0 {
 			p := 0.5 * (alpha - gamma) / denom
-			freq = (float64(maxIndex) + p) * binWidth
-		} else {
-			freq = float64(maxIndex) * binWidth
+			return (float64(index) + p) * binWidth
 		}
-	} else {
-		freq = float64(maxIndex) * binWidth
 	}
+	return float64(index) * binWidth
+}
+
+// PeakInfo 频谱中的一个信号峰
+type PeakInfo struct {
+	Freq  float64 // 插值后的频率 (Hz)
+	Power float64 // 平均功率谱中的峰值功率
+	SNR   float64 // 相对噪声基底的信噪比 (dB)
+}
+
+// Snapshot 返回最近一次 Welch 分析的平均功率谱 (0 到 sampleRate/2 共 FFTSize/2+1 个频点) 的副本，
+// 供瀑布图等界面使用；频点对应的频率见 BinToFreq。尚未分析过时返回 nil。可在任意线程调用。
+func (sm *SpectrumMonitor) Snapshot() []float64 {
+	sm.spectrumMu.Lock()
+	defer sm.spectrumMu.Unlock()
+	if sm.spectrum == nil {
+		return nil
+	}
+	out := make([]float64, len(sm.spectrum))
+	copy(out, sm.spectrum)
+	return out
+}
+
+// BinToFreq 返回 Snapshot 中第 i 个频点的中心频率 (Hz)
+func (sm *SpectrumMonitor) BinToFreq(i int) float64 {
+	return float64(i) * sm.sampleRate / float64(sm.fftSize)
+}
+
+// FindPeaks 返回最近一次 Welch 分析中最强的 n 个信号峰 (按功率从大到小)
+// 只在 [MinFrequency, MaxFrequency) 内搜索高于噪声基底的局部极大值，
+// 相邻两个峰至少相隔 Monitor.PeakMinSpacing Hz，避免同一个信号的主瓣被算成多个峰。
+// 尚未完成过分析时返回 nil。可在任意线程调用。
+func (sm *SpectrumMonitor) FindPeaks(n int) []PeakInfo {
+	sm.spectrumMu.Lock()
+	spectrum, noiseFloor := sm.spectrum, sm.noiseFloor
+	sm.spectrumMu.Unlock()
+	if spectrum == nil || n <= 0 {
+		return nil
+	}
+
+	// 1. 收集局部极大值
+	startIndex, endIndex := sm.searchRange(len(spectrum))
+	var candidates []int
+	for i := startIndex; i < endIndex; i++ {
+		if spectrum[i] <= noiseFloor {
+			continue
+		}
+		if i > 0 && spectrum[i-1] > spectrum[i] {
+			continue
+		}
+		if i < len(spectrum)-1 && spectrum[i+1] >= spectrum[i] {
+			continue
+		}
+		candidates = append(candidates, i)
+	}
+	sort.Slice(candidates, func(a, b int) bool {
+		return spectrum[candidates[a]] > spectrum[candidates[b]]
+	})
 
-	return freq, maxMag, noiseFloor
+	// 2. 从强到弱贪心选取，跳过离已选峰太近的
+	binWidth := sm.sampleRate / float64(sm.fftSize)
+	minBins := int(math.Ceil(sm.cfg.Monitor.PeakMinSpacing / binWidth))
+	var chosen []int
+	var peaks []PeakInfo
+	for _, idx := range candidates {
+		tooClose := false
+		for _, c := range chosen {
+			if abs(float64(idx-c)) < float64(minBins) {
+				tooClose = true
+				break
+			}
+		}
+		if tooClose {
+			continue
+		}
+		chosen = append(chosen, idx)
+		peaks = append(peaks, PeakInfo{
+			Freq:  sm.interpolatePeak(spectrum, idx),
+			Power: spectrum[idx],
+			SNR:   db(spectrum[idx] / noiseFloor),
+		})
+		if len(peaks) == n {
+			break
+		}
+	}
+	return peaks
 }
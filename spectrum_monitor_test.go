@@ -0,0 +1,204 @@
+package cw
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestRSTFromSNR(t *testing.T) {
+	tests := []struct {
+		snrDB float64
+		r, s  int
+	}{
+		{0, 1, 1},
+		{9.9, 1, 1},
+		{10, 2, 1},
+		{20, 3, 2},
+		{30, 5, 4},
+		{58, 5, 9},
+		{80, 5, 9},
+	}
+	for _, tt := range tests {
+		if r, s := readabilityFromSNR(tt.snrDB), strengthFromSNR(tt.snrDB); r != tt.r || s != tt.s {
+			t.Errorf("%.1fdB: got R%d S%d, want R%d S%d", tt.snrDB, r, s, tt.r, tt.s)
+		}
+	}
+}
+
+// measureRST 把带噪声的 700Hz 音调喂给监控器并返回估计的 R/S
+func measureRST(amplitude float64) (int, int) {
+	const sampleRate = 8000
+	sm := NewSpectrumMonitor(sampleRate, nil, nil)
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]float32, len(sm.ringBuffer))
+	for i := range samples {
+		tone := amplitude * math.Sin(2*math.Pi*700*float64(i)/sampleRate)
+		samples[i] = float32(tone + 0.05*rng.NormFloat64())
+	}
+	sm.ingest(samples)
+	sm.update()
+	return sm.EstimateReadability()
+}
+
+func TestSpectrumMonitor_EstimateReadability(t *testing.T) {
+	sm := NewSpectrumMonitor(8000, nil, nil)
+	if r, s := sm.EstimateReadability(); r != 0 || s != 0 {
+		t.Errorf("Expected 0, 0 before any measurement, got %d, %d", r, s)
+	}
+
+	strongR, strongS := measureRST(0.5)
+	weakR, weakS := measureRST(0.005)
+	if strongR != 5 {
+		t.Errorf("Strong signal should be R5, got R%d", strongR)
+	}
+	if weakR >= strongR || weakS >= strongS {
+		t.Errorf("Weak signal should report lower RST: strong R%dS%d, weak R%dS%d", strongR, strongS, weakR, weakS)
+	}
+}
+
+// feedTones 把若干音调叠加 (带少量噪声) 喂给监控器并执行一次分析
+func feedTones(sm *SpectrumMonitor, freqs, amps []float64) {
+	const sampleRate = 8000
+	rng := rand.New(rand.NewSource(1))
+	samples := make([]float32, len(sm.ringBuffer))
+	for i := range samples {
+		v := 0.01 * rng.NormFloat64()
+		for k, f := range freqs {
+			v += amps[k] * math.Sin(2*math.Pi*f*float64(i)/sampleRate)
+		}
+		samples[i] = float32(v)
+	}
+	sm.ingest(samples)
+	sm.update()
+}
+
+func TestSpectrumMonitor_FindPeaks(t *testing.T) {
+	sm := NewSpectrumMonitor(8000, nil, nil)
+	if peaks := sm.FindPeaks(3); peaks != nil {
+		t.Errorf("Expected no peaks before analysis, got %v", peaks)
+	}
+
+	feedTones(sm, []float64{650, 800}, []float64{0.1, 0.3})
+	peaks := sm.FindPeaks(3)
+	if len(peaks) < 2 {
+		t.Fatalf("Expected at least 2 peaks, got %v", peaks)
+	}
+	if math.Abs(peaks[0].Freq-800) > 2 || math.Abs(peaks[1].Freq-650) > 2 {
+		t.Errorf("Expected 800Hz then 650Hz, got %.1f, %.1f", peaks[0].Freq, peaks[1].Freq)
+	}
+	if peaks[0].Power <= peaks[1].Power || peaks[1].SNR < 20 {
+		t.Errorf("Unexpected peak power/SNR: %+v", peaks[:2])
+	}
+	if got := sm.FindPeaks(1); len(got) != 1 || got[0] != peaks[0] {
+		t.Errorf("FindPeaks(1) should return the strongest peak, got %v", got)
+	}
+
+	// 间距小于 PeakMinSpacing 的两个音调只算一个峰
+	sm = NewSpectrumMonitor(8000, nil, nil)
+	feedTones(sm, []float64{700, 712}, []float64{0.3, 0.2})
+	for _, p := range sm.FindPeaks(3)[1:] {
+		if math.Abs(p.Freq-700) < sm.cfg.Monitor.PeakMinSpacing {
+			t.Errorf("Peak %.1fHz is too close to 700Hz", p.Freq)
+		}
+	}
+}
+
+func TestSpectrumMonitor_Lock(t *testing.T) {
+	var reported float64
+	sm := NewSpectrumMonitor(8000, nil, func(f float64) { reported = f })
+
+	// 锁定在较弱的 705Hz 附近，旁边 800Hz 的强信号不能抢走锁定，只做微调
+	sm.Lock(705)
+	for i := 0; i < 20; i++ {
+		feedTones(sm, []float64{700, 800}, []float64{0.05, 0.5})
+	}
+	if reported > 705 || reported < 700 {
+		t.Errorf("Expected locked tracking to stay between 700 and 705Hz, got %.1f", reported)
+	}
+	if reported > 704.9 {
+		t.Errorf("Expected a micro-correction towards 700Hz, still at %.1f", reported)
+	}
+
+	// 解锁后恢复自动跟踪，平滑地移向最强的信号，离开锁定范围
+	sm.Unlock()
+	for i := 0; i < 20; i++ {
+		feedTones(sm, []float64{700, 800}, []float64{0.05, 0.5})
+	}
+	if reported < 705+sm.cfg.Monitor.LockTolerance {
+		t.Errorf("Expected tracking to move towards 800Hz after Unlock, got %.1f", reported)
+	}
+}
+
+func TestSpectrumMonitor_LockLost(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Monitor.LockLossAfter = time.Second // 5 次分析 (200ms)
+	lost := 0
+	sm := NewSpectrumMonitor(8000, cfg, nil)
+	sm.OnLockLost = func() { lost++ }
+
+	sm.SetCenterFreq(700)
+	feedTones(sm, []float64{700}, []float64{0.5})
+	for i := 0; i < 4; i++ {
+		feedTones(sm, nil, nil)
+	}
+	if lost != 0 {
+		t.Fatalf("Signal lost reported too early")
+	}
+	// 信号短暂回来会重新计时
+	feedTones(sm, []float64{700}, []float64{0.5})
+	for i := 0; i < 4; i++ {
+		feedTones(sm, nil, nil)
+	}
+	if lost != 0 {
+		t.Fatalf("Expected the returning signal to restart the timeout")
+	}
+	for i := 0; i < 10; i++ {
+		feedTones(sm, nil, nil)
+	}
+	if lost != 1 {
+		t.Errorf("Expected exactly one loss report, got %d", lost)
+	}
+
+	// 手动锁定时不判定丢失
+	lost = 0
+	sm.Lock(700)
+	for i := 0; i < 10; i++ {
+		feedTones(sm, nil, nil)
+	}
+	if lost != 0 {
+		t.Errorf("Expected no loss report while locked, got %d", lost)
+	}
+}
+
+func TestSpectrumMonitor_Snapshot(t *testing.T) {
+	sm := NewSpectrumMonitor(8000, nil, nil)
+	if s := sm.Snapshot(); s != nil {
+		t.Errorf("Expected no spectrum before analysis, got %d bins", len(s))
+	}
+
+	feedTones(sm, []float64{750}, []float64{0.3})
+	snap := sm.Snapshot()
+	if len(snap) != sm.cfg.Monitor.FFTSize/2+1 {
+		t.Fatalf("Expected %d bins, got %d", sm.cfg.Monitor.FFTSize/2+1, len(snap))
+	}
+	if f := sm.BinToFreq(len(snap) - 1); f != 4000 {
+		t.Errorf("Last bin should be at Nyquist, got %.1fHz", f)
+	}
+	peak := 0
+	for i, p := range snap {
+		if p > snap[peak] {
+			peak = i
+		}
+	}
+	if f := sm.BinToFreq(peak); math.Abs(f-750) > 2 {
+		t.Errorf("Expected the strongest bin at 750Hz, got %.1fHz", f)
+	}
+
+	// 返回的是副本，修改不影响内部状态
+	snap[peak] = 0
+	if sm.Snapshot()[peak] == 0 {
+		t.Error("Snapshot should return a copy")
+	}
+}
@@ -0,0 +1,157 @@
+package cw
+
+import (
+	"math"
+	"math/cmplx"
+	"sync/atomic"
+
+	"github.com/mjibson/go-dsp/fft"
+)
+
+const (
+	squelchBlockSec      = 0.064 // 判决块长度
+	squelchHoldSec       = 3.0   // 最后一个 CW 块之后保持开启的时间 (跨过字间隔和短暂衰落)
+	squelchMinFreq       = 200.0 // 判决频段 (Hz)
+	squelchMaxFreq       = 3000.0
+	squelchPeakWidthHz   = 60.0 // 峰值两侧计入"单音能量"的宽度
+	squelchMaxFlatness   = 0.3  // 频谱平坦度上限：白噪声接近 1，单音接近 0
+	squelchMinPeakShare  = 0.5  // 峰值附近能量占频段能量的最低比例
+	squelchMinZCRRatio   = 0.6  // 过零率推算的频率 / 峰值频率 的允许范围 (噪声会把过零率抬高)
+	squelchMaxZCRRatio   = 1.8
+	squelchMinBlockPower = 1e-8 // 低于此均方值视为无信号
+)
+
+// squelchGate 过零率 / 频谱平坦度预判：音频不像 CW (宽带噪声、语音、空频段) 时置零，避免解码器输出乱码。
+// 按固定块判决，延迟一个块输出，使每个块由它自己的判决决定；判为 CW 后保持开启 squelchHoldSec。
+// 与 muteGate 一样输出静音而不是丢弃，解码器的时间基准保持连续。
+type squelchGate struct {
+	enabled atomic.Bool // SetSquelchMode 在任意线程写，音频线程读
+
+	sampleRate float64
+	blockSize  int
+	pending    []float32 // 尚未凑满一块的输入
+	out        []float32 // 已判决、等待输出的音频
+	holdLeft   int       // 剩余保持开启的采样点数
+}
+
+// apply 返回静噪处理后的音频 (长度与输入相同，不修改调用方的切片)
+func (g *squelchGate) apply(samples []float32, sampleRate float64) []float32 {
+	if !g.enabled.Load() {
+		if g.blockSize != 0 {
+			g.reset(0)
+		}
+		return samples
+	}
+	if g.sampleRate != sampleRate || g.blockSize == 0 {
+		g.reset(sampleRate)
+	}
+
+	g.pending = append(g.pending, samples...)
+	for len(g.pending) >= g.blockSize {
+		block := g.pending[:g.blockSize]
+		if isCWLike(block, g.sampleRate) {
+			g.holdLeft = int(squelchHoldSec * g.sampleRate)
+		}
+		if g.holdLeft > 0 {
+			g.out = append(g.out, block...)
+			g.holdLeft -= g.blockSize
+		} else {
+			g.out = append(g.out, make([]float32, g.blockSize)...)
+		}
+		g.pending = g.pending[g.blockSize:]
+	}
+
+	// 输出长度与输入一致：开头不足的部分用静音补齐 (仅在刚开启时出现)
+	result := make([]float32, len(samples))
+	n := len(g.out)
+	if n > len(samples) {
+		n = len(samples)
+	}
+	copy(result[len(samples)-n:], g.out[:n])
+	g.out = append(g.out[:0], g.out[n:]...)
+	g.pending = append([]float32(nil), g.pending...)
+	return result
+}
+
+// reset 清空内部缓冲 (sampleRate 为 0 表示关闭)
+func (g *squelchGate) reset(sampleRate float64) {
+	g.sampleRate = sampleRate
+	g.blockSize = int(squelchBlockSec * sampleRate)
+	g.pending = nil
+	g.out = nil
+	g.holdLeft = 0
+}
+
+// isCWLike 判断一块音频是否像 CW 单音：
+// 频谱不平坦、能量集中在峰值附近，且过零率与峰值频率大致吻合 (语音的过零率由多个共振峰决定，通常对不上)。
+func isCWLike(block []float32, sampleRate float64) bool {
+	var power float64
+	input := make([]float64, len(block))
+	for i, v := range block {
+		input[i] = float64(v)
+		power += input[i] * input[i]
+	}
+	if power/float64(len(block)) < squelchMinBlockPower {
+		return false
+	}
+
+	spectrum := fft.FFTReal(input)
+	binHz := sampleRate / float64(len(block))
+	lo := int(squelchMinFreq / binHz)
+	hi := int(squelchMaxFreq / binHz)
+	if hi > len(spectrum)/2 {
+		hi = len(spectrum) / 2
+	}
+	if hi <= lo {
+		return false
+	}
+
+	mags := make([]float64, 0, hi-lo)
+	var total, logSum float64
+	peakIdx, peakMag := 0, 0.0
+	for i := lo; i < hi; i++ {
+		m := cmplx.Abs(spectrum[i])
+		p := m*m + 1e-20
+		mags = append(mags, p)
+		total += p
+		logSum += math.Log(p)
+		if m > peakMag {
+			peakIdx, peakMag = i-lo, m
+		}
+	}
+
+	// 频谱平坦度 = 几何平均 / 算术平均
+	flatness := math.Exp(logSum/float64(len(mags))) / (total / float64(len(mags)))
+	if flatness > squelchMaxFlatness {
+		return false
+	}
+
+	width := int(squelchPeakWidthHz / binHz)
+	var peakPower float64
+	for i := peakIdx - width; i <= peakIdx+width; i++ {
+		if i >= 0 && i < len(mags) {
+			peakPower += mags[i]
+		}
+	}
+	if peakPower/total < squelchMinPeakShare {
+		return false
+	}
+
+	// 过零率在判决频段内的带限信号上计算，避免采样率较高时频段外的噪声主导过零
+	band := make([]complex128, len(spectrum))
+	for i := lo; i < hi; i++ {
+		band[i] = spectrum[i]
+		band[len(spectrum)-i] = spectrum[len(spectrum)-i]
+	}
+	filtered := fft.IFFT(band)
+	crossings := 0
+	for i := 1; i < len(filtered); i++ {
+		if (real(filtered[i-1]) >= 0) != (real(filtered[i]) >= 0) {
+			crossings++
+		}
+	}
+	zcrFreq := float64(crossings) * sampleRate / float64(2*(len(filtered)-1))
+	peakFreq := float64(peakIdx+lo) * binHz
+	ratio := zcrFreq / peakFreq
+	return ratio >= squelchMinZCRRatio && ratio <= squelchMaxZCRRatio
+}
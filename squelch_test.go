@@ -0,0 +1,90 @@
+package cw
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// voiceLike 合成类似浊音的信号：基频 120-160Hz 缓慢变化，谐波按三个共振峰加权
+func voiceLike(seconds float64, sampleRate int) []float32 {
+	formants := []float64{500, 1500, 2500}
+	out := make([]float32, int(seconds*float64(sampleRate)))
+	phase := 0.0
+	for i := range out {
+		t := float64(i) / float64(sampleRate)
+		f0 := 140 + 20*math.Sin(2*math.Pi*1.5*t)
+		phase += 2 * math.Pi * f0 / float64(sampleRate)
+		var v float64
+		for h := 1; float64(h)*f0 < 3500; h++ {
+			fh := float64(h) * f0
+			var gain float64
+			for _, fm := range formants {
+				gain += math.Exp(-math.Pow((fh-fm)/150, 2))
+			}
+			v += gain * math.Sin(float64(h)*phase)
+		}
+		out[i] = float32(0.1 * v)
+	}
+	return out
+}
+
+func countNonZero(samples []float32) int {
+	n := 0
+	for _, v := range samples {
+		if v != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func runSquelch(samples []float32, sampleRate int) []float32 {
+	var g squelchGate
+	g.enabled.Store(true)
+	var out []float32
+	for i := 0; i < len(samples); i += 1024 {
+		end := min(i+1024, len(samples))
+		chunk := g.apply(samples[i:end], float64(sampleRate))
+		if len(chunk) != end-i {
+			panic("squelch changed chunk length")
+		}
+		out = append(out, chunk...)
+	}
+	return out
+}
+
+func TestSquelchGate_RejectsNoiseAndVoice(t *testing.T) {
+	const sr = 8000
+	rng := rand.New(rand.NewSource(1))
+	noise := make([]float32, 5*sr)
+	for i := range noise {
+		noise[i] = float32(0.1 * rng.NormFloat64())
+	}
+	if n := countNonZero(runSquelch(noise, sr)); n != 0 {
+		t.Errorf("noise: %d samples passed, want 0", n)
+	}
+	if n := countNonZero(runSquelch(voiceLike(5, sr), sr)); n != 0 {
+		t.Errorf("voice: %d samples passed, want 0", n)
+	}
+}
+
+func TestSquelchGate_PassesCW(t *testing.T) {
+	for _, sr := range []int{8000, 48000} {
+		audio := GenerateCW("CQ CQ DE BG2XYZ K", AudioConfig{WPM: 20, SampleRate: sr, Frequency: 700})
+		audio = ApplyEffects(audio, sr, ChannelEffects{SNRdB: 0, Seed: 3})
+		out := runSquelch(audio, sr)
+		// 除了开头等待第一个判决块的部分，其余音频都应原样通过
+		if n := countNonZero(out); n < len(audio)*9/10 {
+			t.Errorf("sr=%d: %d/%d samples passed", sr, n, len(audio))
+		}
+	}
+}
+
+func TestSquelchGate_DisabledPassesThrough(t *testing.T) {
+	var g squelchGate
+	in := []float32{0.1, -0.2, 0.3}
+	if out := g.apply(in, 8000); &out[0] != &in[0] {
+		t.Error("disabled gate should return input unchanged")
+	}
+}
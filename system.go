@@ -1,11 +1,13 @@
 package cw
 
 import (
+	"cw/adif"
 	"fmt"
-	"log"
+	"io"
 	"math"
-	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -17,30 +19,100 @@ type CWSystem struct {
 	AudioDeviceName string
 	SerialPort      string
 	BaudRate        int
+	RadioAddress    byte          // 电台 CI-V 地址
+	TxWPM           float64       // 电台内置电键的发送速度，用于估算发射时长 (发射期间静音解码器)
+	ReplayChannel   ChannelSelect // 回放多声道 WAV 时使用的声道
 
 	// 组件
 	civClient    *CIVClient
-	decoder      CWDecoder // 使用接口
+	civMu        sync.Mutex    // 串行化 civClient 的访问 (启动同步 / 控制台发送 / 后台轮询频率)
+	radioFreq    atomic.Int64  // 最近一次读到的电台频率 (Hz)，0 = 未知；解码线程只读这个缓存，不访问串口
+	radioStop    chan struct{} // Stop 时关闭，结束频率轮询
+	decoderType  DecoderType   // Start 时创建的解码器类型
+	decoder      CWDecoder     // 使用接口 (SetDecoder 设置后优先使用)
 	analyzer     *SpectrumAnalyzer
+	freqMu       sync.Mutex // 保护 pendingFreq (SpectrumMonitor 线程写，音频线程读)
+	pendingFreq  float64    // SpectrumMonitor 跟踪到、尚未交给解码器的频率 (0 = 无)
 	audioCapture *AudioCapture
 	wavReader    *WavReader
 	wavWriter    *WavWriter
 
 	// 状态
-	isCalibrated      bool
-	calibrationBuffer []float64
-	replayFile        string
-	recordFile        string
+	isCalibrated bool
+	replayFile   string
+	replayStream io.Reader // 回放数据流 (管道/网络)，优先于 replayFile
+	recordFile   string
+	replayMu     sync.Mutex    // 串行化回放循环与 Pause/Resume/Seek
+	replayPaused bool          // 回放已暂停 (到达文件末尾时自动暂停)
+	replaySpeed  float64       // 回放速度倍率，0 表示 1x (受 replayMu 保护)
+	replayStop   chan struct{} // Stop 时关闭，结束回放循环
 
 	// 回调
-	OnTextDecoded   func(text string) // 当解码出文本时回调
-	spectrumMonitor *SpectrumMonitor
+	OnTextDecoded    func(text string)                     // 当解码出文本时回调 (系统不打印解码文本)
+	OnSymbol         func(sym string, durationMs float64)  // 码元回调，解码器实现 SymbolNotifier 时生效
+	OnCharConfidence func(char string, confidence float64) // 字符置信度回调，解码器实现 ConfidenceNotifier 时生效
+	OnReplayEnd      func()                                // 回放到达文件末尾时回调 (在回放线程中调用)
+	qsoLog           *QSOLog                               // 从解码文本中收集呼号
+	spectrumMonitor  *SpectrumMonitor
 
 	// 新增状态字段
 	calibrationState int       // 0: 噪声校准, 1: 信号锁定, 2: 正在解码
 	noiseFloor       float64   // 测量到的噪声基底
 	noiseSampleCount int       // 已采样的噪声帧数
 	calibStartTime   time.Time // 校准开始时间
+
+	// 噪声校准 (Calibrate)
+	noiseStats     NoiseStats        // 最近一次噪声校准结果 (Samples == 0 表示未校准)
+	calibTarget    int               // 本次噪声校准需要的采样点数
+	calibSDR       *SDRDemodulator   // 校准专用解调器，测量噪声包络
+	calibEnvelopes []float64         // 降采样后的噪声包络
+	calibPeaks     []float64         // 每帧 FFT 的频段峰值 (归一化)
+	calibReq       chan calibRequest // Calibrate -> 音频线程
+	calibDone      chan calibResult  // 音频线程 -> Calibrate (只保留最新的一个结果)
+	calibID        uint64            // 正在进行的噪声校准对应的请求编号 (音频线程)
+	calibSeq       atomic.Uint64     // 已发出的校准请求编号
+	recalReq       chan struct{}     // ForceRecalibrate / 信号丢失 -> 音频线程
+
+	squelch   squelchGate      // 非 CW 音频静噪 (SetSquelchMode)
+	formatter *OutputFormatter // OnTextDecoded 之前的大小写和分隔符转换 (Start 时按配置创建)
+
+	// 界面显示用的实时状态 (音频线程写，任意线程读；math.Float64bits 编码)
+	liveWPM atomic.Uint64
+	liveSNR atomic.Uint64
+}
+
+// DecoderType 可选的解码器类型
+type DecoderType int
+
+const (
+	DecoderExperimental DecoderType = iota // SDR I/Q 解调 + Beam Search (默认)
+	DecoderCluster                         // SDR I/Q 解调 + K-Means 聚类
+	DecoderAdaptive                        // 低通包络 + 自适应贝叶斯分类
+	DecoderGoertzel                        // 分块 Goertzel + Beam Search，适合低功耗设备
+)
+
+var decoderTypeNames = map[DecoderType]string{
+	DecoderExperimental: "experimental",
+	DecoderCluster:      "cluster",
+	DecoderAdaptive:     "adaptive",
+	DecoderGoertzel:     "goertzel",
+}
+
+func (t DecoderType) String() string {
+	if name, ok := decoderTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("DecoderType(%d)", int(t))
+}
+
+// ParseDecoderType 将名称 (experimental/cluster/adaptive/goertzel) 解析为 DecoderType
+func ParseDecoderType(name string) (DecoderType, error) {
+	for t, n := range decoderTypeNames {
+		if strings.EqualFold(n, name) {
+			return t, nil
+		}
+	}
+	return DecoderExperimental, fmt.Errorf("unknown decoder type %q", name)
 }
 
 // 定义常量状态
@@ -58,8 +130,71 @@ func NewCWSystem() *CWSystem {
 		AudioDeviceName:  "USB Audio CODEC",
 		SerialPort:       "/dev/tty.SLAB_USBtoUART",
 		BaudRate:         115200,
-		calibrationState: StateSignalLock, // 默认先做噪声校准
+		RadioAddress:     CIV_ADDR_7300,
+		TxWPM:            20,
+		calibrationState: StateSignalLock, // 默认直接搜台，调用 Calibrate 可先做噪声校准
+		calibReq:         make(chan calibRequest, 1),
+		calibDone:        make(chan calibResult, 1),
+		recalReq:         make(chan struct{}, 1),
+	}
+}
+
+// NewCWSystemWithDecoder 创建使用指定解码器类型的系统实例
+func NewCWSystemWithDecoder(t DecoderType) *CWSystem {
+	s := NewCWSystem()
+	s.decoderType = t
+	return s
+}
+
+// SetConfig 替换系统使用的配置 (需在 Start 之前调用)，nil 表示恢复默认配置
+func (s *CWSystem) SetConfig(cfg *Config) {
+	if cfg == nil {
+		cfg = DefaultConfig()
 	}
+	s.cfg = cfg
+}
+
+// SetInitialWPM 设置已知的发送速度 (需在 Start 之前调用)，0 表示自动估计
+func (s *CWSystem) SetInitialWPM(wpm float64) {
+	s.cfg.Decoder.InitialWPM = wpm
+}
+
+// SetDecoder 使用自定义解码器 (需在 Start 之前调用)，优先于 DecoderType
+// Start 会接管解码器的 OnDecoded 回调，请改用 OnTextDecoded；输出按完整文本处理。
+func (s *CWSystem) SetDecoder(d CWDecoder) {
+	s.decoder = d
+}
+
+// SetSquelchMode 开启/关闭静噪：音频看起来不像 CW (宽带噪声、语音) 时不送入解码器，避免输出乱码。
+// 判决有一个块 (约 64ms) 的延迟；可在任意线程调用。
+func (s *CWSystem) SetSquelchMode(on bool) {
+	s.squelch.enabled.Store(on)
+}
+
+// newDecoder 按 decoderType 创建解码器
+func (s *CWSystem) newDecoder(targetFreq float64) (CWDecoder, error) {
+	if err := s.cfg.Validate(); err != nil {
+		return nil, err
+	}
+	sampleRate := float64(s.SampleRate)
+	switch s.decoderType {
+	case DecoderExperimental:
+		// 使用 ExperimentalDecoder (硬编码阈值版本)
+		return NewExperimentalDecoderWithConfig(sampleRate, targetFreq, s.cfg), nil
+	case DecoderCluster:
+		return NewClusterDecoder(sampleRate, targetFreq, s.cfg), nil
+	case DecoderAdaptive:
+		wpm := 20.0
+		if s.cfg.Decoder.InitialWPM > 0 {
+			wpm = s.cfg.Decoder.InitialWPM
+		}
+		d := NewAdaptiveCWDecoder(sampleRate, targetFreq, wpm)
+		d.UnknownChar = s.cfg.Decoder.UnknownChar
+		return d, nil
+	case DecoderGoertzel:
+		return NewGoertzelDecoderWithConfig(sampleRate, targetFreq, s.cfg), nil
+	}
+	return nil, fmt.Errorf("unknown decoder type %v", s.decoderType)
 }
 
 // EnableRecording 开启录音
@@ -72,51 +207,96 @@ func (s *CWSystem) SetReplayFile(filename string) {
 	s.replayFile = filename
 }
 
+// SetReplayStream 设置回放数据流 (设置后将进入回放模式)
+// 可以是 os.Stdin、网络连接或 http.Response.Body 等不可 Seek 的 WAV 流
+func (s *CWSystem) SetReplayStream(r io.Reader) {
+	s.replayStream = r
+}
+
+// isReplay 是否处于回放模式
+func (s *CWSystem) isReplay() bool {
+	return s.replayFile != "" || s.replayStream != nil
+}
+
 // Start 启动系统
 func (s *CWSystem) Start() error {
-	fmt.Print("\033[2J\033[H")
 	// 1. 初始化组件
-	if s.replayFile != "" {
+	if s.replayStream != nil {
+		// 回放模式：从数据流读取采样率
+		var err error
+		s.wavReader, err = NewWavReaderFromStream(s.replayStream)
+		if err != nil {
+			return fmt.Errorf("failed to open replay stream: %v", err)
+		}
+		s.wavReader.Channel = s.ReplayChannel
+		s.SampleRate = s.wavReader.SampleRate
+		logger.Info("replay mode", "source", "stream", "rate_hz", s.SampleRate)
+	} else if s.replayFile != "" {
 		// 回放模式：从文件读取采样率
 		var err error
 		s.wavReader, err = NewWavReader(s.replayFile)
 		if err != nil {
 			return fmt.Errorf("failed to open replay file: %v", err)
 		}
+		s.wavReader.Channel = s.ReplayChannel
 		s.SampleRate = s.wavReader.SampleRate
-		fmt.Printf("Mode: REPLAY (%s, %dHz)\n", s.replayFile, s.SampleRate)
+		logger.Info("replay mode", "source", s.replayFile, "rate_hz", s.SampleRate)
 	} else {
 		// 实时模式：尝试连接电台
-		s.civClient = NewCIVClient(s.SerialPort, s.BaudRate)
-		fmt.Printf("Connecting to radio on %s...\n", s.SerialPort)
+		s.civClient = NewCIVClientAddr(s.SerialPort, s.BaudRate, s.RadioAddress)
+		logger.Info("connecting to radio", "port", s.SerialPort)
 		if err := s.civClient.Open(); err != nil {
-			log.Printf("Warning: Could not open serial port: %v\n", err)
+			logger.Warn("could not open serial port", "port", s.SerialPort, "err", err)
 			s.civClient = nil
 		} else {
-			fmt.Println("Serial port opened.")
+			logger.Info("serial port opened")
+			s.syncWithRadio()
+			s.radioStop = make(chan struct{})
+			go s.pollRadioFrequency(s.radioStop, radioPollInterval)
 		}
 	}
 
 	// 初始化 DSP 组件
-	// 使用 ExperimentalDecoder (硬编码阈值版本)
-	s.decoder = NewExperimentalDecoder(float64(s.SampleRate), 703)
-	s.analyzer = NewSpectrumAnalyzer(float64(s.SampleRate), 4096)
+	if s.decoder == nil {
+		decoder, err := s.newDecoder(s.cfg.initialFreq())
+		if err != nil {
+			return err
+		}
+		s.decoder = decoder
+	}
+	if inv, ok := s.decoder.(sidebandInverter); ok && s.cfg.SDR.SidebandInvert {
+		inv.SetSidebandInvert(true)
+	}
+	s.qsoLog = NewQSOLog(s.IncrementalOutput())
+	s.qsoLog.FreqFunc = s.radioFrequency
+	s.qsoLog.RSTFunc = s.EstimatedRST
+	s.formatter = NewOutputFormatter(s.cfg)
+	s.decoder.SetOnDecoded(s.handleDecodedText)
+	if n, ok := s.decoder.(SymbolNotifier); ok && s.OnSymbol != nil {
+		n.SetOnSymbol(s.OnSymbol)
+	}
+	if n, ok := s.decoder.(ConfidenceNotifier); ok && s.OnCharConfidence != nil {
+		n.SetOnCharConfidence(s.OnCharConfidence)
+	}
+	s.analyzer = NewSpectrumAnalyzer(float64(s.SampleRate), 4096, WindowHanning)
 
 	s.spectrumMonitor = NewSpectrumMonitor(float64(s.SampleRate), s.cfg, s.handleFrequencyUpdate)
+	s.spectrumMonitor.OnLockLost = s.ForceRecalibrate
 	s.spectrumMonitor.Start()
 	// 初始化录音 (仅在实时模式或显式要求时)
-	if s.recordFile != "" && s.replayFile == "" {
+	if s.recordFile != "" && !s.isReplay() {
 		var err error
 		s.wavWriter, err = NewWavWriter(s.recordFile, s.SampleRate)
 		if err != nil {
 			return fmt.Errorf("failed to create wav file: %v", err)
 		}
-		fmt.Printf("Recording audio to %s\n", s.recordFile)
+		logger.Info("recording audio", "file", s.recordFile)
 	}
 
 	// 2. 启动音频流
-	if s.replayFile != "" {
-		go s.runReplayLoop()
+	if s.isReplay() {
+		s.replayStop = make(chan struct{})
+		go s.runReplayLoop(s.replayStop)
 	} else {
 		if err := s.startAudioCapture(); err != nil {
 			return err
@@ -128,23 +308,202 @@ func (s *CWSystem) Start() error {
 
 // Stop 停止系统并释放资源
 func (s *CWSystem) Stop() {
+	// 等待正在处理的音频块结束，之后回放循环不会再碰解码器和 wavReader
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	if s.replayStop != nil {
+		close(s.replayStop)
+		s.replayStop = nil
+	}
 	if s.audioCapture != nil {
 		s.audioCapture.Stop()
 	}
 	if s.wavWriter != nil {
-		fmt.Println("\nSaving recording...")
-		s.wavWriter.Close()
-		fmt.Println("Recording saved.")
+		if err := s.wavWriter.Close(); err != nil {
+			logger.Error("saving recording failed", "file", s.recordFile, "err", err)
+		} else {
+			logger.Info("recording saved", "file", s.recordFile)
+		}
 	}
 	if s.wavReader != nil {
 		s.wavReader.Close()
 	}
+	if s.radioStop != nil {
+		close(s.radioStop)
+		s.radioStop = nil
+	}
 	if s.civClient != nil {
+		// 等待进行中的轮询或发送结束再关闭串口
+		s.civMu.Lock()
 		s.civClient.Close()
+		s.civMu.Unlock()
+	}
+	if s.spectrumMonitor != nil {
+		s.spectrumMonitor.Stop()
 	}
 	s.decoder.Stop()
 }
 
+// Calibrate 在安静的频段上监听 duration 时长，测量噪声包络的分布，
+// 并据此设置解码器的初始阈值和搜台静噪门限，而不是依赖硬编码的默认值。
+// 必须在 Start 之后调用，调用会阻塞直到测量完成。测量结束后系统回到搜台状态。
+func (s *CWSystem) Calibrate(duration time.Duration) (NoiseStats, error) {
+	if s.decoder == nil {
+		return NoiseStats{}, fmt.Errorf("system not started")
+	}
+	if duration <= 0 {
+		return NoiseStats{}, fmt.Errorf("invalid calibration duration: %v", duration)
+	}
+
+	// 之前超时的请求可能在之后才完成，按编号丢弃旧结果
+	id := s.calibSeq.Add(1)
+	select {
+	case s.calibReq <- calibRequest{id: id, duration: duration}:
+	default:
+		return NoiseStats{}, fmt.Errorf("calibration already in progress")
+	}
+
+	timeout := time.After(duration + calibTimeoutSlack)
+	for {
+		select {
+		case res := <-s.calibDone:
+			if res.id == id {
+				return res.stats, nil
+			}
+		case <-timeout:
+			return NoiseStats{}, fmt.Errorf("calibration timed out (no audio?)")
+		}
+	}
+}
+
+// calibRequest Calibrate 发给音频线程的噪声校准请求
+type calibRequest struct {
+	id       uint64
+	duration time.Duration
+}
+
+// calibResult 音频线程返回的噪声校准结果，id 与请求对应
+type calibResult struct {
+	id    uint64
+	stats NoiseStats
+}
+
+// calibTimeoutSlack Calibrate 在测量时长之外最多再等待的时间 (测试中缩短)
+var calibTimeoutSlack = 5 * time.Second
+
+// ForceRecalibrate 放弃当前锁定的频率，回到搜台状态重新寻找信号 (例如电台换台之后)。
+// 信号持续丢失超过 Monitor.LockLossAfter 时系统会自动调用。可在任意线程调用。
+func (s *CWSystem) ForceRecalibrate() {
+	select {
+	case s.recalReq <- struct{}{}:
+	default: // 已有尚未处理的请求
+	}
+}
+
+// restartSignalSearch 在音频线程中处理重新搜台请求 (噪声校准期间忽略)
+func (s *CWSystem) restartSignalSearch() {
+	if s.calibrationState != StateDecoding {
+		return
+	}
+	s.decoder.Flush()
+	s.isCalibrated = false
+	s.calibrationState = StateSignalLock
+	s.analyzer.Reset()
+	s.freqMu.Lock()
+	s.pendingFreq = 0
+	s.freqMu.Unlock()
+	logger.Info("searching for signal")
+}
+
+// handleDecodedText 解码器输出回调：收集呼号后按 OutputFormatter 格式化，转交 OnTextDecoded
+// 系统本身不向终端输出解码文本，显示方式由调用方决定。QSOLog 使用未格式化的原始文本。
+func (s *CWSystem) handleDecodedText(text string) {
+	s.qsoLog.Observe(text)
+
+	if s.OnTextDecoded != nil {
+		s.OnTextDecoded(s.formatter.Format(text))
+	}
+}
+
+// IncrementalOutput 返回解码器的输出方式: true 表示 OnTextDecoded 每次收到新增的片段，
+// false 表示每次收到完整文本 (Beam Search 解码器，末尾可能被修正)
+func (s *CWSystem) IncrementalOutput() bool {
+	return s.decoderType == DecoderCluster || s.decoderType == DecoderAdaptive
+}
+
+// radioPollInterval 后台读取电台频率的间隔
+const radioPollInterval = 5 * time.Second
+
+// radioFrequency 返回最近一次读到的电台频率 (Hz)，还没读到过时返回错误
+// QSO 记录在解码线程中调用：只读缓存，不访问串口 (一次 CI-V 读取最长要等 ReadTimeout，发送期间还要等 SendText 结束)
+func (s *CWSystem) radioFrequency() (int, error) {
+	if hz := s.radioFreq.Load(); hz > 0 {
+		return int(hz), nil
+	}
+	return 0, fmt.Errorf("radio frequency unknown")
+}
+
+// pollRadioFrequency 在后台定期读取电台频率，更新 radioFrequency 的缓存，直到 stop 关闭
+// 读取失败时保留上一次的频率
+func (s *CWSystem) pollRadioFrequency(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		s.civMu.Lock()
+		select {
+		case <-stop:
+			// 等锁期间系统已经停止，串口可能已关闭
+			s.civMu.Unlock()
+			return
+		default:
+		}
+		hz, err := s.civClient.ReadFrequency()
+		s.civMu.Unlock()
+		if err != nil {
+			logger.Debug("could not read radio frequency", "err", err)
+			continue
+		}
+		s.radioFreq.Store(int64(hz))
+	}
+}
+
+// SpectrumMonitor 返回后台频谱监控 (例如用 Snapshot 绘制瀑布图)，Start 之前为 nil
+func (s *CWSystem) SpectrumMonitor() *SpectrumMonitor {
+	return s.spectrumMonitor
+}
+
+// EstimatedRST 根据频谱监控测得的 SNR 给出粗略的信号报告，例如 "579"
+// T (音调) 无法从 SNR 判断，固定为 9；尚无测量结果时返回空串
+func (s *CWSystem) EstimatedRST() string {
+	if s.spectrumMonitor == nil {
+		return ""
+	}
+	r, st := s.spectrumMonitor.EstimateReadability()
+	if r == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d%d9", r, st)
+}
+
+// QSORecords 返回本次会话中识别到的呼号记录
+func (s *CWSystem) QSORecords() []adif.QSORecord {
+	if s.qsoLog == nil {
+		return nil
+	}
+	s.qsoLog.Flush()
+	return s.qsoLog.Records()
+}
+
+// ExportADIF 将本次会话识别到的呼号导出为 ADIF 文件
+func (s *CWSystem) ExportADIF(w io.Writer) error {
+	return adif.WriteADIF(w, s.QSORecords())
+}
+
 // HandleInput 处理用户输入的文本 (发送 CW)
 func (s *CWSystem) HandleInput(text string) {
 	text = strings.TrimSpace(text)
@@ -152,13 +511,28 @@ func (s *CWSystem) HandleInput(text string) {
 		return
 	}
 
+	s.civMu.Lock()
+	defer s.civMu.Unlock()
 	if s.civClient != nil {
-		fmt.Printf("\n[TX]: %s\n", strings.ToUpper(text))
+		logger.Info("transmitting", "text", strings.ToUpper(text))
+		// 发射期间的侧音 / 射频泄漏会被解码成乱码，先静音解码器
+		s.muteDecoder(CWDuration(text, s.TxWPM) + txMuteTail)
 		if err := s.civClient.SendText(strings.ToUpper(text)); err != nil {
-			log.Printf("Error sending text: %v", err)
+			logger.Error("sending text failed", "err", err)
+			s.muteDecoder(0)
 		}
 	} else {
-		fmt.Println("Error: Radio not connected (cannot transmit).")
+		logger.Warn("radio not connected, cannot transmit")
+	}
+}
+
+// txMuteTail 发射结束后继续静音的时间 (电台 QSK 恢复 / 侧音拖尾)
+const txMuteTail = 300 * time.Millisecond
+
+// muteDecoder 在解码器支持 Muter 时静音 d 时长 (d <= 0 取消静音)
+func (s *CWSystem) muteDecoder(d time.Duration) {
+	if m, ok := s.decoder.(Muter); ok {
+		m.Mute(d)
 	}
 }
 
@@ -168,6 +542,16 @@ func (s *CWSystem) processAudioChunk(samples []float32) {
 	if s.wavWriter != nil {
 		_ = s.wavWriter.WriteSamples(samples)
 	}
+	// 检查是否有新的校准请求
+	select {
+	case req := <-s.calibReq:
+		s.calibID = req.id
+		s.beginNoiseCalibration(req.duration)
+	case <-s.recalReq:
+		s.restartSignalSearch()
+	default:
+	}
+
 	//s.spectrumMonitor.PushAudioData(samples)
 	////s.isCalibrated = true
 	//// 校准或解码
@@ -185,63 +569,109 @@ func (s *CWSystem) processAudioChunk(samples []float32) {
 		s.runCalibration(samples)
 	case StateDecoding:
 		// 只有解码阶段才让 Decoder 和 SpectrumMonitor 工作
-		s.spectrumMonitor.PushAudioData(samples) // 如果恢复了 monitor
-		s.decoder.ProcessAudioChunk(samples)
+		s.applyFrequencyUpdate()
+		s.spectrumMonitor.PushAudioData(samples)
+		s.decoder.ProcessAudioChunk(s.squelch.apply(samples, float64(s.SampleRate)))
+		s.updateLiveStatus()
+	}
+}
+
+// updateLiveStatus 在音频线程中记录解码器的速度和信噪比，供 CurrentWPM / CurrentSNR 跨线程读取
+func (s *CWSystem) updateLiveStatus() {
+	if r, ok := s.decoder.(SpeedReporter); ok {
+		s.liveWPM.Store(math.Float64bits(r.CurrentWPM()))
 	}
+	if r, ok := s.decoder.(SNRReporter); ok {
+		s.liveSNR.Store(math.Float64bits(r.CurrentSNR()))
+	}
+}
+
+// CurrentWPM 解码器当前估计的字符速度 (WPM)，解码器不报告速度或尚未开始解码时返回 0。可在任意线程调用。
+func (s *CWSystem) CurrentWPM() float64 {
+	return math.Float64frombits(s.liveWPM.Load())
+}
+
+// CurrentSNR 解码器最近测得的信噪比 (dB，见 ExperimentalDecoder.CurrentSNR)，
+// 解码器不报告信噪比或尚未测量时返回 0。可在任意线程调用。
+func (s *CWSystem) CurrentSNR() float64 {
+	return math.Float64frombits(s.liveSNR.Load())
+}
+
+// beginNoiseCalibration 进入噪声校准状态
+func (s *CWSystem) beginNoiseCalibration(duration time.Duration) {
+	s.calibrationState = StateNoiseCalib
+	s.calibTarget = int(duration.Seconds() * float64(s.SampleRate))
+	s.noiseSampleCount = 0
+	s.calibStartTime = time.Now()
+	s.calibEnvelopes = nil
+	s.calibPeaks = nil
+	s.analyzer.Reset()
+	// 噪声近似白噪声，用解码器的初始频率解调即可代表解码器看到的噪声包络
+	s.calibSDR = NewSDRDemodulator(float64(s.SampleRate), s.cfg.initialFreq(), s.cfg)
+	logger.Info("sampling background noise, keep the band quiet")
 }
 
 // [新增] 阶段一：噪声采样校准
 func (s *CWSystem) runNoiseCalibration(samples []float32) {
-	// 累积音频能量 (RMS)
-	sumSquares := 0.0
-	for _, v := range samples {
-		sumSquares += float64(v * v)
-	}
-	rms := math.Sqrt(sumSquares / float64(len(samples)))
+	settle := int(calibSettleSec * float64(s.SampleRate))
+	fftSize := s.analyzer.FFTSize
 
-	// 使用简单的移动平均来估算底噪
-	if s.noiseSampleCount == 0 {
-		s.noiseFloor = rms
-		s.calibStartTime = time.Now()
-		fmt.Println("[CALIB] Sampling Background Noise... (Please keep silence)")
-	} else {
-		s.noiseFloor = (s.noiseFloor * 0.95) + (rms * 0.05)
+	// 1. 包络域：经过与解码器相同的 SDR 解调，降采样后记录
+	for _, v := range samples {
+		env := s.calibSDR.Process(float64(v))
+		if s.noiseSampleCount >= settle && s.noiseSampleCount%calibDecimation == 0 {
+			s.calibEnvelopes = append(s.calibEnvelopes, env)
+		}
+		s.noiseSampleCount++
 	}
-	s.noiseSampleCount++
 
-	// 采样持续 2 秒
-	if time.Since(s.calibStartTime) > 2*time.Second {
-		// 防止底噪过小导致除零或门限太低 (增加一个安全下限)
-		if s.noiseFloor < 0.0001 {
-			s.noiseFloor = 0.0001
+	// 2. 频谱域：与搜台相同的 FFT 峰值检测
+	// 按半帧喂入，每次最多完成一帧，逐帧记录峰值
+	data := toFloat64(samples)
+	for len(data) > 0 {
+		n := min(len(data), max(fftSize/2, 1))
+		if _, rawMag := s.analyzer.FindDominantFrequency(data[:n], s.cfg.Monitor.MinFrequency, s.cfg.Monitor.MaxFrequency); rawMag > 0 {
+			s.calibPeaks = append(s.calibPeaks, rawMag*2.0/float64(fftSize))
 		}
+		data = data[n:]
+	}
 
-		// 打印结果并切换状态
-		fmt.Printf("[CALIB] Noise Floor Measured: %.5f\n", s.noiseFloor)
-		fmt.Printf("[CALIB] Setting Signal Threshold to: %.5f (10dB margin)\n", s.noiseFloor*3.16) // 3.16 ≈ 10dB
-		fmt.Println("[CALIB] Ready! Please tune to CW signal...")
+	if s.noiseSampleCount >= s.calibTarget {
+		s.finishNoiseCalibration()
+	}
+}
 
-		s.calibrationState = StateSignalLock
-		s.calibrationBuffer = nil // 清空 buffer 准备搜台
-	} else {
-		if s.noiseSampleCount%10 == 0 {
-			fmt.Print(".") // 打印进度点
-		}
+// finishNoiseCalibration 计算噪声统计，设置阈值并切换到搜台状态
+func (s *CWSystem) finishNoiseCalibration() {
+	stats := computeNoiseStats(s.calibEnvelopes, s.calibPeaks)
+	s.noiseStats = stats
+	s.noiseFloor = stats.SpectralPeak
+	s.decoder.SetThreshold(stats.Threshold)
+
+	logger.Info("noise calibration done",
+		"mean", stats.Mean, "std", stats.StdDev, "median", stats.Median, "p95", stats.P95, "samples", stats.Samples,
+		"threshold", stats.Threshold, "squelch", stats.Squelch)
+
+	s.calibrationState = StateSignalLock
+	s.analyzer.Reset()
+	s.calibEnvelopes = nil
+	s.calibPeaks = nil
+	s.calibSDR = nil
+
+	// 只有音频线程发送：先丢掉没人取走的旧结果 (请求方已超时)，保证这次的结果一定能送达
+	select {
+	case <-s.calibDone:
+	default:
 	}
+	s.calibDone <- calibResult{id: s.calibID, stats: stats}
 }
 
 // [修改] 阶段二：信号搜索 (原 runCalibration)
 func (s *CWSystem) runSignalSearch(samples []float32) {
-	for _, v := range samples {
-		s.calibrationBuffer = append(s.calibrationBuffer, float64(v))
-	}
-
 	fftSize := s.analyzer.FFTSize
-	if len(s.calibrationBuffer) >= fftSize {
-		// 1. 分析频谱
-		minFreq, maxFreq := 500.0, 900.0
-		freq, rawMag := s.analyzer.FindDominantFrequency(s.calibrationBuffer, minFreq, maxFreq)
-
+	// 1. 分析频谱 (分析器内部缓冲，凑满一帧才有结果)
+	minFreq, maxFreq := s.cfg.Monitor.MinFrequency, s.cfg.Monitor.MaxFrequency
+	if freq, rawMag := s.analyzer.FindDominantFrequency(toFloat64(samples), minFreq, maxFreq); rawMag > 0 {
 		// 归一化幅度
 		normalizedMag := rawMag * 2.0 / float64(fftSize)
 
@@ -263,69 +693,61 @@ func (s *CWSystem) runSignalSearch(samples []float32) {
 			// 例如设为信号强度的 50%
 			s.decoder.SetThreshold(normalizedMag * 0.5)
 
-			fmt.Printf("\n[LOCKED] Freq: %.1fHz | Signal: %.4f | Threshold: %.4f (SNR: %.1fdB)\n",
-				freq, normalizedMag, dynamicThreshold, 20*math.Log10(normalizedMag/s.noiseFloor))
+			logger.Info("signal locked", "freq_hz", freq, "signal", normalizedMag, "threshold", dynamicThreshold,
+				"snr_db", 20*math.Log10(normalizedMag/s.noiseFloor))
 
 			s.calibrationState = StateDecoding
-			s.calibrationBuffer = nil
-
-			// 如果恢复了 SpectrumMonitor，这里也应该通知它
-			// s.spectrumMonitor.SetCenterFreq(freq)
+			s.analyzer.Reset()
+			s.startFrequencyTracking(freq)
 
-			fmt.Println("Decoding started.")
+			logger.Info("decoding started")
 		} else {
 			// 信号未达到动态门限，继续等待
 			// fmt.Printf("\rSearching... Mag: %.5f / Thresh: %.5f", normalizedMag, dynamicThreshold)
-			s.calibrationBuffer = s.calibrationBuffer[:0]
 		}
 	}
 }
 
 // 内部：执行校准逻辑
 func (s *CWSystem) runCalibration(samples []float32) {
-	for _, v := range samples {
-		s.calibrationBuffer = append(s.calibrationBuffer, float64(v))
-	}
-
 	fftSize := s.analyzer.FFTSize
-	// 只有当 buffer 填满时才进行一次分析
-	if len(s.calibrationBuffer) >= fftSize {
-		// 1. 限制搜索频率范围 (Bandwidth Limiting)
-		minFreq := 500.0
-		maxFreq := 900.0
-
-		freq, rawMag := s.analyzer.FindDominantFrequency(s.calibrationBuffer, minFreq, maxFreq)
+	// 分析器内部缓冲，凑满一帧 (50% 重叠) 才进行一次分析
+	// 1. 限制搜索频率范围 (Bandwidth Limiting)
+	if freq, rawMag := s.analyzer.FindDominantFrequency(toFloat64(samples), s.cfg.Monitor.MinFrequency, s.cfg.Monitor.MaxFrequency); rawMag > 0 {
 
 		// 归一化 FFT 幅度
 		normalizedMag := rawMag * 2.0 / float64(fftSize)
 
 		// 2. 能量绝对阈值 (Magnitude Threshold)
+		// 做过噪声校准时使用实测的静噪门限和阈值下限
 		const MinSignalStrength = 0.01
+		squelch, minThreshold := MinSignalStrength, 0.01
+		if s.noiseStats.Samples > 0 {
+			squelch, minThreshold = s.noiseStats.Squelch, s.noiseStats.Threshold
+		}
 
-		if normalizedMag > MinSignalStrength {
+		if normalizedMag > squelch {
 			s.decoder.UpdateTargetFreq(freq)
 
 			// 动态设置阈值：取信号幅度的 30%
 			newThreshold := normalizedMag * 0.3
 
 			// 增加一个最小阈值保护
-			if newThreshold < 0.01 {
-				newThreshold = 0.01
+			if newThreshold < minThreshold {
+				newThreshold = minThreshold
 			}
 
 			s.decoder.SetThreshold(newThreshold)
 
-			fmt.Printf("\n[CALIB] LOCKED! Freq: %.1f Hz, Mag: %.4f, Thresh: %.4f\n", freq, normalizedMag, newThreshold)
+			logger.Info("signal locked", "freq_hz", freq, "signal", normalizedMag, "threshold", newThreshold)
 
 			s.isCalibrated = true
-			s.calibrationBuffer = nil
+			s.analyzer.Reset()
 			s.calibrationState = StateDecoding
-			fmt.Println("Decoding started. Type text to send.")
-			fmt.Print("> ")
+			s.startFrequencyTracking(freq)
+			logger.Info("decoding started")
 		} else {
 			// 信号太弱，认为是噪声，继续等待
-			fmt.Print(".")
-			s.calibrationBuffer = s.calibrationBuffer[:0]
 		}
 	}
 }
@@ -341,27 +763,175 @@ func (s *CWSystem) startAudioCapture() error {
 }
 
 // 内部：运行回放循环
-func (s *CWSystem) runReplayLoop() {
+// 到达文件末尾时回调 OnReplayEnd 并进入暂停状态，之后仍可 Seek + Resume 重新播放，直到 Stop
+func (s *CWSystem) runReplayLoop(stop <-chan struct{}) {
 	chunkSize := 1024
-	// 计算 ticker 间隔以模拟实时速度
-	interval := time.Second * time.Duration(chunkSize) / time.Duration(s.SampleRate)
+	// 计算 ticker 间隔以模拟实时速度 (按 SetReplaySpeed 缩放)
+	interval := s.replayInterval(chunkSize)
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	fmt.Println("Replay started...")
-	for range ticker.C {
-		samples, err := s.wavReader.ReadSamples(chunkSize)
-		if err != nil {
-			fmt.Println("\nEnd of file.")
-			os.Exit(0) // 回放结束直接退出程序
+	logger.Info("replay started")
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
 		}
-		s.processAudioChunk(samples)
+		if s.replayStep(chunkSize) && s.OnReplayEnd != nil {
+			s.OnReplayEnd()
+		}
+		if next := s.replayInterval(chunkSize); next != interval {
+			interval = next
+			ticker.Reset(interval)
+		}
+	}
+}
+
+// replayInterval 返回回放 chunkSize 个采样的时间间隔
+func (s *CWSystem) replayInterval(chunkSize int) time.Duration {
+	s.replayMu.Lock()
+	speed := s.replaySpeed
+	s.replayMu.Unlock()
+	if speed <= 0 {
+		speed = 1
+	}
+	interval := time.Duration(float64(time.Second) * float64(chunkSize) / float64(s.SampleRate) / speed)
+	return max(interval, time.Microsecond)
+}
+
+// SetReplaySpeed 设置回放速度倍率，例如 4 快进浏览长录音，0.5 慢放研究难解的片段；<= 0 恢复实时速度。
+// 只改变送入音频的节奏，解码器按采样点计时，速度估计不受影响。可在回放过程中随时调用。
+func (s *CWSystem) SetReplaySpeed(factor float64) {
+	s.replayMu.Lock()
+	s.replaySpeed = max(factor, 0)
+	s.replayMu.Unlock()
+}
+
+// replayStep 回放一块音频 (暂停时什么也不做)，到达文件末尾时返回 true
+func (s *CWSystem) replayStep(chunkSize int) bool {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	if s.replayPaused || s.replayStop == nil {
+		return false // 暂停或已经 Stop
+	}
+	samples, err := s.wavReader.ReadSamples(chunkSize)
+	if err != nil {
+		if err == io.EOF {
+			logger.Info("end of replay file")
+		} else {
+			logger.Error("replay read failed", "err", err)
+		}
+		s.replayPaused = true
+		return true
+	}
+	s.processAudioChunk(samples)
+	return false
+}
+
+// Pause 暂停回放 (仅回放模式有效)
+func (s *CWSystem) Pause() {
+	s.replayMu.Lock()
+	s.replayPaused = true
+	s.replayMu.Unlock()
+}
+
+// Resume 继续回放；到达文件末尾后需要先 Seek 回前面
+func (s *CWSystem) Resume() {
+	s.replayMu.Lock()
+	s.replayPaused = false
+	s.replayMu.Unlock()
+}
+
+// Seek 跳到回放文件的第 seconds 秒处，不改变暂停状态。
+// 跳转前先 Flush 解码器，避免跳转两侧的码元拼成一个字符。流式回放 (SetReplayStream) 不支持。
+func (s *CWSystem) Seek(seconds float64) error {
+	if s.wavReader == nil {
+		return fmt.Errorf("not replaying")
+	}
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+	if err := s.wavReader.Seek(seconds); err != nil {
+		return err
+	}
+	s.decoder.Flush()
+	return nil
+}
+
+// sidebandInverter 使用 SDR I/Q 前端的解码器实现此接口
+type sidebandInverter interface {
+	SetSidebandInvert(invert bool)
+}
+
+// syncWithRadio Start 时读取电台状态:
+//   - 电台处于 CW-R (反向边带) 时自动开启 SDR.SidebandInvert；处于 CW 时不关闭，保留用户为边带相反的电台手动设置的值
+//   - 记录工作频率，之后由 pollRadioFrequency 在后台更新，供 QSO 记录使用
+//   - 未指定 Monitor.InitialFreq 时使用电台的 CW 音调作为解码器的初始频率
+//
+// 电台没有响应 (串口读超时) 时只打印警告，按默认设置继续
+func (s *CWSystem) syncWithRadio() {
+	s.civMu.Lock()
+	defer s.civMu.Unlock()
+
+	mode, err := s.civClient.ReadMode()
+	if err != nil {
+		// 第一条指令就超时，说明电台不在线或 CI-V 设置不对，不再逐条等待超时
+		logger.Warn("radio not responding, continuing without radio settings", "err", err)
+		return
+	}
+	if mode == "CW-R" {
+		s.cfg.SDR.SidebandInvert = true
+		logger.Info("radio is in CW-R mode, inverting sideband")
+	}
+
+	if hz, err := s.civClient.ReadFrequency(); err != nil {
+		logger.Warn("could not read radio frequency", "err", err)
+	} else {
+		s.radioFreq.Store(int64(hz))
+		logger.Info("radio settings", "mode", mode, "freq_mhz", formatMHz(hz))
+	}
+
+	if s.cfg.Monitor.InitialFreq > 0 {
+		return
+	}
+	pitch, err := s.civClient.ReadCWPitch()
+	if err != nil {
+		logger.Warn("could not read CW pitch", "err", err)
+		return
+	}
+	s.cfg.Monitor.InitialFreq = float64(pitch)
+	logger.Info("radio CW pitch", "pitch_hz", pitch)
+	if p := float64(pitch); p < s.cfg.Monitor.MinFrequency || p > s.cfg.Monitor.MaxFrequency {
+		logger.Warn("CW pitch is outside the search band",
+			"pitch_hz", pitch, "min_hz", s.cfg.Monitor.MinFrequency, "max_hz", s.cfg.Monitor.MaxFrequency)
 	}
 }
 
-// 内部：处理频率更新回调
+// 内部：处理频率更新回调 (在 SpectrumMonitor 的后台线程中调用)
+// 解码器不是线程安全的，这里只记下频率，由音频线程在下一个音频块前交给解码器
 func (s *CWSystem) handleFrequencyUpdate(freq float64) {
-	// 这里可以添加平滑逻辑，但为了快速验证，我们先直接更新
 	//log.Printf("[MONITOR] Detected dominant frequency: %.1f Hz\n", freq)
-	s.decoder.UpdateTargetFreq(freq)
+	s.freqMu.Lock()
+	s.pendingFreq = freq
+	s.freqMu.Unlock()
+}
+
+// startFrequencyTracking 搜台锁定后调用：以锁定频率作为 SpectrumMonitor 的起点，
+// 并丢弃锁定之前残留的跟踪结果，保证初始校准的频率先生效
+func (s *CWSystem) startFrequencyTracking(freq float64) {
+	s.freqMu.Lock()
+	s.pendingFreq = 0
+	s.freqMu.Unlock()
+	s.spectrumMonitor.SetCenterFreq(freq)
+}
+
+// applyFrequencyUpdate 在音频线程中把 SpectrumMonitor 跟踪到的频率交给解码器
+func (s *CWSystem) applyFrequencyUpdate() {
+	s.freqMu.Lock()
+	freq := s.pendingFreq
+	s.pendingFreq = 0
+	s.freqMu.Unlock()
+	if freq > 0 {
+		s.decoder.UpdateTargetFreq(freq)
+	}
 }
@@ -0,0 +1,492 @@
+package cw
+
+import (
+	"math"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseDecoderType(t *testing.T) {
+	for _, dt := range []DecoderType{DecoderExperimental, DecoderCluster, DecoderAdaptive, DecoderGoertzel} {
+		got, err := ParseDecoderType(dt.String())
+		if err != nil || got != dt {
+			t.Errorf("ParseDecoderType(%q) = %v, %v", dt.String(), got, err)
+		}
+	}
+	if got, err := ParseDecoderType("GOERTZEL"); err != nil || got != DecoderGoertzel {
+		t.Errorf("ParseDecoderType should be case-insensitive, got %v, %v", got, err)
+	}
+	if _, err := ParseDecoderType("fft"); err == nil {
+		t.Error("Expected error for unknown decoder type")
+	}
+}
+
+func TestCWSystem_SetDecoder(t *testing.T) {
+	s := NewCWSystemWithDecoder(DecoderAdaptive)
+	if s.decoderType != DecoderAdaptive {
+		t.Errorf("Expected decoder type adaptive, got %v", s.decoderType)
+	}
+
+	custom := NewAdaptiveCWDecoder(48000, 700, 25)
+	s.SetDecoder(custom)
+	if s.decoder != custom {
+		t.Error("SetDecoder should install the given decoder")
+	}
+
+	s = NewCWSystemWithDecoder(DecoderType(99))
+	if _, err := s.newDecoder(700); err == nil {
+		t.Error("Expected error for invalid decoder type")
+	}
+}
+
+// freqRecorder 只记录 UpdateTargetFreq 的解码器
+type freqRecorder struct {
+	freqs []float64
+}
+
+func (r *freqRecorder) ProcessAudioChunk(samples []float32) {}
+func (r *freqRecorder) UpdateTargetFreq(freq float64)       { r.freqs = append(r.freqs, freq) }
+func (r *freqRecorder) SetThreshold(threshold float64)      {}
+func (r *freqRecorder) SetOnDecoded(func(string))           {}
+func (r *freqRecorder) Flush() string                       { return "" }
+func (r *freqRecorder) Reset()                              {}
+func (r *freqRecorder) Stop()                               {}
+
+// writeDriftingTone 写一个从 from 线性漂移到 to 再保持的音调 WAV (带少量噪声)
+func writeDriftingTone(t *testing.T, filename string, sampleRate int, from, to float64, driftSec, holdSec float64) {
+	w, err := NewWavWriter(filename, sampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	total := int((driftSec + holdSec) * float64(sampleRate))
+	samples := make([]float32, total)
+	phase := 0.0
+	for i := range samples {
+		sec := float64(i) / float64(sampleRate)
+		freq := to
+		if sec < driftSec {
+			freq = from + (to-from)*sec/driftSec
+		}
+		phase += 2 * math.Pi * freq / float64(sampleRate)
+		samples[i] = float32(0.5*math.Sin(phase) + 0.01*rng.NormFloat64())
+	}
+	if err := w.WriteSamples(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCWSystem_FollowsFrequencyDrift(t *testing.T) {
+	const sampleRate = 8000
+	filename := filepath.Join(t.TempDir(), "drift.wav")
+	// 8s 内从 700Hz 漂到 740Hz；默认平滑参数下跟踪的时间常数约 7s，再保持 24s 让其收敛
+	writeDriftingTone(t, filename, sampleRate, 700, 740, 8, 24)
+
+	reader, err := NewWavReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	s := NewCWSystem()
+	s.SampleRate = sampleRate
+	dec := &freqRecorder{}
+	s.SetDecoder(dec)
+	// 不启动后台线程，由测试按音频时间驱动 SpectrumMonitor
+	s.spectrumMonitor = NewSpectrumMonitor(sampleRate, s.cfg, s.handleFrequencyUpdate)
+	s.calibrationState = StateDecoding
+	s.startFrequencyTracking(700) // 模拟搜台锁定在 700Hz
+
+	monitor := s.spectrumMonitor
+	chunk := int(s.cfg.Monitor.UpdateInterval.Seconds() * sampleRate)
+	for {
+		samples, err := reader.ReadSamples(chunk)
+		if err != nil {
+			break
+		}
+		s.processAudioChunk(samples)
+		for len(monitor.audioInChan) > 0 {
+			monitor.ingest(<-monitor.audioInChan)
+		}
+		monitor.update()
+	}
+	s.processAudioChunk(nil) // 交付最后一次跟踪结果
+
+	if len(dec.freqs) == 0 {
+		t.Fatal("Decoder never received a frequency update")
+	}
+	if dec.freqs[0] > 710 {
+		t.Errorf("Tracking should start from the calibrated 700Hz, first update %.1fHz", dec.freqs[0])
+	}
+	if last := dec.freqs[len(dec.freqs)-1]; math.Abs(last-740) > 3 {
+		t.Errorf("Decoder should follow the drift to 740Hz, ended at %.1fHz", last)
+	}
+}
+
+func TestCWSystem_CalibrationLocksWithSmallChunks(t *testing.T) {
+	const sampleRate, chunk = 8000, 1024
+	s := NewCWSystem()
+	s.SampleRate = sampleRate
+	dec := &freqRecorder{}
+	s.SetDecoder(dec)
+	s.analyzer = NewSpectrumAnalyzer(sampleRate, 4096, WindowHanning)
+	s.spectrumMonitor = NewSpectrumMonitor(sampleRate, s.cfg, s.handleFrequencyUpdate)
+	s.calibrationState = StateSignalLock
+
+	// 音频以 1024 个样本为一块到达，小于 4096 的 FFT
+	audio := make([]float32, sampleRate)
+	for i := range audio {
+		audio[i] = float32(0.5 * math.Sin(2*math.Pi*650*float64(i)/sampleRate))
+	}
+	for i := 0; i < len(audio) && s.calibrationState != StateDecoding; i += chunk {
+		s.processAudioChunk(audio[i:min(i+chunk, len(audio))])
+	}
+
+	if s.calibrationState != StateDecoding {
+		t.Fatal("Expected the search to lock onto the tone")
+	}
+	if len(dec.freqs) != 1 || math.Abs(dec.freqs[0]-650) > 2 {
+		t.Errorf("Expected to lock at ~650Hz, got %v", dec.freqs)
+	}
+}
+
+func TestCWSystem_CalibrateIgnoresStaleResult(t *testing.T) {
+	const sampleRate, chunk = 8000, 400
+	defer func(d time.Duration) { calibTimeoutSlack = d }(calibTimeoutSlack)
+	calibTimeoutSlack = 50 * time.Millisecond
+
+	s := NewCWSystem()
+	s.SampleRate = sampleRate
+	s.SetDecoder(&freqRecorder{})
+	s.analyzer = NewSpectrumAnalyzer(sampleRate, 4096, WindowHanning)
+	s.spectrumMonitor = NewSpectrumMonitor(sampleRate, s.cfg, s.handleFrequencyUpdate)
+
+	rng := rand.New(rand.NewSource(1))
+	noise := func(level float64) []float32 {
+		out := make([]float32, chunk)
+		for i := range out {
+			out[i] = float32(level * rng.NormFloat64())
+		}
+		return out
+	}
+
+	// 第一次校准时没有音频，超时返回；请求留在队列里，之后收到弱噪声才完成，结果没人取走
+	if _, err := s.Calibrate(300 * time.Millisecond); err == nil {
+		t.Fatal("Expected a timeout without audio")
+	}
+	s.processAudioChunk(noise(0.001))
+	for s.calibrationState == StateNoiseCalib {
+		s.processAudioChunk(noise(0.001))
+	}
+	stale := s.noiseStats
+	if stale.Samples == 0 {
+		t.Fatal("Expected the late calibration to finish")
+	}
+
+	// 第二次校准在强噪声下测量，应返回这一次的结果而不是上一次留下的
+	done := make(chan NoiseStats)
+	errc := make(chan error, 1)
+	go func() {
+		stats, err := s.Calibrate(300 * time.Millisecond)
+		if err != nil {
+			errc <- err
+			return
+		}
+		done <- stats
+	}()
+	for {
+		select {
+		case stats := <-done:
+			if stats != s.noiseStats || stats == stale {
+				t.Errorf("Expected the new measurement %+v, got %+v (stale %+v)", s.noiseStats, stats, stale)
+			}
+			if stats.Mean < 10*stale.Mean {
+				t.Errorf("Expected the louder noise to be measured, mean %g vs stale %g", stats.Mean, stale.Mean)
+			}
+			return
+		case err := <-errc:
+			t.Fatalf("Calibrate: %v", err)
+		default:
+			s.processAudioChunk(noise(0.05))
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestCWSystem_CalibrationUsesConfiguredBand(t *testing.T) {
+	const sampleRate = 8000
+	s := NewCWSystem()
+	s.SampleRate = sampleRate
+	cfg := DefaultConfig()
+	cfg.Monitor.MinFrequency, cfg.Monitor.MaxFrequency = 400, 600
+	s.SetConfig(cfg)
+	dec := &freqRecorder{}
+	s.SetDecoder(dec)
+	s.analyzer = NewSpectrumAnalyzer(sampleRate, 4096, WindowHanning)
+	s.spectrumMonitor = NewSpectrumMonitor(sampleRate, s.cfg, s.handleFrequencyUpdate)
+	s.calibrationState = StateSignalLock
+
+	// 450Hz 侧音在默认频段之外
+	audio := make([]float32, sampleRate)
+	for i := range audio {
+		audio[i] = float32(0.5 * math.Sin(2*math.Pi*450*float64(i)/sampleRate))
+	}
+	s.processAudioChunk(audio)
+
+	if s.calibrationState != StateDecoding {
+		t.Fatal("Expected the search to lock onto the tone")
+	}
+	if len(dec.freqs) != 1 || math.Abs(dec.freqs[0]-450) > 2 {
+		t.Errorf("Expected to lock at ~450Hz, got %v", dec.freqs)
+	}
+	if f := cfg.initialFreq(); f != 500 {
+		t.Errorf("initialFreq = %v, want band center 500", f)
+	}
+	cfg.Monitor.InitialFreq = 550
+	if f := cfg.initialFreq(); f != 550 {
+		t.Errorf("initialFreq = %v, want 550", f)
+	}
+}
+
+func TestCWSystem_ForceRecalibrate(t *testing.T) {
+	const sampleRate = 8000
+	s := NewCWSystem()
+	s.SampleRate = sampleRate
+	dec := &freqRecorder{}
+	s.SetDecoder(dec)
+	s.analyzer = NewSpectrumAnalyzer(sampleRate, 4096, WindowHanning)
+	s.spectrumMonitor = NewSpectrumMonitor(sampleRate, s.cfg, s.handleFrequencyUpdate)
+	s.calibrationState = StateDecoding
+
+	// 换台：新的信号在 820Hz
+	audio := make([]float32, sampleRate)
+	for i := range audio {
+		audio[i] = float32(0.5 * math.Sin(2*math.Pi*820*float64(i)/sampleRate))
+	}
+	s.ForceRecalibrate()
+	s.ForceRecalibrate() // 重复请求只处理一次
+	s.processAudioChunk(audio[:1024])
+	if s.calibrationState != StateSignalLock {
+		t.Fatalf("Expected to return to signal search, state %d", s.calibrationState)
+	}
+	s.processAudioChunk(audio[1024:])
+	if s.calibrationState != StateDecoding {
+		t.Fatal("Expected the search to lock onto the new tone")
+	}
+	if len(dec.freqs) != 1 || math.Abs(dec.freqs[0]-820) > 2 {
+		t.Errorf("Expected to lock at ~820Hz, got %v", dec.freqs)
+	}
+}
+
+func TestCWSystem_SyncWithRadio(t *testing.T) {
+	port := NewMockSerialPort()
+	port.ReadBuffer.Write(makeResponseFrame(0x04, []byte{0x07}))                         // CW-R
+	port.ReadBuffer.Write(makeResponseFrame(0x03, []byte{0x00, 0x00, 0x05, 0x07, 0x00})) // 7.050 MHz
+	port.ReadBuffer.Write(makeResponseFrame(0x14, []byte{0x09, 0x00, 0x85}))             // 85 -> 500Hz
+
+	s := NewCWSystem()
+	s.civClient = &CIVClient{conn: port}
+	s.syncWithRadio()
+
+	if !s.cfg.SDR.SidebandInvert {
+		t.Error("Expected CW-R to invert the sideband")
+	}
+	if s.cfg.Monitor.InitialFreq != 500 {
+		t.Errorf("Expected the radio's CW pitch as initial frequency, got %v", s.cfg.Monitor.InitialFreq)
+	}
+	// QSO 记录使用启动时读到的频率
+	if hz, err := s.radioFrequency(); err != nil || hz != 7050000 {
+		t.Errorf("Expected 7050000 Hz, got %d (%v)", hz, err)
+	}
+}
+
+func TestCWSystem_PollRadioFrequency(t *testing.T) {
+	port := NewMockSerialPort()
+	port.ReadBuffer.Write(makeResponseFrame(0x03, []byte{0x00, 0x00, 0x05, 0x07, 0x00})) // 7.050 MHz
+	s := NewCWSystem()
+	s.civClient = &CIVClient{conn: port, ReadTimeout: 20 * time.Millisecond}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.pollRadioFrequency(stop, time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for s.radioFreq.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 串口被占用 (例如正在发送) 时，解码线程读频率不等待
+	s.civMu.Lock()
+	start := time.Now()
+	hz, err := s.radioFrequency()
+	elapsed := time.Since(start)
+	s.civMu.Unlock()
+	close(stop)
+	<-done
+	if err != nil || hz != 7050000 {
+		t.Errorf("Expected the polled 7050000 Hz, got %d (%v)", hz, err)
+	}
+	if elapsed > 10*time.Millisecond {
+		t.Errorf("Expected radioFrequency not to wait for the serial port, took %v", elapsed)
+	}
+}
+
+func TestCWSystem_SyncWithRadioTimeout(t *testing.T) {
+	port := NewMockSerialPort()
+	s := NewCWSystem()
+	s.civClient = &CIVClient{conn: port, ReadTimeout: 20 * time.Millisecond}
+
+	start := time.Now()
+	s.syncWithRadio()
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected to give up after the first timeout, took %v", elapsed)
+	}
+	if s.cfg.SDR.SidebandInvert || s.cfg.Monitor.InitialFreq != 0 {
+		t.Error("Expected defaults to be kept when the radio does not respond")
+	}
+	if _, err := s.radioFrequency(); err == nil {
+		t.Error("Expected an error without a known frequency")
+	}
+}
+
+// chunkRecorder 记录收到的音频长度和 Flush 次数
+type chunkRecorder struct {
+	freqRecorder
+	samples, flushes int
+}
+
+func (r *chunkRecorder) ProcessAudioChunk(samples []float32) { r.samples += len(samples) }
+func (r *chunkRecorder) Flush() string                       { r.flushes++; return "" }
+
+func TestCWSystem_ReplayPauseSeek(t *testing.T) {
+	const sampleRate = 8000
+	filename := filepath.Join(t.TempDir(), "replay.wav")
+	writeDriftingTone(t, filename, sampleRate, 700, 700, 0, 1) // 1s
+	reader, err := NewWavReader(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	s := NewCWSystem()
+	s.SampleRate = sampleRate
+	dec := &chunkRecorder{}
+	s.SetDecoder(dec)
+	s.spectrumMonitor = NewSpectrumMonitor(sampleRate, s.cfg, s.handleFrequencyUpdate)
+	s.calibrationState = StateDecoding
+	s.wavReader = reader
+	s.replayStop = make(chan struct{})
+
+	s.replayStep(4000)
+	s.Pause()
+	s.replayStep(4000)
+	if dec.samples != 4000 {
+		t.Fatalf("Expected paused replay to stop feeding audio, got %d samples", dec.samples)
+	}
+
+	// 往回跳 0.25s 再播放到结尾：共 4000 + 6000 个采样
+	if err := s.Seek(0.25); err != nil {
+		t.Fatal(err)
+	}
+	if dec.flushes != 1 {
+		t.Errorf("Expected Seek to flush the decoder, got %d flushes", dec.flushes)
+	}
+	s.Resume()
+	ended := false
+	for i := 0; i < 10 && !ended; i++ {
+		ended = s.replayStep(4000)
+	}
+	if !ended || dec.samples != 10000 {
+		t.Errorf("Expected EOF after 10000 samples, got ended=%v samples=%d", ended, dec.samples)
+	}
+
+	// 到达末尾后自动暂停，仍然可以跳回去重播
+	if s.replayStep(4000) {
+		t.Error("Expected no further EOF reports while paused")
+	}
+	if err := s.Seek(0); err != nil {
+		t.Fatal(err)
+	}
+	s.Resume()
+	s.replayStep(4000)
+	if dec.samples != 14000 {
+		t.Errorf("Expected replay to restart after seeking back, got %d samples", dec.samples)
+	}
+}
+
+func TestCWSystem_SetReplaySpeed(t *testing.T) {
+	s := NewCWSystem()
+	s.SampleRate = 8000
+	tests := []struct {
+		speed float64
+		want  time.Duration
+	}{
+		{0, 128 * time.Millisecond}, // 未设置：实时
+		{4, 32 * time.Millisecond},
+		{0.5, 256 * time.Millisecond},
+		{-1, 128 * time.Millisecond}, // 非法值恢复实时
+	}
+	for _, tt := range tests {
+		s.SetReplaySpeed(tt.speed)
+		if got := s.replayInterval(1024); got != tt.want {
+			t.Errorf("speed %v: interval %v, want %v", tt.speed, got, tt.want)
+		}
+	}
+}
+
+// muteRecorder 记录 Mute 调用的解码器
+type muteRecorder struct {
+	freqRecorder
+	mutes []time.Duration
+}
+
+func (r *muteRecorder) Mute(d time.Duration) { r.mutes = append(r.mutes, d) }
+
+func TestCWSystem_HandleInputMutesDecoder(t *testing.T) {
+	s := NewCWSystem()
+	dec := &muteRecorder{}
+	s.SetDecoder(dec)
+	s.civClient = &CIVClient{conn: NewMockSerialPort()}
+
+	s.HandleInput("cq test")
+	want := CWDuration("CQ TEST", s.TxWPM) + txMuteTail
+	if len(dec.mutes) != 1 || dec.mutes[0] != want {
+		t.Errorf("Expected one Mute(%v), got %v", want, dec.mutes)
+	}
+
+	// 发送失败 (超过 30 个字符) 时取消静音
+	s.HandleInput("THIS MESSAGE IS MUCH TOO LONG TO SEND")
+	if len(dec.mutes) != 3 || dec.mutes[2] != 0 {
+		t.Errorf("Expected mute to be cancelled after a failed send, got %v", dec.mutes)
+	}
+}
+
+func TestCWSystem_FormatsOutput(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Decoder.OutputCase = CaseLower
+	cfg.Decoder.WordSeparator = "|"
+	s := NewCWSystemWithDecoder(DecoderAdaptive)
+	s.SetConfig(cfg)
+	s.qsoLog = NewQSOLog(true)
+	s.formatter = NewOutputFormatter(cfg)
+
+	var got string
+	s.OnTextDecoded = func(text string) { got += text }
+	for _, chunk := range []string{"CQ", " ", "DE", " ", "BG1ABC", " "} {
+		s.handleDecodedText(chunk)
+	}
+	if got != "cq|de|bg1abc|" {
+		t.Errorf("Expected formatted output, got %q", got)
+	}
+	// 呼号识别使用原始文本
+	if recs := s.qsoLog.Records(); len(recs) != 1 || recs[0].Call != "BG1ABC" {
+		t.Errorf("Expected QSO log to see BG1ABC, got %v", recs)
+	}
+}
@@ -0,0 +1,81 @@
+package cw
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TranscriptLog 将解码文本按行追加写入 w，每行带 UTC 时间戳，用于无人值守时长期留存记录
+// 只写已经结束的单词 (后面跟着空格)：Beam Search 解码器的完整文本末尾可能还会被修正，
+// 已写出的单词不会再改动。
+type TranscriptLog struct {
+	mu          sync.Mutex
+	w           io.Writer
+	incremental bool   // 与 QSOLog 相同：true 表示解码器每次输出新增片段
+	pending     string // 逐字符模式下尚未结束的单词; 完整文本模式下为最近一次的文本
+	written     int    // 完整文本模式下已写出的单词数
+	err         error  // 第一次写入错误，之后不再写入
+	now         func() time.Time
+}
+
+// NewTranscriptLog 创建记录器，incremental 见 CWSystem.IncrementalOutput
+func NewTranscriptLog(w io.Writer, incremental bool) *TranscriptLog {
+	return &TranscriptLog{w: w, incremental: incremental, now: time.Now}
+}
+
+// Observe 处理一次解码输出，有新结束的单词时写入一行
+func (t *TranscriptLog) Observe(text string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.incremental {
+		t.pending += text
+		i := strings.LastIndexByte(t.pending, ' ')
+		if i < 0 {
+			return
+		}
+		done := strings.Fields(t.pending[:i])
+		t.pending = t.pending[i+1:]
+		t.writeLine(done)
+		return
+	}
+
+	t.pending = text
+	words := strings.Fields(text)
+	if len(words) > 0 && !strings.HasSuffix(text, " ") {
+		words = words[:len(words)-1]
+	}
+	if len(words) < t.written {
+		// 解码器重新开始了一段文本
+		t.written = len(words)
+	}
+	t.writeLine(words[t.written:])
+	t.written = len(words)
+}
+
+// Flush 写出最后一个未结束的单词 (会话结束时调用)，返回期间遇到的第一个写入错误
+func (t *TranscriptLog) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	words := strings.Fields(t.pending)
+	if t.incremental {
+		t.writeLine(words)
+		t.pending = ""
+	} else if len(words) > t.written {
+		t.writeLine(words[t.written:])
+		t.written = len(words)
+	}
+	return t.err
+}
+
+func (t *TranscriptLog) writeLine(words []string) {
+	if len(words) == 0 || t.err != nil {
+		return
+	}
+	stamp := t.now().UTC().Format(time.RFC3339)
+	_, t.err = fmt.Fprintf(t.w, "%s %s\n", stamp, strings.Join(words, " "))
+}
@@ -0,0 +1,51 @@
+package cw
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func newTestTranscript(incremental bool) (*TranscriptLog, *bytes.Buffer) {
+	var buf bytes.Buffer
+	tl := NewTranscriptLog(&buf, incremental)
+	tl.now = func() time.Time { return time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC) }
+	return tl, &buf
+}
+
+func TestTranscriptLog_FullText(t *testing.T) {
+	tl, buf := newTestTranscript(false)
+
+	// 未结束的单词不写入，已写出的单词不会因末尾修正而重复
+	tl.Observe("CQ DE BG1A")
+	tl.Observe("CQ DE BG1ABC")
+	tl.Observe("CQ DE BG1ABC K")
+	if err := tl.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "2024-05-01T12:30:00Z CQ DE\n" +
+		"2024-05-01T12:30:00Z BG1ABC\n" +
+		"2024-05-01T12:30:00Z K\n"
+	if got := buf.String(); got != want {
+		t.Errorf("transcript = %q, want %q", got, want)
+	}
+}
+
+func TestTranscriptLog_Incremental(t *testing.T) {
+	tl, buf := newTestTranscript(true)
+
+	for _, s := range []string{"C", "Q", " ", "D", "E", " B", "G1"} {
+		tl.Observe(s)
+	}
+	if err := tl.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "2024-05-01T12:30:00Z CQ\n" +
+		"2024-05-01T12:30:00Z DE\n" +
+		"2024-05-01T12:30:00Z BG1\n"
+	if got := buf.String(); got != want {
+		t.Errorf("transcript = %q, want %q", got, want)
+	}
+}
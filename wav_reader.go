@@ -4,16 +4,100 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strings"
 )
 
-// WavReader 简单的 WAV 文件读取器 (仅支持 16-bit PCM Mono/Stereo)
+// WAV 格式标签
+const (
+	wavFormatPCM        = 1      // 整数 PCM
+	wavFormatFloat      = 3      // IEEE 浮点
+	wavFormatExtensible = 0xFFFE // WAVE_FORMAT_EXTENSIBLE，真实格式在子格式 GUID 中
+)
+
+// ChannelSelect 多声道 WAV 取哪个声道作为单声道输出
+type ChannelSelect int
+
+const (
+	ChannelLeft  ChannelSelect = iota // 第一个声道 (默认)
+	ChannelRight                      // 第二个声道，单声道文件退化为 Left
+	ChannelMix                        // 所有声道取平均
+)
+
+var channelSelectNames = map[ChannelSelect]string{
+	ChannelLeft:  "left",
+	ChannelRight: "right",
+	ChannelMix:   "mix",
+}
+
+func (c ChannelSelect) String() string {
+	if name, ok := channelSelectNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("ChannelSelect(%d)", int(c))
+}
+
+// ParseChannelSelect 将名称 (left/right/mix) 解析为 ChannelSelect
+func ParseChannelSelect(name string) (ChannelSelect, error) {
+	for c, n := range channelSelectNames {
+		if strings.EqualFold(n, name) {
+			return c, nil
+		}
+	}
+	return ChannelLeft, fmt.Errorf("unknown channel %q", name)
+}
+
+// WavReader 简单的 WAV 文件读取器
+// 支持 8-bit (无符号) / 16-bit / 24-bit PCM 与 32-bit float，Mono/Stereo
 type WavReader struct {
-	file       *os.File
-	SampleRate int
-	Channels   int
-	DataSize   int
-	dataStart  int64
+	src           io.Reader // 采样数据来源 (文件或流)
+	closer        io.Closer // Close 时需要关闭的对象，可能为 nil
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	DataSize      int
+	Channel       ChannelSelect // 多声道时输出哪个声道，ReadSamples 始终返回单声道
+	dataStart     int64
+	isFloat       bool
+	seekable      bool // 从文件打开时可以 Seek
+}
+
+// wavFormat fmt chunk 中解析出的格式信息
+type wavFormat struct {
+	formatTag     int
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+}
+
+// parseWavFmt 解析 fmt chunk 的内容 (至少 16 字节)
+func parseWavFmt(fmtData []byte) wavFormat {
+	wf := wavFormat{
+		formatTag:     int(binary.LittleEndian.Uint16(fmtData[0:2])),
+		channels:      int(binary.LittleEndian.Uint16(fmtData[2:4])),
+		sampleRate:    int(binary.LittleEndian.Uint32(fmtData[4:8])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(fmtData[14:16])),
+	}
+	// EXTENSIBLE 格式：子格式 GUID 的前两个字节就是真实的格式标签
+	if wf.formatTag == wavFormatExtensible && len(fmtData) >= 26 {
+		wf.formatTag = int(binary.LittleEndian.Uint16(fmtData[24:26]))
+	}
+	return wf
+}
+
+// validate 检查是否是支持的采样格式
+func (wf wavFormat) validate() error {
+	switch {
+	case wf.formatTag == wavFormatPCM && (wf.bitsPerSample == 8 || wf.bitsPerSample == 16 || wf.bitsPerSample == 24):
+	case wf.formatTag == wavFormatFloat && wf.bitsPerSample == 32:
+	default:
+		return fmt.Errorf("unsupported wav format %d with %d bits (want 8/16/24-bit PCM or 32-bit float)", wf.formatTag, wf.bitsPerSample)
+	}
+	if wf.channels < 1 {
+		return fmt.Errorf("invalid channel count %d", wf.channels)
+	}
+	return nil
 }
 
 func NewWavReader(filename string) (*WavReader, error) {
@@ -34,7 +118,8 @@ func NewWavReader(filename string) (*WavReader, error) {
 		return nil, fmt.Errorf("invalid wav file")
 	}
 
-	var channels, sampleRate, bitsPerSample, dataSize int
+	var wf wavFormat
+	var dataSize int
 	var dataStart int64
 	foundFmt := false
 	foundData := false
@@ -69,9 +154,7 @@ func NewWavReader(filename string) (*WavReader, error) {
 				f.Seek(padding, io.SeekCurrent)
 			}
 
-			channels = int(binary.LittleEndian.Uint16(fmtData[2:4]))
-			sampleRate = int(binary.LittleEndian.Uint32(fmtData[4:8]))
-			bitsPerSample = int(binary.LittleEndian.Uint16(fmtData[14:16]))
+			wf = parseWavFmt(fmtData)
 			foundFmt = true
 		} else if chunkID == "data" {
 			dataSize = int(chunkSize)
@@ -101,9 +184,9 @@ func NewWavReader(filename string) (*WavReader, error) {
 		return nil, fmt.Errorf("invalid wav file: missing fmt or data chunk")
 	}
 
-	if bitsPerSample != 16 {
+	if err := wf.validate(); err != nil {
 		f.Close()
-		return nil, fmt.Errorf("only 16-bit wav supported, got %d", bitsPerSample)
+		return nil, err
 	}
 
 	// 确保文件指针指向 data 开始
@@ -113,48 +196,177 @@ func NewWavReader(filename string) (*WavReader, error) {
 	}
 
 	return &WavReader{
-		file:       f,
-		SampleRate: sampleRate,
-		Channels:   channels,
-		DataSize:   dataSize,
-		dataStart:  dataStart,
+		src:           f,
+		closer:        f,
+		SampleRate:    wf.sampleRate,
+		Channels:      wf.channels,
+		BitsPerSample: wf.bitsPerSample,
+		DataSize:      dataSize,
+		dataStart:     dataStart,
+		isFloat:       wf.formatTag == wavFormatFloat,
+		seekable:      true,
 	}, nil
 }
 
+// NewWavReaderFromStream 从不可 Seek 的流 (管道、网络连接、http.Response.Body 等) 读取 WAV
+// 按顺序解析文件头，要求 fmt chunk 出现在 data chunk 之前；读到 data chunk 即停止解析。
+// 流式录音常把 data 大小写成 0 或 0xFFFFFFFF，这里不依赖它，一直读到 EOF。
+// 如果 r 实现了 io.Closer，Close 时会一并关闭。
+func NewWavReaderFromStream(r io.Reader) (*WavReader, error) {
+	// 读取 RIFF 头
+	riffHeader := make([]byte, 12)
+	if _, err := io.ReadFull(r, riffHeader); err != nil {
+		return nil, err
+	}
+
+	if string(riffHeader[0:4]) != "RIFF" || string(riffHeader[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("invalid wav file")
+	}
+
+	var wf wavFormat
+	foundFmt := false
+
+	for {
+		chunkHeader := make([]byte, 8)
+		if _, err := io.ReadFull(r, chunkHeader); err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("invalid wav stream: missing fmt or data chunk")
+			}
+			return nil, err
+		}
+
+		chunkID := string(chunkHeader[0:4])
+		chunkSize := binary.LittleEndian.Uint32(chunkHeader[4:8])
+
+		// Pad byte if chunk size is odd
+		padding := int64(chunkSize % 2)
+
+		switch chunkID {
+		case "fmt ":
+			if chunkSize < 16 {
+				return nil, fmt.Errorf("fmt chunk too small")
+			}
+			fmtData := make([]byte, chunkSize)
+			if _, err := io.ReadFull(r, fmtData); err != nil {
+				return nil, err
+			}
+			if _, err := io.CopyN(io.Discard, r, padding); err != nil {
+				return nil, err
+			}
+			wf = parseWavFmt(fmtData)
+			foundFmt = true
+		case "data":
+			if !foundFmt {
+				return nil, fmt.Errorf("invalid wav stream: data chunk before fmt chunk")
+			}
+			if err := wf.validate(); err != nil {
+				return nil, err
+			}
+
+			wr := &WavReader{
+				src:           r,
+				SampleRate:    wf.sampleRate,
+				Channels:      wf.channels,
+				BitsPerSample: wf.bitsPerSample,
+				DataSize:      int(chunkSize),
+				isFloat:       wf.formatTag == wavFormatFloat,
+			}
+			if c, ok := r.(io.Closer); ok {
+				wr.closer = c
+			}
+			return wr, nil
+		default:
+			// Skip unknown chunk
+			if _, err := io.CopyN(io.Discard, r, int64(chunkSize)+padding); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
 // ReadSamples 读取音频采样数据并转换为 float32
 // count: 要读取的采样点数 (每个通道)
 func (r *WavReader) ReadSamples(count int) ([]float32, error) {
+	bytesPerSample := r.BitsPerSample / 8
+	frameSize := bytesPerSample * r.Channels
+
 	// 每次读取 count * channels 个采样点
-	totalSamples := count * r.Channels
-	buf := make([]byte, totalSamples*2) // 16-bit = 2 bytes
+	buf := make([]byte, count*frameSize)
 
-	n, err := r.file.Read(buf)
-	if err != nil && err != io.EOF {
+	n, err := io.ReadFull(r.src, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
 		return nil, err
 	}
 	if n == 0 {
 		return nil, io.EOF
 	}
 
-	// 转换
-	// 如果是立体声，我们只取左声道 (或者混合)
-	// 这里简单起见，只取第一个通道
-
-	numFrames := n / (2 * r.Channels)
+	// 转换：按 Channel 选择声道或混合成单声道
+	numFrames := n / frameSize
 	out := make([]float32, numFrames)
 
 	for i := 0; i < numFrames; i++ {
-		// 读取第一个通道的 16-bit 数据
-		offset := i * 2 * r.Channels
-		val := int16(binary.LittleEndian.Uint16(buf[offset : offset+2]))
-
-		// 归一化到 -1.0 ~ 1.0
-		out[i] = float32(val) / 32768.0
+		out[i] = r.decodeFrame(buf[i*frameSize:(i+1)*frameSize], bytesPerSample)
 	}
 
 	return out, nil
 }
 
+// decodeFrame 从一帧交错采样中取出 Channel 指定的声道
+func (r *WavReader) decodeFrame(frame []byte, bytesPerSample int) float32 {
+	switch {
+	case r.Channel == ChannelMix && r.Channels > 1:
+		var sum float32
+		for ch := 0; ch < r.Channels; ch++ {
+			sum += r.decodeSample(frame[ch*bytesPerSample : (ch+1)*bytesPerSample])
+		}
+		return sum / float32(r.Channels)
+	case r.Channel == ChannelRight && r.Channels > 1:
+		return r.decodeSample(frame[bytesPerSample : 2*bytesPerSample])
+	default:
+		return r.decodeSample(frame[:bytesPerSample])
+	}
+}
+
+// decodeSample 将单个采样点解码并归一化到 -1.0 ~ 1.0
+func (r *WavReader) decodeSample(b []byte) float32 {
+	switch r.BitsPerSample {
+	case 8:
+		// 8-bit PCM 是无符号的，以 128 为零点
+		return (float32(b[0]) - 128) / 128.0
+	case 16:
+		val := int16(binary.LittleEndian.Uint16(b))
+		return float32(val) / 32768.0
+	case 24:
+		// 小端有符号 24-bit：拼到 int32 的高 24 位，再算术右移完成符号扩展
+		val := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float32(val) / 8388608.0
+	case 32:
+		return math.Float32frombits(binary.LittleEndian.Uint32(b))
+	}
+	return 0
+}
+
+// Seek 跳到 data 中第 seconds 秒处 (超出范围时钳位到开头或结尾)
+// 只支持文件，流式读取 (NewWavReaderFromStream) 返回错误
+func (r *WavReader) Seek(seconds float64) error {
+	seeker, ok := r.src.(io.Seeker)
+	if !ok || !r.seekable {
+		return fmt.Errorf("wav stream is not seekable")
+	}
+	frameSize := r.BitsPerSample / 8 * r.Channels
+	frame := int64(math.Round(seconds * float64(r.SampleRate)))
+	totalFrames := int64(r.DataSize / frameSize)
+	frame = max(0, min(frame, totalFrames))
+	if _, err := seeker.Seek(r.dataStart+frame*int64(frameSize), io.SeekStart); err != nil {
+		return fmt.Errorf("seek wav: %w", err)
+	}
+	return nil
+}
+
 func (r *WavReader) Close() error {
-	return r.file.Close()
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
 }
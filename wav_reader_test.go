@@ -0,0 +1,234 @@
+package cw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestWav 生成一个最小的 WAV 文件 (fmt + data)
+func writeTestWav(t *testing.T, formatTag, channels, bitsPerSample int, data []byte) string {
+	t.Helper()
+	blockAlign := channels * bitsPerSample / 8
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+len(data)))
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], uint16(formatTag))
+	binary.LittleEndian.PutUint16(header[22:], uint16(channels))
+	binary.LittleEndian.PutUint32(header[24:], 8000)
+	binary.LittleEndian.PutUint32(header[28:], uint32(8000*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:], uint16(bitsPerSample))
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(len(data)))
+
+	path := filepath.Join(t.TempDir(), "test.wav")
+	if err := os.WriteFile(path, append(header, data...), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func readAllSamples(t *testing.T, path string) []float32 {
+	t.Helper()
+	r, err := NewWavReader(path)
+	if err != nil {
+		t.Fatalf("NewWavReader failed: %v", err)
+	}
+	defer r.Close()
+	samples, err := r.ReadSamples(16)
+	if err != nil {
+		t.Fatalf("ReadSamples failed: %v", err)
+	}
+	return samples
+}
+
+func assertSamples(t *testing.T, got, want []float32) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d samples, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if math.Abs(float64(got[i]-want[i])) > 1e-4 {
+			t.Errorf("Sample %d: expected %f, got %f", i, want[i], got[i])
+		}
+	}
+}
+
+func TestWavReader_8Bit(t *testing.T) {
+	path := writeTestWav(t, wavFormatPCM, 1, 8, []byte{128, 255, 0, 192})
+	assertSamples(t, readAllSamples(t, path), []float32{0, 127.0 / 128.0, -1, 0.5})
+}
+
+func TestWavReader_24Bit(t *testing.T) {
+	data := []byte{
+		0x00, 0x00, 0x00, // 0
+		0xFF, 0xFF, 0x7F, // max
+		0x00, 0x00, 0x80, // min
+		0x00, 0x00, 0xC0, // -0.5
+	}
+	path := writeTestWav(t, wavFormatPCM, 1, 24, data)
+	assertSamples(t, readAllSamples(t, path), []float32{0, 8388607.0 / 8388608.0, -1, -0.5})
+}
+
+func TestWavReader_Float32Stereo(t *testing.T) {
+	// 左右声道交错，只取左声道
+	values := []float32{0.25, 0.9, -0.75, 0.9}
+	data := make([]byte, len(values)*4)
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+	path := writeTestWav(t, wavFormatFloat, 2, 32, data)
+	assertSamples(t, readAllSamples(t, path), []float32{0.25, -0.75})
+}
+
+func TestWavReader_ChannelSelect(t *testing.T) {
+	// 16-bit 立体声：左 0.5 / 右 -0.25，两帧
+	frames := []int16{16384, -8192, 16384, -8192}
+	data := make([]byte, len(frames)*2)
+	for i, v := range frames {
+		binary.LittleEndian.PutUint16(data[i*2:], uint16(v))
+	}
+	path := writeTestWav(t, wavFormatPCM, 2, 16, data)
+
+	tests := []struct {
+		channel ChannelSelect
+		want    float32
+	}{
+		{ChannelLeft, 0.5},
+		{ChannelRight, -0.25},
+		{ChannelMix, 0.125},
+	}
+	for _, tt := range tests {
+		r, err := NewWavReader(path)
+		if err != nil {
+			t.Fatalf("NewWavReader failed: %v", err)
+		}
+		r.Channel = tt.channel
+		samples, err := r.ReadSamples(16)
+		r.Close()
+		if err != nil {
+			t.Fatalf("%v: ReadSamples failed: %v", tt.channel, err)
+		}
+		assertSamples(t, samples, []float32{tt.want, tt.want})
+	}
+}
+
+func TestWavReader_ChannelSelectMono(t *testing.T) {
+	// 单声道文件选择 Right 时退化为唯一的声道
+	path := writeTestWav(t, wavFormatPCM, 1, 8, []byte{192, 64})
+	r, err := NewWavReader(path)
+	if err != nil {
+		t.Fatalf("NewWavReader failed: %v", err)
+	}
+	defer r.Close()
+	r.Channel = ChannelRight
+	samples, err := r.ReadSamples(16)
+	if err != nil {
+		t.Fatalf("ReadSamples failed: %v", err)
+	}
+	assertSamples(t, samples, []float32{0.5, -0.5})
+}
+
+func TestParseChannelSelect(t *testing.T) {
+	for _, c := range []ChannelSelect{ChannelLeft, ChannelRight, ChannelMix} {
+		got, err := ParseChannelSelect(c.String())
+		if err != nil || got != c {
+			t.Errorf("ParseChannelSelect(%q) = %v, %v", c.String(), got, err)
+		}
+	}
+	if _, err := ParseChannelSelect("center"); err == nil {
+		t.Error("Expected error for unknown channel")
+	}
+}
+
+func TestWavReader_Unsupported(t *testing.T) {
+	path := writeTestWav(t, wavFormatPCM, 1, 32, make([]byte, 8))
+	if _, err := NewWavReader(path); err == nil {
+		t.Error("32-bit integer PCM should be rejected")
+	}
+}
+
+func TestWavReaderFromStream(t *testing.T) {
+	path := writeTestWav(t, wavFormatPCM, 1, 8, []byte{128, 255, 0, 192})
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 在 fmt 和 data 之间插入一个未知 chunk (奇数长度，带填充字节)
+	list := []byte{'L', 'I', 'S', 'T', 3, 0, 0, 0, 'a', 'b', 'c', 0}
+	stream := append(append(append([]byte{}, data[:36]...), list...), data[36:]...)
+
+	// bytes.Reader 可以 Seek，这里用 io.MultiReader 包一层保证不依赖 Seek
+	r, err := NewWavReaderFromStream(io.MultiReader(bytes.NewReader(stream)))
+	if err != nil {
+		t.Fatalf("NewWavReaderFromStream failed: %v", err)
+	}
+	defer r.Close()
+	if r.SampleRate != 8000 || r.BitsPerSample != 8 {
+		t.Errorf("Unexpected format: %d Hz, %d bits", r.SampleRate, r.BitsPerSample)
+	}
+	samples, err := r.ReadSamples(16)
+	if err != nil {
+		t.Fatalf("ReadSamples failed: %v", err)
+	}
+	assertSamples(t, samples, []float32{0, 127.0 / 128.0, -1, 0.5})
+	if _, err := r.ReadSamples(16); err != io.EOF {
+		t.Errorf("Expected io.EOF at end of stream, got %v", err)
+	}
+}
+
+func TestWavReader_Seek(t *testing.T) {
+	// 8 个 8-bit 采样 (8000Hz)，第 i 个采样值为 128+i
+	data := []byte{128, 129, 130, 131, 132, 133, 134, 135}
+	r, err := NewWavReader(writeTestWav(t, wavFormatPCM, 1, 8, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if err := r.Seek(5.0 / 8000); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	samples, _ := r.ReadSamples(16)
+	assertSamples(t, samples, []float32{5.0 / 128, 6.0 / 128, 7.0 / 128})
+
+	// 超出范围钳位到开头
+	if err := r.Seek(-1); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	if samples, _ := r.ReadSamples(1); len(samples) != 1 || samples[0] != 0 {
+		t.Errorf("Expected to restart from the first sample, got %v", samples)
+	}
+	if err := r.Seek(10); err != nil {
+		t.Fatalf("Seek failed: %v", err)
+	}
+	if _, err := r.ReadSamples(1); err != io.EOF {
+		t.Errorf("Expected io.EOF after seeking past the end, got %v", err)
+	}
+
+	stream, err := NewWavReaderFromStream(bytes.NewReader(mustReadFile(t, writeTestWav(t, wavFormatPCM, 1, 8, data))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Seek(0); err == nil {
+		t.Error("Expected Seek on a stream to fail")
+	}
+}
+
+func mustReadFile(t *testing.T, path string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
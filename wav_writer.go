@@ -2,18 +2,37 @@ package cw
 
 import (
 	"encoding/binary"
+	"fmt"
+	"math"
 	"os"
 )
 
 // WavWriter 简单的 WAV 文件写入器
+// 支持 16-bit PCM 与 32-bit IEEE float，单声道或多声道交错
 type WavWriter struct {
-	file       *os.File
-	sampleRate int
-	dataSize   int
+	file          *os.File
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	dataSize      int
 }
 
-// NewWavWriter 创建新的 WAV 写入器
+// NewWavWriter 创建新的 WAV 写入器 (16-bit PCM 单声道)
 func NewWavWriter(filename string, sampleRate int) (*WavWriter, error) {
+	return NewWavWriterFormat(filename, sampleRate, 1, 16)
+}
+
+// NewWavWriterFormat 创建指定格式的 WAV 写入器
+// channels: 声道数 (>= 1)
+// bitsPerSample: 16 (PCM) 或 32 (IEEE float，无量化损失)
+func NewWavWriterFormat(filename string, sampleRate, channels, bitsPerSample int) (*WavWriter, error) {
+	if channels < 1 {
+		return nil, fmt.Errorf("invalid channel count %d", channels)
+	}
+	if bitsPerSample != 16 && bitsPerSample != 32 {
+		return nil, fmt.Errorf("unsupported bits per sample %d (want 16 or 32)", bitsPerSample)
+	}
+
 	f, err := os.Create(filename)
 	if err != nil {
 		return nil, err
@@ -28,25 +47,48 @@ func NewWavWriter(filename string, sampleRate int) (*WavWriter, error) {
 	}
 
 	return &WavWriter{
-		file:       f,
-		sampleRate: sampleRate,
-		dataSize:   0,
+		file:          f,
+		sampleRate:    sampleRate,
+		channels:      channels,
+		bitsPerSample: bitsPerSample,
+		dataSize:      0,
 	}, nil
 }
 
 // WriteSamples 写入音频采样数据 (float32)
+// 多声道时，同一路信号会复制到每个声道
 func (w *WavWriter) WriteSamples(samples []float32) error {
-	// 将 float32 (-1.0 ~ 1.0) 转换为 int16
-	buf := make([]byte, len(samples)*2)
-	for i, s := range samples {
-		// 简单的限幅
-		if s > 1.0 {
-			s = 1.0
-		} else if s < -1.0 {
-			s = -1.0
+	if w.channels == 1 {
+		return w.WriteChannels(samples)
+	}
+	chans := make([][]float32, w.channels)
+	for i := range chans {
+		chans[i] = samples
+	}
+	return w.WriteChannels(chans...)
+}
+
+// WriteChannels 按声道写入采样数据，并交错成 L R L R ... 的帧顺序
+// 参数个数必须等于声道数，且每个声道长度相同
+func (w *WavWriter) WriteChannels(channels ...[]float32) error {
+	if len(channels) != w.channels {
+		return fmt.Errorf("expected %d channels, got %d", w.channels, len(channels))
+	}
+	numFrames := len(channels[0])
+	for _, ch := range channels[1:] {
+		if len(ch) != numFrames {
+			return fmt.Errorf("channel length mismatch: %d vs %d", len(ch), numFrames)
+		}
+	}
+
+	bytesPerSample := w.bitsPerSample / 8
+	buf := make([]byte, numFrames*w.channels*bytesPerSample)
+	offset := 0
+	for i := 0; i < numFrames; i++ {
+		for _, ch := range channels {
+			w.encodeSample(buf[offset:], ch[i])
+			offset += bytesPerSample
 		}
-		val := int16(s * 32767)
-		binary.LittleEndian.PutUint16(buf[i*2:], uint16(val))
 	}
 
 	n, err := w.file.Write(buf)
@@ -57,6 +99,24 @@ func (w *WavWriter) WriteSamples(samples []float32) error {
 	return nil
 }
 
+// encodeSample 将单个 float32 采样点编码到 buf
+func (w *WavWriter) encodeSample(buf []byte, s float32) {
+	if w.bitsPerSample == 32 {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(s))
+		return
+	}
+
+	// 将 float32 (-1.0 ~ 1.0) 转换为 int16
+	// 简单的限幅
+	if s > 1.0 {
+		s = 1.0
+	} else if s < -1.0 {
+		s = -1.0
+	}
+	val := int16(s * 32767)
+	binary.LittleEndian.PutUint16(buf, uint16(val))
+}
+
 // Close 关闭文件并回写 WAV 头
 func (w *WavWriter) Close() error {
 	// 回写 WAV 头
@@ -65,6 +125,12 @@ func (w *WavWriter) Close() error {
 	// fmt chunk
 	// data chunk
 
+	formatTag := wavFormatPCM
+	if w.bitsPerSample == 32 {
+		formatTag = wavFormatFloat
+	}
+	blockAlign := w.channels * w.bitsPerSample / 8
+
 	totalSize := 36 + w.dataSize
 	header := make([]byte, 44)
 
@@ -75,13 +141,13 @@ func (w *WavWriter) Close() error {
 
 	// fmt chunk
 	copy(header[12:], []byte("fmt "))
-	binary.LittleEndian.PutUint32(header[16:], 16) // Subchunk1Size (16 for PCM)
-	binary.LittleEndian.PutUint16(header[20:], 1)  // AudioFormat (1 for PCM)
-	binary.LittleEndian.PutUint16(header[22:], 1)  // NumChannels (1 for Mono)
-	binary.LittleEndian.PutUint32(header[24:], uint32(w.sampleRate)) // SampleRate
-	binary.LittleEndian.PutUint32(header[28:], uint32(w.sampleRate*2)) // ByteRate (SampleRate * NumChannels * BitsPerSample/8)
-	binary.LittleEndian.PutUint16(header[32:], 2)  // BlockAlign (NumChannels * BitsPerSample/8)
-	binary.LittleEndian.PutUint16(header[34:], 16) // BitsPerSample
+	binary.LittleEndian.PutUint32(header[16:], 16)                              // Subchunk1Size (16 for PCM)
+	binary.LittleEndian.PutUint16(header[20:], uint16(formatTag))               // AudioFormat (1 for PCM, 3 for IEEE float)
+	binary.LittleEndian.PutUint16(header[22:], uint16(w.channels))              // NumChannels
+	binary.LittleEndian.PutUint32(header[24:], uint32(w.sampleRate))            // SampleRate
+	binary.LittleEndian.PutUint32(header[28:], uint32(w.sampleRate*blockAlign)) // ByteRate (SampleRate * NumChannels * BitsPerSample/8)
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))              // BlockAlign (NumChannels * BitsPerSample/8)
+	binary.LittleEndian.PutUint16(header[34:], uint16(w.bitsPerSample))         // BitsPerSample
 
 	// data chunk
 	copy(header[36:], []byte("data"))
@@ -0,0 +1,68 @@
+package cw
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWavWriter_Float32RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "float.wav")
+	w, err := NewWavWriterFormat(path, 8000, 1, 32)
+	if err != nil {
+		t.Fatalf("NewWavWriterFormat failed: %v", err)
+	}
+	// float32 不限幅，也没有量化误差
+	want := []float32{0.123456, -0.5, 1.5}
+	if err := w.WriteSamples(want); err != nil {
+		t.Fatalf("WriteSamples failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	r, err := NewWavReader(path)
+	if err != nil {
+		t.Fatalf("NewWavReader failed: %v", err)
+	}
+	defer r.Close()
+	if r.SampleRate != 8000 || r.Channels != 1 || r.BitsPerSample != 32 {
+		t.Errorf("Unexpected format: %d Hz, %d ch, %d bits", r.SampleRate, r.Channels, r.BitsPerSample)
+	}
+	got, err := r.ReadSamples(16)
+	if err != nil {
+		t.Fatalf("ReadSamples failed: %v", err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Sample %d: expected %f, got %f", i, want[i], got[i])
+		}
+	}
+}
+
+func TestWavWriter_StereoInterleave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stereo.wav")
+	w, err := NewWavWriterFormat(path, 8000, 2, 16)
+	if err != nil {
+		t.Fatalf("NewWavWriterFormat failed: %v", err)
+	}
+	if err := w.WriteChannels([]float32{0.5, -0.5}, []float32{0.25, 0.25}); err != nil {
+		t.Fatalf("WriteChannels failed: %v", err)
+	}
+	if err := w.WriteChannels([]float32{0.5}); err == nil {
+		t.Error("Expected error for wrong channel count")
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	r, err := NewWavReader(path)
+	if err != nil {
+		t.Fatalf("NewWavReader failed: %v", err)
+	}
+	defer r.Close()
+	if r.Channels != 2 || r.DataSize != 8 {
+		t.Errorf("Expected 2 channels and 8 data bytes, got %d / %d", r.Channels, r.DataSize)
+	}
+	// WavReader 只取左声道
+	assertSamples(t, readAllSamples(t, path), []float32{0.5, -0.5})
+}
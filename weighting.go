@@ -0,0 +1,83 @@
+package cw
+
+import (
+	"cw/BeamDecoder"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// weightingMinMarks 校准至少需要的 Mark 个数
+const weightingMinMarks = 10
+
+// CalibrateWeighting 用一段已知内容的录音校准发报者的点划比例：
+// 先把录音送入解码器 (ProcessAudioChunk)，再以录音的原文 knownText 调用本方法。
+// 按原文中点、划和字符内间隔的个数，从触发器记录的时长 (见 DumpTimingHistogram) 中分出三类，
+// 取中位数得到实际的 划/点 和 间隔/点 比，之后的发射分按这个比例打分 (Reset 后保留)。
+// 适合划不是点 3 倍的机械键 (bug) 或调过比重的电键。结果同时写入配置的 Decoder.DahRatio / GapRatio，
+// 可以用 Config.Save 保存下来，以后直接加载。
+func (d *ExperimentalDecoder) CalibrateWeighting(knownText string) (BeamDecoder.Weighting, error) {
+	w, err := measureWeighting(knownText, d.markDurations, d.spaceDurations)
+	if err != nil {
+		return w, err
+	}
+	if err := d.beam.SetWeighting(w); err != nil {
+		return w, err
+	}
+	d.cfg.Decoder.DahRatio = w.DahRatio
+	d.cfg.Decoder.GapRatio = w.GapRatio
+	logger.Info("weighting calibrated", "dah_ratio", w.DahRatio, "gap_ratio", w.GapRatio)
+	return w, nil
+}
+
+// measureWeighting 按原文中点/划/元素间隔的比例切分实测时长 (ms)，返回中位数之比
+// 按比例而不是按顺序对齐：噪声产生的毛刺或漏掉的元素只会让切分点移动一点，不会让整段错位。
+func measureWeighting(knownText string, marks, spaces []float64) (BeamDecoder.Weighting, error) {
+	var dits, dahs, gaps, boundaries int
+	words := strings.Fields(strings.ToUpper(knownText))
+	for wi, word := range words {
+		chars := 0
+		for _, r := range word {
+			code, ok := morseEncodeTable[r]
+			if !ok {
+				continue
+			}
+			dots := strings.Count(code, ".")
+			dits += dots
+			dahs += len(code) - dots
+			gaps += len(code) - 1
+			chars++
+		}
+		// 字符间隔和单词间隔
+		boundaries += max(chars-1, 0)
+		if wi > 0 && chars > 0 {
+			boundaries++
+		}
+	}
+	if dits == 0 || dahs == 0 {
+		return BeamDecoder.Weighting{}, fmt.Errorf("known text %q needs both dits and dahs", knownText)
+	}
+	if len(marks) < weightingMinMarks {
+		return BeamDecoder.Weighting{}, fmt.Errorf("only %d marks recorded, need at least %d", len(marks), weightingMinMarks)
+	}
+
+	sortedMarks := slices.Sorted(slices.Values(marks))
+	split := int(math.Round(float64(len(sortedMarks)) * float64(dits) / float64(dits+dahs)))
+	split = min(max(split, 1), len(sortedMarks)-1)
+	dit := percentile(sortedMarks[:split], 0.5)
+	dah := percentile(sortedMarks[split:], 0.5)
+
+	w := BeamDecoder.Weighting{DahRatio: dah / dit}
+	// 元素间隔是最短的一类空窗 (录音开头的静音等多出来的空窗都比它长)
+	if gaps > 0 && len(spaces) > 0 {
+		sortedSpaces := slices.Sorted(slices.Values(spaces))
+		n := int(math.Round(float64(len(sortedSpaces)) * float64(gaps) / float64(gaps+boundaries)))
+		n = min(max(n, 1), len(sortedSpaces))
+		w.GapRatio = percentile(sortedSpaces[:n], 0.5) / dit
+	}
+	if err := w.Validate(); err != nil {
+		return BeamDecoder.Weighting{}, fmt.Errorf("dits and dahs not separable: %w", err)
+	}
+	return w, nil
+}
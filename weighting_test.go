@@ -0,0 +1,72 @@
+package cw
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestExperimentalDecoder_CalibrateWeighting(t *testing.T) {
+	const sampleRate = 8000
+	record := func(text string, dahRatio float64) []float32 {
+		audio := GenerateCW(text, AudioConfig{WPM: 20, SampleRate: sampleRate, Frequency: 700, DahRatio: dahRatio})
+		return noisyRecording(audio, sampleRate, 1, ChannelEffects{SNRdB: 20, Seed: 1})
+	}
+
+	// 划偏短 (1.8 倍点长，机械键) 和偏长 (4.5 倍) 的发报者：先发一段已知内容校准，再解码另一段
+	const known = "PARIS PARIS PARIS QUICK"
+	const message = "THE QUICK BROWN FOX"
+	for _, ratio := range []float64{1.8, 4.5} {
+		t.Run(fmt.Sprintf("1:%.1f", ratio), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Decoder.InitialWPM = 20
+			dec := newExperimentalDecoder(sampleRate, 700, cfg, newTestLanguageModel())
+			defer dec.Stop()
+
+			if got := decodeSamples(dec, record(message, ratio)); got == message {
+				t.Errorf("Expected the 1:3 template to fail, got %q", got)
+			}
+			dec.Reset()
+
+			decodeSamples(dec, record(known, ratio))
+			w, err := dec.CalibrateWeighting(known)
+			if err != nil {
+				t.Fatalf("CalibrateWeighting: %v", err)
+			}
+			// 触发器测得的 Mark 比发送时略长、间隔略短，比例不会正好等于发送的比例
+			if math.Abs(w.DahRatio-ratio) > 0.3 || math.Abs(w.GapRatio-1) > 0.2 {
+				t.Errorf("Expected ~%.1f dah ratio and ~1 gap ratio, got %+v", ratio, w)
+			}
+			if dec.cfg.Decoder.DahRatio != w.DahRatio {
+				t.Errorf("Expected the calibration to be stored in the config, got %.2f", dec.cfg.Decoder.DahRatio)
+			}
+
+			dec.Reset()
+			if got := decodeSamples(dec, record(message, ratio)); got != message {
+				t.Errorf("After calibration got %q, want %q", got, message)
+			}
+		})
+	}
+}
+
+func TestMeasureWeighting_Errors(t *testing.T) {
+	marks := []float64{60, 180, 60, 60, 180, 60, 60, 180, 60, 180, 60, 60}
+	if _, err := measureWeighting("EEE III", marks, nil); err == nil {
+		t.Error("Expected error for a text without dahs")
+	}
+	if _, err := measureWeighting("PARIS", marks[:4], nil); err == nil {
+		t.Error("Expected error for too few marks")
+	}
+	// 点划长度完全一样，无法区分
+	same := []float64{60, 60, 60, 60, 60, 60, 60, 60, 60, 60, 60, 60}
+	if _, err := measureWeighting("PARIS", same, nil); err == nil {
+		t.Error("Expected error when dits and dahs have the same length")
+	}
+	w, err := measureWeighting("PARIS", marks, []float64{60, 60, 60, 60, 180, 180})
+	if err != nil {
+		t.Fatalf("measureWeighting: %v", err)
+	}
+	if w.DahRatio != 3 || w.GapRatio != 1 {
+		t.Errorf("Expected 1:3 with unit gaps, got %+v", w)
+	}
+}